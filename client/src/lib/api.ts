@@ -9,6 +9,17 @@ export const apiService = axios.create({
   },
 });
 
+const TOKEN_KEY = 'financebros.token';
+
+// Send the token from the last login with every request
+apiService.interceptors.request.use((config) => {
+  const token = localStorage.getItem(TOKEN_KEY);
+  if (token) {
+    config.headers.Authorization = `Bearer ${token}`;
+  }
+  return config;
+});
+
 // Add response interceptor for better error handling
 apiService.interceptors.response.use(
   (response) => response,
@@ -22,6 +33,28 @@ apiService.interceptors.response.use(
   }
 );
 
+// Auth Endpoints
+export const register = async (username: string, password: string) => {
+    const response = await apiService.post('/api/auth/register', { username, password });
+    localStorage.setItem(TOKEN_KEY, response.data.token);
+    return response.data;
+};
+
+export const login = async (username: string, password: string) => {
+    const response = await apiService.post('/api/auth/login', { username, password });
+    localStorage.setItem(TOKEN_KEY, response.data.token);
+    return response.data;
+};
+
+export const logout = () => {
+    localStorage.removeItem(TOKEN_KEY);
+};
+
+export const claimAccount = async (accountId: string, claimCode: string) => {
+    const response = await apiService.post('/api/auth/accounts', { account_id: accountId, claim_code: claimCode });
+    return response.data;
+};
+
 export const getUser = async (accountId: string) => {
     try {
         const response = await apiService.get(`/api/user/${accountId}`);
@@ -3,10 +3,18 @@ package handler
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"server/analytics/repository"
 	"server/analytics/service"
+	"server/fx/rates"
+	fxRepository "server/fx/repository"
+	fxService "server/fx/service"
+	ownersHandler "server/owners/handler"
+	ownersRepository "server/owners/repository"
+	ownersService "server/owners/service"
+	"server/types"
 	"time"
 
 	"github.com/gorilla/mux"
@@ -14,17 +22,20 @@ import (
 
 type Handler struct {
 	service service.Service
+	owners  ownersService.Service
 }
 
-func NewHandler(service service.Service) *Handler {
-	return &Handler{service: service}
+func NewHandler(service service.Service, owners ownersService.Service) *Handler {
+	return &Handler{service: service, owners: owners}
 }
 
 // SetupRoutes configures all the analytics-related routes for the API
 func SetupRoutes(router *mux.Router, db *sql.DB) {
 	repo := repository.NewPostgresRepository(db)
-	svc := service.NewService(repo)
-	handler := NewHandler(svc)
+	fx := fxService.NewService(fxRepository.NewPostgresRepository(db))
+	svc := service.NewService(repo, fx)
+	owners := ownersService.NewService(ownersRepository.NewPostgresRepository(db))
+	handler := NewHandler(svc, owners)
 
 	// Register all routes
 	handler.RegisterRoutes(router)
@@ -36,28 +47,40 @@ func (h *Handler) RegisterRoutes(router *mux.Router) {
 	router.HandleFunc("/api/predictions/{accountId}", h.HandlePredictions).Methods("GET")
 	router.HandleFunc("/api/patterns/{accountId}", h.HandleTimePatterns).Methods("GET")
 	router.HandleFunc("/api/insights/{accountId}", h.HandleInsights).Methods("GET")
+
+	// The same views across every account linked to an owner
+	router.HandleFunc("/api/owners/{ownerId:[0-9]+}/analytics", h.HandleSpendingAnalytics).Methods("GET")
+	router.HandleFunc("/api/owners/{ownerId:[0-9]+}/predictions", h.HandlePredictions).Methods("GET")
+	router.HandleFunc("/api/owners/{ownerId:[0-9]+}/patterns", h.HandleTimePatterns).Methods("GET")
+	router.HandleFunc("/api/owners/{ownerId:[0-9]+}/insights", h.HandleInsights).Methods("GET")
 }
 
 // HandleSpendingAnalytics handles requests for spending analytics
 func (h *Handler) HandleSpendingAnalytics(w http.ResponseWriter, r *http.Request) {
 	log.Printf("Handling spending analytics request: %s", r.URL.String())
 	
-	vars := mux.Vars(r)
-	accountID := vars["accountId"]
+	accountIDs, currency, err := ownersHandler.RequestAccounts(r, h.owners)
+	if err != nil {
+		writeError(w, "Failed to analyze spending", err)
+		return
+	}
 	timeRange := r.URL.Query().Get("timeRange")
 	if timeRange == "" {
 		timeRange = "1 month"
 		log.Printf("Using default time range: %s", timeRange)
 	}
 
-	analytics, err := h.service.AnalyzeSpending(r.Context(), accountID, timeRange)
+	if requested := r.URL.Query().Get("currency"); requested != "" {
+		currency = requested
+	}
+
+	analytics, err := h.service.AnalyzeSpending(r.Context(), accountIDs, timeRange, currency)
 	if err != nil {
-		log.Printf("Error analyzing spending: %v", err)
-		http.Error(w, "Failed to analyze spending", http.StatusInternalServerError)
+		writeError(w, "Failed to analyze spending", err)
 		return
 	}
 
-	log.Printf("Successfully analyzed spending for account %s", accountID)
+	log.Printf("Successfully analyzed spending for accounts %v", accountIDs)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(analytics)
 }
@@ -66,22 +89,27 @@ func (h *Handler) HandleSpendingAnalytics(w http.ResponseWriter, r *http.Request
 func (h *Handler) HandleTimePatterns(w http.ResponseWriter, r *http.Request) {
 	log.Printf("Handling time patterns request: %s", r.URL.String())
 	
-	vars := mux.Vars(r)
-	accountID := vars["accountId"]
+	accountIDs, currency, err := ownersHandler.RequestAccounts(r, h.owners)
+	if err != nil {
+		writeError(w, "Failed to get time patterns", err)
+		return
+	}
+	if requested := r.URL.Query().Get("currency"); requested != "" {
+		currency = requested
+	}
 
 	// Default to last month if no dates provided
 	endDate := time.Now()
 	startDate := endDate.AddDate(0, -1, 0)
 	log.Printf("Using date range: %s to %s", startDate.Format(time.RFC3339), endDate.Format(time.RFC3339))
 
-	patterns, err := h.service.GetTimePatterns(r.Context(), accountID, startDate, endDate)
+	patterns, err := h.service.GetTimePatterns(r.Context(), accountIDs, startDate, endDate, currency)
 	if err != nil {
-		log.Printf("Error getting time patterns: %v", err)
-		http.Error(w, "Failed to get time patterns", http.StatusInternalServerError)
+		writeError(w, "Failed to get time patterns", err)
 		return
 	}
 
-	log.Printf("Successfully retrieved time patterns for account %s", accountID)
+	log.Printf("Successfully retrieved time patterns for accounts %v", accountIDs)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(patterns)
 }
@@ -90,17 +118,22 @@ func (h *Handler) HandleTimePatterns(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) HandlePredictions(w http.ResponseWriter, r *http.Request) {
 	log.Printf("Handling predictions request: %s", r.URL.String())
 	
-	vars := mux.Vars(r)
-	accountID := vars["accountId"]
+	accountIDs, currency, err := ownersHandler.RequestAccounts(r, h.owners)
+	if err != nil {
+		writeError(w, "Failed to predict spending", err)
+		return
+	}
+	if requested := r.URL.Query().Get("currency"); requested != "" {
+		currency = requested
+	}
 
-	predictions, err := h.service.PredictSpending(r.Context(), accountID)
+	predictions, err := h.service.PredictSpending(r.Context(), accountIDs, currency)
 	if err != nil {
-		log.Printf("Error predicting spending: %v", err)
-		http.Error(w, "Failed to predict spending", http.StatusInternalServerError)
+		writeError(w, "Failed to predict spending", err)
 		return
 	}
 
-	log.Printf("Successfully generated predictions for account %s", accountID)
+	log.Printf("Successfully generated predictions for accounts %v", accountIDs)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(predictions)
 }
@@ -109,21 +142,26 @@ func (h *Handler) HandlePredictions(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) HandleInsights(w http.ResponseWriter, r *http.Request) {
 	log.Printf("Handling insights request: %s", r.URL.String())
 	
-	vars := mux.Vars(r)
-	accountID := vars["accountId"]
+	accountIDs, currency, err := ownersHandler.RequestAccounts(r, h.owners)
+	if err != nil {
+		writeError(w, "Failed to get analytics", err)
+		return
+	}
+	if requested := r.URL.Query().Get("currency"); requested != "" {
+		currency = requested
+	}
 
 	// Get analytics for the past month
-	analytics, err := h.service.AnalyzeSpending(r.Context(), accountID, "1 month")
+	analytics, err := h.service.AnalyzeSpending(r.Context(), accountIDs, "1 month", currency)
 	if err != nil {
-		log.Printf("Error getting analytics: %v", err)
-		http.Error(w, "Failed to get analytics", http.StatusInternalServerError)
+		writeError(w, "Failed to get analytics", err)
 		return
 	}
 
 	// Transform analytics data into insights format
-	var insightData []map[string]string
+	var insightData []map[string]interface{}
 	for _, cat := range analytics.TopCategories {
-		insightData = append(insightData, map[string]string{
+		insightData = append(insightData, map[string]interface{}{
 			"category":   cat.Category,
 			"totalSpent": cat.TotalSpent,
 			"percentage": cat.Percentage,
@@ -143,10 +181,27 @@ func (h *Handler) HandleInsights(w http.ResponseWriter, r *http.Request) {
 		"insights":       insights,
 		"totalSpent":     analytics.TotalSpent,
 		"monthlyAverage": analytics.MonthlyAverage,
+		"currency":       analytics.Currency,
 	}
 
-	log.Printf("Successfully generated insights for account %s", accountID)
+	log.Printf("Successfully generated insights for accounts %v", accountIDs)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 } 
 
+// writeError converts service errors into HTTP responses: an invalid or
+// unconvertible reporting currency is the client's error, anything else is ours
+func writeError(w http.ResponseWriter, message string, err error) {
+	var validationErr *types.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		http.Error(w, validationErr.Error(), http.StatusBadRequest)
+	case errors.Is(err, rates.ErrNoRate):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, ownersRepository.ErrOwnerNotFound):
+		http.Error(w, "Owner not found", http.StatusNotFound)
+	default:
+		log.Printf("%s: %v", message, err)
+		http.Error(w, message, http.StatusInternalServerError)
+	}
+}
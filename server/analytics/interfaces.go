@@ -14,56 +14,60 @@ type UserInfo struct {
 // Service defines the interface for analytics operations
 type Service interface {
 	// AnalyzeSpending analyzes spending patterns for a given account and time range
-	AnalyzeSpending(ctx context.Context, accountID string, timeRange string) (*types.SpendingAnalytics, error)
+	AnalyzeSpending(ctx context.Context, accountIDs []string, timeRange string, currency string) (*types.SpendingAnalytics, error)
 	
-	// GetTimePatterns analyzes spending patterns by time of day and day of week
-	GetTimePatterns(ctx context.Context, accountID string, startDate, endDate time.Time) ([]types.TimePattern, error)
+	// GetTimePatterns analyzes spending patterns by time of day and day of
+	// week, with amounts in the given currency
+	GetTimePatterns(ctx context.Context, accountIDs []string, startDate, endDate time.Time, currency string) ([]types.TimePattern, error)
 	
-	// PredictSpending generates spending predictions for each category
-	PredictSpending(ctx context.Context, accountID string) ([]types.PredictedSpend, error)
+	// PredictSpending generates spending predictions for each category,
+	// weighing amounts in the given currency
+	PredictSpending(ctx context.Context, accountIDs []string, currency string) ([]types.PredictedSpend, error)
 
 	// GetMonthlyIncome retrieves income transactions for a specific month
-	GetMonthlyIncome(ctx context.Context, accountID string, year int, month int) ([]types.Transaction, error)
+	GetMonthlyIncome(ctx context.Context, accountIDs []string, year int, month int) ([]types.Transaction, error)
 
 	// GetBillPayments retrieves bill payment transactions for a specific month
-	GetBillPayments(ctx context.Context, accountID string, year int, month int) ([]types.Transaction, error)
+	GetBillPayments(ctx context.Context, accountIDs []string, year int, month int) ([]types.Transaction, error)
 
-	// GetDailyPatterns retrieves daily spending patterns for a specific month
-	GetDailyPatterns(ctx context.Context, accountID string, year int, month int) ([]types.DailyPattern, error)
+	// GetDailyPatterns retrieves daily spending patterns for a specific
+	// month, with amounts in the given currency
+	GetDailyPatterns(ctx context.Context, accountIDs []string, year int, month int, currency string) ([]types.DailyPattern, error)
 
-	// GetMonthlyPatterns retrieves monthly spending patterns for a specific month
-	GetMonthlyPatterns(ctx context.Context, accountID string, year int, month int) ([]types.MonthlyPattern, error)
+	// GetMonthlyPatterns retrieves monthly spending patterns for a specific
+	// month, with amounts in the given currency
+	GetMonthlyPatterns(ctx context.Context, accountIDs []string, year int, month int, currency string) ([]types.MonthlyPattern, error)
 	
 }
 
 // Repository defines the interface for analytics data operations
 type Repository interface {
 	// GetTransactions retrieves transactions for analysis
-	GetTransactions(ctx context.Context, accountID string, timeRange string) ([]types.Transaction, error)
+	GetTransactions(ctx context.Context, accountIDs []string, timeRange string) ([]types.Transaction, error)
 	
-	// GetCategoryTotals retrieves total spending by category
-	GetCategoryTotals(ctx context.Context, accountID string, timeRange string) (map[string]float64, error)
+	// GetCategoryTotals retrieves total spending by category, per original currency
+	GetCategoryTotals(ctx context.Context, accountIDs []string, timeRange string) (map[string]types.Amounts, error)
 	
 	// GetAccount retrieves account information
 	GetAccount(ctx context.Context, accountID string) (*types.Account, error) 
 
 	// GetMonthlyIncome retrieves income transactions for a specific month
-	GetMonthlyIncome(ctx context.Context, accountID string, year int, month int) ([]types.Transaction, error)
+	GetMonthlyIncome(ctx context.Context, accountIDs []string, year int, month int) ([]types.Transaction, error)
 
 	// GetBillPayments retrieves bill payment transactions for a specific month
-	GetBillPayments(ctx context.Context, accountID string, year int, month int) ([]types.Transaction, error)
+	GetBillPayments(ctx context.Context, accountIDs []string, year int, month int) ([]types.Transaction, error)
 
 	// GetRecentSpending retrieves and analyzes spending data for the recent period
-	GetRecentSpending(ctx context.Context, accountID string, startDate, endDate time.Time) ([]types.Transaction, error)
+	GetRecentSpending(ctx context.Context, accountIDs []string, startDate, endDate time.Time) ([]types.Transaction, error)
 
 	// GetDailySpending retrieves daily spending transactions for a specific month
-	GetDailySpending(ctx context.Context, accountID string, year int, month int) ([]types.Transaction, error)
+	GetDailySpending(ctx context.Context, accountIDs []string, year int, month int) ([]types.Transaction, error)
 
 	// GetMonthlySpending retrieves monthly spending transactions for a specific month
-	GetMonthlySpending(ctx context.Context, accountID string, year int, month int) ([]types.Transaction, error)
+	GetMonthlySpending(ctx context.Context, accountIDs []string, year int, month int) ([]types.Transaction, error)
 
 	// GetCategoryDiversity retrieves category diversity for a specific month
-	GetCategoryDiversity(ctx context.Context, accountID string, year int, month int) (map[string]int, error)
+	GetCategoryDiversity(ctx context.Context, accountIDs []string, year int, month int) (map[string]int, error)
 
 	
 	
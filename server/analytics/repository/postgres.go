@@ -7,6 +7,8 @@ import (
 	"log"
 	"server/types"
 	"strings"
+
+	"github.com/lib/pq"
 )
 
 type postgresRepo struct {
@@ -55,26 +57,33 @@ func (r *postgresRepo) GetAccount(ctx context.Context, accountID string) (*types
 		log.Printf("Error fetching account: %v", err)
 		return nil, fmt.Errorf("failed to fetch account: %w", err)
 	}
+	account.Balance.Current.Currency = account.Balance.Currency
+	account.Balance.Available.Currency = account.Balance.Currency
 
 	log.Printf("Successfully retrieved account information for ID: %s", accountID)
 	return account, nil
 }
 
-func (r *postgresRepo) GetTransactions(ctx context.Context, accountID string, timeRange string) ([]types.Transaction, error) {
-	if accountID == "" {
-		return nil, fmt.Errorf("account ID is required")
+// GetTransactions retrieves the spending lines of the accounts over the time
+// range: one line per split of a split transaction, so that each split is
+// analyzed under its own category
+func (r *postgresRepo) GetTransactions(ctx context.Context, accountIDs []string, timeRange string) ([]types.Transaction, error) {
+	if len(accountIDs) == 0 {
+		return nil, fmt.Errorf("at least one account ID is required")
 	}
 
-	log.Printf("Fetching transactions for account %s with time range %s", accountID, timeRange)
+	log.Printf("Fetching transactions for accounts %v with time range %s", accountIDs, timeRange)
 
 	query := `
-		SELECT transaction_id, account_id, date, amount, category, merchant, location
-		FROM transactions 
-		WHERE account_id = $1 
+		SELECT transaction_id, account_id, date, amount, category, merchant, location, currency
+		FROM transaction_lines 
+		WHERE account_id = ANY($1) 
+		  AND transfer_id IS NULL
+		  AND category_type(account_id, category) IS DISTINCT FROM 'transfer'
 		  AND date >= NOW() - $2::INTERVAL
 		ORDER BY date DESC`
 	
-	rows, err := r.db.QueryContext(ctx, query, accountID, timeRange)
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(accountIDs), timeRange)
 	if err != nil {
 		log.Printf("Error querying transactions: %v", err)
 		return nil, fmt.Errorf("failed to query transactions: %w", err)
@@ -93,6 +102,7 @@ func (r *postgresRepo) GetTransactions(ctx context.Context, accountID string, ti
 			&t.Category,
 			&t.Merchant,
 			&t.Location,
+			&t.Currency,
 		); err != nil {
 			log.Printf("Error scanning transaction: %v", err)
 			return nil, fmt.Errorf("failed to scan transaction: %w", err)
@@ -115,41 +125,48 @@ func (r *postgresRepo) GetTransactions(ctx context.Context, accountID string, ti
 		return nil, fmt.Errorf("error iterating transactions: %w", err)
 	}
 
-	log.Printf("Found %d transactions for account %s", len(transactions), accountID)
+	log.Printf("Found %d transactions for accounts %v", len(transactions), accountIDs)
 	return transactions, nil
 }
 
-func (r *postgresRepo) GetCategoryTotals(ctx context.Context, accountID string, timeRange string) (map[string]float64, error) {
-	if accountID == "" {
-		return nil, fmt.Errorf("account ID is required")
+// GetCategoryTotals retrieves total spending by category, counting each split
+// of a split transaction under its own category
+func (r *postgresRepo) GetCategoryTotals(ctx context.Context, accountIDs []string, timeRange string) (map[string]types.Amounts, error) {
+	if len(accountIDs) == 0 {
+		return nil, fmt.Errorf("at least one account ID is required")
 	}
 
-	log.Printf("Fetching category totals for account %s with time range %s", accountID, timeRange)
+	log.Printf("Fetching category totals for accounts %v with time range %s", accountIDs, timeRange)
 
 	query := `
-		SELECT category, COALESCE(SUM(ABS(amount)), 0) as total
-		FROM transactions 
-		WHERE account_id = $1 
+		SELECT category, currency, COALESCE(SUM(ABS(amount)), 0) as total
+		FROM transaction_lines 
+		WHERE account_id = ANY($1) 
+		  AND transfer_id IS NULL
+		  AND category_type(account_id, category) IS DISTINCT FROM 'transfer'
 		  AND date >= NOW() - $2::INTERVAL
-		GROUP BY category
+		GROUP BY category, currency
 		ORDER BY total DESC`
 	
-	rows, err := r.db.QueryContext(ctx, query, accountID, timeRange)
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(accountIDs), timeRange)
 	if err != nil {
 		log.Printf("Error querying category totals: %v", err)
 		return nil, fmt.Errorf("failed to query category totals: %w", err)
 	}
 	defer rows.Close()
 
-	categoryTotals := make(map[string]float64)
+	categoryTotals := make(map[string]types.Amounts)
 	for rows.Next() {
-		var category string
-		var total float64
-		if err := rows.Scan(&category, &total); err != nil {
+		var category, currency string
+		var total types.Money
+		if err := rows.Scan(&category, &currency, &total); err != nil {
 			log.Printf("Error scanning category total: %v", err)
 			return nil, fmt.Errorf("failed to scan category total: %w", err)
 		}
-		categoryTotals[category] = total
+		if categoryTotals[category] == nil {
+			categoryTotals[category] = make(types.Amounts)
+		}
+		categoryTotals[category].Add(currency, total)
 	}
 
 	if err = rows.Err(); err != nil {
@@ -157,7 +174,7 @@ func (r *postgresRepo) GetCategoryTotals(ctx context.Context, accountID string,
 		return nil, fmt.Errorf("error iterating category totals: %w", err)
 	}
 
-	log.Printf("Found %d categories for account %s", len(categoryTotals), accountID)
+	log.Printf("Found %d categories for accounts %v", len(categoryTotals), accountIDs)
 	return categoryTotals, nil
 }
 
@@ -191,9 +208,9 @@ func GetDaysInMonth(year, month int) int {
 }
 
 // GetMonthlyIncome retrieves income transactions for a specific month
-func (r *postgresRepo) GetMonthlyIncome(ctx context.Context, accountID string, year int, month int) ([]types.Transaction, error) {
-	if accountID == "" {
-		return nil, fmt.Errorf("account ID is required")
+func (r *postgresRepo) GetMonthlyIncome(ctx context.Context, accountIDs []string, year int, month int) ([]types.Transaction, error) {
+	if len(accountIDs) == 0 {
+		return nil, fmt.Errorf("at least one account ID is required")
 	}
 
 	daysInMonth := GetDaysInMonth(year, month)
@@ -201,15 +218,16 @@ func (r *postgresRepo) GetMonthlyIncome(ctx context.Context, accountID string, y
 	endDate := fmt.Sprintf("%d-%02d-%02d 23:59:59", year, month, daysInMonth)
 
 	query := `
-		SELECT transaction_id, account_id, date, amount, category, merchant, location
+		SELECT transaction_id, account_id, date, amount, category, merchant, location, currency
 		FROM transactions 
-		WHERE account_id = $1 
-		  AND category = 'Income'
+		WHERE account_id = ANY($1) 
+		  AND transfer_id IS NULL
+		  AND category_type(account_id, category) = 'income'
 		  AND date >= $2
 		  AND date <= $3
 		ORDER BY date DESC`
 
-	rows, err := r.db.QueryContext(ctx, query, accountID, startDate, endDate)
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(accountIDs), startDate, endDate)
 	if err != nil {
 		log.Printf("Error querying monthly income: %v", err)
 		return nil, fmt.Errorf("failed to query monthly income: %w", err)
@@ -227,6 +245,7 @@ func (r *postgresRepo) GetMonthlyIncome(ctx context.Context, accountID string, y
 			&t.Category,
 			&t.Merchant,
 			&t.Location,
+			&t.Currency,
 		); err != nil {
 			log.Printf("Error scanning transaction: %v", err)
 			return nil, fmt.Errorf("failed to scan transaction: %w", err)
@@ -243,9 +262,9 @@ func (r *postgresRepo) GetMonthlyIncome(ctx context.Context, accountID string, y
 }
 
 // GetBillPayments retrieves bill payment transactions for a specific month
-func (r *postgresRepo) GetBillPayments(ctx context.Context, accountID string, year int, month int) ([]types.Transaction, error) {
-	if accountID == "" {
-		return nil, fmt.Errorf("account ID is required")
+func (r *postgresRepo) GetBillPayments(ctx context.Context, accountIDs []string, year int, month int) ([]types.Transaction, error) {
+	if len(accountIDs) == 0 {
+		return nil, fmt.Errorf("at least one account ID is required")
 	}
 
 	daysInMonth := GetDaysInMonth(year, month)
@@ -253,15 +272,16 @@ func (r *postgresRepo) GetBillPayments(ctx context.Context, accountID string, ye
 	endDate := fmt.Sprintf("%d-%02d-%02d 23:59:59", year, month, daysInMonth)
 
 	query := `
-		SELECT transaction_id, account_id, date, amount, category, merchant, location
+		SELECT transaction_id, account_id, date, amount, category, merchant, location, currency
 		FROM transactions 
-		WHERE account_id = $1 
-		  AND (category = 'Bill Payment' OR category = 'Subscription')
+		WHERE account_id = ANY($1) 
+		  AND transfer_id IS NULL
+		  AND category_type(account_id, category) = 'bill'
 		  AND date >= $2
 		  AND date <= $3
 		ORDER BY date DESC`
 
-	rows, err := r.db.QueryContext(ctx, query, accountID, startDate, endDate)
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(accountIDs), startDate, endDate)
 	if err != nil {
 		log.Printf("Error querying bill payments: %v", err)
 		return nil, fmt.Errorf("failed to query bill payments: %w", err)
@@ -279,6 +299,7 @@ func (r *postgresRepo) GetBillPayments(ctx context.Context, accountID string, ye
 			&t.Category,
 			&t.Merchant,
 			&t.Location,
+			&t.Currency,
 		); err != nil {
 			log.Printf("Error scanning transaction: %v", err)
 			return nil, fmt.Errorf("failed to scan transaction: %w", err)
@@ -294,9 +315,9 @@ func (r *postgresRepo) GetBillPayments(ctx context.Context, accountID string, ye
 	return transactions, nil
 }
 
-func (r *postgresRepo) GetCategoryDiversity(ctx context.Context, accountID string, year int, month int) (map[string]int, error) {
-	if accountID == "" {
-		return nil, fmt.Errorf("account ID is required")
+func (r *postgresRepo) GetCategoryDiversity(ctx context.Context, accountIDs []string, year int, month int) (map[string]int, error) {
+	if len(accountIDs) == 0 {
+		return nil, fmt.Errorf("at least one account ID is required")
 	}
 
 	daysInMonth := GetDaysInMonth(year, month)
@@ -305,14 +326,16 @@ func (r *postgresRepo) GetCategoryDiversity(ctx context.Context, accountID strin
 
 	query := `
 		SELECT category, COUNT(*) as count
-		FROM transactions
-		WHERE account_id = $1
+		FROM transaction_lines
+		WHERE account_id = ANY($1)
+		  AND transfer_id IS NULL
+		  AND category_type(account_id, category) IS DISTINCT FROM 'transfer'
 		  AND date >= $2
 		  AND date <= $3
 		GROUP BY category
 		ORDER BY count DESC`
 
-	rows, err := r.db.QueryContext(ctx, query, accountID, startDate, endDate)
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(accountIDs), startDate, endDate)
 	if err != nil {
 		log.Printf("Error querying category diversity: %v", err)
 		return nil, fmt.Errorf("failed to query category diversity: %w", err)
@@ -335,13 +358,15 @@ func (r *postgresRepo) GetCategoryDiversity(ctx context.Context, accountID strin
 		return nil, fmt.Errorf("error iterating category counts: %w", err)
 	}
 
-	log.Printf("Found %d categories for account %s", len(categoryCounts), accountID)
+	log.Printf("Found %d categories for accounts %v", len(categoryCounts), accountIDs)
 	return categoryCounts, nil
 }
 
-func (r *postgresRepo) GetDailySpending(ctx context.Context, accountID string, year int, month int) ([]types.Transaction, error) {
-	if accountID == "" {
-		return nil, fmt.Errorf("account ID is required")
+// GetDailySpending retrieves the spending lines of the accounts in the month,
+// one per split of a split transaction
+func (r *postgresRepo) GetDailySpending(ctx context.Context, accountIDs []string, year int, month int) ([]types.Transaction, error) {
+	if len(accountIDs) == 0 {
+		return nil, fmt.Errorf("at least one account ID is required")
 	}
 
 	daysInMonth := GetDaysInMonth(year, month)
@@ -349,14 +374,16 @@ func (r *postgresRepo) GetDailySpending(ctx context.Context, accountID string, y
 	endDate := fmt.Sprintf("%d-%02d-%02d 23:59:59", year, month, daysInMonth)
 
 	query := `
-		SELECT transaction_id, account_id, date, amount, category, merchant, location
-		FROM transactions 
-		WHERE account_id = $1 
+		SELECT transaction_id, account_id, date, amount, category, merchant, location, currency
+		FROM transaction_lines 
+		WHERE account_id = ANY($1) 
+		  AND transfer_id IS NULL
+		  AND category_type(account_id, category) IS DISTINCT FROM 'transfer'
 		  AND date >= $2
 		  AND date <= $3
 		ORDER BY date ASC`
 
-	rows, err := r.db.QueryContext(ctx, query, accountID, startDate, endDate)
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(accountIDs), startDate, endDate)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query daily spending: %w", err)
 	}
@@ -373,6 +400,7 @@ func (r *postgresRepo) GetDailySpending(ctx context.Context, accountID string, y
 			&t.Category,
 			&t.Merchant,
 			&t.Location,
+			&t.Currency,
 		); err != nil {
 			return nil, fmt.Errorf("failed to scan transaction: %w", err)
 		}
@@ -382,9 +410,11 @@ func (r *postgresRepo) GetDailySpending(ctx context.Context, accountID string, y
 	return transactions, nil
 }
 
-func (r *postgresRepo) GetMonthlySpending(ctx context.Context, accountID string, year int, month int) ([]types.Transaction, error) {
-	if accountID == "" {
-		return nil, fmt.Errorf("account ID is required")
+// GetMonthlySpending retrieves the spending lines of the accounts in the
+// month, one per split of a split transaction, ordered by category
+func (r *postgresRepo) GetMonthlySpending(ctx context.Context, accountIDs []string, year int, month int) ([]types.Transaction, error) {
+	if len(accountIDs) == 0 {
+		return nil, fmt.Errorf("at least one account ID is required")
 	}
 
 	daysInMonth := GetDaysInMonth(year, month)
@@ -392,14 +422,16 @@ func (r *postgresRepo) GetMonthlySpending(ctx context.Context, accountID string,
 	endDate := fmt.Sprintf("%d-%02d-%02d 23:59:59", year, month, daysInMonth)
 
 	query := `
-		SELECT transaction_id, account_id, date, amount, category, merchant, location
-		FROM transactions 
-		WHERE account_id = $1 
+		SELECT transaction_id, account_id, date, amount, category, merchant, location, currency
+		FROM transaction_lines 
+		WHERE account_id = ANY($1) 
+		  AND transfer_id IS NULL
+		  AND category_type(account_id, category) IS DISTINCT FROM 'transfer'
 		  AND date >= $2
 		  AND date <= $3
 		ORDER BY category, date ASC`
 
-	rows, err := r.db.QueryContext(ctx, query, accountID, startDate, endDate)
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(accountIDs), startDate, endDate)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query monthly spending: %w", err)
 	}
@@ -416,6 +448,7 @@ func (r *postgresRepo) GetMonthlySpending(ctx context.Context, accountID string,
 			&t.Category,
 			&t.Merchant,
 			&t.Location,
+			&t.Currency,
 		); err != nil {
 			return nil, fmt.Errorf("failed to scan transaction: %w", err)
 		}
@@ -5,99 +5,138 @@ import (
 	"fmt"
 	"math"
 	"server/analytics/repository"
+	"server/fx/rates"
 	"server/types"
 	"sort"
-	"strconv"
 	"time"
 )
 
 // Service defines the interface for analytics operations
 type Service interface {
-	// AnalyzeSpending analyzes spending patterns for a given account and time range
-	AnalyzeSpending(ctx context.Context, accountID string, timeRange string) (*types.SpendingAnalytics, error)
+	// AnalyzeSpending analyzes spending patterns for the given accounts and time range
+	AnalyzeSpending(ctx context.Context, accountIDs []string, timeRange string, currency string) (*types.SpendingAnalytics, error)
 	
-	// GetTimePatterns analyzes spending patterns by time of day and day of week
-	GetTimePatterns(ctx context.Context, accountID string, startDate, endDate time.Time) ([]types.TimePattern, error)
+	// GetTimePatterns analyzes spending patterns by time of day and day of
+	// week, with amounts in the given currency
+	GetTimePatterns(ctx context.Context, accountIDs []string, startDate, endDate time.Time, currency string) ([]types.TimePattern, error)
 	
-	// PredictSpending generates spending predictions for each category
-	PredictSpending(ctx context.Context, accountID string) ([]types.PredictedSpend, error)
+	// PredictSpending generates spending predictions for each category,
+	// weighing amounts in the given currency
+	PredictSpending(ctx context.Context, accountIDs []string, currency string) ([]types.PredictedSpend, error)
 
 	// GetMonthlyIncome retrieves income transactions for a specific month
-	GetMonthlyIncome(ctx context.Context, accountID string, year int, month int) ([]types.Transaction, error)
+	GetMonthlyIncome(ctx context.Context, accountIDs []string, year int, month int) ([]types.Transaction, error)
 
 	// GetBillPayments retrieves bill payment transactions for a specific month
-	GetBillPayments(ctx context.Context, accountID string, year int, month int) ([]types.Transaction, error)
+	GetBillPayments(ctx context.Context, accountIDs []string, year int, month int) ([]types.Transaction, error)
 
-	// GetDailyPatterns retrieves daily spending patterns for a specific month
-	GetDailyPatterns(ctx context.Context, accountID string, year int, month int) ([]types.DailyPattern, error)
+	// GetDailyPatterns retrieves daily spending patterns for a specific
+	// month, with amounts in the given currency
+	GetDailyPatterns(ctx context.Context, accountIDs []string, year int, month int, currency string) ([]types.DailyPattern, error)
 
-	// GetMonthlyPatterns retrieves monthly spending patterns for a specific month
-	GetMonthlyPatterns(ctx context.Context, accountID string, year int, month int) ([]types.MonthlyPattern, error)
+	// GetMonthlyPatterns retrieves monthly spending patterns for a specific
+	// month, with amounts in the given currency
+	GetMonthlyPatterns(ctx context.Context, accountIDs []string, year int, month int, currency string) ([]types.MonthlyPattern, error)
 }
 
 type service struct {
-	repo repository.Repository
+	repo  repository.Repository
+	rates rates.Source
 }
 
-func NewService(repo repository.Repository) Service {
-	return &service{repo: repo}
+func NewService(repo repository.Repository, source rates.Source) Service {
+	return &service{repo: repo, rates: source}
 }
 
-// AnalyzeSpending implements Service.AnalyzeSpending
-func (s *service) AnalyzeSpending(ctx context.Context, accountID string, timeRange string) (*types.SpendingAnalytics, error) {
-	// First, verify the account exists
-	account, err := s.repo.GetAccount(ctx, accountID)
+// AnalyzeSpending implements Service.AnalyzeSpending. The category and bill
+// totals are converted into the requested currency (the first account's
+// currency by default) at the latest known rates, as of the report rather
+// than of each transaction, and the unconverted totals are reported
+// alongside.
+func (s *service) AnalyzeSpending(ctx context.Context, accountIDs []string, timeRange string, currency string) (*types.SpendingAnalytics, error) {
+	// First, verify the accounts exist
+	accounts, err := s.accounts(ctx, accountIDs)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get account: %w", err)
+		return nil, err
 	}
 
-	categoryTotals, err := s.repo.GetCategoryTotals(ctx, accountID, timeRange)
+	currency, err = rates.ReportingCurrency(currency, accounts[0].Balance.Currency)
+	if err != nil {
+		return nil, err
+	}
+	table, err := s.rates.Table(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	categoryTotals, err := s.repo.GetCategoryTotals(ctx, accountIDs, timeRange)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get category totals: %w", err)
 	}
 
-	var totalSpent float64
+	now := time.Now()
+	totalSpent := types.NewMoney(0, currency)
+	originalTotals := make(types.Amounts)
+	convertedTotals := make(map[string]types.Money, len(categoryTotals))
+	for category, amounts := range categoryTotals {
+		converted, err := table.ConvertAll(amounts, currency, now)
+		if err != nil {
+			return nil, err
+		}
+		convertedTotals[category] = converted
+		totalSpent = totalSpent.Add(converted)
+		for c, amount := range amounts {
+			originalTotals.Add(c, amount)
+		}
+	}
+
 	var topCategories []types.CategorySpend
-	for category, amount := range categoryTotals {
-		totalSpent += amount
+	for category, amount := range convertedTotals {
 		topCategories = append(topCategories, types.CategorySpend{
 			Category:   category,
-			TotalSpent: fmt.Sprintf("%.2f", amount),
-			Percentage: fmt.Sprintf("%.2f", (amount/totalSpent)*100),
+			TotalSpent: amount,
+			Percentage: fmt.Sprintf("%.2f", amount.Ratio(totalSpent)*100),
+			Original:   categoryTotals[category],
 		})
-	} 
+	}
+
+	// Sort by amount spent
+	sort.Slice(topCategories, func(i, j int) bool {
+		return topCategories[i].TotalSpent.Cmp(topCategories[j].TotalSpent) > 0
+	})
 
 	// Get current month's bill payments
-	now := time.Now()
-	billPayments, err := s.repo.GetBillPayments(ctx, accountID, now.Year(), int(now.Month()))
+	billPayments, err := s.repo.GetBillPayments(ctx, accountIDs, now.Year(), int(now.Month()))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get bill payments: %w", err)
 	}
 
-	var totalSpentBills float64
-	billTotalsByMerchant := make(map[string]float64)
+	var totalSpentBills types.Money
+	billTotalsByMerchant := make(map[string]types.Money)
 	
 	// Calculate totals by merchant for bill payments
 	for _, payment := range billPayments {
-		amount := math.Abs(payment.Amount)
-		totalSpentBills += amount
-		billTotalsByMerchant[payment.Merchant] += amount
+		converted, err := table.Convert(payment.Amount, payment.Currency, currency, now)
+		if err != nil {
+			return nil, err
+		}
+		amount := converted.Abs()
+		totalSpentBills = totalSpentBills.Add(amount)
+		billTotalsByMerchant[payment.Merchant] = billTotalsByMerchant[payment.Merchant].Add(amount)
 	}
 
 	var topBills []types.BillPayment
 	for merchant, amount := range billTotalsByMerchant {
 		topBills = append(topBills, types.BillPayment{
 			Category:   merchant,
-			TotalSpent: fmt.Sprintf("%.2f", amount),
-			Percentage: fmt.Sprintf("%.2f", (amount/totalSpentBills)*100),
+			TotalSpent: amount,
+			Percentage: fmt.Sprintf("%.2f", amount.Ratio(totalSpentBills)*100),
 		})
 	}
 
 	// Sort by amount spent
 	sort.Slice(topBills, func(i, j int) bool {
-		amtI, _ := strconv.ParseFloat(topBills[i].TotalSpent, 64)
-		amtJ, _ := strconv.ParseFloat(topBills[j].TotalSpent, 64)
-		return amtI > amtJ
+		return topBills[i].TotalSpent.Cmp(topBills[j].TotalSpent) > 0
 	})
 
 	// Get top 5 categories
@@ -108,45 +147,59 @@ func (s *service) AnalyzeSpending(ctx context.Context, accountID string, timeRan
 	// Get time patterns for the last month
 	endDate := time.Now()
 	startDate := endDate.AddDate(0, -1, 0)
-	patterns, err := s.GetTimePatterns(ctx, accountID, startDate, endDate)
+	patterns, err := s.GetTimePatterns(ctx, accountIDs, startDate, endDate, currency)
 	if err != nil {
 		return nil, fmt.Errorf("failed to analyze time patterns: %w", err)
 	}
 
 	// Get spending predictions
-	predictions, err := s.PredictSpending(ctx, accountID)
+	predictions, err := s.PredictSpending(ctx, accountIDs, currency)
 	if err != nil {
 		return nil, fmt.Errorf("failed to predict spending: %w", err)
 	}
 
-	return &types.SpendingAnalytics{
-		Account:          account,
+	analytics := &types.SpendingAnalytics{
 		TopCategories:    topCategories,
 		SpendingPatterns: patterns,
 		PredictedSpending: predictions,
 		TotalSpent:       totalSpent,
-		MonthlyAverage:   totalSpent / float64(timeRangeToMonths(timeRange)),
-	}, nil
+		MonthlyAverage:   totalSpent.Div(timeRangeToMonths(timeRange)),
+		Currency:         currency,
+		OriginalTotals:   originalTotals,
+	}
+	if len(accounts) == 1 {
+		analytics.Account = accounts[0]
+	} else {
+		analytics.Accounts = accounts
+	}
+	return analytics, nil
 }
 
-// GetTimePatterns implements Service.GetTimePatterns
-func (s *service) GetTimePatterns(ctx context.Context, accountID string, startDate, endDate time.Time) ([]types.TimePattern, error) {
-	// First, verify the account exists
-	if _, err := s.repo.GetAccount(ctx, accountID); err != nil {
-		return nil, fmt.Errorf("failed to get account: %w", err)
+// GetTimePatterns implements Service.GetTimePatterns. Each transaction is
+// converted into the requested currency (the first account's currency by
+// default) at the rate of its date.
+func (s *service) GetTimePatterns(ctx context.Context, accountIDs []string, startDate, endDate time.Time, currency string) ([]types.TimePattern, error) {
+	// First, verify the accounts exist
+	accounts, err := s.accounts(ctx, accountIDs)
+	if err != nil {
+		return nil, err
 	}
 
 	// Convert the date range to a PostgreSQL interval string
 	timeRange := "1 month"
 	
-	transactions, err := s.repo.GetTransactions(ctx, accountID, timeRange)
+	currency, err = rates.ReportingCurrency(currency, accounts[0].Balance.Currency)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get transactions: %w", err)
+		return nil, err
+	}
+	transactions, err := s.convertedTransactions(ctx, accountIDs, timeRange, currency)
+	if err != nil {
+		return nil, err
 	}
 
 	// Group transactions by day and hour
 	patterns := make(map[string]map[string]struct {
-		totalAmount float64
+		totalAmount types.Money
 		count      int
 	})
 
@@ -156,13 +209,13 @@ func (s *service) GetTimePatterns(ctx context.Context, accountID string, startDa
 
 		if _, exists := patterns[dayOfWeek]; !exists {
 			patterns[dayOfWeek] = make(map[string]struct {
-				totalAmount float64
+				totalAmount types.Money
 				count      int
 			})
 		}
 
 		stats := patterns[dayOfWeek][hourOfDay]
-		stats.totalAmount += math.Abs(t.Amount)
+		stats.totalAmount = stats.totalAmount.Add(t.ConvertedAmount.Abs())
 		stats.count++
 		patterns[dayOfWeek][hourOfDay] = stats
 	}
@@ -175,7 +228,8 @@ func (s *service) GetTimePatterns(ctx context.Context, accountID string, startDa
 				TimeOfDay:    hour,
 				DayOfWeek:    day,
 				Frequency:    stats.count,
-				AverageSpend: stats.totalAmount / float64(stats.count),
+				AverageSpend: stats.totalAmount.Div(stats.count),
+				Currency:     currency,
 			})
 		}
 	}
@@ -183,7 +237,7 @@ func (s *service) GetTimePatterns(ctx context.Context, accountID string, startDa
 	// Sort by frequency and average spend
 	sort.Slice(result, func(i, j int) bool {
 		if result[i].Frequency == result[j].Frequency {
-			return result[i].AverageSpend > result[j].AverageSpend
+			return result[i].AverageSpend.Cmp(result[j].AverageSpend) > 0
 		}
 		return result[i].Frequency > result[j].Frequency
 	})
@@ -191,17 +245,24 @@ func (s *service) GetTimePatterns(ctx context.Context, accountID string, startDa
 	return result, nil
 }
 
-// PredictSpending implements Service.PredictSpending
-func (s *service) PredictSpending(ctx context.Context, accountID string) ([]types.PredictedSpend, error) {
-	// First, verify the account exists
-	if _, err := s.repo.GetAccount(ctx, accountID); err != nil {
-		return nil, fmt.Errorf("failed to get account: %w", err)
+// PredictSpending implements Service.PredictSpending. Amounts are compared
+// in the requested currency (the first account's currency by default).
+func (s *service) PredictSpending(ctx context.Context, accountIDs []string, currency string) ([]types.PredictedSpend, error) {
+	// First, verify the accounts exist
+	accounts, err := s.accounts(ctx, accountIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	currency, err = rates.ReportingCurrency(currency, accounts[0].Balance.Currency)
+	if err != nil {
+		return nil, err
 	}
 
 	// Get last 6 months of transactions for better prediction
-	transactions, err := s.repo.GetTransactions(ctx, accountID, "6 months")
+	transactions, err := s.convertedTransactions(ctx, accountIDs, "6 months", currency)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get transactions: %w", err)
+		return nil, err
 	}
 
 	// Group transactions by category
@@ -230,11 +291,11 @@ func (s *service) PredictSpending(ctx context.Context, accountID string) ([]type
 
 		// Calculate frequency and amount metrics
 		frequency := float64(len(txns)) / 180 // Normalize by 6 months (180 days)
-		var totalAmount float64
+		var totalAmount types.Money
 		for _, t := range txns {
-			totalAmount += math.Abs(t.Amount)
+			totalAmount = totalAmount.Add(t.ConvertedAmount.Abs())
 		}
-		avgAmount := totalAmount / float64(len(txns))
+		avgAmount := totalAmount.Div(len(txns)).Float64()
 
 		// Calculate likelihood score
 		normalizedFreq := math.Min(frequency*30, 1.0)  // Normalize to max 1.0 (30 days)
@@ -267,7 +328,52 @@ func (s *service) PredictSpending(ctx context.Context, accountID string) ([]type
 	return predictions, nil
 }
 
-func timeRangeToMonths(timeRange string) float64 {
+// convertedTransactions fetches the accounts' transactions over the time
+// range with each amount converted into the currency at the rate of its date
+func (s *service) convertedTransactions(ctx context.Context, accountIDs []string, timeRange string, currency string) ([]types.Transaction, error) {
+	transactions, err := s.repo.GetTransactions(ctx, accountIDs, timeRange)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transactions: %w", err)
+	}
+	if err := s.convert(ctx, transactions, currency); err != nil {
+		return nil, err
+	}
+	return transactions, nil
+}
+
+// convert sets each transaction's converted amount in the currency at the
+// rate of its date
+func (s *service) convert(ctx context.Context, transactions []types.Transaction, currency string) error {
+	if len(transactions) == 0 {
+		return nil
+	}
+
+	table, err := s.rates.Table(ctx)
+	if err != nil {
+		return err
+	}
+	return table.ConvertTransactions(transactions, currency)
+}
+
+// accounts fetches every account a request covers, which also verifies
+// that they exist
+func (s *service) accounts(ctx context.Context, accountIDs []string) ([]*types.Account, error) {
+	if len(accountIDs) == 0 {
+		return nil, &types.ValidationError{Field: "account_id", Message: "at least one account is required"}
+	}
+
+	accounts := make([]*types.Account, 0, len(accountIDs))
+	for _, id := range accountIDs {
+		account, err := s.repo.GetAccount(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get account: %w", err)
+		}
+		accounts = append(accounts, account)
+	}
+	return accounts, nil
+}
+
+func timeRangeToMonths(timeRange string) int {
 	switch timeRange {
 	case "1 month":
 		return 1
@@ -283,42 +389,52 @@ func timeRangeToMonths(timeRange string) float64 {
 }
 
 // GetMonthlyIncome implements Service.GetMonthlyIncome
-func (s *service) GetMonthlyIncome(ctx context.Context, accountID string, year int, month int) ([]types.Transaction, error) {
-	// First, verify the account exists
-	if _, err := s.repo.GetAccount(ctx, accountID); err != nil {
-		return nil, fmt.Errorf("failed to get account: %w", err)
+func (s *service) GetMonthlyIncome(ctx context.Context, accountIDs []string, year int, month int) ([]types.Transaction, error) {
+	// First, verify the accounts exist
+	if _, err := s.accounts(ctx, accountIDs); err != nil {
+		return nil, err
 	}
 
-	return s.repo.GetMonthlyIncome(ctx, accountID, year, month)
+	return s.repo.GetMonthlyIncome(ctx, accountIDs, year, month)
 }
 
 // GetBillPayments implements Service.GetBillPayments
-func (s *service) GetBillPayments(ctx context.Context, accountID string, year int, month int) ([]types.Transaction, error) {
-	// First, verify the account exists
-	if _, err := s.repo.GetAccount(ctx, accountID); err != nil {
-		return nil, fmt.Errorf("failed to get account: %w", err)
+func (s *service) GetBillPayments(ctx context.Context, accountIDs []string, year int, month int) ([]types.Transaction, error) {
+	// First, verify the accounts exist
+	if _, err := s.accounts(ctx, accountIDs); err != nil {
+		return nil, err
 	}
 
-	return s.repo.GetBillPayments(ctx, accountID, year, month)
+	return s.repo.GetBillPayments(ctx, accountIDs, year, month)
 }
 
-// GetDailyPatterns implements Service.GetDailyPatterns
-func (s *service) GetDailyPatterns(ctx context.Context, accountID string, year int, month int) ([]types.DailyPattern, error) {
-	// First, verify the account exists
-	if _, err := s.repo.GetAccount(ctx, accountID); err != nil {
-		return nil, fmt.Errorf("failed to get account: %w", err)
+// GetDailyPatterns implements Service.GetDailyPatterns. Each transaction is
+// converted into the requested currency (the first account's currency by
+// default) at the rate of its date.
+func (s *service) GetDailyPatterns(ctx context.Context, accountIDs []string, year int, month int, currency string) ([]types.DailyPattern, error) {
+	// First, verify the accounts exist
+	accounts, err := s.accounts(ctx, accountIDs)
+	if err != nil {
+		return nil, err
 	}
 
-	transactions, err := s.repo.GetDailySpending(ctx, accountID, year, month)
+	currency, err = rates.ReportingCurrency(currency, accounts[0].Balance.Currency)
+	if err != nil {
+		return nil, err
+	}
+	transactions, err := s.repo.GetDailySpending(ctx, accountIDs, year, month)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get daily spending: %w", err)
 	}
+	if err := s.convert(ctx, transactions, currency); err != nil {
+		return nil, err
+	}
 
 	// Group transactions by day
-	dailyTotals := make(map[string]float64)
+	dailyTotals := make(map[string]types.Money)
 	for _, tx := range transactions {
 		day := tx.Date.Format("Monday")
-		dailyTotals[day] += math.Abs(tx.Amount)
+		dailyTotals[day] = dailyTotals[day].Add(tx.ConvertedAmount.Abs())
 	}
 
 	// Convert to DailyPattern slice
@@ -327,29 +443,40 @@ func (s *service) GetDailyPatterns(ctx context.Context, accountID string, year i
 		patterns = append(patterns, types.DailyPattern{
 			DayOfWeek: day,
 			AverageAmount: total,
+			Currency: currency,
 		})
 	}
 
 	return patterns, nil
 }
 
-// GetMonthlyPatterns implements Service.GetMonthlyPatterns
-func (s *service) GetMonthlyPatterns(ctx context.Context, accountID string, year int, month int) ([]types.MonthlyPattern, error) {
-	// First, verify the account exists
-	if _, err := s.repo.GetAccount(ctx, accountID); err != nil {
-		return nil, fmt.Errorf("failed to get account: %w", err)
+// GetMonthlyPatterns implements Service.GetMonthlyPatterns. Each transaction
+// is converted into the requested currency (the first account's currency by
+// default) at the rate of its date.
+func (s *service) GetMonthlyPatterns(ctx context.Context, accountIDs []string, year int, month int, currency string) ([]types.MonthlyPattern, error) {
+	// First, verify the accounts exist
+	accounts, err := s.accounts(ctx, accountIDs)
+	if err != nil {
+		return nil, err
 	}
 
-	transactions, err := s.repo.GetMonthlySpending(ctx, accountID, year, month)
+	currency, err = rates.ReportingCurrency(currency, accounts[0].Balance.Currency)
+	if err != nil {
+		return nil, err
+	}
+	transactions, err := s.repo.GetMonthlySpending(ctx, accountIDs, year, month)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get monthly spending: %w", err)
 	}
+	if err := s.convert(ctx, transactions, currency); err != nil {
+		return nil, err
+	}
 
 	// Group transactions by month
-	monthlyTotals := make(map[string]float64)
+	monthlyTotals := make(map[string]types.Money)
 	for _, tx := range transactions {
 		monthName := tx.Date.Format("January")
-		monthlyTotals[monthName] += math.Abs(tx.Amount)
+		monthlyTotals[monthName] = monthlyTotals[monthName].Add(tx.ConvertedAmount.Abs())
 	}
 
 	// Convert to MonthlyPattern slice
@@ -358,6 +485,7 @@ func (s *service) GetMonthlyPatterns(ctx context.Context, accountID string, year
 		patterns = append(patterns, types.MonthlyPattern{
 			Month: month,
 			AverageAmount: total,
+			Currency: currency,
 		})
 	}
 
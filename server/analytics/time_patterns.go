@@ -18,7 +18,7 @@ func analyzeTimePatterns(transactions []types.Transaction) []types.TimePattern {
 		day  string
 	}
 	patterns := make(map[timeKey]struct {
-		sum   float64
+		sum   types.Money
 		count int
 	})
 
@@ -28,7 +28,7 @@ func analyzeTimePatterns(transactions []types.Transaction) []types.TimePattern {
 			day:  t.Date.Format("Monday"),
 		}
 		val := patterns[key]
-		val.sum += t.Amount
+		val.sum = val.sum.Add(t.Amount)
 		val.count++
 		patterns[key] = val
 	}
@@ -40,7 +40,7 @@ func analyzeTimePatterns(transactions []types.Transaction) []types.TimePattern {
 			TimeOfDay:    key.hour,
 			DayOfWeek:    key.day,
 			Frequency:    val.count,
-			AverageSpend: val.sum / float64(val.count),
+			AverageSpend: val.sum.Div(val.count),
 		})
 	}
 
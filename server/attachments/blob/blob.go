@@ -0,0 +1,89 @@
+// Package blob stores the content of attachments. The metadata lives in
+// Postgres; a Store only maps keys to bytes.
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// ErrNotFound is returned when no blob is stored under the key
+var ErrNotFound = errors.New("blob not found")
+
+// Store keeps blobs under string keys. Keys are slash-separated paths made of
+// letters, digits, '-', '_' and '.'.
+type Store interface {
+	// Put stores size bytes read from body under key, replacing any blob
+	// already there
+	Put(ctx context.Context, key string, contentType string, body io.Reader, size int64) error
+
+	// Get opens the blob stored under key; the caller closes it
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+
+	// Delete removes the blob stored under key. Deleting a missing blob is
+	// not an error.
+	Delete(ctx context.Context, key string) error
+}
+
+// defaultDir is where the local store keeps blobs unless ATTACHMENTS_DIR says
+// otherwise
+const defaultDir = "data/attachments"
+
+// FromEnv builds the store selected by the ATTACHMENTS_STORE environment
+// variable: "local" (the default) keeps blobs under ATTACHMENTS_DIR, "s3"
+// keeps them in the S3-compatible bucket configured by the ATTACHMENTS_S3_*
+// variables.
+func FromEnv() (Store, error) {
+	switch kind := os.Getenv("ATTACHMENTS_STORE"); kind {
+	case "", "local":
+		dir := os.Getenv("ATTACHMENTS_DIR")
+		if dir == "" {
+			dir = defaultDir
+		}
+		return NewLocalStore(dir), nil
+	case "s3":
+		return NewS3Store(S3Config{
+			Endpoint:        os.Getenv("ATTACHMENTS_S3_ENDPOINT"),
+			Region:          os.Getenv("ATTACHMENTS_S3_REGION"),
+			Bucket:          os.Getenv("ATTACHMENTS_S3_BUCKET"),
+			AccessKeyID:     os.Getenv("ATTACHMENTS_S3_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("ATTACHMENTS_S3_SECRET_ACCESS_KEY"),
+		}, nil)
+	default:
+		return nil, fmt.Errorf("unknown ATTACHMENTS_STORE %q: expected local or s3", kind)
+	}
+}
+
+// validKey reports whether key is a relative slash-separated path without
+// empty, "." or ".." segments
+func validKey(key string) bool {
+	if key == "" {
+		return false
+	}
+	segment := 0
+	for i := 0; i <= len(key); i++ {
+		if i == len(key) || key[i] == '/' {
+			part := key[segment:i]
+			if part == "" || part == "." || part == ".." {
+				return false
+			}
+			segment = i + 1
+			continue
+		}
+		c := key[i]
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+func checkKey(key string) error {
+	if !validKey(key) {
+		return fmt.Errorf("invalid blob key %q", key)
+	}
+	return nil
+}
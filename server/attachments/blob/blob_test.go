@@ -0,0 +1,35 @@
+package blob
+
+import "testing"
+
+func TestValidKey(t *testing.T) {
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{"3f/3f2a9c", true},
+		{"receipts/2025/scan_01.pdf", true},
+		{"a.b-c_D", true},
+		{"", false},
+		{"/3f/3f2a9c", false},
+		{"3f/", false},
+		{"3f//3f2a9c", false},
+		{".", false},
+		{"..", false},
+		{"../etc/passwd", false},
+		{"3f/../../etc/passwd", false},
+		{"3f/./3f2a9c", false},
+		{"..hidden", true},
+		{`3f\..\3f2a9c`, false},
+		{"3f/3f 2a", false},
+		{"3f/3f\x002a", false},
+		{"3f/3f\n2a", false},
+		{"3f/ключ", false},
+		{"3f/3f%2F..", false},
+	}
+	for _, tt := range tests {
+		if got := validKey(tt.key); got != tt.want {
+			t.Errorf("validKey(%q) = %v, want %v", tt.key, got, tt.want)
+		}
+	}
+}
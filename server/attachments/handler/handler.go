@@ -0,0 +1,204 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"server/attachments/blob"
+	"server/attachments/repository"
+	"server/attachments/service"
+	"server/types"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// maxRequestSize limits the size of an upload request: the largest allowed
+// file plus room for the multipart framing around it
+const maxRequestSize = types.MaxAttachmentSize + 1<<20
+
+type Handler struct {
+	service service.Service
+}
+
+func NewHandler(service service.Service) *Handler {
+	return &Handler{service: service}
+}
+
+// SetupAttachmentRoutes configures all the transaction attachment routes.
+// Content goes to the blob store selected by the ATTACHMENTS_STORE
+// environment variable.
+func SetupAttachmentRoutes(router *mux.Router, db *sql.DB) {
+	store, err := blob.FromEnv()
+	if err != nil {
+		log.Fatalf("Error configuring attachment storage: %v", err)
+	}
+	repo := repository.NewPostgresRepository(db)
+	svc := service.NewService(repo, store)
+	handler := NewHandler(svc)
+	handler.RegisterRoutes(router)
+}
+
+// RegisterRoutes registers all attachment routes
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/api/transactions/{accountId}/{transactionId}/attachments", h.HandleListAttachments).Methods("GET")
+	router.HandleFunc("/api/transactions/{accountId}/{transactionId}/attachments", h.HandleUploadAttachment).Methods("POST")
+	router.HandleFunc("/api/transactions/{accountId}/{transactionId}/attachments/{attachmentId:[0-9]+}", h.HandleGetAttachment).Methods("GET")
+	router.HandleFunc("/api/transactions/{accountId}/{transactionId}/attachments/{attachmentId:[0-9]+}", h.HandleDeleteAttachment).Methods("DELETE")
+	router.HandleFunc("/api/transactions/{accountId}/{transactionId}/attachments/{attachmentId:[0-9]+}/content", h.HandleDownloadAttachment).Methods("GET")
+}
+
+// HandleListAttachments handles requests for a transaction's attachments
+func (h *Handler) HandleListAttachments(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	attachments, err := h.service.ListAttachments(r.Context(), vars["accountId"], vars["transactionId"])
+	if err != nil {
+		writeError(w, "Failed to get attachments", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(attachments)
+}
+
+// HandleUploadAttachment handles file uploads, sent either as the "file" field
+// of a multipart form or as the raw request body with the name in the
+// "filename" query parameter
+func (h *Handler) HandleUploadAttachment(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	upload, closeFile, err := uploadedFile(w, r)
+	if err != nil {
+		writeError(w, "Failed to read attachment", err)
+		return
+	}
+	defer closeFile()
+
+	attachment, err := h.service.CreateAttachment(r.Context(), vars["accountId"], vars["transactionId"], *upload)
+	if err != nil {
+		writeError(w, "Failed to create attachment", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(attachment)
+}
+
+// HandleGetAttachment handles requests for an attachment's metadata
+func (h *Handler) HandleGetAttachment(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	attachmentID, _ := strconv.ParseInt(vars["attachmentId"], 10, 64)
+
+	attachment, err := h.service.GetAttachment(r.Context(), vars["accountId"], vars["transactionId"], attachmentID)
+	if err != nil {
+		writeError(w, "Failed to get attachment", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(attachment)
+}
+
+// HandleDownloadAttachment handles requests for an attachment's content. It
+// is sent as a download unless inline=true is given.
+func (h *Handler) HandleDownloadAttachment(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	attachmentID, _ := strconv.ParseInt(vars["attachmentId"], 10, 64)
+
+	attachment, content, err := h.service.OpenAttachment(r.Context(), vars["accountId"], vars["transactionId"], attachmentID)
+	if err != nil {
+		writeError(w, "Failed to download attachment", err)
+		return
+	}
+	defer content.Close()
+
+	disposition := "attachment"
+	if inline, _ := strconv.ParseBool(r.URL.Query().Get("inline")); inline {
+		disposition = "inline"
+	}
+	w.Header().Set("Content-Type", attachment.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(attachment.Size, 10))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": attachment.Filename}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if _, err := io.Copy(w, content); err != nil {
+		log.Printf("Error sending attachment %d: %v", attachment.ID, err)
+	}
+}
+
+// HandleDeleteAttachment handles requests to delete an attachment
+func (h *Handler) HandleDeleteAttachment(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	attachmentID, _ := strconv.ParseInt(vars["attachmentId"], 10, 64)
+
+	if err := h.service.DeleteAttachment(r.Context(), vars["accountId"], vars["transactionId"], attachmentID); err != nil {
+		writeError(w, "Failed to delete attachment", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// uploadedFile returns the uploaded file from a multipart form or, for any
+// other content type, the request body itself, together with a function that
+// releases it
+func uploadedFile(w http.ResponseWriter, r *http.Request) (*service.Upload, func(), error) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
+
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		upload := &service.Upload{
+			Filename:    r.URL.Query().Get("filename"),
+			ContentType: r.Header.Get("Content-Type"),
+			Body:        r.Body,
+		}
+		return upload, func() {}, nil
+	}
+
+	if err := r.ParseMultipartForm(maxRequestSize); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return nil, nil, service.ErrTooLarge
+		}
+		return nil, nil, &types.ValidationError{Field: "file", Message: "invalid multipart form"}
+	}
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		r.MultipartForm.RemoveAll()
+		return nil, nil, &types.ValidationError{Field: "file", Message: "missing \"file\" form field"}
+	}
+	upload := &service.Upload{
+		Filename:    header.Filename,
+		ContentType: header.Header.Get("Content-Type"),
+		Body:        file,
+	}
+	release := func() {
+		file.Close()
+		r.MultipartForm.RemoveAll()
+	}
+	return upload, release, nil
+}
+
+// writeError converts service and repository errors into HTTP responses
+func writeError(w http.ResponseWriter, message string, err error) {
+	var validationErr *types.ValidationError
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &validationErr):
+		http.Error(w, validationErr.Error(), http.StatusBadRequest)
+	case errors.Is(err, service.ErrTooLarge), errors.As(err, &tooLarge):
+		http.Error(w, service.ErrTooLarge.Error(), http.StatusRequestEntityTooLarge)
+	case errors.Is(err, repository.ErrTransactionNotFound):
+		http.Error(w, "Transaction not found", http.StatusNotFound)
+	case errors.Is(err, repository.ErrAttachmentNotFound):
+		http.Error(w, "Attachment not found", http.StatusNotFound)
+	default:
+		log.Printf("%s: %v", message, err)
+		http.Error(w, message, http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"server/types"
+)
+
+type postgresRepo struct {
+	db *sql.DB
+}
+
+func NewPostgresRepository(db *sql.DB) Repository {
+	if db == nil {
+		panic("database connection is required")
+	}
+	return &postgresRepo{db: db}
+}
+
+// TransactionExists reports whether the transaction belongs to the account
+func (r *postgresRepo) TransactionExists(ctx context.Context, accountID string, transactionID string) (bool, error) {
+	var exists bool
+	err := r.db.QueryRowContext(ctx,
+		`SELECT EXISTS(SELECT 1 FROM transactions WHERE account_id = $1 AND transaction_id = $2)`,
+		accountID, transactionID,
+	).Scan(&exists)
+	if err != nil {
+		log.Printf("Error checking transaction %s: %v", transactionID, err)
+		return false, fmt.Errorf("failed to check transaction: %w", err)
+	}
+	return exists, nil
+}
+
+const attachmentColumns = `
+	id, account_id, transaction_id, filename, content_type, size, sha256, storage_key, created_at`
+
+// ListAttachments retrieves a transaction's attachments, oldest first
+func (r *postgresRepo) ListAttachments(ctx context.Context, accountID string, transactionID string) ([]types.Attachment, error) {
+	query := `SELECT` + attachmentColumns + `
+		FROM attachments
+		WHERE account_id = $1 AND transaction_id = $2
+		ORDER BY created_at, id`
+
+	rows, err := r.db.QueryContext(ctx, query, accountID, transactionID)
+	if err != nil {
+		log.Printf("Error querying attachments: %v", err)
+		return nil, fmt.Errorf("failed to query attachments: %w", err)
+	}
+	defer rows.Close()
+
+	attachments := []types.Attachment{}
+	for rows.Next() {
+		attachment, err := scanAttachment(rows)
+		if err != nil {
+			log.Printf("Error scanning attachment: %v", err)
+			return nil, fmt.Errorf("failed to scan attachment: %w", err)
+		}
+		attachments = append(attachments, *attachment)
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("Error iterating attachments: %v", err)
+		return nil, fmt.Errorf("error iterating attachments: %w", err)
+	}
+	return attachments, nil
+}
+
+// GetAttachment retrieves a single attachment of a transaction
+func (r *postgresRepo) GetAttachment(ctx context.Context, accountID string, transactionID string, attachmentID int64) (*types.Attachment, error) {
+	query := `SELECT` + attachmentColumns + `
+		FROM attachments
+		WHERE account_id = $1 AND transaction_id = $2 AND id = $3`
+
+	attachment, err := scanAttachment(r.db.QueryRowContext(ctx, query, accountID, transactionID, attachmentID))
+	if err == sql.ErrNoRows {
+		return nil, ErrAttachmentNotFound
+	}
+	if err != nil {
+		log.Printf("Error fetching attachment %d: %v", attachmentID, err)
+		return nil, fmt.Errorf("failed to fetch attachment: %w", err)
+	}
+	return attachment, nil
+}
+
+// CreateAttachment inserts an attachment, setting its ID and CreatedAt
+func (r *postgresRepo) CreateAttachment(ctx context.Context, attachment *types.Attachment) error {
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO attachments (account_id, transaction_id, filename, content_type, size, sha256, storage_key)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at`,
+		attachment.AccountID, attachment.TransactionID, attachment.Filename, attachment.ContentType,
+		attachment.Size, attachment.SHA256, attachment.StorageKey,
+	).Scan(&attachment.ID, &attachment.CreatedAt)
+	if err != nil {
+		log.Printf("Error creating attachment: %v", err)
+		return fmt.Errorf("failed to create attachment: %w", err)
+	}
+	return nil
+}
+
+// DeleteAttachment removes an attachment and returns what it was, so that the
+// caller can delete its content from the blob store
+func (r *postgresRepo) DeleteAttachment(ctx context.Context, accountID string, transactionID string, attachmentID int64) (*types.Attachment, error) {
+	query := `DELETE FROM attachments
+		WHERE account_id = $1 AND transaction_id = $2 AND id = $3
+		RETURNING` + attachmentColumns
+
+	attachment, err := scanAttachment(r.db.QueryRowContext(ctx, query, accountID, transactionID, attachmentID))
+	if err == sql.ErrNoRows {
+		return nil, ErrAttachmentNotFound
+	}
+	if err != nil {
+		log.Printf("Error deleting attachment %d: %v", attachmentID, err)
+		return nil, fmt.Errorf("failed to delete attachment: %w", err)
+	}
+	return attachment, nil
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanAttachment(row rowScanner) (*types.Attachment, error) {
+	var a types.Attachment
+	err := row.Scan(&a.ID, &a.AccountID, &a.TransactionID, &a.Filename, &a.ContentType,
+		&a.Size, &a.SHA256, &a.StorageKey, &a.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
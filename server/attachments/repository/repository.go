@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"server/types"
+)
+
+var (
+	// ErrTransactionNotFound is returned when the transaction does not exist for the account
+	ErrTransactionNotFound = errors.New("transaction not found")
+
+	// ErrAttachmentNotFound is returned when the attachment does not exist for the transaction
+	ErrAttachmentNotFound = errors.New("attachment not found")
+)
+
+// Repository defines the interface for attachment metadata operations. The
+// content of each attachment is kept in a blob store, not in the database.
+type Repository interface {
+	// TransactionExists reports whether the transaction belongs to the account
+	TransactionExists(ctx context.Context, accountID string, transactionID string) (bool, error)
+
+	// ListAttachments retrieves a transaction's attachments, oldest first
+	ListAttachments(ctx context.Context, accountID string, transactionID string) ([]types.Attachment, error)
+
+	// GetAttachment retrieves a single attachment of a transaction
+	GetAttachment(ctx context.Context, accountID string, transactionID string, attachmentID int64) (*types.Attachment, error)
+
+	// CreateAttachment inserts an attachment, setting its ID and CreatedAt
+	CreateAttachment(ctx context.Context, attachment *types.Attachment) error
+
+	// DeleteAttachment removes an attachment and returns what it was
+	DeleteAttachment(ctx context.Context, accountID string, transactionID string, attachmentID int64) (*types.Attachment, error)
+}
@@ -0,0 +1,230 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path"
+	"server/attachments/blob"
+	"server/attachments/repository"
+	"server/types"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxFilenameLength matches the filename column of the attachments table
+const maxFilenameLength = 255
+
+// ErrTooLarge is returned when an uploaded file exceeds types.MaxAttachmentSize
+var ErrTooLarge = fmt.Errorf("attachment must be at most %d MB", types.MaxAttachmentSize>>20)
+
+// Upload is a file sent to be attached to a transaction
+type Upload struct {
+	// Filename is the name the client gave the file; it may be empty
+	Filename string
+
+	// ContentType is the type the client declared for the file; it may be
+	// empty. The stored type is always sniffed from the content.
+	ContentType string
+
+	Body io.Reader
+}
+
+type Service interface {
+	ListAttachments(ctx context.Context, accountID string, transactionID string) ([]types.Attachment, error)
+	GetAttachment(ctx context.Context, accountID string, transactionID string, attachmentID int64) (*types.Attachment, error)
+	CreateAttachment(ctx context.Context, accountID string, transactionID string, upload Upload) (*types.Attachment, error)
+	OpenAttachment(ctx context.Context, accountID string, transactionID string, attachmentID int64) (*types.Attachment, io.ReadCloser, error)
+	DeleteAttachment(ctx context.Context, accountID string, transactionID string, attachmentID int64) error
+	DeleteContent(ctx context.Context, attachments []types.Attachment)
+}
+
+type service struct {
+	repo  repository.Repository
+	store blob.Store
+}
+
+func NewService(repo repository.Repository, store blob.Store) Service {
+	return &service{repo: repo, store: store}
+}
+
+// requireTransaction returns ErrTransactionNotFound when the transaction does
+// not belong to the account
+func (s *service) requireTransaction(ctx context.Context, accountID string, transactionID string) error {
+	exists, err := s.repo.TransactionExists(ctx, accountID, transactionID)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return repository.ErrTransactionNotFound
+	}
+	return nil
+}
+
+func (s *service) ListAttachments(ctx context.Context, accountID string, transactionID string) ([]types.Attachment, error) {
+	if err := s.requireTransaction(ctx, accountID, transactionID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListAttachments(ctx, accountID, transactionID)
+}
+
+func (s *service) GetAttachment(ctx context.Context, accountID string, transactionID string, attachmentID int64) (*types.Attachment, error) {
+	return s.repo.GetAttachment(ctx, accountID, transactionID, attachmentID)
+}
+
+// CreateAttachment validates an uploaded file, stores its content in the blob
+// store and records it against the transaction. Only the content types of
+// types.AttachmentContentTypes are accepted, judged by the content itself.
+func (s *service) CreateAttachment(ctx context.Context, accountID string, transactionID string, upload Upload) (*types.Attachment, error) {
+	if err := s.requireTransaction(ctx, accountID, transactionID); err != nil {
+		return nil, err
+	}
+
+	content, err := io.ReadAll(io.LimitReader(upload.Body, types.MaxAttachmentSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(content) == 0 {
+		return nil, &types.ValidationError{Field: "file", Message: "is empty"}
+	}
+	if len(content) > types.MaxAttachmentSize {
+		return nil, ErrTooLarge
+	}
+	contentType, err := detectContentType(content, upload.ContentType)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(content)
+	attachment := &types.Attachment{
+		AccountID:     accountID,
+		TransactionID: transactionID,
+		Filename:      cleanFilename(upload.Filename, contentType),
+		ContentType:   contentType,
+		Size:          int64(len(content)),
+		SHA256:        hex.EncodeToString(sum[:]),
+		StorageKey:    newStorageKey(),
+	}
+
+	if err := s.store.Put(ctx, attachment.StorageKey, contentType, bytes.NewReader(content), attachment.Size); err != nil {
+		return nil, fmt.Errorf("failed to store attachment: %w", err)
+	}
+	if err := s.repo.CreateAttachment(ctx, attachment); err != nil {
+		s.deleteBlob(ctx, attachment.StorageKey)
+		return nil, err
+	}
+	return attachment, nil
+}
+
+// OpenAttachment returns an attachment together with its content; the caller
+// closes the content
+func (s *service) OpenAttachment(ctx context.Context, accountID string, transactionID string, attachmentID int64) (*types.Attachment, io.ReadCloser, error) {
+	attachment, err := s.repo.GetAttachment(ctx, accountID, transactionID, attachmentID)
+	if err != nil {
+		return nil, nil, err
+	}
+	content, err := s.store.Get(ctx, attachment.StorageKey)
+	if errors.Is(err, blob.ErrNotFound) {
+		log.Printf("Content of attachment %d is missing from the blob store", attachment.ID)
+		return nil, nil, repository.ErrAttachmentNotFound
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open attachment: %w", err)
+	}
+	return attachment, content, nil
+}
+
+// DeleteAttachment removes an attachment, then its content. A blob that
+// cannot be deleted is only logged: the attachment is already gone.
+func (s *service) DeleteAttachment(ctx context.Context, accountID string, transactionID string, attachmentID int64) error {
+	attachment, err := s.repo.DeleteAttachment(ctx, accountID, transactionID, attachmentID)
+	if err != nil {
+		return err
+	}
+	s.deleteBlob(ctx, attachment.StorageKey)
+	return nil
+}
+
+// DeleteContent deletes the content of attachments whose rows are already
+// gone, such as those of a deleted transaction
+func (s *service) DeleteContent(ctx context.Context, attachments []types.Attachment) {
+	for _, a := range attachments {
+		s.deleteBlob(ctx, a.StorageKey)
+	}
+}
+
+func (s *service) deleteBlob(ctx context.Context, key string) {
+	if err := s.store.Delete(ctx, key); err != nil {
+		log.Printf("Error deleting attachment content %s: %v", key, err)
+	}
+}
+
+// jpegAliases are non-standard names clients use for image/jpeg
+var jpegAliases = map[string]bool{"image/jpg": true, "image/pjpeg": true}
+
+// detectContentType sniffs the type of the content and checks it is one that
+// may be attached. A declared type, unless generic, has to agree with it.
+func detectContentType(content []byte, declared string) (string, error) {
+	detected, _, _ := mime.ParseMediaType(http.DetectContentType(content))
+	if _, ok := types.AttachmentContentTypes[detected]; !ok {
+		return "", &types.ValidationError{Field: "file", Message: "must be a JPEG, PNG, GIF or WebP image or a PDF"}
+	}
+
+	if declared == "" {
+		return detected, nil
+	}
+	declared, _, err := mime.ParseMediaType(declared)
+	if err != nil {
+		return "", &types.ValidationError{Field: "content_type", Message: "is not a valid media type"}
+	}
+	if jpegAliases[declared] {
+		declared = "image/jpeg"
+	}
+	if declared != "application/octet-stream" && declared != detected {
+		return "", &types.ValidationError{Field: "content_type", Message: fmt.Sprintf("is %s but the file is %s", declared, detected)}
+	}
+	return detected, nil
+}
+
+// cleanFilename reduces a client-supplied name to its last path element
+// without control characters, shortened to fit the column. Files sent without
+// a usable name are called "attachment" with the extension of their type.
+func cleanFilename(name string, contentType string) string {
+	name = path.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || r == utf8.RuneError {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimSpace(name)
+	if name == "" || name == "." || name == "/" || name == ".." {
+		return "attachment" + types.AttachmentContentTypes[contentType]
+	}
+
+	for len(name) > maxFilenameLength {
+		_, size := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-size]
+	}
+	return name
+}
+
+// newStorageKey returns a random blob key, fanned out over directories by its
+// first two characters
+func newStorageKey() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("failed to generate storage key: %v", err))
+	}
+	key := hex.EncodeToString(b)
+	return key[:2] + "/" + key
+}
@@ -0,0 +1,82 @@
+package service
+
+import (
+	"errors"
+	"server/types"
+	"strings"
+	"testing"
+)
+
+var (
+	pdf  = []byte("%PDF-1.7\n1 0 obj\n<< /Type /Catalog >>\nendobj\n")
+	png  = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\x00\x00\x00\x01\x00\x00\x00\x01\x08\x06\x00\x00\x00")
+	jpeg = []byte("\xff\xd8\xff\xe0\x00\x10JFIF\x00\x01\x01\x00\x00\x01\x00\x01\x00\x00")
+	html = []byte("<!DOCTYPE html><html><script>alert(1)</script></html>")
+)
+
+func TestDetectContentType(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  []byte
+		declared string
+		want     string
+		field    string // the field of the validation error, "" for none
+	}{
+		{"sniffed without a declared type", pdf, "", "application/pdf", ""},
+		{"declared type that agrees", png, "image/png", "image/png", ""},
+		{"declared type with parameters", pdf, "application/pdf; name=receipt.pdf", "application/pdf", ""},
+		{"jpeg alias", jpeg, "image/jpg", "image/jpeg", ""},
+		{"generic declared type", jpeg, "application/octet-stream", "image/jpeg", ""},
+		{"html declared as an image", html, "image/png", "", "file"},
+		{"html declared as html", html, "text/html", "", "file"},
+		{"plain text", []byte("just some notes"), "", "", "file"},
+		{"png declared as a pdf", png, "application/pdf", "", "content_type"},
+		{"pdf declared as html", pdf, "text/html", "", "content_type"},
+		{"malformed declared type", pdf, "application/", "", "content_type"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := detectContentType(tt.content, tt.declared)
+			var validationErr *types.ValidationError
+			switch {
+			case tt.field == "" && err != nil:
+				t.Fatalf("detectContentType() error = %v", err)
+			case tt.field != "" && (!errors.As(err, &validationErr) || validationErr.Field != tt.field):
+				t.Fatalf("detectContentType() error = %v, want a validation error on %s", err, tt.field)
+			}
+			if got != tt.want {
+				t.Errorf("detectContentType() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCleanFilename(t *testing.T) {
+	long := strings.Repeat("é", 200) + ".pdf"
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"plain name", "receipt.pdf", "receipt.pdf"},
+		{"unix path", "/home/me/scans/receipt.pdf", "receipt.pdf"},
+		{"windows path", `C:\Users\me\receipt.pdf`, "receipt.pdf"},
+		{"path traversal", "../../etc/passwd", "passwd"},
+		{"windows traversal", `..\..\boot.ini`, "boot.ini"},
+		{"only a parent directory", "..", "attachment.pdf"},
+		{"trailing slash", "scans/", "scans"},
+		{"control characters", "rec\x00ei\r\npt\t.pdf", "receipt.pdf"},
+		{"invalid UTF-8", "re\xffceipt.pdf", "receipt.pdf"},
+		{"surrounding spaces", "  receipt.pdf  ", "receipt.pdf"},
+		{"empty", "", "attachment.pdf"},
+		{"only control characters", "\x01\x02", "attachment.pdf"},
+		{"shortened without splitting a character", long, strings.Repeat("é", 127)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cleanFilename(tt.in, "application/pdf"); got != tt.want {
+				t.Errorf("cleanFilename(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
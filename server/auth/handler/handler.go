@@ -0,0 +1,190 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"server/auth/repository"
+	"server/auth/service"
+	"server/types"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+type contextKey struct{}
+
+type Handler struct {
+	service service.Service
+}
+
+func NewHandler(service service.Service) *Handler {
+	return &Handler{service: service}
+}
+
+// SetupAuthRoutes configures the login routes and returns the handler whose
+// Authorize middleware protects the rest of the API
+func SetupAuthRoutes(router *mux.Router, db *sql.DB) *Handler {
+	repo := repository.NewPostgresRepository(db)
+	svc := service.NewService(repo, service.Config{Secret: []byte(os.Getenv("JWT_SECRET"))})
+	handler := NewHandler(svc)
+	handler.RegisterRoutes(router)
+	return handler
+}
+
+// RegisterRoutes registers all auth routes
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/api/auth/register", h.HandleRegister).Methods("POST")
+	router.HandleFunc("/api/auth/login", h.HandleLogin).Methods("POST")
+	router.Handle("/api/auth/me", h.Authorize(http.HandlerFunc(h.HandleGetLogin))).Methods("GET")
+	router.Handle("/api/auth/accounts", h.Authorize(http.HandlerFunc(h.HandleClaimAccount))).Methods("POST")
+}
+
+// LoginID returns the ID of the login that made the request, as set by Authorize
+func LoginID(ctx context.Context) (int64, bool) {
+	id, ok := ctx.Value(contextKey{}).(int64)
+	return id, ok
+}
+
+// Authorize is a middleware that requires a valid bearer token and, on routes
+// with an {accountId} or {ownerId} variable, that the caller owns that
+// account or owner profile
+func (h *Handler) Authorize(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := bearerToken(r)
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+			http.Error(w, "Authentication required", http.StatusUnauthorized)
+			return
+		}
+
+		loginID, err := h.service.Authenticate(token)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="api", error="invalid_token"`)
+			http.Error(w, "Invalid or expired token", http.StatusUnauthorized)
+			return
+		}
+
+		vars := mux.Vars(r)
+		if accountID := vars["accountId"]; accountID != "" {
+			if err := h.service.Authorize(r.Context(), loginID, accountID); err != nil {
+				writeError(w, "Failed to authorize request", err)
+				return
+			}
+		}
+		if ownerID := vars["ownerId"]; ownerID != "" {
+			if err := h.service.AuthorizeOwner(r.Context(), loginID, ownerID); err != nil {
+				writeError(w, "Failed to authorize request", err)
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), contextKey{}, loginID)))
+	})
+}
+
+// HandleRegister handles requests to create a login
+func (h *Handler) HandleRegister(w http.ResponseWriter, r *http.Request) {
+	var credentials types.Credentials
+	if err := json.NewDecoder(r.Body).Decode(&credentials); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	token, err := h.service.Register(r.Context(), credentials)
+	if err != nil {
+		writeError(w, "Failed to register", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(token)
+}
+
+// HandleLogin handles requests to sign in
+func (h *Handler) HandleLogin(w http.ResponseWriter, r *http.Request) {
+	var credentials types.Credentials
+	if err := json.NewDecoder(r.Body).Decode(&credentials); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	token, err := h.service.Login(r.Context(), credentials)
+	if err != nil {
+		writeError(w, "Failed to log in", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(token)
+}
+
+// HandleGetLogin handles requests for the caller's login and accounts
+func (h *Handler) HandleGetLogin(w http.ResponseWriter, r *http.Request) {
+	loginID, _ := LoginID(r.Context())
+
+	login, err := h.service.GetLogin(r.Context(), loginID)
+	if err != nil {
+		writeError(w, "Failed to get login", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(login)
+}
+
+// HandleClaimAccount handles requests to link an account to the caller's login
+func (h *Handler) HandleClaimAccount(w http.ResponseWriter, r *http.Request) {
+	loginID, _ := LoginID(r.Context())
+
+	var claim types.AccountClaim
+	if err := json.NewDecoder(r.Body).Decode(&claim); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	login, err := h.service.ClaimAccount(r.Context(), loginID, claim)
+	if err != nil {
+		writeError(w, "Failed to claim account", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(login)
+}
+
+// bearerToken reads the token from an "Authorization: Bearer <token>" header
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
+
+// writeError converts service and repository errors into HTTP responses
+func writeError(w http.ResponseWriter, message string, err error) {
+	var validationErr *types.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		http.Error(w, validationErr.Error(), http.StatusBadRequest)
+	case errors.Is(err, service.ErrInvalidCredentials):
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+	case errors.Is(err, service.ErrForbidden):
+		http.Error(w, "Forbidden", http.StatusForbidden)
+	case errors.Is(err, repository.ErrLoginNotFound):
+		http.Error(w, "Login not found", http.StatusNotFound)
+	case errors.Is(err, repository.ErrAccountNotFound):
+		http.Error(w, "Account not found", http.StatusNotFound)
+	case errors.Is(err, repository.ErrUsernameTaken), errors.Is(err, repository.ErrAccountClaimed):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		log.Printf("%s: %v", message, err)
+		http.Error(w, message, http.StatusInternalServerError)
+	}
+}
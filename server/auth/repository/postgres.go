@@ -0,0 +1,194 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"server/types"
+
+	"github.com/lib/pq"
+)
+
+type postgresRepo struct {
+	db *sql.DB
+}
+
+func NewPostgresRepository(db *sql.DB) Repository {
+	if db == nil {
+		panic("database connection is required")
+	}
+	return &postgresRepo{db: db}
+}
+
+// CreateLogin inserts a new login and sets its ID and creation time
+func (r *postgresRepo) CreateLogin(ctx context.Context, login *types.Login) error {
+	query := `
+		INSERT INTO logins (username, password_hash)
+		VALUES ($1, $2)
+		RETURNING id, created_at`
+
+	err := r.db.QueryRowContext(ctx, query, login.Username, login.PasswordHash).Scan(&login.ID, &login.CreatedAt)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return ErrUsernameTaken
+		}
+		log.Printf("Error creating login: %v", err)
+		return fmt.Errorf("failed to create login: %w", err)
+	}
+	login.AccountIDs = []string{}
+	return nil
+}
+
+// GetLogin retrieves a login and the accounts it owns
+func (r *postgresRepo) GetLogin(ctx context.Context, id int64) (*types.Login, error) {
+	var login types.Login
+	err := r.db.QueryRowContext(ctx,
+		`SELECT id, username, password_hash, created_at FROM logins WHERE id = $1`, id,
+	).Scan(&login.ID, &login.Username, &login.PasswordHash, &login.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrLoginNotFound
+	}
+	if err != nil {
+		log.Printf("Error fetching login %d: %v", id, err)
+		return nil, fmt.Errorf("failed to fetch login: %w", err)
+	}
+
+	if login.AccountIDs, err = r.accountIDs(ctx, login.ID); err != nil {
+		return nil, err
+	}
+	return &login, nil
+}
+
+// GetLoginByUsername retrieves a login, including its password hash
+func (r *postgresRepo) GetLoginByUsername(ctx context.Context, username string) (*types.Login, error) {
+	var login types.Login
+	err := r.db.QueryRowContext(ctx,
+		`SELECT id, username, password_hash, created_at FROM logins WHERE username = $1`, username,
+	).Scan(&login.ID, &login.Username, &login.PasswordHash, &login.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrLoginNotFound
+	}
+	if err != nil {
+		log.Printf("Error fetching login %q: %v", username, err)
+		return nil, fmt.Errorf("failed to fetch login: %w", err)
+	}
+
+	if login.AccountIDs, err = r.accountIDs(ctx, login.ID); err != nil {
+		return nil, err
+	}
+	return &login, nil
+}
+
+// GetClaimCode retrieves the hash of the account's claim code
+func (r *postgresRepo) GetClaimCode(ctx context.Context, accountID string) (string, error) {
+	var codeHash sql.NullString
+	err := r.db.QueryRowContext(ctx,
+		`SELECT claim_code_hash FROM users WHERE account_id = $1`, accountID,
+	).Scan(&codeHash)
+	if err == sql.ErrNoRows || (err == nil && !codeHash.Valid) {
+		return "", ErrAccountNotFound
+	}
+	if err != nil {
+		log.Printf("Error fetching claim code of account %s: %v", accountID, err)
+		return "", fmt.Errorf("failed to fetch claim code: %w", err)
+	}
+	return codeHash.String, nil
+}
+
+// SetClaimCode stores the hash of a new claim code for the account,
+// replacing any earlier one
+func (r *postgresRepo) SetClaimCode(ctx context.Context, accountID string, codeHash string) error {
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE users SET claim_code_hash = $1 WHERE account_id = $2`, codeHash, accountID,
+	)
+	if err != nil {
+		log.Printf("Error setting claim code of account %s: %v", accountID, err)
+		return fmt.Errorf("failed to set claim code: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrAccountNotFound
+	}
+	return nil
+}
+
+// ClaimAccount links an unclaimed account to a login and clears its claim
+// code, so that each code can be used once
+func (r *postgresRepo) ClaimAccount(ctx context.Context, loginID int64, accountID string, codeHash string) error {
+	// The conditions stop two logins claiming the same account at once, and
+	// a code replaced since it was checked from being used
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE users SET login_id = $1, claim_code_hash = NULL
+		 WHERE account_id = $2 AND login_id IS NULL AND claim_code_hash = $3`,
+		loginID, accountID, codeHash,
+	)
+	if err != nil {
+		log.Printf("Error claiming account %s: %v", accountID, err)
+		return fmt.Errorf("failed to claim account: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n > 0 {
+		return nil
+	}
+
+	var owner sql.NullInt64
+	err = r.db.QueryRowContext(ctx, `SELECT login_id FROM users WHERE account_id = $1`, accountID).Scan(&owner)
+	if err != nil && err != sql.ErrNoRows {
+		log.Printf("Error fetching account %s: %v", accountID, err)
+		return fmt.Errorf("failed to fetch account: %w", err)
+	}
+	if owner.Valid {
+		return ErrAccountClaimed
+	}
+	return ErrAccountNotFound
+}
+
+// OwnsAccount reports whether the account belongs to the login
+func (r *postgresRepo) OwnsAccount(ctx context.Context, loginID int64, accountID string) (bool, error) {
+	var owns bool
+	err := r.db.QueryRowContext(ctx,
+		`SELECT EXISTS(SELECT 1 FROM users WHERE account_id = $1 AND login_id = $2)`, accountID, loginID,
+	).Scan(&owns)
+	if err != nil {
+		log.Printf("Error checking owner of account %s: %v", accountID, err)
+		return false, fmt.Errorf("failed to check account owner: %w", err)
+	}
+	return owns, nil
+}
+
+// HasOwner reports whether the owner profile belongs to the login
+func (r *postgresRepo) HasOwner(ctx context.Context, loginID int64, ownerID int64) (bool, error) {
+	var has bool
+	err := r.db.QueryRowContext(ctx,
+		`SELECT EXISTS(SELECT 1 FROM owners WHERE id = $1 AND login_id = $2)`, ownerID, loginID,
+	).Scan(&has)
+	if err != nil {
+		log.Printf("Error checking login of owner %d: %v", ownerID, err)
+		return false, fmt.Errorf("failed to check owner: %w", err)
+	}
+	return has, nil
+}
+
+func (r *postgresRepo) accountIDs(ctx context.Context, loginID int64) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT account_id FROM users WHERE login_id = $1 ORDER BY account_id`, loginID)
+	if err != nil {
+		log.Printf("Error querying accounts of login %d: %v", loginID, err)
+		return nil, fmt.Errorf("failed to query accounts: %w", err)
+	}
+	defer rows.Close()
+
+	ids := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan account: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating accounts: %w", err)
+	}
+	return ids, nil
+}
@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"server/types"
+)
+
+var (
+	// ErrLoginNotFound is returned when no login has the given ID or username
+	ErrLoginNotFound = errors.New("login not found")
+
+	// ErrUsernameTaken is returned when registering a username that is already in use
+	ErrUsernameTaken = errors.New("username is already taken")
+
+	// ErrAccountNotFound is returned when no account matches a claim
+	ErrAccountNotFound = errors.New("account not found")
+
+	// ErrAccountClaimed is returned when an account already belongs to another login
+	ErrAccountClaimed = errors.New("account already belongs to another login")
+)
+
+// Repository defines the interface for login data operations
+type Repository interface {
+	// CreateLogin inserts a new login and sets its ID and creation time
+	CreateLogin(ctx context.Context, login *types.Login) error
+
+	// GetLogin retrieves a login and the accounts it owns
+	GetLogin(ctx context.Context, id int64) (*types.Login, error)
+
+	// GetLoginByUsername retrieves a login, including its password hash
+	GetLoginByUsername(ctx context.Context, username string) (*types.Login, error)
+
+	// GetClaimCode retrieves the hash of the account's claim code. It returns
+	// ErrAccountNotFound when the account has no code to claim it with.
+	GetClaimCode(ctx context.Context, accountID string) (string, error)
+
+	// SetClaimCode stores the hash of a new claim code for the account
+	SetClaimCode(ctx context.Context, accountID string, codeHash string) error
+
+	// ClaimAccount links an unclaimed account to a login and clears its
+	// claim code, as long as the code is still the one that was checked
+	ClaimAccount(ctx context.Context, loginID int64, accountID string, codeHash string) error
+
+	// OwnsAccount reports whether the account belongs to the login
+	OwnsAccount(ctx context.Context, loginID int64, accountID string) (bool, error)
+
+	// HasOwner reports whether the owner profile belongs to the login
+	HasOwner(ctx context.Context, loginID int64, ownerID int64) (bool, error)
+}
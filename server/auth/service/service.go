@@ -0,0 +1,285 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"log"
+	"server/auth/repository"
+	"server/types"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	// ErrInvalidCredentials is returned for an unknown username or a wrong password
+	ErrInvalidCredentials = errors.New("invalid username or password")
+
+	// ErrInvalidToken is returned for a missing, malformed, forged or expired token
+	ErrInvalidToken = errors.New("invalid or expired token")
+
+	// ErrForbidden is returned when the caller does not own the account or owner profile
+	ErrForbidden = errors.New("account does not belong to the caller")
+)
+
+// issuer is stamped on every token and checked when it is read back
+const issuer = "financebros"
+
+// claimCodeBytes is the length of the random part of a claim code: 80 bits,
+// 16 characters in base32
+const claimCodeBytes = 10
+
+// dummyHash is compared against when a username is unknown, so that a failed
+// login takes as long whether or not the username exists
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("financebros"), bcrypt.DefaultCost)
+
+// Config holds the token settings
+type Config struct {
+	// Secret signs the HS256 tokens. A random secret is used when it is
+	// empty, so tokens do not survive a restart.
+	Secret []byte
+
+	// TokenTTL is how long a token stays valid after login
+	TokenTTL time.Duration
+}
+
+type Service interface {
+	Register(ctx context.Context, credentials types.Credentials) (*types.AuthToken, error)
+	Login(ctx context.Context, credentials types.Credentials) (*types.AuthToken, error)
+	GetLogin(ctx context.Context, loginID int64) (*types.Login, error)
+	ClaimAccount(ctx context.Context, loginID int64, claim types.AccountClaim) (*types.Login, error)
+
+	// IssueClaimCode sets a new claim code for the account and returns it.
+	// It is for operators and is not served by the API.
+	IssueClaimCode(ctx context.Context, accountID string) (string, error)
+
+	// Authenticate verifies a token and returns the ID of its login
+	Authenticate(token string) (int64, error)
+
+	// Authorize checks that the account belongs to the login
+	Authorize(ctx context.Context, loginID int64, accountID string) error
+
+	// AuthorizeOwner checks that the owner profile belongs to the login
+	AuthorizeOwner(ctx context.Context, loginID int64, ownerID string) error
+}
+
+type service struct {
+	repo   repository.Repository
+	config Config
+}
+
+func NewService(repo repository.Repository, config Config) Service {
+	if len(config.Secret) == 0 {
+		log.Printf("Warning: JWT_SECRET is not set; using a random secret, so tokens will not survive a restart")
+		config.Secret = make([]byte, 32)
+		if _, err := rand.Read(config.Secret); err != nil {
+			panic(fmt.Sprintf("failed to generate token secret: %v", err))
+		}
+	}
+	if config.TokenTTL <= 0 {
+		config.TokenTTL = 24 * time.Hour
+	}
+	return &service{repo: repo, config: config}
+}
+
+// Register creates a login and signs it in
+func (s *service) Register(ctx context.Context, credentials types.Credentials) (*types.AuthToken, error) {
+	username := normalizeUsername(credentials.Username)
+	if err := validateCredentials(username, credentials.Password); err != nil {
+		return nil, err
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(credentials.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	login := &types.Login{Username: username, PasswordHash: string(hash)}
+	if err := s.repo.CreateLogin(ctx, login); err != nil {
+		return nil, err
+	}
+	return s.issue(login)
+}
+
+// Login checks a username and password and returns a new token
+func (s *service) Login(ctx context.Context, credentials types.Credentials) (*types.AuthToken, error) {
+	login, err := s.repo.GetLoginByUsername(ctx, normalizeUsername(credentials.Username))
+	if errors.Is(err, repository.ErrLoginNotFound) {
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(credentials.Password))
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(login.PasswordHash), []byte(credentials.Password)); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	return s.issue(login)
+}
+
+func (s *service) GetLogin(ctx context.Context, loginID int64) (*types.Login, error) {
+	return s.repo.GetLogin(ctx, loginID)
+}
+
+// ClaimAccount links an existing account to the login when the claim code
+// matches the one issued for it. A wrong code and an account without one
+// both respond as an unknown account, so that codes cannot be probed for.
+func (s *service) ClaimAccount(ctx context.Context, loginID int64, claim types.AccountClaim) (*types.Login, error) {
+	claim.AccountID = strings.TrimSpace(claim.AccountID)
+	code := normalizeClaimCode(claim.ClaimCode)
+	if claim.AccountID == "" {
+		return nil, &types.ValidationError{Field: "account_id", Message: "is required"}
+	}
+	if code == "" {
+		return nil, &types.ValidationError{Field: "claim_code", Message: "is required"}
+	}
+
+	codeHash, err := s.repo.GetClaimCode(ctx, claim.AccountID)
+	if errors.Is(err, repository.ErrAccountNotFound) {
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(code))
+		return nil, repository.ErrAccountNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(codeHash), []byte(code)); err != nil {
+		return nil, repository.ErrAccountNotFound
+	}
+
+	if err := s.repo.ClaimAccount(ctx, loginID, claim.AccountID, codeHash); err != nil {
+		return nil, err
+	}
+	return s.repo.GetLogin(ctx, loginID)
+}
+
+// IssueClaimCode generates a new claim code for the account, replacing any
+// earlier one, and returns it. Only its hash is stored, so the code is shown
+// once to the operator who hands it to the account holder.
+func (s *service) IssueClaimCode(ctx context.Context, accountID string) (string, error) {
+	secret := make([]byte, claimCodeBytes)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("failed to generate claim code: %w", err)
+	}
+	code := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(secret)
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(code), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash claim code: %w", err)
+	}
+	if err := s.repo.SetClaimCode(ctx, strings.TrimSpace(accountID), string(hash)); err != nil {
+		return "", err
+	}
+
+	// Group the code in fours so that it is easier to read out and type
+	var groups []string
+	for len(code) > 4 {
+		groups, code = append(groups, code[:4]), code[4:]
+	}
+	return strings.Join(append(groups, code), "-"), nil
+}
+
+// Authenticate verifies a token and returns the ID of its login
+func (s *service) Authenticate(token string) (int64, error) {
+	claims := &jwt.RegisteredClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(*jwt.Token) (interface{}, error) {
+		return s.config.Secret, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithIssuer(issuer),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return 0, ErrInvalidToken
+	}
+
+	loginID, err := strconv.ParseInt(claims.Subject, 10, 64)
+	if err != nil {
+		return 0, ErrInvalidToken
+	}
+	return loginID, nil
+}
+
+// Authorize checks that the account belongs to the login
+func (s *service) Authorize(ctx context.Context, loginID int64, accountID string) error {
+	owns, err := s.repo.OwnsAccount(ctx, loginID, accountID)
+	if err != nil {
+		return err
+	}
+	if !owns {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// AuthorizeOwner checks that the owner profile belongs to the login
+func (s *service) AuthorizeOwner(ctx context.Context, loginID int64, ownerID string) error {
+	id, err := strconv.ParseInt(ownerID, 10, 64)
+	if err != nil {
+		return ErrForbidden
+	}
+	has, err := s.repo.HasOwner(ctx, loginID, id)
+	if err != nil {
+		return err
+	}
+	if !has {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// issue signs a token for the login
+func (s *service) issue(login *types.Login) (*types.AuthToken, error) {
+	now := time.Now()
+	expiresAt := now.Add(s.config.TokenTTL)
+	claims := jwt.RegisteredClaims{
+		Issuer:    issuer,
+		Subject:   strconv.FormatInt(login.ID, 10),
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(expiresAt),
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.config.Secret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign token: %w", err)
+	}
+	return &types.AuthToken{Token: token, TokenType: "Bearer", ExpiresAt: expiresAt, Login: login}, nil
+}
+
+// normalizeClaimCode drops the separators and case a claim code may be typed with
+func normalizeClaimCode(code string) string {
+	code = strings.ToUpper(code)
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, code)
+}
+
+func normalizeUsername(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
+
+func validateCredentials(username, password string) error {
+	switch {
+	case len(username) < 3:
+		return &types.ValidationError{Field: "username", Message: "must be at least 3 characters"}
+	case len(username) > 255:
+		return &types.ValidationError{Field: "username", Message: "must be at most 255 characters"}
+	case strings.ContainsAny(username, " \t\r\n"):
+		return &types.ValidationError{Field: "username", Message: "cannot contain spaces"}
+	case len(password) < 8:
+		return &types.ValidationError{Field: "password", Message: "must be at least 8 characters"}
+	case len(password) > 72:
+		// bcrypt ignores everything past 72 bytes
+		return &types.ValidationError{Field: "password", Message: "must be at most 72 bytes"}
+	}
+	return nil
+}
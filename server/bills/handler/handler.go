@@ -3,10 +3,20 @@ package handler
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"server/bills/repository"
 	"server/bills/service"
+	categoriesRepository "server/categories/repository"
+	categoriesService "server/categories/service"
+	"server/fx/rates"
+	fxRepository "server/fx/repository"
+	fxService "server/fx/service"
+	ownersHandler "server/owners/handler"
+	ownersRepository "server/owners/repository"
+	ownersService "server/owners/service"
+	"server/types"
 	"strconv"
 	"time"
 
@@ -15,17 +25,21 @@ import (
 
 type Handler struct {
 	service service.Service
+	owners  ownersService.Service
 }
 
-func NewHandler(service service.Service) *Handler {
-	return &Handler{service: service}
+func NewHandler(service service.Service, owners ownersService.Service) *Handler {
+	return &Handler{service: service, owners: owners}
 }
 
 // SetupBillRoutes configures all the bill-related routes
 func SetupBillRoutes(router *mux.Router, db *sql.DB) {
 	repo := repository.NewPostgresRepository(db)
-	svc := service.NewService(repo)
-	handler := NewHandler(svc)
+	fx := fxService.NewService(fxRepository.NewPostgresRepository(db))
+	categories := categoriesService.NewService(categoriesRepository.NewPostgresRepository(db), fx)
+	svc := service.NewService(repo, categories, fx)
+	owners := ownersService.NewService(ownersRepository.NewPostgresRepository(db))
+	handler := NewHandler(svc, owners)
 	handler.RegisterRoutes(router)
 }
 
@@ -34,13 +48,31 @@ func (h *Handler) RegisterRoutes(router *mux.Router) {
 	router.HandleFunc("/api/bills/{accountId}", h.HandleGetBills).Methods("GET")
 	router.HandleFunc("/api/bills/{accountId}/recurring", h.HandleGetRecurringBills).Methods("GET")
 	router.HandleFunc("/api/bills/{accountId}/upcoming", h.HandleGetUpcomingBills).Methods("GET")
+	router.HandleFunc("/api/bills/{accountId}/status", h.HandleGetBillStatus).Methods("GET")
+	router.HandleFunc("/api/bills/{accountId}/paychecks", h.HandleGetPaycheckPlan).Methods("GET")
 	router.HandleFunc("/api/bills/{accountId}/history/{merchant}", h.HandleGetBillHistory).Methods("GET")
+	router.HandleFunc("/api/bills/{accountId}/declared", h.HandleListDeclaredBills).Methods("GET")
+	router.HandleFunc("/api/bills/{accountId}/declared", h.HandleCreateDeclaredBill).Methods("POST")
+	router.HandleFunc("/api/bills/{accountId}/declared/{billId:[0-9]+}", h.HandleGetDeclaredBill).Methods("GET")
+	router.HandleFunc("/api/bills/{accountId}/declared/{billId:[0-9]+}", h.HandleUpdateDeclaredBill).Methods("PUT")
+	router.HandleFunc("/api/bills/{accountId}/declared/{billId:[0-9]+}", h.HandleDeleteDeclaredBill).Methods("DELETE")
+
+	// The same views across every account linked to an owner
+	router.HandleFunc("/api/owners/{ownerId:[0-9]+}/bills", h.HandleGetBills).Methods("GET")
+	router.HandleFunc("/api/owners/{ownerId:[0-9]+}/bills/recurring", h.HandleGetRecurringBills).Methods("GET")
+	router.HandleFunc("/api/owners/{ownerId:[0-9]+}/bills/upcoming", h.HandleGetUpcomingBills).Methods("GET")
+	router.HandleFunc("/api/owners/{ownerId:[0-9]+}/bills/status", h.HandleGetBillStatus).Methods("GET")
+	router.HandleFunc("/api/owners/{ownerId:[0-9]+}/bills/paychecks", h.HandleGetPaycheckPlan).Methods("GET")
+	router.HandleFunc("/api/owners/{ownerId:[0-9]+}/bills/history/{merchant}", h.HandleGetBillHistory).Methods("GET")
 }
 
 // HandleGetBills handles requests for bill payments
 func (h *Handler) HandleGetBills(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	accountID := vars["accountId"]
+	accountIDs, _, err := ownersHandler.RequestAccounts(r, h.owners)
+	if err != nil {
+		writeError(w, "Failed to get bills", err)
+		return
+	}
 
 	year, _ := strconv.Atoi(r.URL.Query().Get("year"))
 	month, _ := strconv.Atoi(r.URL.Query().Get("month"))
@@ -51,7 +83,13 @@ func (h *Handler) HandleGetBills(w http.ResponseWriter, r *http.Request) {
 		month = int(now.Month())
 	}
 
-	bills, err := h.service.GetBillsByMonth(r.Context(), accountID, year, month)
+	tags, err := types.ParseTagFilter(r.URL.Query()["tag"])
+	if err != nil {
+		writeError(w, "Failed to get bills", err)
+		return
+	}
+
+	bills, err := h.service.GetBillsByMonth(r.Context(), accountIDs, year, month, tags)
 	if err != nil {
 		log.Printf("Error getting bills: %v", err)
 		http.Error(w, "Failed to get bills", http.StatusInternalServerError)
@@ -64,10 +102,13 @@ func (h *Handler) HandleGetBills(w http.ResponseWriter, r *http.Request) {
 
 // HandleGetRecurringBills handles requests for recurring bills
 func (h *Handler) HandleGetRecurringBills(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	accountID := vars["accountId"]
+	accountIDs, _, err := ownersHandler.RequestAccounts(r, h.owners)
+	if err != nil {
+		writeError(w, "Failed to get recurring bills", err)
+		return
+	}
 
-	bills, err := h.service.GetRecurringBills(r.Context(), accountID)
+	bills, err := h.service.GetRecurringBills(r.Context(), accountIDs)
 	if err != nil {
 		log.Printf("Error getting recurring bills: %v", err)
 		http.Error(w, "Failed to get recurring bills", http.StatusInternalServerError)
@@ -80,10 +121,13 @@ func (h *Handler) HandleGetRecurringBills(w http.ResponseWriter, r *http.Request
 
 // HandleGetUpcomingBills handles requests for upcoming bills
 func (h *Handler) HandleGetUpcomingBills(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	accountID := vars["accountId"]
+	accountIDs, _, err := ownersHandler.RequestAccounts(r, h.owners)
+	if err != nil {
+		writeError(w, "Failed to get upcoming bills", err)
+		return
+	}
 
-	bills, err := h.service.GetUpcomingBills(r.Context(), accountID)
+	bills, err := h.service.GetUpcomingBills(r.Context(), accountIDs)
 	if err != nil {
 		log.Printf("Error getting upcoming bills: %v", err)
 		http.Error(w, "Failed to get upcoming bills", http.StatusInternalServerError)
@@ -94,13 +138,78 @@ func (h *Handler) HandleGetUpcomingBills(w http.ResponseWriter, r *http.Request)
 	json.NewEncoder(w).Encode(bills)
 }
 
+// HandleGetBillStatus handles requests for whether each bill was paid on
+// time over the last "months" months, six unless given
+func (h *Handler) HandleGetBillStatus(w http.ResponseWriter, r *http.Request) {
+	accountIDs, _, err := ownersHandler.RequestAccounts(r, h.owners)
+	if err != nil {
+		writeError(w, "Failed to get bill status", err)
+		return
+	}
+
+	months := 6
+	if value := r.URL.Query().Get("months"); value != "" {
+		months, err = strconv.Atoi(value)
+		if err != nil {
+			http.Error(w, "Invalid months", http.StatusBadRequest)
+			return
+		}
+	}
+
+	report, err := h.service.GetBillStatus(r.Context(), accountIDs, months)
+	if err != nil {
+		writeError(w, "Failed to get bill status", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// HandleGetPaycheckPlan handles requests for which paycheck covers each bill
+// due over the next "months" months, one unless given
+func (h *Handler) HandleGetPaycheckPlan(w http.ResponseWriter, r *http.Request) {
+	accountIDs, _, err := ownersHandler.RequestAccounts(r, h.owners)
+	if err != nil {
+		writeError(w, "Failed to get paycheck plan", err)
+		return
+	}
+
+	months := 1
+	if value := r.URL.Query().Get("months"); value != "" {
+		months, err = strconv.Atoi(value)
+		if err != nil {
+			http.Error(w, "Invalid months", http.StatusBadRequest)
+			return
+		}
+	}
+
+	plan, err := h.service.GetPaycheckPlan(r.Context(), accountIDs, months)
+	if err != nil {
+		writeError(w, "Failed to get paycheck plan", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(plan)
+}
+
 // HandleGetBillHistory handles requests for bill history
 func (h *Handler) HandleGetBillHistory(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	accountID := vars["accountId"]
-	merchant := vars["merchant"]
+	accountIDs, _, err := ownersHandler.RequestAccounts(r, h.owners)
+	if err != nil {
+		writeError(w, "Failed to get bill history", err)
+		return
+	}
+	merchant := mux.Vars(r)["merchant"]
+
+	tags, err := types.ParseTagFilter(r.URL.Query()["tag"])
+	if err != nil {
+		writeError(w, "Failed to get bill history", err)
+		return
+	}
 
-	history, err := h.service.GetBillHistory(r.Context(), accountID, merchant)
+	history, err := h.service.GetBillHistory(r.Context(), accountIDs, merchant, tags)
 	if err != nil {
 		log.Printf("Error getting bill history: %v", err)
 		http.Error(w, "Failed to get bill history", http.StatusInternalServerError)
@@ -109,5 +218,109 @@ func (h *Handler) HandleGetBillHistory(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(history)
-} 
+}
+
+// HandleListDeclaredBills handles requests for the bills an account declared
+func (h *Handler) HandleListDeclaredBills(w http.ResponseWriter, r *http.Request) {
+	accountID := mux.Vars(r)["accountId"]
+
+	bills, err := h.service.ListBills(r.Context(), accountID)
+	if err != nil {
+		writeError(w, "Failed to get declared bills", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(bills)
+}
+
+// HandleCreateDeclaredBill handles requests to declare a bill
+func (h *Handler) HandleCreateDeclaredBill(w http.ResponseWriter, r *http.Request) {
+	accountID := mux.Vars(r)["accountId"]
+
+	var input types.Bill
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
 
+	bill, err := h.service.CreateBill(r.Context(), accountID, input)
+	if err != nil {
+		writeError(w, "Failed to create declared bill", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(bill)
+}
+
+// HandleGetDeclaredBill handles requests for a single declared bill
+func (h *Handler) HandleGetDeclaredBill(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	billID, _ := strconv.ParseInt(vars["billId"], 10, 64)
+
+	bill, err := h.service.GetBill(r.Context(), vars["accountId"], billID)
+	if err != nil {
+		writeError(w, "Failed to get declared bill", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(bill)
+}
+
+// HandleUpdateDeclaredBill handles requests to replace a declared bill
+func (h *Handler) HandleUpdateDeclaredBill(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	billID, _ := strconv.ParseInt(vars["billId"], 10, 64)
+
+	var input types.Bill
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	bill, err := h.service.UpdateBill(r.Context(), vars["accountId"], billID, input)
+	if err != nil {
+		writeError(w, "Failed to update declared bill", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(bill)
+}
+
+// HandleDeleteDeclaredBill handles requests to delete a declared bill
+func (h *Handler) HandleDeleteDeclaredBill(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	billID, _ := strconv.ParseInt(vars["billId"], 10, 64)
+
+	if err := h.service.DeleteBill(r.Context(), vars["accountId"], billID); err != nil {
+		writeError(w, "Failed to delete declared bill", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeError converts errors resolving the accounts of a request and
+// declared bill errors into HTTP responses; anything else is ours
+func writeError(w http.ResponseWriter, message string, err error) {
+	var validationErr *types.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		http.Error(w, validationErr.Error(), http.StatusBadRequest)
+	case errors.Is(err, ownersRepository.ErrOwnerNotFound):
+		http.Error(w, "Owner not found", http.StatusNotFound)
+	case errors.Is(err, repository.ErrAccountNotFound):
+		http.Error(w, "Account not found", http.StatusNotFound)
+	case errors.Is(err, repository.ErrBillNotFound):
+		http.Error(w, "Bill not found", http.StatusNotFound)
+	case errors.Is(err, rates.ErrNoRate):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		log.Printf("%s: %v", message, err)
+		http.Error(w, message, http.StatusInternalServerError)
+	}
+}
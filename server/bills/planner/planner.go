@@ -0,0 +1,159 @@
+package planner
+
+import (
+	"math"
+	"server/bills/recurrence"
+	"server/fx/rates"
+	"server/types"
+	"sort"
+	"time"
+)
+
+// Config controls how pay schedules are detected
+type Config struct {
+	Recurrence recurrence.Config
+}
+
+// DefaultConfig takes income paid more regularly than not as a pay schedule
+var DefaultConfig = Config{Recurrence: recurrence.Config{MinConfidence: 0.5, Income: true}}
+
+// Bill is one due date of a bill that a paycheck must cover
+type Bill struct {
+	DueDate time.Time
+	Detail  types.BillDetail
+}
+
+// Paychecks detects pay schedules in income transactions and returns them
+// with their paychecks through to: those received since the earliest of the
+// schedules' last paychecks, so that every schedule's current paycheck is
+// included, then those each schedule is expected to pay at its median
+// amount. Paychecks on the same day in the same currency are combined, and
+// the result is ordered by date.
+func (c Config) Paychecks(income []types.Transaction, today, to time.Time) ([]types.PaySchedule, []types.Paycheck) {
+	detected := c.Recurrence.Detect(income, today)
+
+	type payday struct {
+		date     time.Time
+		currency string
+	}
+	schedules := make([]types.PaySchedule, 0, len(detected))
+	byDay := make(map[payday]*types.Paycheck)
+	add := func(date time.Time, source string, amount types.Money, received bool) {
+		key := payday{date, amount.Currency}
+		p, ok := byDay[key]
+		if !ok {
+			p = &types.Paycheck{Date: date, Sources: []string{}, Received: received, Currency: amount.Currency}
+			byDay[key] = p
+		}
+		p.Sources = append(p.Sources, source)
+		p.Amount = p.Amount.Add(amount)
+		p.Received = p.Received && received
+	}
+
+	var start time.Time
+	for _, r := range detected {
+		if start.IsZero() || r.LastOccurrence.Before(start) {
+			start = r.LastOccurrence
+		}
+	}
+
+	for _, r := range detected {
+		schedules = append(schedules, types.PaySchedule{
+			Source:      r.Merchant,
+			Frequency:   r.Frequency,
+			Amount:      r.MedianAmount,
+			Confidence:  r.Confidence,
+			LastPayDate: r.LastOccurrence,
+			NextPayDate: r.NextDueDate,
+		})
+
+		received := make(map[time.Time]types.Money)
+		var dates []time.Time
+		for _, t := range income {
+			date := day(t.Date)
+			if t.Merchant != r.Merchant || t.Category != r.Category || t.Amount.Sign() <= 0 ||
+				date.Before(start) || date.After(r.LastOccurrence) {
+				continue
+			}
+			if _, ok := received[date]; !ok {
+				dates = append(dates, date)
+			}
+			received[date] = received[date].Add(types.NewMoney(t.Amount.Cents, t.Currency))
+		}
+		for _, date := range dates {
+			add(date, r.Merchant, received[date], true)
+		}
+
+		for _, date := range recurrence.Schedule(r).DueDates(r.NextDueDate, to) {
+			add(date, r.Merchant, r.MedianAmount, false)
+		}
+	}
+
+	paychecks := make([]types.Paycheck, 0, len(byDay))
+	for _, p := range byDay {
+		paychecks = append(paychecks, *p)
+	}
+	sort.Slice(paychecks, func(i, j int) bool {
+		if !paychecks[i].Date.Equal(paychecks[j].Date) {
+			return paychecks[i].Date.Before(paychecks[j].Date)
+		}
+		return paychecks[i].Currency < paychecks[j].Currency
+	})
+	return schedules, paychecks
+}
+
+// Assign gives each bill to the paycheck that must cover it, the last one on
+// or before its due date, and works out what share of each paycheck its
+// bills take and what remains. Of several paychecks on that day, the one in
+// the bill's currency is preferred; a bill in another currency than its
+// paycheck is converted into the paycheck's at the rate of its due date. The
+// paychecks must be ordered by date; bills due before the first of them are
+// returned unassigned.
+func Assign(paychecks []types.Paycheck, bills []Bill, table *rates.Table) ([]types.BillDetail, error) {
+	sorted := append([]Bill(nil), bills...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].DueDate.Before(sorted[j].DueDate) })
+
+	unassigned := []types.BillDetail{}
+	for i := range paychecks {
+		paychecks[i].Bills = []types.BillDetail{}
+		paychecks[i].Committed = types.NewMoney(0, paychecks[i].Currency)
+	}
+	for _, b := range sorted {
+		n := sort.Search(len(paychecks), func(i int) bool { return paychecks[i].Date.After(b.DueDate) }) - 1
+		if n < 0 {
+			unassigned = append(unassigned, b.Detail)
+			continue
+		}
+		for i := n; i >= 0 && paychecks[i].Date.Equal(paychecks[n].Date); i-- {
+			if paychecks[i].Currency == b.Detail.Amount.Currency {
+				n = i
+				break
+			}
+		}
+		p := &paychecks[n]
+		detail := b.Detail
+		amount, err := table.Convert(detail.Amount, detail.Amount.Currency, p.Currency, b.DueDate)
+		if err != nil {
+			return nil, err
+		}
+		detail.Amount = amount
+		detail.PaycheckPercentage = round(100*detail.Amount.Ratio(p.Amount), 1)
+		p.Bills = append(p.Bills, detail)
+		p.Committed = p.Committed.Add(detail.Amount)
+	}
+	for i := range paychecks {
+		p := &paychecks[i]
+		p.CommittedPercentage = round(100*p.Committed.Ratio(p.Amount), 1)
+		p.Remaining = p.Amount.Sub(p.Committed)
+	}
+	return unassigned, nil
+}
+
+func day(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func round(value float64, places int) float64 {
+	scale := math.Pow(10, float64(places))
+	return math.Round(value*scale) / scale
+}
@@ -0,0 +1,140 @@
+package planner
+
+import (
+	"server/fx/rates"
+	"server/types"
+	"testing"
+	"time"
+)
+
+// paycheck is a paycheck paid on the given day of January 2025
+func paycheck(day int, cents int64, currency string) types.Paycheck {
+	date := time.Date(2025, time.January, day, 0, 0, 0, 0, time.UTC)
+	return types.Paycheck{Date: date, Currency: currency, Amount: types.NewMoney(cents, currency)}
+}
+
+// bill is a bill due on the given day of January 2025
+func bill(name string, due int, cents int64, currency string) Bill {
+	date := time.Date(2025, time.January, due, 0, 0, 0, 0, time.UTC)
+	return Bill{DueDate: date, Detail: types.BillDetail{Name: name, Amount: types.NewMoney(cents, currency)}}
+}
+
+// assigned is where a bill should end up: the index of its paycheck, or -1
+// when it is unassigned, and its amount in the paycheck's currency
+type assigned struct {
+	paycheck   int
+	cents      int64
+	percentage float64
+}
+
+func TestAssign(t *testing.T) {
+	table := rates.NewTable([]types.ExchangeRate{
+		{Base: "EUR", Quote: "USD", Date: time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC), Rate: 1.1},
+		{Base: "GBP", Quote: "USD", Date: time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC), Rate: 1.25},
+	})
+
+	tests := []struct {
+		name      string
+		paychecks []types.Paycheck
+		bills     []Bill
+		want      map[string]assigned
+	}{
+		{
+			name: "the last paycheck on or before the due date",
+			paychecks: []types.Paycheck{
+				paycheck(3, 200000, "USD"),
+				paycheck(17, 200000, "USD"),
+			},
+			bills: []Bill{
+				bill("Rent", 16, 150000, "USD"),
+				bill("Phone", 17, 5000, "USD"),
+				bill("Insurance", 2, 10000, "USD"),
+			},
+			want: map[string]assigned{
+				"Rent":      {0, 150000, 75},
+				"Phone":     {1, 5000, 2.5},
+				"Insurance": {-1, 10000, 0},
+			},
+		},
+		{
+			name:      "converted into the paycheck's currency",
+			paychecks: []types.Paycheck{paycheck(3, 220000, "USD")},
+			bills:     []Bill{bill("Streaming", 10, 1000, "EUR")},
+			want:      map[string]assigned{"Streaming": {0, 1100, 0.5}},
+		},
+		{
+			// Paychecks on the same day are ordered EUR before USD, so the
+			// EUR bill must not fall through to the later USD one
+			name: "the paycheck in the bill's currency on the same day",
+			paychecks: []types.Paycheck{
+				paycheck(3, 300000, "EUR"),
+				paycheck(3, 200000, "USD"),
+			},
+			bills: []Bill{
+				bill("Rent", 5, 90000, "EUR"),
+				bill("Car", 5, 40000, "USD"),
+				bill("Gym", 5, 2000, "GBP"),
+			},
+			want: map[string]assigned{
+				"Rent": {0, 90000, 30},
+				"Car":  {1, 40000, 20},
+				"Gym":  {1, 2500, 1.3},
+			},
+		},
+		{
+			// An earlier paycheck in the bill's currency is not preferred
+			// over a later one in another currency
+			name: "only the same day",
+			paychecks: []types.Paycheck{
+				paycheck(1, 300000, "EUR"),
+				paycheck(3, 200000, "USD"),
+			},
+			bills: []Bill{bill("Rent", 5, 100000, "EUR")},
+			want:  map[string]assigned{"Rent": {1, 110000, 55}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			unassigned, err := Assign(tt.paychecks, tt.bills, table)
+			if err != nil {
+				t.Fatalf("Assign() error = %v", err)
+			}
+
+			found := make(map[string]bool)
+			for _, d := range unassigned {
+				found[d.Name] = true
+				if w := tt.want[d.Name]; w.paycheck != -1 {
+					t.Errorf("%s is unassigned, want paycheck %d", d.Name, w.paycheck)
+				}
+			}
+			for i, p := range tt.paychecks {
+				var committed int64
+				for _, d := range p.Bills {
+					found[d.Name] = true
+					committed += d.Amount.Cents
+					w := tt.want[d.Name]
+					if w.paycheck != i {
+						t.Errorf("%s is on paycheck %d, want %d", d.Name, i, w.paycheck)
+						continue
+					}
+					if d.Amount.Cents != w.cents || d.Amount.Currency != p.Currency {
+						t.Errorf("%s amount = %d %s, want %d %s", d.Name, d.Amount.Cents, d.Amount.Currency, w.cents, p.Currency)
+					}
+					if d.PaycheckPercentage != w.percentage {
+						t.Errorf("%s PaycheckPercentage = %v, want %v", d.Name, d.PaycheckPercentage, w.percentage)
+					}
+				}
+				if p.Committed.Cents != committed || p.Remaining.Cents != p.Amount.Cents-committed {
+					t.Errorf("paycheck %d: Committed, Remaining = %d, %d, want %d, %d",
+						i, p.Committed.Cents, p.Remaining.Cents, committed, p.Amount.Cents-committed)
+				}
+			}
+			for name := range tt.want {
+				if !found[name] {
+					t.Errorf("%s is missing from the result", name)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,227 @@
+package reconciler
+
+import (
+	"math"
+	"server/merchants/normalizer"
+	"server/types"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Config controls how far a payment may be from its due date and expected
+// amount
+type Config struct {
+	// EarlyDays is how many days before its due date a bill may be paid
+	EarlyDays int
+
+	// GraceDays is how many days after its due date a payment still counts
+	// as on time
+	GraceDays int
+
+	// LateDays is how many days after its due date a payment is still
+	// matched, as a late one; after that the payment is missed
+	LateDays int
+
+	// AmountTolerance is the share of the expected amount by which a
+	// payment may differ before the amount counts as changed
+	AmountTolerance float64
+
+	// VariableAmountTolerance replaces AmountTolerance for declared bills
+	// with a variable amount, whose expected amount is only an estimate
+	VariableAmountTolerance float64
+}
+
+// DefaultConfig accepts payments a week early or a few days late as on time,
+// gives up on them a month after the due date and allows the small price
+// differences of taxes and rounding. The windows of a bill paid more often
+// than monthly are narrowed to its cycle, so that two payments are never
+// candidates for the same due date.
+var DefaultConfig = Config{
+	EarlyDays:               7,
+	GraceDays:               3,
+	LateDays:                30,
+	AmountTolerance:         0.05,
+	VariableAmountTolerance: 0.5,
+}
+
+// SameMerchant reports whether a payment to merchant is a payment of a bill
+// to payee: their normalized names are the same, or the merchant's name
+// continues the payee's, so that "City Water" covers "CITY WATER UTIL 4471"
+func SameMerchant(payee, merchant string) bool {
+	p, m := normalizer.Normalize(payee), normalizer.Normalize(merchant)
+	if p == "" || m == "" {
+		return false
+	}
+	return p == m || strings.HasPrefix(m, p+" ")
+}
+
+// window is the range of days around a due date that payments are matched in
+type window struct {
+	early, late int
+}
+
+type candidate struct {
+	expected, transaction int
+	distance              int
+}
+
+// Reconcile matches expected bill payments to the transactions that paid
+// them and returns the status of each expected payment, in the order given.
+// A payment is matched when it went to the bill's merchant within the
+// window around the due date; each transaction pays at most one expected
+// payment and each expected payment is paid by at most one transaction, the
+// closest pairs being taken first. Expected payments of the same bill are
+// told apart by merchant and BillID.
+func (c Config) Reconcile(expected []types.UpcomingBill, transactions []types.Transaction, today time.Time) []types.BillPaymentStatus {
+	today = day(today)
+	windows := c.windows(expected)
+
+	var candidates []candidate
+	for i, bill := range expected {
+		due := day(bill.DueDate)
+		for j, t := range transactions {
+			if t.Amount.Sign() >= 0 || !SameMerchant(bill.Merchant, t.Merchant) {
+				continue
+			}
+			days := daysBetween(due, day(t.Date))
+			if days < -windows[i].early || days > windows[i].late {
+				continue
+			}
+			candidates = append(candidates, candidate{expected: i, transaction: j, distance: abs(days)})
+		}
+	}
+	sort.SliceStable(candidates, func(a, b int) bool {
+		return candidates[a].distance < candidates[b].distance
+	})
+
+	statuses := make([]types.BillPaymentStatus, len(expected))
+	matched := make([]bool, len(expected))
+	used := make([]bool, len(transactions))
+	for _, cand := range candidates {
+		if matched[cand.expected] || used[cand.transaction] {
+			continue
+		}
+		matched[cand.expected], used[cand.transaction] = true, true
+		statuses[cand.expected] = c.paid(expected[cand.expected], transactions[cand.transaction])
+	}
+
+	for i, bill := range expected {
+		if matched[i] {
+			continue
+		}
+		statuses[i] = types.BillPaymentStatus{
+			DueDate:        bill.DueDate,
+			ExpectedAmount: bill.ExpectedAmount,
+			Status:         types.BillPending,
+		}
+		if daysBetween(day(bill.DueDate), today) > windows[i].late {
+			statuses[i].Status = types.BillMissed
+		}
+	}
+	return statuses
+}
+
+// paid describes an expected payment that transaction t paid
+func (c Config) paid(bill types.UpcomingBill, t types.Transaction) types.BillPaymentStatus {
+	paidDate := t.Date
+	paidAmount := t.Amount.Abs()
+	status := types.BillPaymentStatus{
+		DueDate:        bill.DueDate,
+		ExpectedAmount: bill.ExpectedAmount,
+		Status:         types.BillPaid,
+		TransactionID:  t.TransactionID,
+		PaidDate:       &paidDate,
+		PaidAmount:     &paidAmount,
+	}
+
+	tolerance := c.AmountTolerance
+	if bill.AmountType == types.BillAmountVariable {
+		tolerance = c.VariableAmountTolerance
+	}
+	difference := math.Abs(float64(paidAmount.Cents - bill.ExpectedAmount.Cents))
+
+	if days := daysBetween(day(bill.DueDate), day(t.Date)); days > 0 {
+		status.DaysLate = days
+		if days > c.GraceDays {
+			status.Status = types.BillLate
+			return status
+		}
+	}
+	if difference > tolerance*float64(bill.ExpectedAmount.Abs().Cents) {
+		status.Status = types.BillAmountChanged
+	}
+	return status
+}
+
+// windows narrows the configured window around each due date to half the
+// gap to the bill's previous due date before it and the rest of the gap to
+// its next one after it, so that the windows of one bill never overlap
+func (c Config) windows(expected []types.UpcomingBill) []window {
+	type key struct {
+		merchant string
+		billID   int64
+	}
+	series := make(map[key][]int)
+	for i, bill := range expected {
+		k := key{merchant: normalizer.Normalize(bill.Merchant)}
+		if bill.BillID != nil {
+			k.billID = *bill.BillID
+		}
+		series[k] = append(series[k], i)
+	}
+
+	windows := make([]window, len(expected))
+	for _, indexes := range series {
+		sort.SliceStable(indexes, func(a, b int) bool {
+			return expected[indexes[a]].DueDate.Before(expected[indexes[b]].DueDate)
+		})
+		gaps := make([]int, len(indexes)) // days to the previous due date
+		for n := 1; n < len(indexes); n++ {
+			gaps[n] = daysBetween(day(expected[indexes[n-1]].DueDate), day(expected[indexes[n]].DueDate))
+		}
+
+		for n, i := range indexes {
+			w := window{early: c.EarlyDays, late: c.LateDays}
+			previous, next := gaps[n], 0
+			if n+1 < len(indexes) {
+				next = gaps[n+1]
+			} else if n > 0 {
+				next = previous // the next due date is about one cycle away
+			}
+			if n == 0 && next > 0 {
+				previous = next
+			}
+			if previous > 0 && w.early > previous/2 {
+				w.early = previous / 2
+			}
+			if next > 0 {
+				nextEarly := c.EarlyDays
+				if nextEarly > next/2 {
+					nextEarly = next / 2
+				}
+				if limit := next - nextEarly - 1; w.late > limit {
+					w.late = limit
+				}
+			}
+			windows[i] = w
+		}
+	}
+	return windows
+}
+
+func day(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// daysBetween returns the whole days from a to b, negative when b is earlier
+func daysBetween(a, b time.Time) int {
+	return int(math.Round(b.Sub(a).Hours() / 24))
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
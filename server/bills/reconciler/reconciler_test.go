@@ -0,0 +1,292 @@
+package reconciler
+
+import (
+	"server/types"
+	"testing"
+	"time"
+)
+
+// bill is a fixed bill of merchant due on a day of 2025
+func bill(merchant string, cents int64, month time.Month, day int) types.UpcomingBill {
+	return types.UpcomingBill{
+		Merchant:       merchant,
+		ExpectedAmount: types.NewMoney(cents, ""),
+		DueDate:        time.Date(2025, month, day, 0, 0, 0, 0, time.UTC),
+		AmountType:     types.BillAmountFixed,
+	}
+}
+
+// payment is a transaction at merchant on a day of 2025
+func payment(id, merchant string, cents int64, month time.Month, day int) types.Transaction {
+	return types.Transaction{
+		TransactionID: id,
+		Merchant:      merchant,
+		Amount:        types.NewMoney(cents, ""),
+		Date:          time.Date(2025, month, day, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	variable := bill("Power Co", 10000, time.January, 15)
+	variable.AmountType = types.BillAmountVariable
+	first, second := int64(1), int64(2)
+	declared1, declared2 := bill("Insurance", 5000, time.January, 15), bill("Insurance", 5000, time.January, 15)
+	declared1.BillID, declared2.BillID = &first, &second
+
+	type want struct {
+		status   string
+		tx       string
+		daysLate int
+	}
+	tests := []struct {
+		name         string
+		expected     []types.UpcomingBill
+		transactions []types.Transaction
+		today        time.Time
+		want         []want
+	}{
+		{
+			name:         "paid within the grace period",
+			expected:     []types.UpcomingBill{bill("Rent", 150000, time.January, 15)},
+			transactions: []types.Transaction{payment("t1", "Rent", -150000, time.January, 17)},
+			today:        time.Date(2025, time.January, 20, 0, 0, 0, 0, time.UTC),
+			want:         []want{{types.BillPaid, "t1", 2}},
+		},
+		{
+			name:         "paid early",
+			expected:     []types.UpcomingBill{bill("Rent", 150000, time.January, 15)},
+			transactions: []types.Transaction{payment("t1", "Rent", -150000, time.January, 9)},
+			today:        time.Date(2025, time.January, 20, 0, 0, 0, 0, time.UTC),
+			want:         []want{{types.BillPaid, "t1", 0}},
+		},
+		{
+			name:         "paid too early to count",
+			expected:     []types.UpcomingBill{bill("Rent", 150000, time.January, 15)},
+			transactions: []types.Transaction{payment("t1", "Rent", -150000, time.January, 7)},
+			today:        time.Date(2025, time.January, 20, 0, 0, 0, 0, time.UTC),
+			want:         []want{{types.BillPending, "", 0}},
+		},
+		{
+			name:         "late",
+			expected:     []types.UpcomingBill{bill("Rent", 150000, time.January, 15)},
+			transactions: []types.Transaction{payment("t1", "Rent", -150000, time.January, 20)},
+			today:        time.Date(2025, time.January, 25, 0, 0, 0, 0, time.UTC),
+			want:         []want{{types.BillLate, "t1", 5}},
+		},
+		{
+			name:         "late takes precedence over a changed amount",
+			expected:     []types.UpcomingBill{bill("Rent", 150000, time.January, 15)},
+			transactions: []types.Transaction{payment("t1", "Rent", -175000, time.January, 20)},
+			today:        time.Date(2025, time.January, 25, 0, 0, 0, 0, time.UTC),
+			want:         []want{{types.BillLate, "t1", 5}},
+		},
+		{
+			name:         "changed amount paid on time",
+			expected:     []types.UpcomingBill{bill("Rent", 150000, time.January, 15)},
+			transactions: []types.Transaction{payment("t1", "Rent", -175000, time.January, 15)},
+			today:        time.Date(2025, time.January, 25, 0, 0, 0, 0, time.UTC),
+			want:         []want{{types.BillAmountChanged, "t1", 0}},
+		},
+		{
+			name:         "a changed amount within the grace period is still a changed amount",
+			expected:     []types.UpcomingBill{bill("Rent", 150000, time.January, 15)},
+			transactions: []types.Transaction{payment("t1", "Rent", -175000, time.January, 18)},
+			today:        time.Date(2025, time.January, 25, 0, 0, 0, 0, time.UTC),
+			want:         []want{{types.BillAmountChanged, "t1", 3}},
+		},
+		{
+			name:         "rounding within the amount tolerance",
+			expected:     []types.UpcomingBill{bill("Rent", 150000, time.January, 15)},
+			transactions: []types.Transaction{payment("t1", "Rent", -152500, time.January, 15)},
+			today:        time.Date(2025, time.January, 25, 0, 0, 0, 0, time.UTC),
+			want:         []want{{types.BillPaid, "t1", 0}},
+		},
+		{
+			name:         "a variable amount has a wider tolerance",
+			expected:     []types.UpcomingBill{variable},
+			transactions: []types.Transaction{payment("t1", "Power Co", -14000, time.January, 15)},
+			today:        time.Date(2025, time.January, 25, 0, 0, 0, 0, time.UTC),
+			want:         []want{{types.BillPaid, "t1", 0}},
+		},
+		{
+			name:     "pending while it can still be paid",
+			expected: []types.UpcomingBill{bill("Rent", 150000, time.January, 15)},
+			today:    time.Date(2025, time.February, 14, 0, 0, 0, 0, time.UTC),
+			want:     []want{{types.BillPending, "", 0}},
+		},
+		{
+			name:     "missed once the late window has passed",
+			expected: []types.UpcomingBill{bill("Rent", 150000, time.January, 15)},
+			today:    time.Date(2025, time.February, 15, 0, 0, 0, 0, time.UTC),
+			want:     []want{{types.BillMissed, "", 0}},
+		},
+		{
+			name:     "refunds and other merchants do not pay a bill",
+			expected: []types.UpcomingBill{bill("Rent", 150000, time.January, 15)},
+			transactions: []types.Transaction{
+				payment("t1", "Rent", 150000, time.January, 15),
+				payment("t2", "Grocer", -150000, time.January, 15),
+			},
+			today: time.Date(2025, time.February, 15, 0, 0, 0, 0, time.UTC),
+			want:  []want{{types.BillMissed, "", 0}},
+		},
+		{
+			name:         "the bank's description continues the payee's name",
+			expected:     []types.UpcomingBill{bill("City Water", 4000, time.January, 15)},
+			transactions: []types.Transaction{payment("t1", "CITY WATER UTIL 4471", -4000, time.January, 15)},
+			today:        time.Date(2025, time.January, 20, 0, 0, 0, 0, time.UTC),
+			want:         []want{{types.BillPaid, "t1", 0}},
+		},
+		{
+			name:     "each payment pays one due date, the closest",
+			expected: []types.UpcomingBill{bill("Rent", 150000, time.January, 15), bill("Rent", 150000, time.February, 15)},
+			transactions: []types.Transaction{
+				payment("t1", "Rent", -150000, time.February, 14),
+			},
+			today: time.Date(2025, time.February, 20, 0, 0, 0, 0, time.UTC),
+			want:  []want{{types.BillMissed, "", 0}, {types.BillPaid, "t1", 0}},
+		},
+		{
+			name:         "declared bills of the same payee are told apart",
+			expected:     []types.UpcomingBill{declared1, declared2},
+			transactions: []types.Transaction{payment("t1", "Insurance", -5000, time.January, 15)},
+			today:        time.Date(2025, time.January, 20, 0, 0, 0, 0, time.UTC),
+			want:         []want{{types.BillPaid, "t1", 0}, {types.BillPending, "", 0}},
+		},
+		{
+			// A payment four days after one weekly due date and three before
+			// the next only counts for the next one
+			name: "weekly windows are narrowed to the cycle",
+			expected: []types.UpcomingBill{
+				bill("Dog Walker", 6000, time.January, 6),
+				bill("Dog Walker", 6000, time.January, 13),
+			},
+			transactions: []types.Transaction{payment("t1", "Dog Walker", -6000, time.January, 10)},
+			today:        time.Date(2025, time.January, 14, 0, 0, 0, 0, time.UTC),
+			want:         []want{{types.BillMissed, "", 0}, {types.BillPaid, "t1", 0}},
+		},
+		{
+			name: "weekly payments pay their own due dates",
+			expected: []types.UpcomingBill{
+				bill("Dog Walker", 6000, time.January, 6),
+				bill("Dog Walker", 6000, time.January, 13),
+				bill("Dog Walker", 6000, time.January, 20),
+			},
+			transactions: []types.Transaction{
+				payment("t1", "Dog Walker", -6000, time.January, 8),
+				payment("t2", "Dog Walker", -6000, time.January, 12),
+				payment("t3", "Dog Walker", -6000, time.January, 22),
+			},
+			today: time.Date(2025, time.January, 22, 0, 0, 0, 0, time.UTC),
+			want:  []want{{types.BillPaid, "t1", 2}, {types.BillPaid, "t2", 0}, {types.BillPaid, "t3", 2}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			statuses := DefaultConfig.Reconcile(tt.expected, tt.transactions, tt.today)
+			if len(statuses) != len(tt.want) {
+				t.Fatalf("Reconcile() returned %d statuses, want %d", len(statuses), len(tt.want))
+			}
+			for i, w := range tt.want {
+				s := statuses[i]
+				if s.Status != w.status || s.TransactionID != w.tx || s.DaysLate != w.daysLate {
+					t.Errorf("status %d = %q, %q, %d days late, want %q, %q, %d days late",
+						i, s.Status, s.TransactionID, s.DaysLate, w.status, w.tx, w.daysLate)
+				}
+				if !s.DueDate.Equal(tt.expected[i].DueDate) {
+					t.Errorf("status %d is for %s, want %s", i, s.DueDate, tt.expected[i].DueDate)
+				}
+			}
+		})
+	}
+}
+
+func TestWindows(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected []types.UpcomingBill
+		want     []window
+	}{
+		{
+			name:     "a single due date keeps the configured window",
+			expected: []types.UpcomingBill{bill("Rent", 150000, time.January, 15)},
+			want:     []window{{early: 7, late: 30}},
+		},
+		{
+			// Payments may be up to a week early and are late until the
+			// next due date's window opens
+			name: "monthly",
+			expected: []types.UpcomingBill{
+				bill("Rent", 150000, time.January, 15),
+				bill("Rent", 150000, time.February, 15),
+				bill("Rent", 150000, time.March, 15),
+			},
+			want: []window{{early: 7, late: 23}, {early: 7, late: 20}, {early: 7, late: 20}},
+		},
+		{
+			name: "biweekly",
+			expected: []types.UpcomingBill{
+				bill("Daycare", 40000, time.January, 3),
+				bill("Daycare", 40000, time.January, 17),
+			},
+			want: []window{{early: 7, late: 6}, {early: 7, late: 6}},
+		},
+		{
+			name: "weekly",
+			expected: []types.UpcomingBill{
+				bill("Dog Walker", 6000, time.January, 6),
+				bill("Dog Walker", 6000, time.January, 13),
+				bill("Dog Walker", 6000, time.January, 20),
+			},
+			want: []window{{early: 3, late: 3}, {early: 3, late: 3}, {early: 3, late: 3}},
+		},
+		{
+			name: "given out of order",
+			expected: []types.UpcomingBill{
+				bill("Dog Walker", 6000, time.January, 20),
+				bill("Dog Walker", 6000, time.January, 6),
+				bill("Dog Walker", 6000, time.January, 13),
+			},
+			want: []window{{early: 3, late: 3}, {early: 3, late: 3}, {early: 3, late: 3}},
+		},
+		{
+			name: "other bills do not narrow each other",
+			expected: []types.UpcomingBill{
+				bill("Dog Walker", 6000, time.January, 6),
+				bill("Rent", 150000, time.January, 8),
+			},
+			want: []window{{early: 7, late: 30}, {early: 7, late: 30}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DefaultConfig.windows(tt.expected)
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Errorf("window %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestSameMerchant(t *testing.T) {
+	tests := []struct {
+		payee, merchant string
+		want            bool
+	}{
+		{"Rent", "Rent", true},
+		{"Rent", "RENT", true},
+		{"City Water", "CITY WATER UTIL 4471", true},
+		{"City Water", "City Waterworks", false},
+		{"City", "", false},
+		{"", "City Water", false},
+	}
+	for _, tt := range tests {
+		if got := SameMerchant(tt.payee, tt.merchant); got != tt.want {
+			t.Errorf("SameMerchant(%q, %q) = %v, want %v", tt.payee, tt.merchant, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,316 @@
+package recurrence
+
+import (
+	"math"
+	"server/types"
+	"sort"
+	"time"
+)
+
+// Config controls which series of payments count as recurring
+type Config struct {
+	// MinConfidence is the confidence below which a series is not reported
+	MinConfidence float64
+
+	// Income looks for series of incoming payments, such as paychecks,
+	// instead of outgoing ones
+	Income bool
+}
+
+// DefaultConfig reports bills that are more regular than not
+var DefaultConfig = Config{MinConfidence: 0.5}
+
+// cycle is a period the detector recognizes
+type cycle struct {
+	frequency string
+	days      float64 // the nominal period
+	tolerance float64 // how far an interval may stray from a multiple of the period
+	months    int     // the period in months, for cycles anchored to a day of the month
+
+	// minOccurrences is how many payments make a series: two for cycles so
+	// long that a third payment takes years to arrive
+	minOccurrences int
+}
+
+var cycles = []cycle{
+	{frequency: types.BillWeekly, days: 7, tolerance: 1.5, minOccurrences: 3},
+	{frequency: types.BillBiweekly, days: 14, tolerance: 2.5, minOccurrences: 3},
+	{frequency: types.BillMonthly, days: 30.44, tolerance: 4, months: 1, minOccurrences: 3},
+	{frequency: types.BillQuarterly, days: 91.31, tolerance: 10, months: 3, minOccurrences: 3},
+	{frequency: types.BillSemiannual, days: 182.62, tolerance: 15, months: 6, minOccurrences: 2},
+	{frequency: types.BillAnnual, days: 365.25, tolerance: 20, months: 12, minOccurrences: 2},
+}
+
+// occurrence is one payment of a series; payments on the same day count as one
+type occurrence struct {
+	date   time.Time
+	amount types.Money
+}
+
+// Detect groups outgoing transactions, or incoming ones for Income, by
+// merchant and category and reports the groups that recur: their period is
+// inferred from the median time between payments, and payments skipped now
+// and then are allowed for. Bills anchored to a day of the month keep it
+// through short months, and those paid on the last day of the month stay
+// there. Each series comes with a confidence from 0 to 1 in how regular it
+// is; series below MinConfidence and those that stopped more than two
+// periods before today are left out. The result is ordered by average
+// amount, largest first.
+func (c Config) Detect(transactions []types.Transaction, today time.Time) []types.RecurringBill {
+	type key struct {
+		merchant, category string
+	}
+	groups := make(map[key][]types.Transaction)
+	var keys []key
+	sign := -1
+	if c.Income {
+		sign = 1
+	}
+	for _, t := range transactions {
+		if t.Amount.Sign() != sign {
+			continue
+		}
+		k := key{t.Merchant, t.Category}
+		if _, ok := groups[k]; !ok {
+			keys = append(keys, k)
+		}
+		groups[k] = append(groups[k], t)
+	}
+
+	var bills []types.RecurringBill
+	for _, k := range keys {
+		bill, ok := c.detect(groups[k], day(today))
+		if !ok {
+			continue
+		}
+		bill.Merchant, bill.Category = k.merchant, k.category
+		bills = append(bills, bill)
+	}
+	sort.SliceStable(bills, func(i, j int) bool {
+		return bills[i].AverageAmount.Cmp(bills[j].AverageAmount) > 0
+	})
+	return bills
+}
+
+func (c Config) detect(transactions []types.Transaction, today time.Time) (types.RecurringBill, bool) {
+	occurrences := collapse(transactions)
+	if len(occurrences) < 2 {
+		return types.RecurringBill{}, false
+	}
+
+	intervals := make([]float64, len(occurrences)-1)
+	for i := range intervals {
+		intervals[i] = occurrences[i+1].date.Sub(occurrences[i].date).Hours() / 24
+	}
+	period := median(intervals)
+	cyc, ok := closestCycle(period)
+	if !ok || len(occurrences) < cyc.minOccurrences {
+		return types.RecurringBill{}, false
+	}
+	last := occurrences[len(occurrences)-1].date
+	if today.Sub(last).Hours()/24 > 2*cyc.days+cyc.tolerance {
+		return types.RecurringBill{}, false // the series has ended
+	}
+
+	// fit is the share of intervals that are a whole number of periods;
+	// coverage discounts series that skip many of their periods
+	fits, periods := 0, 0
+	for _, interval := range intervals {
+		n := math.Round(interval / cyc.days)
+		if n >= 1 && math.Abs(interval-n*cyc.days) <= cyc.tolerance*n {
+			fits++
+		}
+		if n < 1 {
+			n = 1
+		}
+		periods += int(n)
+	}
+	fit := float64(fits) / float64(len(intervals))
+	coverage := float64(len(intervals)) / float64(periods)
+
+	bill := types.RecurringBill{
+		Frequency:       cyc.frequency,
+		Occurrences:     len(occurrences),
+		PeriodDays:      round(period, 1),
+		FirstOccurrence: occurrences[0].date,
+		LastOccurrence:  last,
+	}
+	var jitter float64
+	if cyc.months > 0 {
+		bill.DueDay, bill.MonthEnd, jitter = anchorDay(occurrences)
+	} else {
+		bill.DueDay, jitter = anchorWeekday(intervals, occurrences, cyc)
+	}
+	bill.JitterDays = round(jitter, 1)
+
+	steadiness := 1 - math.Min(1, jitter/cyc.tolerance)
+	support := math.Min(1, float64(len(intervals))/4)
+	bill.Confidence = round(fit*coverage*(0.5+0.5*steadiness)*(0.5+0.5*support), 2)
+	if bill.Confidence < c.MinConfidence {
+		return types.RecurringBill{}, false
+	}
+
+	amounts := make([]int64, len(occurrences))
+	months := make(map[time.Time]bool)
+	total := types.NewMoney(0, occurrences[0].amount.Currency)
+	for i, o := range occurrences {
+		amounts[i] = o.amount.Cents
+		total = total.Add(o.amount)
+		months[time.Date(o.date.Year(), o.date.Month(), 1, 0, 0, 0, 0, time.UTC)] = true
+	}
+	sort.Slice(amounts, func(i, j int) bool { return amounts[i] < amounts[j] })
+	n := len(amounts)
+	bill.MedianAmount = types.NewMoney(amounts[n/2], total.Currency)
+	if n%2 == 0 {
+		bill.MedianAmount = types.NewMoney(amounts[n/2-1]+amounts[n/2], total.Currency).Div(2)
+	}
+	bill.AverageAmount = total.Div(len(occurrences))
+	bill.MonthsPresent = len(months)
+
+	// The next payment is due one period after the due date the last
+	// payment was for, which may lie a little before or after it
+	schedule := Schedule(bill)
+	lastDue := schedule.NextDue(last.AddDate(0, 0, -int(cyc.days/2)))
+	bill.NextDueDate = schedule.NextDue(lastDue.AddDate(0, 0, 1))
+	return bill, true
+}
+
+// Schedule returns the due dates of a recurring bill as a Bill, whose
+// NextDue and DueDates give its due dates from its first payment on
+func Schedule(bill types.RecurringBill) types.Bill {
+	first := day(bill.FirstOccurrence)
+	start := first.AddDate(0, 0, -3)
+	if months := types.BillFrequencies[bill.Frequency]; months > 0 {
+		// Start at the beginning of the month so that a first payment made
+		// after its due date still counts for that month
+		start = time.Date(first.Year(), first.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+	return types.Bill{
+		Payee:      bill.Merchant,
+		Category:   bill.Category,
+		Amount:     bill.AverageAmount,
+		AmountType: types.BillAmountVariable,
+		Frequency:  bill.Frequency,
+		DueDay:     bill.DueDay,
+		StartDate:  start,
+	}
+}
+
+// collapse sorts the transactions by date and merges those on the same day.
+// The amounts carry the transactions' currency.
+func collapse(transactions []types.Transaction) []occurrence {
+	sorted := append([]types.Transaction(nil), transactions...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Date.Before(sorted[j].Date) })
+
+	var occurrences []occurrence
+	for _, t := range sorted {
+		date := day(t.Date)
+		amount := types.NewMoney(t.Amount.Abs().Cents, t.Currency)
+		if n := len(occurrences); n > 0 && occurrences[n-1].date.Equal(date) {
+			occurrences[n-1].amount = occurrences[n-1].amount.Add(amount)
+			continue
+		}
+		occurrences = append(occurrences, occurrence{date: date, amount: amount})
+	}
+	return occurrences
+}
+
+// closestCycle returns the cycle whose period is nearest to the median
+// interval, if it is within the cycle's tolerance
+func closestCycle(period float64) (cycle, bool) {
+	best, found := cycle{}, false
+	for _, c := range cycles {
+		if math.Abs(period-c.days) > c.tolerance {
+			continue
+		}
+		if !found || math.Abs(period-c.days)/c.days < math.Abs(period-best.days)/best.days {
+			best, found = c, true
+		}
+	}
+	return best, found
+}
+
+// anchorDay finds the day of the month a bill is due: the last day when most
+// payments fall within a day of the end of their month, the median day of the
+// month otherwise. The jitter is the median distance of the payments from
+// the nearest such day.
+func anchorDay(occurrences []occurrence) (dueDay int, monthEnd bool, jitter float64) {
+	days := make([]float64, len(occurrences))
+	atEnd := 0
+	for i, o := range occurrences {
+		days[i] = float64(o.date.Day())
+		if lastDay(o.date)-o.date.Day() <= 1 {
+			atEnd++
+		}
+	}
+	dueDay = int(math.Round(median(days)))
+	if 2*atEnd > len(occurrences) {
+		dueDay, monthEnd = 31, true
+	}
+
+	distances := make([]float64, len(occurrences))
+	for i, o := range occurrences {
+		distances[i] = math.Inf(1)
+		for _, shift := range []int{-1, 0, 1} {
+			month := time.Date(o.date.Year(), o.date.Month()+time.Month(shift), 1, 0, 0, 0, 0, time.UTC)
+			d := dueDay
+			if last := lastDay(month); d > last {
+				d = last
+			}
+			due := month.AddDate(0, 0, d-1)
+			distances[i] = math.Min(distances[i], math.Abs(o.date.Sub(due).Hours()/24))
+		}
+	}
+	return dueDay, monthEnd, median(distances)
+}
+
+// anchorWeekday finds the day of the week a weekly or biweekly bill is
+// usually paid on, from 1 (Monday) to 7 (Sunday). The jitter is the median
+// distance of the intervals from a whole number of periods.
+func anchorWeekday(intervals []float64, occurrences []occurrence, cyc cycle) (int, float64) {
+	counts := make(map[int]int)
+	best := 0
+	for _, o := range occurrences {
+		weekday := int(o.date.Weekday())
+		if weekday == 0 {
+			weekday = 7
+		}
+		counts[weekday]++
+		if best == 0 || counts[weekday] > counts[best] || counts[weekday] == counts[best] && weekday < best {
+			best = weekday
+		}
+	}
+
+	distances := make([]float64, len(intervals))
+	for i, interval := range intervals {
+		n := math.Max(1, math.Round(interval/cyc.days))
+		distances[i] = math.Abs(interval - n*cyc.days)
+	}
+	return best, median(distances)
+}
+
+func median(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
+
+func lastDay(t time.Time) int {
+	return time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+func day(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func round(value float64, places int) float64 {
+	scale := math.Pow(10, float64(places))
+	return math.Round(value*scale) / scale
+}
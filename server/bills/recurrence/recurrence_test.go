@@ -0,0 +1,288 @@
+package recurrence
+
+import (
+	"server/types"
+	"testing"
+	"time"
+)
+
+func date(s string) time.Time {
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// payments returns a payment of cents to merchant on each date; negative
+// cents are outgoing
+func payments(merchant string, cents int64, dates ...string) []types.Transaction {
+	transactions := make([]types.Transaction, len(dates))
+	for i, d := range dates {
+		transactions[i] = types.Transaction{
+			Date:     date(d),
+			Amount:   types.NewMoney(cents, ""),
+			Merchant: merchant,
+			Category: types.CategoryBillPayment,
+			Currency: "USD",
+		}
+	}
+	return transactions
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   Config
+		payments []types.Transaction
+		today    string
+
+		want       bool
+		frequency  string
+		dueDay     int
+		monthEnd   bool
+		jitter     float64
+		confidence float64
+		nextDue    string
+	}{
+		{
+			name:     "weekly on Mondays",
+			payments: payments("Gym", -1500, "2025-01-06", "2025-01-13", "2025-01-20", "2025-01-27", "2025-02-03"),
+			today:    "2025-02-05",
+			want:     true, frequency: types.BillWeekly, dueDay: 1, confidence: 1, nextDue: "2025-02-10",
+		},
+		{
+			// Three intervals are not yet full support
+			name:     "biweekly on Fridays",
+			payments: payments("Daycare", -40000, "2025-01-03", "2025-01-17", "2025-01-31", "2025-02-14"),
+			today:    "2025-02-20",
+			want:     true, frequency: types.BillBiweekly, dueDay: 5, confidence: 0.88, nextDue: "2025-02-28",
+		},
+		{
+			name:     "quarterly on the 15th",
+			payments: payments("Water", -9000, "2024-01-15", "2024-04-15", "2024-07-15", "2024-10-15", "2025-01-15"),
+			today:    "2025-02-01",
+			want:     true, frequency: types.BillQuarterly, dueDay: 15, confidence: 1, nextDue: "2025-04-15",
+		},
+		{
+			// A payment two days late does not move the due day
+			name:     "annual paid twice and once late",
+			payments: payments("Insurance", -120000, "2023-03-10", "2024-03-10", "2025-03-12"),
+			today:    "2025-04-01",
+			want:     true, frequency: types.BillAnnual, dueDay: 10, confidence: 0.75, nextDue: "2026-03-10",
+		},
+		{
+			name:     "paid on the 31st across February",
+			payments: payments("Rent", -150000, "2025-01-31", "2025-02-28", "2025-03-31", "2025-04-30", "2025-05-31"),
+			today:    "2025-06-10",
+			want:     true, frequency: types.BillMonthly, dueDay: 31, monthEnd: true, confidence: 1, nextDue: "2025-06-30",
+		},
+		{
+			// The 59-day gap is two periods, so it fits but costs coverage
+			name:     "one skipped payment",
+			payments: payments("Phone", -6000, "2025-01-05", "2025-02-05", "2025-04-05", "2025-05-05", "2025-06-05"),
+			today:    "2025-06-10",
+			want:     true, frequency: types.BillMonthly, dueDay: 5, confidence: 0.8, nextDue: "2025-07-05",
+		},
+		{
+			name:     "one skipped payment below the minimum confidence",
+			config:   Config{MinConfidence: 0.9},
+			payments: payments("Phone", -6000, "2025-01-05", "2025-02-05", "2025-04-05", "2025-05-05", "2025-06-05"),
+			today:    "2025-06-10",
+		},
+		{
+			// Payments stray a day from the 4th on average
+			name:     "jitter around the due day",
+			payments: payments("Power", -8000, "2025-01-03", "2025-02-05", "2025-03-04", "2025-04-02", "2025-05-04"),
+			today:    "2025-05-10",
+			want:     true, frequency: types.BillMonthly, dueDay: 4, jitter: 1, confidence: 0.88, nextDue: "2025-06-04",
+		},
+		{
+			name:     "ended more than two periods ago",
+			payments: payments("Streaming", -1299, "2025-01-05", "2025-02-05", "2025-03-05", "2025-04-05"),
+			today:    "2025-07-01",
+		},
+		{
+			name:     "too few payments for a monthly series",
+			payments: payments("Streaming", -1299, "2025-01-05", "2025-02-05"),
+			today:    "2025-02-10",
+		},
+		{
+			name:     "no cycle between monthly and quarterly",
+			payments: payments("Dentist", -5000, "2025-01-05", "2025-02-19", "2025-04-05", "2025-05-20"),
+			today:    "2025-05-25",
+		},
+		{
+			name:     "income is ignored by default",
+			payments: payments("Employer", 250000, "2025-01-03", "2025-01-17", "2025-01-31", "2025-02-14"),
+			today:    "2025-02-20",
+		},
+		{
+			name:     "income detected with Income",
+			config:   Config{Income: true},
+			payments: payments("Employer", 250000, "2025-01-03", "2025-01-17", "2025-01-31", "2025-02-14"),
+			today:    "2025-02-20",
+			want:     true, frequency: types.BillBiweekly, dueDay: 5, confidence: 0.88, nextDue: "2025-02-28",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bills := tt.config.Detect(tt.payments, date(tt.today))
+			if !tt.want {
+				if len(bills) != 0 {
+					t.Fatalf("Detect() = %+v, want no bills", bills)
+				}
+				return
+			}
+			if len(bills) != 1 {
+				t.Fatalf("Detect() returned %d bills, want 1", len(bills))
+			}
+
+			b := bills[0]
+			if b.Frequency != tt.frequency {
+				t.Errorf("Frequency = %q, want %q", b.Frequency, tt.frequency)
+			}
+			if b.DueDay != tt.dueDay || b.MonthEnd != tt.monthEnd {
+				t.Errorf("DueDay, MonthEnd = %d, %v, want %d, %v", b.DueDay, b.MonthEnd, tt.dueDay, tt.monthEnd)
+			}
+			if b.JitterDays != tt.jitter {
+				t.Errorf("JitterDays = %v, want %v", b.JitterDays, tt.jitter)
+			}
+			if b.Confidence != tt.confidence {
+				t.Errorf("Confidence = %v, want %v", b.Confidence, tt.confidence)
+			}
+			if !b.NextDueDate.Equal(date(tt.nextDue)) {
+				t.Errorf("NextDueDate = %s, want %s", b.NextDueDate.Format("2006-01-02"), tt.nextDue)
+			}
+			if b.Occurrences != len(tt.payments) {
+				t.Errorf("Occurrences = %d, want %d", b.Occurrences, len(tt.payments))
+			}
+		})
+	}
+}
+
+func TestDetectAmounts(t *testing.T) {
+	transactions := payments("Power", -8000, "2025-01-04", "2025-02-04", "2025-03-04", "2025-04-04")
+	transactions[1].Amount = types.NewMoney(-10000, "")
+	// A second payment on the same day counts toward the same occurrence
+	transactions = append(transactions, payments("Power", -1000, "2025-04-04")...)
+
+	bills := DefaultConfig.Detect(transactions, date("2025-04-10"))
+	if len(bills) != 1 {
+		t.Fatalf("Detect() returned %d bills, want 1", len(bills))
+	}
+	b := bills[0]
+	if b.Occurrences != 4 {
+		t.Errorf("Occurrences = %d, want 4", b.Occurrences)
+	}
+	if got := b.MedianAmount; got.Cents != 8500 || got.Currency != "USD" {
+		t.Errorf("MedianAmount = %s %s, want 85.00 USD", got, got.Currency)
+	}
+	if got := b.AverageAmount; got.Cents != 8750 {
+		t.Errorf("AverageAmount = %s, want 87.50", got)
+	}
+	if b.MonthsPresent != 4 {
+		t.Errorf("MonthsPresent = %d, want 4", b.MonthsPresent)
+	}
+}
+
+func TestClosestCycle(t *testing.T) {
+	tests := []struct {
+		period float64
+		want   string // "" when no cycle is close enough
+	}{
+		{7, types.BillWeekly},
+		{8.5, types.BillWeekly},
+		{10, ""},
+		{14, types.BillBiweekly},
+		{16.5, types.BillBiweekly},
+		{28, types.BillMonthly},
+		{31, types.BillMonthly},
+		{45, ""},
+		{91, types.BillQuarterly},
+		{100, types.BillQuarterly},
+		{183, types.BillSemiannual},
+		{365, types.BillAnnual},
+		{384, types.BillAnnual},
+		{400, ""},
+	}
+	for _, tt := range tests {
+		c, ok := closestCycle(tt.period)
+		got := ""
+		if ok {
+			got = c.frequency
+		}
+		if got != tt.want {
+			t.Errorf("closestCycle(%v) = %q, want %q", tt.period, got, tt.want)
+		}
+	}
+}
+
+func TestAnchorDay(t *testing.T) {
+	tests := []struct {
+		name     string
+		dates    []string
+		dueDay   int
+		monthEnd bool
+		jitter   float64
+	}{
+		{
+			name:   "the same day every month",
+			dates:  []string{"2025-01-15", "2025-02-15", "2025-03-15"},
+			dueDay: 15,
+		},
+		{
+			name:     "the last day of each month",
+			dates:    []string{"2025-01-31", "2025-02-28", "2025-03-31", "2025-04-30"},
+			dueDay:   31,
+			monthEnd: true,
+		},
+		{
+			// The 31st falls on the 29th in a leap February
+			name:     "the last day through a leap February",
+			dates:    []string{"2024-01-31", "2024-02-29", "2024-03-31"},
+			dueDay:   31,
+			monthEnd: true,
+		},
+		{
+			// The payment on March 31st is nearest to the April due date
+			name:   "early in the month, sometimes the month before",
+			dates:  []string{"2025-01-03", "2025-02-01", "2025-03-02", "2025-03-31", "2025-05-03"},
+			dueDay: 3,
+			jitter: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			occurrences := make([]occurrence, len(tt.dates))
+			for i, d := range tt.dates {
+				occurrences[i] = occurrence{date: date(d)}
+			}
+			dueDay, monthEnd, jitter := anchorDay(occurrences)
+			if dueDay != tt.dueDay || monthEnd != tt.monthEnd || jitter != tt.jitter {
+				t.Errorf("anchorDay() = %d, %v, %v, want %d, %v, %v",
+					dueDay, monthEnd, jitter, tt.dueDay, tt.monthEnd, tt.jitter)
+			}
+		})
+	}
+}
+
+func TestSchedule(t *testing.T) {
+	bill := types.RecurringBill{
+		Frequency:       types.BillMonthly,
+		DueDay:          31,
+		MonthEnd:        true,
+		FirstOccurrence: date("2025-01-31"),
+	}
+	got := Schedule(bill).DueDates(date("2025-01-01"), date("2025-04-30"))
+	want := []string{"2025-01-31", "2025-02-28", "2025-03-31", "2025-04-30"}
+	if len(got) != len(want) {
+		t.Fatalf("DueDates() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if !got[i].Equal(date(want[i])) {
+			t.Errorf("DueDates()[%d] = %s, want %s", i, got[i].Format("2006-01-02"), want[i])
+		}
+	}
+}
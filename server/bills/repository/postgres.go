@@ -3,10 +3,13 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"server/types"
 	"time"
+
+	"github.com/lib/pq"
 )
 
 type postgresRepo struct {
@@ -20,35 +23,42 @@ func NewPostgresRepository(db *sql.DB) Repository {
 	return &postgresRepo{db: db}
 }
 
+// canonicalMerchant is the canonical name of a transaction's merchant, or
+// its raw description when it is not linked to one. Queries that use it join
+// transactions t with merchants m.
+const canonicalMerchant = `COALESCE(m.name, t.merchant)`
+
 // GetBillTotals retrieves total bill payments by category for a given time period
-func (r *postgresRepo) GetBillTotals(ctx context.Context, accountID string, startDate, endDate time.Time) (map[string]float64, error) {
-	if accountID == "" {
-		return nil, fmt.Errorf("account ID is required")
+func (r *postgresRepo) GetBillTotals(ctx context.Context, accountIDs []string, startDate, endDate time.Time) (map[string]types.Money, error) {
+	if len(accountIDs) == 0 {
+		return nil, fmt.Errorf("at least one account ID is required")
 	}
 
-	log.Printf("Fetching bill totals for account %s between %s and %s", accountID, startDate.Format("2006-01-02"), endDate.Format("2006-01-02"))
+	log.Printf("Fetching bill totals for accounts %v between %s and %s", accountIDs, startDate.Format("2006-01-02"), endDate.Format("2006-01-02"))
 
 	query := `
-		SELECT merchant, COALESCE(SUM(ABS(amount)), 0) as total
-		FROM transactions 
-		WHERE account_id = $1 
-		  AND date >= $2 
-		  AND date <= $3
-		  AND (category = 'Bill Payment' OR category = 'Subscription')
-		GROUP BY merchant
+		SELECT ` + canonicalMerchant + ` AS merchant, COALESCE(SUM(ABS(t.amount)), 0) as total
+		FROM transactions t
+		LEFT JOIN merchants m ON m.id = t.merchant_id
+		WHERE t.account_id = ANY($1) 
+		  AND t.transfer_id IS NULL
+		  AND t.date >= $2 
+		  AND t.date <= $3
+		  AND category_type(t.account_id, t.category) = 'bill'
+		GROUP BY 1
 		ORDER BY total DESC`
 
-	rows, err := r.db.QueryContext(ctx, query, accountID, startDate, endDate)
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(accountIDs), startDate, endDate)
 	if err != nil {
 		log.Printf("Error querying bill totals: %v", err)
 		return nil, fmt.Errorf("failed to query bill totals: %w", err)
 	}
 	defer rows.Close()
 
-	billTotals := make(map[string]float64)
+	billTotals := make(map[string]types.Money)
 	for rows.Next() {
 		var merchant string
-		var total float64
+		var total types.Money
 		if err := rows.Scan(&merchant, &total); err != nil {
 			log.Printf("Error scanning bill total: %v", err)
 			return nil, fmt.Errorf("failed to scan bill total: %w", err)
@@ -65,168 +75,116 @@ func (r *postgresRepo) GetBillTotals(ctx context.Context, accountID string, star
 	return billTotals, nil
 }
 
-// GetRecurringBills retrieves recurring bill payments for an account
-func (r *postgresRepo) GetRecurringBills(ctx context.Context, accountID string) ([]types.RecurringBill, error) {
-	if accountID == "" {
-		return nil, fmt.Errorf("account ID is required")
+// ListBillPayments retrieves the accounts' bill payments between from and
+// to, excluding transfers, by date. Linked transactions carry the canonical
+// merchant name.
+func (r *postgresRepo) ListBillPayments(ctx context.Context, accountIDs []string, from, to time.Time) ([]types.Transaction, error) {
+	if len(accountIDs) == 0 {
+		return nil, fmt.Errorf("at least one account ID is required")
 	}
 
-	log.Printf("Fetching recurring bills for account %s", accountID)
-
 	query := `
-		WITH monthly_bills AS (
-			SELECT 
-				merchant,
-				category,
-				COUNT(DISTINCT DATE_TRUNC('month', date)) as months_present,
-				AVG(ABS(amount)) as avg_amount,
-				PERCENTILE_CONT(0.5) WITHIN GROUP (ORDER BY ABS(amount)) as median_amount,
-				MIN(date) as first_occurrence,
-				MAX(date) as last_occurrence
-			FROM transactions
-			WHERE account_id = $1
-				AND (category = 'Bill Payment' OR category = 'Subscription')
-				AND date >= NOW() - INTERVAL '6 months'
-			GROUP BY merchant, category
-			HAVING COUNT(DISTINCT DATE_TRUNC('month', date)) >= 3
-		)
-		SELECT 
-			merchant,
-			category,
-			months_present,
-			avg_amount,
-			median_amount,
-			first_occurrence,
-			last_occurrence
-		FROM monthly_bills
-		ORDER BY avg_amount DESC`
-
-	rows, err := r.db.QueryContext(ctx, query, accountID)
-	if err != nil {
-		log.Printf("Error querying recurring bills: %v", err)
-		return nil, fmt.Errorf("failed to query recurring bills: %w", err)
-	}
-	defer rows.Close()
-
-	var bills []types.RecurringBill
-	for rows.Next() {
-		var bill types.RecurringBill
-		if err := rows.Scan(
-			&bill.Merchant,
-			&bill.Category,
-			&bill.MonthsPresent,
-			&bill.AverageAmount,
-			&bill.MedianAmount,
-			&bill.FirstOccurrence,
-			&bill.LastOccurrence,
-		); err != nil {
-			log.Printf("Error scanning recurring bill: %v", err)
-			return nil, fmt.Errorf("failed to scan recurring bill: %w", err)
-		}
-		bills = append(bills, bill)
-	}
-
-	if err = rows.Err(); err != nil {
-		log.Printf("Error iterating recurring bills: %v", err)
-		return nil, fmt.Errorf("error iterating recurring bills: %w", err)
-	}
-
-	log.Printf("Found %d recurring bills", len(bills))
-	return bills, nil
+		SELECT t.transaction_id, t.account_id, t.date, t.amount, t.category,
+			` + canonicalMerchant + `, t.location, t.currency, t.merchant_id
+		FROM transactions t
+		LEFT JOIN merchants m ON m.id = t.merchant_id
+		WHERE t.account_id = ANY($1)
+		  AND t.transfer_id IS NULL
+		  AND t.date >= $2
+		  AND t.date < $3
+		  AND category_type(t.account_id, t.category) = 'bill'
+		ORDER BY t.date, t.transaction_id`
+
+	return r.queryPayments(ctx, query, pq.Array(accountIDs), from, to)
 }
 
-// GetUpcomingBills predicts upcoming bill payments based on recurring patterns
-func (r *postgresRepo) GetUpcomingBills(ctx context.Context, accountID string) ([]types.UpcomingBill, error) {
-	if accountID == "" {
-		return nil, fmt.Errorf("account ID is required")
+// GetBillHistory retrieves historical bill payments for a specific merchant,
+// given by its canonical name or a raw description, that carry every tag of
+// the filter
+func (r *postgresRepo) GetBillHistory(ctx context.Context, accountIDs []string, merchantName string, tags types.TagFilter) ([]types.Transaction, error) {
+	if len(accountIDs) == 0 || merchantName == "" {
+		return nil, fmt.Errorf("account IDs and merchant name are required")
 	}
 
-	log.Printf("Predicting upcoming bills for account %s", accountID)
+	log.Printf("Fetching bill history for accounts %v and merchant %s", accountIDs, merchantName)
 
 	query := `
-		WITH recurring_bills AS (
-			SELECT 
-				merchant,
-				category,
-				AVG(ABS(amount)) as expected_amount,
-				MAX(date) as last_payment_date,
-				AVG(EXTRACT(DAY FROM date)) as avg_day_of_month
-			FROM transactions
-			WHERE account_id = $1
-				AND (category = 'Bill Payment' OR category = 'Subscription')
-				AND date >= NOW() - INTERVAL '6 months'
-			GROUP BY merchant, category
-			HAVING COUNT(DISTINCT DATE_TRUNC('month', date)) >= 3
-		)
-		SELECT 
-			merchant,
-			category,
-			expected_amount,
-			last_payment_date,
-			avg_day_of_month
-		FROM recurring_bills
-		ORDER BY avg_day_of_month ASC`
-
-	rows, err := r.db.QueryContext(ctx, query, accountID)
+		SELECT t.transaction_id, t.account_id, t.date, t.amount, t.category,
+			` + canonicalMerchant + `, t.location, t.currency, t.merchant_id
+		FROM transactions t
+		LEFT JOIN merchants m ON m.id = t.merchant_id
+		WHERE t.account_id = ANY($1) 
+		  AND t.transfer_id IS NULL
+		  AND (` + canonicalMerchant + ` = $2 OR t.merchant = $2)
+		  AND category_type(t.account_id, t.category) = 'bill'
+		  AND has_tags(t.transaction_id, $3)
+		ORDER BY t.date DESC`
+
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(accountIDs), merchantName, pq.Array([]string(tags)))
 	if err != nil {
-		log.Printf("Error querying upcoming bills: %v", err)
-		return nil, fmt.Errorf("failed to query upcoming bills: %w", err)
+		log.Printf("Error querying bill history: %v", err)
+		return nil, fmt.Errorf("failed to query bill history: %w", err)
 	}
 	defer rows.Close()
 
-	var bills []types.UpcomingBill
+	var transactions []types.Transaction
 	for rows.Next() {
-		var bill types.UpcomingBill
-		var lastPaymentDate time.Time
-		var avgDayOfMonth float64
-
+		var t types.Transaction
 		if err := rows.Scan(
-			&bill.Merchant,
-			&bill.Category,
-			&bill.ExpectedAmount,
-			&lastPaymentDate,
-			&avgDayOfMonth,
+			&t.TransactionID,
+			&t.AccountID,
+			&t.Date,
+			&t.Amount,
+			&t.Category,
+			&t.Merchant,
+			&t.Location,
+			&t.Currency,
+			&t.MerchantID,
 		); err != nil {
-			log.Printf("Error scanning upcoming bill: %v", err)
-			return nil, fmt.Errorf("failed to scan upcoming bill: %w", err)
+			log.Printf("Error scanning transaction: %v", err)
+			return nil, fmt.Errorf("failed to scan transaction: %w", err)
 		}
-
-		// Calculate next due date based on last payment and average day of month
-		nextDueDate := lastPaymentDate.AddDate(0, 1, 0)
-		bill.DueDate = time.Date(nextDueDate.Year(), nextDueDate.Month(), int(avgDayOfMonth), 0, 0, 0, 0, time.UTC)
-		
-		bills = append(bills, bill)
+		transactions = append(transactions, t)
 	}
 
 	if err = rows.Err(); err != nil {
-		log.Printf("Error iterating upcoming bills: %v", err)
-		return nil, fmt.Errorf("error iterating upcoming bills: %w", err)
+		log.Printf("Error iterating transactions: %v", err)
+		return nil, fmt.Errorf("error iterating transactions: %w", err)
 	}
 
-	log.Printf("Predicted %d upcoming bills", len(bills))
-	return bills, nil
+	log.Printf("Found %d bill payments for merchant %s", len(transactions), merchantName)
+	return transactions, nil
 }
 
-// GetBillHistory retrieves historical bill payments for a specific merchant
-func (r *postgresRepo) GetBillHistory(ctx context.Context, accountID string, merchantName string) ([]types.Transaction, error) {
-	if accountID == "" || merchantName == "" {
-		return nil, fmt.Errorf("account ID and merchant name are required")
+// GetBillsByMonth retrieves all bill payments for a specific month that
+// carry every tag of the filter
+func (r *postgresRepo) GetBillsByMonth(ctx context.Context, accountIDs []string, year int, month int, tags types.TagFilter) ([]types.Transaction, error) {
+	if len(accountIDs) == 0 {
+		return nil, fmt.Errorf("at least one account ID is required")
 	}
 
-	log.Printf("Fetching bill history for account %s and merchant %s", accountID, merchantName)
+	startDate := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
+	endDate := startDate.AddDate(0, 1, 0).Add(-time.Second)
+
+	log.Printf("Fetching bills for accounts %v for %s", accountIDs, startDate.Format("2006-01"))
 
 	query := `
-		SELECT transaction_id, account_id, date, amount, category, merchant, location
-		FROM transactions 
-		WHERE account_id = $1 
-		  AND merchant = $2
-		  AND (category = 'Bill Payment' OR category = 'Subscription')
-		ORDER BY date DESC`
-
-	rows, err := r.db.QueryContext(ctx, query, accountID, merchantName)
+		SELECT t.transaction_id, t.account_id, t.date, t.amount, t.category,
+			` + canonicalMerchant + `, t.location, t.currency, t.merchant_id
+		FROM transactions t
+		LEFT JOIN merchants m ON m.id = t.merchant_id
+		WHERE t.account_id = ANY($1) 
+		  AND t.transfer_id IS NULL
+		  AND t.date >= $2
+		  AND t.date <= $3
+		  AND category_type(t.account_id, t.category) = 'bill'
+		  AND has_tags(t.transaction_id, $4)
+		ORDER BY t.date ASC`
+
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(accountIDs), startDate, endDate, pq.Array([]string(tags)))
 	if err != nil {
-		log.Printf("Error querying bill history: %v", err)
-		return nil, fmt.Errorf("failed to query bill history: %w", err)
+		log.Printf("Error querying monthly bills: %v", err)
+		return nil, fmt.Errorf("failed to query monthly bills: %w", err)
 	}
 	defer rows.Close()
 
@@ -241,6 +199,8 @@ func (r *postgresRepo) GetBillHistory(ctx context.Context, accountID string, mer
 			&t.Category,
 			&t.Merchant,
 			&t.Location,
+			&t.Currency,
+			&t.MerchantID,
 		); err != nil {
 			log.Printf("Error scanning transaction: %v", err)
 			return nil, fmt.Errorf("failed to scan transaction: %w", err)
@@ -253,34 +213,61 @@ func (r *postgresRepo) GetBillHistory(ctx context.Context, accountID string, mer
 		return nil, fmt.Errorf("error iterating transactions: %w", err)
 	}
 
-	log.Printf("Found %d bill payments for merchant %s", len(transactions), merchantName)
+	log.Printf("Found %d bills for %s", len(transactions), startDate.Format("2006-01"))
 	return transactions, nil
-}
-
-// GetBillsByMonth retrieves all bill payments for a specific month
-func (r *postgresRepo) GetBillsByMonth(ctx context.Context, accountID string, year int, month int) ([]types.Transaction, error) {
-	if accountID == "" {
-		return nil, fmt.Errorf("account ID is required")
+} 
+// ListPayments retrieves the accounts' outgoing transactions between from and
+// to, excluding transfers, by date. Bills are matched by merchant, so
+// payments of every category are included.
+func (r *postgresRepo) ListPayments(ctx context.Context, accountIDs []string, from, to time.Time) ([]types.Transaction, error) {
+	if len(accountIDs) == 0 {
+		return nil, fmt.Errorf("at least one account ID is required")
 	}
 
-	startDate := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
-	endDate := startDate.AddDate(0, 1, 0).Add(-time.Second)
+	query := `
+		SELECT t.transaction_id, t.account_id, t.date, t.amount, t.category,
+			` + canonicalMerchant + `, t.location, t.currency, t.merchant_id
+		FROM transactions t
+		LEFT JOIN merchants m ON m.id = t.merchant_id
+		WHERE t.account_id = ANY($1)
+		  AND t.transfer_id IS NULL
+		  AND t.amount < 0
+		  AND t.date >= $2
+		  AND t.date < $3
+		  AND category_type(t.account_id, t.category) IS DISTINCT FROM 'transfer'
+		ORDER BY t.date, t.transaction_id`
+
+	return r.queryPayments(ctx, query, pq.Array(accountIDs), from, to)
+}
 
-	log.Printf("Fetching bills for account %s for %s", accountID, startDate.Format("2006-01"))
+// ListIncome retrieves the accounts' income transactions between from and
+// to, excluding transfers, by date
+func (r *postgresRepo) ListIncome(ctx context.Context, accountIDs []string, from, to time.Time) ([]types.Transaction, error) {
+	if len(accountIDs) == 0 {
+		return nil, fmt.Errorf("at least one account ID is required")
+	}
 
 	query := `
-		SELECT transaction_id, account_id, date, amount, category, merchant, location
-		FROM transactions 
-		WHERE account_id = $1 
-		  AND date >= $2
-		  AND date <= $3
-		  AND (category = 'Bill Payment' OR category = 'Subscription')
-		ORDER BY date ASC`
-
-	rows, err := r.db.QueryContext(ctx, query, accountID, startDate, endDate)
+		SELECT t.transaction_id, t.account_id, t.date, t.amount, t.category,
+			` + canonicalMerchant + `, t.location, t.currency, t.merchant_id
+		FROM transactions t
+		LEFT JOIN merchants m ON m.id = t.merchant_id
+		WHERE t.account_id = ANY($1)
+		  AND t.transfer_id IS NULL
+		  AND t.date >= $2
+		  AND t.date < $3
+		  AND category_type(t.account_id, t.category) = 'income'
+		ORDER BY t.date, t.transaction_id`
+
+	return r.queryPayments(ctx, query, pq.Array(accountIDs), from, to)
+}
+
+// queryPayments runs a query for transactions with their canonical merchant
+func (r *postgresRepo) queryPayments(ctx context.Context, query string, args ...interface{}) ([]types.Transaction, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		log.Printf("Error querying monthly bills: %v", err)
-		return nil, fmt.Errorf("failed to query monthly bills: %w", err)
+		log.Printf("Error querying bill payments: %v", err)
+		return nil, fmt.Errorf("failed to query bill payments: %w", err)
 	}
 	defer rows.Close()
 
@@ -295,6 +282,8 @@ func (r *postgresRepo) GetBillsByMonth(ctx context.Context, accountID string, ye
 			&t.Category,
 			&t.Merchant,
 			&t.Location,
+			&t.Currency,
+			&t.MerchantID,
 		); err != nil {
 			log.Printf("Error scanning transaction: %v", err)
 			return nil, fmt.Errorf("failed to scan transaction: %w", err)
@@ -306,7 +295,154 @@ func (r *postgresRepo) GetBillsByMonth(ctx context.Context, accountID string, ye
 		log.Printf("Error iterating transactions: %v", err)
 		return nil, fmt.Errorf("error iterating transactions: %w", err)
 	}
-
-	log.Printf("Found %d bills for %s", len(transactions), startDate.Format("2006-01"))
 	return transactions, nil
-} 
\ No newline at end of file
+}
+
+const billColumns = `
+	id, account_id, payee, category, amount, amount_type, frequency, due_day, start_date, autopay,
+	created_at, updated_at`
+
+// ListBills retrieves the bills the accounts declared, by payee
+func (r *postgresRepo) ListBills(ctx context.Context, accountIDs []string) ([]types.Bill, error) {
+	if len(accountIDs) == 0 {
+		return nil, fmt.Errorf("at least one account ID is required")
+	}
+
+	query := `SELECT` + billColumns + ` FROM bills WHERE account_id = ANY($1) ORDER BY payee, id`
+
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(accountIDs))
+	if err != nil {
+		log.Printf("Error querying declared bills: %v", err)
+		return nil, fmt.Errorf("failed to query declared bills: %w", err)
+	}
+	defer rows.Close()
+
+	bills := []types.Bill{}
+	for rows.Next() {
+		bill, err := scanBill(rows)
+		if err != nil {
+			log.Printf("Error scanning declared bill: %v", err)
+			return nil, fmt.Errorf("failed to scan declared bill: %w", err)
+		}
+		bills = append(bills, *bill)
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("Error iterating declared bills: %v", err)
+		return nil, fmt.Errorf("error iterating declared bills: %w", err)
+	}
+	return bills, nil
+}
+
+// GetBill retrieves a single declared bill of an account
+func (r *postgresRepo) GetBill(ctx context.Context, accountID string, billID int64) (*types.Bill, error) {
+	query := `SELECT` + billColumns + ` FROM bills WHERE account_id = $1 AND id = $2`
+
+	bill, err := scanBill(r.db.QueryRowContext(ctx, query, accountID, billID))
+	if err == sql.ErrNoRows {
+		return nil, ErrBillNotFound
+	}
+	if err != nil {
+		log.Printf("Error fetching declared bill %d: %v", billID, err)
+		return nil, fmt.Errorf("failed to fetch declared bill: %w", err)
+	}
+	return bill, nil
+}
+
+// CreateBill inserts a declared bill, setting its ID and timestamps
+func (r *postgresRepo) CreateBill(ctx context.Context, bill *types.Bill) error {
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO bills (account_id, payee, category, amount, amount_type, frequency, due_day, start_date, autopay)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id, created_at, updated_at`,
+		bill.AccountID, bill.Payee, bill.Category, bill.Amount, bill.AmountType, bill.Frequency,
+		bill.DueDay, bill.StartDate, bill.Autopay,
+	).Scan(&bill.ID, &bill.CreatedAt, &bill.UpdatedAt)
+	if err != nil {
+		return translateError(err)
+	}
+	return nil
+}
+
+// UpdateBill replaces every field a client can set of a declared bill
+func (r *postgresRepo) UpdateBill(ctx context.Context, bill *types.Bill) error {
+	err := r.db.QueryRowContext(ctx, `
+		UPDATE bills SET payee = $3, category = $4, amount = $5, amount_type = $6, frequency = $7,
+			due_day = $8, start_date = $9, autopay = $10, updated_at = NOW()
+		WHERE account_id = $1 AND id = $2
+		RETURNING created_at, updated_at`,
+		bill.AccountID, bill.ID, bill.Payee, bill.Category, bill.Amount, bill.AmountType, bill.Frequency,
+		bill.DueDay, bill.StartDate, bill.Autopay,
+	).Scan(&bill.CreatedAt, &bill.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return ErrBillNotFound
+	}
+	if err != nil {
+		return translateError(err)
+	}
+	return nil
+}
+
+// DeleteBill removes a declared bill
+func (r *postgresRepo) DeleteBill(ctx context.Context, accountID string, billID int64) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM bills WHERE account_id = $1 AND id = $2`, accountID, billID)
+	if err != nil {
+		log.Printf("Error deleting declared bill %d: %v", billID, err)
+		return fmt.Errorf("failed to delete declared bill: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrBillNotFound
+	}
+	return nil
+}
+
+func translateError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code.Name() == "foreign_key_violation" {
+		return ErrAccountNotFound
+	}
+	log.Printf("Error writing declared bill: %v", err)
+	return fmt.Errorf("failed to write declared bill: %w", err)
+}
+
+// AccountCurrencies retrieves the currency of each account, which its
+// declared bills are in
+func (r *postgresRepo) AccountCurrencies(ctx context.Context, accountIDs []string) (map[string]string, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT account_id, COALESCE(NULLIF(balance_currency, ''), 'USD') FROM users WHERE account_id = ANY($1)`,
+		pq.Array(accountIDs))
+	if err != nil {
+		log.Printf("Error querying account currencies: %v", err)
+		return nil, fmt.Errorf("failed to query account currencies: %w", err)
+	}
+	defer rows.Close()
+
+	currencies := make(map[string]string, len(accountIDs))
+	for rows.Next() {
+		var accountID, currency string
+		if err := rows.Scan(&accountID, &currency); err != nil {
+			log.Printf("Error scanning account currency: %v", err)
+			return nil, fmt.Errorf("failed to scan account currency: %w", err)
+		}
+		currencies[accountID] = currency
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("Error iterating account currencies: %v", err)
+		return nil, fmt.Errorf("error iterating account currencies: %w", err)
+	}
+	return currencies, nil
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanBill(row rowScanner) (*types.Bill, error) {
+	var b types.Bill
+	err := row.Scan(&b.ID, &b.AccountID, &b.Payee, &b.Category, &b.Amount, &b.AmountType, &b.Frequency,
+		&b.DueDay, &b.StartDate, &b.Autopay, &b.CreatedAt, &b.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	b.StartDate = time.Date(b.StartDate.Year(), b.StartDate.Month(), b.StartDate.Day(), 0, 0, 0, 0, time.UTC)
+	return &b, nil
+}
@@ -2,24 +2,63 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"server/types"
 	"time"
 )
 
+var (
+	// ErrAccountNotFound is returned when the account does not exist
+	ErrAccountNotFound = errors.New("account not found")
+
+	// ErrBillNotFound is returned when the declared bill does not exist for the account
+	ErrBillNotFound = errors.New("bill not found")
+)
+
 // Repository defines the interface for bill-related data operations
 type Repository interface {
 	// GetBillTotals retrieves total bill payments by category for a given time period
-	GetBillTotals(ctx context.Context, accountID string, startDate, endDate time.Time) (map[string]float64, error)
+	GetBillTotals(ctx context.Context, accountIDs []string, startDate, endDate time.Time) (map[string]types.Money, error)
+
+	// ListBillPayments retrieves the accounts' bill payments between from
+	// and to, excluding transfers, by date. Linked transactions carry the
+	// canonical merchant name.
+	ListBillPayments(ctx context.Context, accountIDs []string, from, to time.Time) ([]types.Transaction, error)
+
+	// GetBillHistory retrieves historical bill payments for a specific
+	// merchant that carry every tag of the filter
+	GetBillHistory(ctx context.Context, accountIDs []string, merchantName string, tags types.TagFilter) ([]types.Transaction, error)
+
+	// GetBillsByMonth retrieves all bill payments for a specific month that
+	// carry every tag of the filter
+	GetBillsByMonth(ctx context.Context, accountIDs []string, year int, month int, tags types.TagFilter) ([]types.Transaction, error)
+
+	// ListPayments retrieves the accounts' outgoing transactions between
+	// from and to, excluding transfers, by date. Linked transactions carry the
+	// canonical merchant name.
+	ListPayments(ctx context.Context, accountIDs []string, from, to time.Time) ([]types.Transaction, error)
+
+	// ListIncome retrieves the accounts' income between from and to,
+	// excluding transfers, by date. Linked transactions carry the canonical
+	// merchant name.
+	ListIncome(ctx context.Context, accountIDs []string, from, to time.Time) ([]types.Transaction, error)
+
+	// ListBills retrieves the bills the accounts declared, by payee
+	ListBills(ctx context.Context, accountIDs []string) ([]types.Bill, error)
+
+	// GetBill retrieves a single declared bill of an account
+	GetBill(ctx context.Context, accountID string, billID int64) (*types.Bill, error)
 
-	// GetRecurringBills retrieves recurring bill payments for an account
-	GetRecurringBills(ctx context.Context, accountID string) ([]types.RecurringBill, error)
+	// CreateBill inserts a declared bill, setting its ID and timestamps
+	CreateBill(ctx context.Context, bill *types.Bill) error
 
-	// GetUpcomingBills retrieves upcoming bill payments based on recurring patterns
-	GetUpcomingBills(ctx context.Context, accountID string) ([]types.UpcomingBill, error)
+	// UpdateBill replaces every field a client can set of a declared bill
+	UpdateBill(ctx context.Context, bill *types.Bill) error
 
-	// GetBillHistory retrieves historical bill payments for a specific merchant
-	GetBillHistory(ctx context.Context, accountID string, merchantName string) ([]types.Transaction, error)
+	// DeleteBill removes a declared bill
+	DeleteBill(ctx context.Context, accountID string, billID int64) error
 
-	// GetBillsByMonth retrieves all bill payments for a specific month
-	GetBillsByMonth(ctx context.Context, accountID string, year int, month int) ([]types.Transaction, error)
+	// AccountCurrencies retrieves the currency of each account, which its
+	// declared bills are in
+	AccountCurrencies(ctx context.Context, accountIDs []string) (map[string]string, error)
 } 
\ No newline at end of file
@@ -2,43 +2,343 @@ package service
 
 import (
 	"context"
+	"fmt"
+	"server/bills/planner"
+	"server/bills/reconciler"
+	"server/bills/recurrence"
 	"server/bills/repository"
+	categoriesService "server/categories/service"
+	"server/fx/rates"
+	"server/merchants/normalizer"
 	"server/types"
+	"sort"
 	"time"
 )
 
+// maxStatusMonths limits how many months of bill payments are reconciled
+const maxStatusMonths = 24
+
+// maxPlanMonths limits how far ahead paychecks are planned
+const maxPlanMonths = 12
+
+// recurrenceMonths is how far back recurring bills are looked for: long
+// enough to see an annual bill paid twice
+const recurrenceMonths = 25
+
 type Service interface {
-	GetBillTotals(ctx context.Context, accountID string, startDate, endDate time.Time) (map[string]float64, error)
-	GetRecurringBills(ctx context.Context, accountID string) ([]types.RecurringBill, error)
-	GetUpcomingBills(ctx context.Context, accountID string) ([]types.UpcomingBill, error)
-	GetBillHistory(ctx context.Context, accountID string, merchantName string) ([]types.Transaction, error)
-	GetBillsByMonth(ctx context.Context, accountID string, year int, month int) ([]types.Transaction, error)
+	GetBillTotals(ctx context.Context, accountIDs []string, startDate, endDate time.Time) (map[string]types.Money, error)
+	GetRecurringBills(ctx context.Context, accountIDs []string) ([]types.RecurringBill, error)
+	GetUpcomingBills(ctx context.Context, accountIDs []string) ([]types.UpcomingBill, error)
+	GetBillStatus(ctx context.Context, accountIDs []string, months int) (*types.BillStatusReport, error)
+	GetPaycheckPlan(ctx context.Context, accountIDs []string, months int) (*types.PaycheckPlan, error)
+	GetBillHistory(ctx context.Context, accountIDs []string, merchantName string, tags types.TagFilter) ([]types.Transaction, error)
+	GetBillsByMonth(ctx context.Context, accountIDs []string, year int, month int, tags types.TagFilter) ([]types.Transaction, error)
+	ListBills(ctx context.Context, accountID string) ([]types.Bill, error)
+	GetBill(ctx context.Context, accountID string, billID int64) (*types.Bill, error)
+	CreateBill(ctx context.Context, accountID string, input types.Bill) (*types.Bill, error)
+	UpdateBill(ctx context.Context, accountID string, billID int64, input types.Bill) (*types.Bill, error)
+	DeleteBill(ctx context.Context, accountID string, billID int64) error
 }
 
 type service struct {
-	repo repository.Repository
+	repo       repository.Repository
+	categories categoriesService.Service
+	rates      rates.Source
+}
+
+func NewService(repo repository.Repository, categories categoriesService.Service, source rates.Source) Service {
+	return &service{repo: repo, categories: categories, rates: source}
+}
+
+func (s *service) GetBillTotals(ctx context.Context, accountIDs []string, startDate, endDate time.Time) (map[string]types.Money, error) {
+	return s.repo.GetBillTotals(ctx, accountIDs, startDate, endDate)
+}
+
+// GetRecurringBills detects the accounts' recurring bills in their bill
+// payments over the last recurrenceMonths months
+func (s *service) GetRecurringBills(ctx context.Context, accountIDs []string) ([]types.RecurringBill, error) {
+	return s.recurringBills(ctx, accountIDs, time.Now().UTC())
+}
+
+func (s *service) recurringBills(ctx context.Context, accountIDs []string, today time.Time) ([]types.RecurringBill, error) {
+	payments, err := s.repo.ListBillPayments(ctx, accountIDs, today.AddDate(0, -recurrenceMonths, 0), today.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+	return recurrence.DefaultConfig.Detect(payments, today), nil
+}
+
+// GetUpcomingBills returns the next payment of every bill the accounts
+// declared and of every recurring bill inferred from their payments, by due
+// date. A declared bill stands in for the inferred bill of the same payee;
+// when its amount is variable, the inferred average is the better estimate.
+func (s *service) GetUpcomingBills(ctx context.Context, accountIDs []string) ([]types.UpcomingBill, error) {
+	bills, err := s.trackedBills(ctx, accountIDs, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+
+	upcoming := make([]types.UpcomingBill, 0, len(bills))
+	for _, b := range bills {
+		upcoming = append(upcoming, b.next)
+	}
+	sort.SliceStable(upcoming, func(i, j int) bool {
+		return upcoming[i].DueDate.Before(upcoming[j].DueDate)
+	})
+	return upcoming, nil
+}
+
+// GetBillStatus reconciles the due dates of the accounts' bills over the last
+// months with their payments and reports how each one went
+func (s *service) GetBillStatus(ctx context.Context, accountIDs []string, months int) (*types.BillStatusReport, error) {
+	if months < 1 || months > maxStatusMonths {
+		return nil, &types.ValidationError{Field: "months", Message: fmt.Sprintf("must be between 1 and %d", maxStatusMonths)}
+	}
+	now := time.Now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	from := today.AddDate(0, -months, 0)
+	config := reconciler.DefaultConfig
+
+	bills, err := s.trackedBills(ctx, accountIDs, today)
+	if err != nil {
+		return nil, err
+	}
+	payments, err := s.repo.ListPayments(ctx, accountIDs, from.AddDate(0, 0, -config.EarlyDays), today.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+
+	report := &types.BillStatusReport{
+		From:  from.Format("2006-01-02"),
+		To:    today.Format("2006-01-02"),
+		Bills: make([]types.BillStatusHistory, len(bills)),
+	}
+	var expected []types.UpcomingBill
+	var owners []int
+	for i, b := range bills {
+		report.Bills[i] = types.BillStatusHistory{
+			Merchant: b.next.Merchant,
+			Category: b.next.Category,
+			Source:   b.next.Source,
+			BillID:   b.next.BillID,
+			Autopay:  b.next.Autopay,
+			Payments: []types.BillPaymentStatus{},
+			Counts:   map[string]int{},
+		}
+		for _, due := range b.schedule.DueDates(from, today) {
+			payment := b.next
+			payment.DueDate = due
+			expected = append(expected, payment)
+			owners = append(owners, i)
+		}
+	}
+
+	for n, status := range config.Reconcile(expected, payments, today) {
+		history := &report.Bills[owners[n]]
+		history.Payments = append(history.Payments, status)
+		history.Counts[status.Status]++
+	}
+	sort.SliceStable(report.Bills, func(i, j int) bool {
+		return report.Bills[i].Merchant < report.Bills[j].Merchant
+	})
+	return report, nil
 }
 
-func NewService(repo repository.Repository) Service {
-	return &service{repo: repo}
+// GetPaycheckPlan detects the accounts' pay schedules in their income and
+// assigns every bill due over the next months to the paycheck that must
+// cover it, the last one paid or expected on or before its due date. The
+// plan starts at the earliest paycheck already received, so that the
+// current paychecks count every bill of their pay period. Each paycheck is
+// in the currency it is paid in, and its bills are converted into it.
+func (s *service) GetPaycheckPlan(ctx context.Context, accountIDs []string, months int) (*types.PaycheckPlan, error) {
+	if months < 1 || months > maxPlanMonths {
+		return nil, &types.ValidationError{Field: "months", Message: fmt.Sprintf("must be between 1 and %d", maxPlanMonths)}
+	}
+	now := time.Now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	to := today.AddDate(0, months, 0)
+
+	income, err := s.repo.ListIncome(ctx, accountIDs, today.AddDate(0, -recurrenceMonths, 0), today.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+	schedules, paychecks := planner.DefaultConfig.Paychecks(income, today, to)
+
+	from := today
+	if len(paychecks) > 0 && paychecks[0].Date.Before(from) {
+		from = paychecks[0].Date
+	}
+	bills, err := s.trackedBills(ctx, accountIDs, today)
+	if err != nil {
+		return nil, err
+	}
+	currencies, err := s.repo.AccountCurrencies(ctx, accountIDs)
+	if err != nil {
+		return nil, err
+	}
+	var due []planner.Bill
+	for _, b := range bills {
+		detail := types.BillDetail{
+			Name:     b.next.Merchant,
+			Category: b.next.Category,
+			Amount:   b.next.ExpectedAmount,
+			Merchant: b.next.Merchant,
+		}
+		// Inferred amounts carry the currency of their payments; declared
+		// ones are in their account's
+		if detail.Amount.Currency == "" {
+			detail.Amount.Currency = currencies[b.schedule.AccountID]
+		}
+		if !b.lastPaid.IsZero() {
+			detail.LastPaidDate = b.lastPaid.Format("2006-01-02")
+		}
+		for _, date := range b.schedule.DueDates(from, to) {
+			detail.DueDate = date.Format("2006-01-02")
+			due = append(due, planner.Bill{DueDate: date, Detail: detail})
+		}
+	}
+
+	table, err := s.rates.Table(ctx)
+	if err != nil {
+		return nil, err
+	}
+	unassigned, err := planner.Assign(paychecks, due, table)
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.PaycheckPlan{
+		From:       from.Format("2006-01-02"),
+		To:         to.Format("2006-01-02"),
+		Schedules:  schedules,
+		Paychecks:  paychecks,
+		Unassigned: unassigned,
+	}, nil
+}
+
+// trackedBill is a bill the accounts pay: one they declared, or one inferred
+// from their payments that no declared bill stands in for
+type trackedBill struct {
+	next     types.UpcomingBill // its next payment
+	schedule types.Bill         // its due dates
+	lastPaid time.Time          // its last payment, when one was seen
+}
+
+// trackedBills returns the accounts' declared and inferred bills with their
+// next payment: for a declared bill the first on or after today, for an
+// inferred one the first after its last payment, which is overdue when that
+// payment was missed
+func (s *service) trackedBills(ctx context.Context, accountIDs []string, today time.Time) ([]trackedBill, error) {
+	recurring, err := s.recurringBills(ctx, accountIDs, today)
+	if err != nil {
+		return nil, err
+	}
+	declared, err := s.repo.ListBills(ctx, accountIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	byPayee := make(map[string]int, len(recurring))
+	for i := range recurring {
+		byPayee[normalizer.Normalize(recurring[i].Merchant)] = i
+	}
+
+	replaced := make(map[int]bool)
+	bills := make([]trackedBill, 0, len(recurring)+len(declared))
+	for i := range declared {
+		b := &declared[i]
+		next := types.UpcomingBill{
+			Merchant:       b.Payee,
+			Category:       b.Category,
+			ExpectedAmount: b.Amount,
+			DueDate:        b.NextDue(today),
+			Source:         types.BillDeclared,
+			BillID:         &b.ID,
+			AmountType:     b.AmountType,
+			Autopay:        b.Autopay,
+			Frequency:      b.Frequency,
+			Confidence:     1,
+		}
+		var lastPaid time.Time
+		if i, ok := byPayee[normalizer.Normalize(b.Payee)]; ok {
+			replaced[i] = true
+			lastPaid = recurring[i].LastOccurrence
+			if b.AmountType == types.BillAmountVariable {
+				next.ExpectedAmount = recurring[i].AverageAmount
+			}
+		}
+		bills = append(bills, trackedBill{next: next, schedule: *b, lastPaid: lastPaid})
+	}
+	for i, r := range recurring {
+		if replaced[i] {
+			continue
+		}
+		next := types.UpcomingBill{
+			Merchant:       r.Merchant,
+			Category:       r.Category,
+			ExpectedAmount: r.AverageAmount,
+			DueDate:        r.NextDueDate,
+			Source:         types.BillInferred,
+			Frequency:      r.Frequency,
+			Confidence:     r.Confidence,
+		}
+		bills = append(bills, trackedBill{next: next, schedule: recurrence.Schedule(r), lastPaid: r.LastOccurrence})
+	}
+	return bills, nil
 }
 
-func (s *service) GetBillTotals(ctx context.Context, accountID string, startDate, endDate time.Time) (map[string]float64, error) {
-	return s.repo.GetBillTotals(ctx, accountID, startDate, endDate)
+func (s *service) GetBillHistory(ctx context.Context, accountIDs []string, merchantName string, tags types.TagFilter) ([]types.Transaction, error) {
+	return s.repo.GetBillHistory(ctx, accountIDs, merchantName, tags)
 }
 
-func (s *service) GetRecurringBills(ctx context.Context, accountID string) ([]types.RecurringBill, error) {
-	return s.repo.GetRecurringBills(ctx, accountID)
+func (s *service) GetBillsByMonth(ctx context.Context, accountIDs []string, year int, month int, tags types.TagFilter) ([]types.Transaction, error) {
+	return s.repo.GetBillsByMonth(ctx, accountIDs, year, month, tags)
 }
 
-func (s *service) GetUpcomingBills(ctx context.Context, accountID string) ([]types.UpcomingBill, error) {
-	return s.repo.GetUpcomingBills(ctx, accountID)
+func (s *service) ListBills(ctx context.Context, accountID string) ([]types.Bill, error) {
+	return s.repo.ListBills(ctx, []string{accountID})
 }
 
-func (s *service) GetBillHistory(ctx context.Context, accountID string, merchantName string) ([]types.Transaction, error) {
-	return s.repo.GetBillHistory(ctx, accountID, merchantName)
+func (s *service) GetBill(ctx context.Context, accountID string, billID int64) (*types.Bill, error) {
+	return s.repo.GetBill(ctx, accountID, billID)
 }
 
-func (s *service) GetBillsByMonth(ctx context.Context, accountID string, year int, month int) ([]types.Transaction, error) {
-	return s.repo.GetBillsByMonth(ctx, accountID, year, month)
-} 
\ No newline at end of file
+// CreateBill declares a bill, so that it is tracked before it has been paid
+func (s *service) CreateBill(ctx context.Context, accountID string, input types.Bill) (*types.Bill, error) {
+	if err := s.validate(ctx, accountID, &input); err != nil {
+		return nil, err
+	}
+	input.AccountID = accountID
+
+	if err := s.repo.CreateBill(ctx, &input); err != nil {
+		return nil, err
+	}
+	return &input, nil
+}
+
+// UpdateBill replaces a declared bill
+func (s *service) UpdateBill(ctx context.Context, accountID string, billID int64, input types.Bill) (*types.Bill, error) {
+	if err := s.validate(ctx, accountID, &input); err != nil {
+		return nil, err
+	}
+	input.AccountID = accountID
+	input.ID = billID
+
+	if err := s.repo.UpdateBill(ctx, &input); err != nil {
+		return nil, err
+	}
+	return &input, nil
+}
+
+func (s *service) DeleteBill(ctx context.Context, accountID string, billID int64) error {
+	return s.repo.DeleteBill(ctx, accountID, billID)
+}
+
+// validate checks the bill against the account's categories
+func (s *service) validate(ctx context.Context, accountID string, b *types.Bill) error {
+	categories, err := s.categories.Categories(ctx, accountID)
+	if err != nil {
+		return err
+	}
+	return b.Validate(categories)
+}
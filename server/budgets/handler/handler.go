@@ -0,0 +1,291 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"server/budgets/repository"
+	"server/budgets/service"
+	categoriesRepository "server/categories/repository"
+	categoriesService "server/categories/service"
+	"server/fx/rates"
+	fxRepository "server/fx/repository"
+	fxService "server/fx/service"
+	"server/types"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+type Handler struct {
+	service service.Service
+}
+
+func NewHandler(service service.Service) *Handler {
+	return &Handler{service: service}
+}
+
+// SetupBudgetRoutes configures all the budget-related routes
+func SetupBudgetRoutes(router *mux.Router, db *sql.DB) {
+	repo := repository.NewPostgresRepository(db)
+	fx := fxService.NewService(fxRepository.NewPostgresRepository(db))
+	categories := categoriesService.NewService(categoriesRepository.NewPostgresRepository(db), fx)
+	svc := service.NewService(repo, categories, fx)
+	handler := NewHandler(svc)
+	handler.RegisterRoutes(router)
+}
+
+// RegisterRoutes registers all budget routes
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/api/budgets/{accountId}", h.HandleListBudgets).Methods("GET")
+	router.HandleFunc("/api/budgets/{accountId}", h.HandleCreateBudget).Methods("POST")
+	router.HandleFunc("/api/budgets/{accountId}/status", h.HandleGetStatus).Methods("GET")
+	router.HandleFunc("/api/budgets/{accountId}/envelopes", h.HandleGetEnvelopes).Methods("GET")
+	router.HandleFunc("/api/budgets/{accountId}/deposits", h.HandleListDeposits).Methods("GET")
+	router.HandleFunc("/api/budgets/{accountId}/allocations", h.HandleListAllocations).Methods("GET")
+	router.HandleFunc("/api/budgets/{accountId}/allocations", h.HandleCreateAllocation).Methods("POST")
+	router.HandleFunc("/api/budgets/{accountId}/allocations/{allocationId:[0-9]+}", h.HandleDeleteAllocation).Methods("DELETE")
+	router.HandleFunc("/api/budgets/{accountId}/{budgetId:[0-9]+}", h.HandleGetBudget).Methods("GET")
+	router.HandleFunc("/api/budgets/{accountId}/{budgetId:[0-9]+}", h.HandleUpdateBudget).Methods("PUT")
+	router.HandleFunc("/api/budgets/{accountId}/{budgetId:[0-9]+}", h.HandleDeleteBudget).Methods("DELETE")
+}
+
+// HandleListBudgets handles requests for an account's budgets
+func (h *Handler) HandleListBudgets(w http.ResponseWriter, r *http.Request) {
+	accountID := mux.Vars(r)["accountId"]
+
+	budgets, err := h.service.ListBudgets(r.Context(), accountID)
+	if err != nil {
+		writeError(w, "Failed to get budgets", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(budgets)
+}
+
+// HandleCreateBudget handles requests to create a budget
+func (h *Handler) HandleCreateBudget(w http.ResponseWriter, r *http.Request) {
+	accountID := mux.Vars(r)["accountId"]
+
+	var input types.Budget
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	budget, err := h.service.CreateBudget(r.Context(), accountID, input)
+	if err != nil {
+		writeError(w, "Failed to create budget", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(budget)
+}
+
+// HandleGetBudget handles requests for a single budget
+func (h *Handler) HandleGetBudget(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	budgetID, _ := strconv.ParseInt(vars["budgetId"], 10, 64)
+
+	budget, err := h.service.GetBudget(r.Context(), vars["accountId"], budgetID)
+	if err != nil {
+		writeError(w, "Failed to get budget", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(budget)
+}
+
+// HandleUpdateBudget handles requests to replace a budget
+func (h *Handler) HandleUpdateBudget(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	budgetID, _ := strconv.ParseInt(vars["budgetId"], 10, 64)
+
+	var input types.Budget
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	budget, err := h.service.UpdateBudget(r.Context(), vars["accountId"], budgetID, input)
+	if err != nil {
+		writeError(w, "Failed to update budget", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(budget)
+}
+
+// HandleDeleteBudget handles requests to delete a budget
+func (h *Handler) HandleDeleteBudget(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	budgetID, _ := strconv.ParseInt(vars["budgetId"], 10, 64)
+
+	if err := h.service.DeleteBudget(r.Context(), vars["accountId"], budgetID); err != nil {
+		writeError(w, "Failed to delete budget", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleGetStatus handles requests for a month's spending against the
+// account's budgets; the month defaults to the current one
+func (h *Handler) HandleGetStatus(w http.ResponseWriter, r *http.Request) {
+	accountID := mux.Vars(r)["accountId"]
+	year, month := requestMonth(r)
+
+	status, err := h.service.GetStatus(r.Context(), accountID, year, month)
+	if err != nil {
+		writeError(w, "Failed to get budget status", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
+// HandleGetEnvelopes handles requests for the running balance of each
+// budget. from and to are months (YYYY-MM) and default to the last twelve.
+func (h *Handler) HandleGetEnvelopes(w http.ResponseWriter, r *http.Request) {
+	accountID := mux.Vars(r)["accountId"]
+
+	to := time.Now()
+	if value := r.URL.Query().Get("to"); value != "" {
+		parsed, err := time.Parse("2006-01", value)
+		if err != nil {
+			http.Error(w, "Invalid to month: expected YYYY-MM", http.StatusBadRequest)
+			return
+		}
+		to = parsed
+	}
+	from := to.AddDate(0, -11, 0)
+	if value := r.URL.Query().Get("from"); value != "" {
+		parsed, err := time.Parse("2006-01", value)
+		if err != nil {
+			http.Error(w, "Invalid from month: expected YYYY-MM", http.StatusBadRequest)
+			return
+		}
+		from = parsed
+	}
+
+	report, err := h.service.GetEnvelopes(r.Context(), accountID, from, to)
+	if err != nil {
+		writeError(w, "Failed to get envelopes", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// HandleListDeposits handles requests for a month's income deposits and how
+// much of each is still unallocated
+func (h *Handler) HandleListDeposits(w http.ResponseWriter, r *http.Request) {
+	accountID := mux.Vars(r)["accountId"]
+	year, month := requestMonth(r)
+
+	deposits, err := h.service.ListDeposits(r.Context(), accountID, year, month)
+	if err != nil {
+		writeError(w, "Failed to get deposits", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(deposits)
+}
+
+// HandleListAllocations handles requests for a month's allocations
+func (h *Handler) HandleListAllocations(w http.ResponseWriter, r *http.Request) {
+	accountID := mux.Vars(r)["accountId"]
+	year, month := requestMonth(r)
+
+	allocations, err := h.service.ListAllocations(r.Context(), accountID, year, month)
+	if err != nil {
+		writeError(w, "Failed to get allocations", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(allocations)
+}
+
+// HandleCreateAllocation handles requests to assign money to a budget
+func (h *Handler) HandleCreateAllocation(w http.ResponseWriter, r *http.Request) {
+	accountID := mux.Vars(r)["accountId"]
+
+	var input types.BudgetAllocation
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	allocation, err := h.service.CreateAllocation(r.Context(), accountID, input)
+	if err != nil {
+		writeError(w, "Failed to create allocation", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(allocation)
+}
+
+// HandleDeleteAllocation handles requests to remove an allocation
+func (h *Handler) HandleDeleteAllocation(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	allocationID, _ := strconv.ParseInt(vars["allocationId"], 10, 64)
+
+	if err := h.service.DeleteAllocation(r.Context(), vars["accountId"], allocationID); err != nil {
+		writeError(w, "Failed to delete allocation", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// requestMonth reads the year and month query parameters, defaulting to the
+// current month when either is missing
+func requestMonth(r *http.Request) (int, int) {
+	year, _ := strconv.Atoi(r.URL.Query().Get("year"))
+	month, _ := strconv.Atoi(r.URL.Query().Get("month"))
+
+	if year == 0 || month == 0 {
+		now := time.Now()
+		year = now.Year()
+		month = int(now.Month())
+	}
+	return year, month
+}
+
+// writeError converts service and repository errors into HTTP responses
+func writeError(w http.ResponseWriter, message string, err error) {
+	var validationErr *types.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		http.Error(w, validationErr.Error(), http.StatusBadRequest)
+	case errors.Is(err, rates.ErrNoRate):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, repository.ErrAccountNotFound):
+		http.Error(w, "Account not found", http.StatusNotFound)
+	case errors.Is(err, repository.ErrBudgetNotFound):
+		http.Error(w, "Budget not found", http.StatusNotFound)
+	case errors.Is(err, repository.ErrBudgetExists), errors.Is(err, repository.ErrOverAllocated):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, repository.ErrAllocationNotFound):
+		http.Error(w, "Allocation not found", http.StatusNotFound)
+	case errors.Is(err, repository.ErrDepositNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		log.Printf("%s: %v", message, err)
+		http.Error(w, message, http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,431 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"server/types"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type postgresRepo struct {
+	db *sql.DB
+}
+
+func NewPostgresRepository(db *sql.DB) Repository {
+	if db == nil {
+		panic("database connection is required")
+	}
+	return &postgresRepo{db: db}
+}
+
+// GetAccountCurrency retrieves the currency of the account's balance; it is
+// empty when the account or its balance is unknown
+func (r *postgresRepo) GetAccountCurrency(ctx context.Context, accountID string) (string, error) {
+	var currency string
+	err := r.db.QueryRowContext(ctx,
+		`SELECT COALESCE(balance_currency, '') FROM users WHERE account_id = $1`, accountID,
+	).Scan(&currency)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		log.Printf("Error fetching account currency: %v", err)
+		return "", fmt.Errorf("failed to fetch account currency: %w", err)
+	}
+	return currency, nil
+}
+
+const budgetColumns = `id, account_id, category, monthly_limit, rollover, created_at, updated_at`
+
+// ListBudgets retrieves the budgets of an account ordered by category
+func (r *postgresRepo) ListBudgets(ctx context.Context, accountID string) ([]types.Budget, error) {
+	query := `SELECT ` + budgetColumns + ` FROM budgets WHERE account_id = $1 ORDER BY category`
+
+	rows, err := r.db.QueryContext(ctx, query, accountID)
+	if err != nil {
+		log.Printf("Error querying budgets: %v", err)
+		return nil, fmt.Errorf("failed to query budgets: %w", err)
+	}
+	defer rows.Close()
+
+	budgets := []types.Budget{}
+	for rows.Next() {
+		budget, err := scanBudget(rows)
+		if err != nil {
+			log.Printf("Error scanning budget: %v", err)
+			return nil, fmt.Errorf("failed to scan budget: %w", err)
+		}
+		budgets = append(budgets, *budget)
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("Error iterating budgets: %v", err)
+		return nil, fmt.Errorf("error iterating budgets: %w", err)
+	}
+	return budgets, nil
+}
+
+// GetBudget retrieves a single budget of an account
+func (r *postgresRepo) GetBudget(ctx context.Context, accountID string, budgetID int64) (*types.Budget, error) {
+	query := `SELECT ` + budgetColumns + ` FROM budgets WHERE account_id = $1 AND id = $2`
+
+	budget, err := scanBudget(r.db.QueryRowContext(ctx, query, accountID, budgetID))
+	if err == sql.ErrNoRows {
+		return nil, ErrBudgetNotFound
+	}
+	if err != nil {
+		log.Printf("Error fetching budget %d: %v", budgetID, err)
+		return nil, fmt.Errorf("failed to fetch budget: %w", err)
+	}
+	return budget, nil
+}
+
+// CreateBudget inserts a budget, setting its ID and timestamps
+func (r *postgresRepo) CreateBudget(ctx context.Context, budget *types.Budget) error {
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO budgets (account_id, category, monthly_limit, rollover)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at, updated_at`,
+		budget.AccountID, budget.Category, budget.MonthlyLimit, budget.Rollover,
+	).Scan(&budget.ID, &budget.CreatedAt, &budget.UpdatedAt)
+	if err != nil {
+		return translateError(err)
+	}
+	return nil
+}
+
+// UpdateBudget replaces a budget's category, limit and rollover
+func (r *postgresRepo) UpdateBudget(ctx context.Context, budget *types.Budget) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE budgets SET category = $3, monthly_limit = $4, rollover = $5, updated_at = NOW()
+		WHERE account_id = $1 AND id = $2`,
+		budget.AccountID, budget.ID, budget.Category, budget.MonthlyLimit, budget.Rollover,
+	)
+	if err != nil {
+		return translateError(err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrBudgetNotFound
+	}
+	return nil
+}
+
+// DeleteBudget removes a budget
+func (r *postgresRepo) DeleteBudget(ctx context.Context, accountID string, budgetID int64) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM budgets WHERE account_id = $1 AND id = $2`, accountID, budgetID)
+	if err != nil {
+		log.Printf("Error deleting budget %d: %v", budgetID, err)
+		return fmt.Errorf("failed to delete budget: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrBudgetNotFound
+	}
+	return nil
+}
+
+// GetMonthlySpending retrieves the account's spending per month, category
+// and original currency between from (inclusive) and to (exclusive). Months
+// are keyed by their first day in UTC. Income and transfers are left out,
+// and a split transaction counts towards the category of each split.
+func (r *postgresRepo) GetMonthlySpending(ctx context.Context, accountID string, from, to time.Time) (map[time.Time]map[string]types.Amounts, error) {
+	query := `
+		SELECT DATE_TRUNC('month', date) AS month, category, currency, COALESCE(SUM(ABS(amount)), 0) AS total
+		FROM transaction_lines
+		WHERE account_id = $1
+		  AND transfer_id IS NULL
+		  AND category_type(account_id, category) IN ('expense', 'bill')
+		  AND date >= $2
+		  AND date < $3
+		GROUP BY month, category, currency`
+
+	rows, err := r.db.QueryContext(ctx, query, accountID, from, to)
+	if err != nil {
+		log.Printf("Error querying budget spending: %v", err)
+		return nil, fmt.Errorf("failed to query spending: %w", err)
+	}
+	defer rows.Close()
+
+	spending := make(map[time.Time]map[string]types.Amounts)
+	for rows.Next() {
+		var month time.Time
+		var category, currency string
+		var total types.Money
+		if err := rows.Scan(&month, &category, &currency, &total); err != nil {
+			log.Printf("Error scanning spending: %v", err)
+			return nil, fmt.Errorf("failed to scan spending: %w", err)
+		}
+		month = monthStart(month)
+		if spending[month] == nil {
+			spending[month] = make(map[string]types.Amounts)
+		}
+		if spending[month][category] == nil {
+			spending[month][category] = make(types.Amounts)
+		}
+		spending[month][category].Add(currency, total)
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("Error iterating spending: %v", err)
+		return nil, fmt.Errorf("error iterating spending: %w", err)
+	}
+	return spending, nil
+}
+
+const allocationColumns = `a.id, a.budget_id, a.month, a.amount, COALESCE(a.transaction_id, ''), a.note, a.created_at`
+
+// ListAllocations retrieves the allocations to the account's budgets for the
+// months from (inclusive) to to (exclusive), ordered by month
+func (r *postgresRepo) ListAllocations(ctx context.Context, accountID string, from, to time.Time) ([]types.BudgetAllocation, error) {
+	query := `
+		SELECT ` + allocationColumns + `
+		FROM budget_allocations a
+		JOIN budgets b ON b.id = a.budget_id
+		WHERE b.account_id = $1 AND a.month >= $2 AND a.month < $3
+		ORDER BY a.month, a.id`
+
+	rows, err := r.db.QueryContext(ctx, query, accountID, from, to)
+	if err != nil {
+		log.Printf("Error querying budget allocations: %v", err)
+		return nil, fmt.Errorf("failed to query allocations: %w", err)
+	}
+	defer rows.Close()
+
+	allocations := []types.BudgetAllocation{}
+	for rows.Next() {
+		allocation, err := scanAllocation(rows)
+		if err != nil {
+			log.Printf("Error scanning allocation: %v", err)
+			return nil, fmt.Errorf("failed to scan allocation: %w", err)
+		}
+		allocations = append(allocations, *allocation)
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("Error iterating allocations: %v", err)
+		return nil, fmt.Errorf("error iterating allocations: %w", err)
+	}
+	return allocations, nil
+}
+
+// GetAllocation retrieves a single allocation to one of the account's budgets
+func (r *postgresRepo) GetAllocation(ctx context.Context, accountID string, allocationID int64) (*types.BudgetAllocation, error) {
+	query := `
+		SELECT ` + allocationColumns + `
+		FROM budget_allocations a
+		JOIN budgets b ON b.id = a.budget_id
+		WHERE b.account_id = $1 AND a.id = $2`
+
+	allocation, err := scanAllocation(r.db.QueryRowContext(ctx, query, accountID, allocationID))
+	if err == sql.ErrNoRows {
+		return nil, ErrAllocationNotFound
+	}
+	if err != nil {
+		log.Printf("Error fetching allocation %d: %v", allocationID, err)
+		return nil, fmt.Errorf("failed to fetch allocation: %w", err)
+	}
+	return allocation, nil
+}
+
+// CreateAllocation inserts an allocation to one of the account's budgets,
+// setting its ID. The deposit row is locked while its allocations are
+// summed, so that two concurrent allocations cannot both take the last of it.
+func (r *postgresRepo) CreateAllocation(ctx context.Context, accountID string, allocation *types.BudgetAllocation) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	err = tx.QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM budgets WHERE id = $1 AND account_id = $2)`,
+		allocation.BudgetID, accountID,
+	).Scan(&exists)
+	if err != nil {
+		log.Printf("Error checking budget %d: %v", allocation.BudgetID, err)
+		return fmt.Errorf("failed to check budget: %w", err)
+	}
+	if !exists {
+		return ErrBudgetNotFound
+	}
+
+	var transactionID interface{}
+	if allocation.TransactionID != "" {
+		transactionID = allocation.TransactionID
+
+		var deposit, allocated types.Money
+		err := tx.QueryRowContext(ctx, `
+			SELECT amount FROM transactions
+			WHERE transaction_id = $1 AND account_id = $2 AND category_type(account_id, category) = 'income' AND transfer_id IS NULL
+			FOR UPDATE`,
+			allocation.TransactionID, accountID,
+		).Scan(&deposit)
+		if err == sql.ErrNoRows {
+			return ErrDepositNotFound
+		}
+		if err != nil {
+			log.Printf("Error locking deposit %s: %v", allocation.TransactionID, err)
+			return fmt.Errorf("failed to fetch deposit: %w", err)
+		}
+		err = tx.QueryRowContext(ctx,
+			`SELECT COALESCE(SUM(amount), 0) FROM budget_allocations WHERE transaction_id = $1`,
+			allocation.TransactionID,
+		).Scan(&allocated)
+		if err != nil {
+			log.Printf("Error summing allocations of deposit %s: %v", allocation.TransactionID, err)
+			return fmt.Errorf("failed to sum allocations: %w", err)
+		}
+		if allocated.Add(allocation.Amount).Cmp(deposit) > 0 {
+			return ErrOverAllocated
+		}
+	}
+
+	month := time.Date(allocation.Year, time.Month(allocation.Month), 1, 0, 0, 0, 0, time.UTC)
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO budget_allocations (budget_id, month, amount, transaction_id, note)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at`,
+		allocation.BudgetID, month, allocation.Amount, transactionID, allocation.Note,
+	).Scan(&allocation.ID, &allocation.CreatedAt)
+	if err != nil {
+		log.Printf("Error creating allocation: %v", err)
+		return fmt.Errorf("failed to create allocation: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit allocation: %w", err)
+	}
+	return nil
+}
+
+// DeleteAllocation removes an allocation
+func (r *postgresRepo) DeleteAllocation(ctx context.Context, accountID string, allocationID int64) error {
+	result, err := r.db.ExecContext(ctx, `
+		DELETE FROM budget_allocations a
+		USING budgets b
+		WHERE b.id = a.budget_id AND b.account_id = $1 AND a.id = $2`,
+		accountID, allocationID,
+	)
+	if err != nil {
+		log.Printf("Error deleting allocation %d: %v", allocationID, err)
+		return fmt.Errorf("failed to delete allocation: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrAllocationNotFound
+	}
+	return nil
+}
+
+// depositQuery selects income transactions t of an account with their
+// resolved currency and allocated total
+const depositQuery = `
+	SELECT t.transaction_id, t.account_id, t.date, t.amount, t.category, t.merchant, t.location,
+		COALESCE(NULLIF(t.currency, ''), NULLIF(u.balance_currency, ''), 'USD'),
+		COALESCE((SELECT SUM(a.amount) FROM budget_allocations a WHERE a.transaction_id = t.transaction_id), 0)
+	FROM transactions t
+	JOIN users u ON u.account_id = t.account_id
+	WHERE t.account_id = $1
+	  AND category_type(t.account_id, t.category) = 'income'
+	  AND t.transfer_id IS NULL`
+
+// ListDeposits retrieves the account's income between from (inclusive) and
+// to (exclusive) with how much of each deposit is allocated
+func (r *postgresRepo) ListDeposits(ctx context.Context, accountID string, from, to time.Time) ([]types.IncomeDeposit, error) {
+	query := depositQuery + ` AND t.date >= $2 AND t.date < $3 ORDER BY t.date, t.transaction_id`
+
+	rows, err := r.db.QueryContext(ctx, query, accountID, from, to)
+	if err != nil {
+		log.Printf("Error querying income deposits: %v", err)
+		return nil, fmt.Errorf("failed to query deposits: %w", err)
+	}
+	defer rows.Close()
+
+	deposits := []types.IncomeDeposit{}
+	for rows.Next() {
+		deposit, err := scanDeposit(rows)
+		if err != nil {
+			log.Printf("Error scanning deposit: %v", err)
+			return nil, fmt.Errorf("failed to scan deposit: %w", err)
+		}
+		deposits = append(deposits, *deposit)
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("Error iterating deposits: %v", err)
+		return nil, fmt.Errorf("error iterating deposits: %w", err)
+	}
+	return deposits, nil
+}
+
+// GetDeposit retrieves a single income deposit of the account
+func (r *postgresRepo) GetDeposit(ctx context.Context, accountID string, transactionID string) (*types.IncomeDeposit, error) {
+	query := depositQuery + ` AND t.transaction_id = $2`
+
+	deposit, err := scanDeposit(r.db.QueryRowContext(ctx, query, accountID, transactionID))
+	if err == sql.ErrNoRows {
+		return nil, ErrDepositNotFound
+	}
+	if err != nil {
+		log.Printf("Error fetching deposit %s: %v", transactionID, err)
+		return nil, fmt.Errorf("failed to fetch deposit: %w", err)
+	}
+	return deposit, nil
+}
+
+// translateError maps Postgres constraint violations onto the repository errors
+func translateError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code.Name() {
+		case "unique_violation":
+			return ErrBudgetExists
+		case "foreign_key_violation":
+			return ErrAccountNotFound
+		}
+	}
+	log.Printf("Error writing budget: %v", err)
+	return fmt.Errorf("failed to write budget: %w", err)
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanBudget(row rowScanner) (*types.Budget, error) {
+	var b types.Budget
+	if err := row.Scan(&b.ID, &b.AccountID, &b.Category, &b.MonthlyLimit, &b.Rollover, &b.CreatedAt, &b.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+func scanAllocation(row rowScanner) (*types.BudgetAllocation, error) {
+	var a types.BudgetAllocation
+	var month time.Time
+	if err := row.Scan(&a.ID, &a.BudgetID, &month, &a.Amount, &a.TransactionID, &a.Note, &a.CreatedAt); err != nil {
+		return nil, err
+	}
+	a.Year = month.Year()
+	a.Month = int(month.Month())
+	return &a, nil
+}
+
+func scanDeposit(row rowScanner) (*types.IncomeDeposit, error) {
+	var d types.IncomeDeposit
+	t := &d.Transaction
+	err := row.Scan(
+		&t.TransactionID, &t.AccountID, &t.Date, &t.Amount, &t.Category,
+		&t.Merchant, &t.Location, &t.Currency, &d.Allocated,
+	)
+	if err != nil {
+		return nil, err
+	}
+	d.Unallocated = t.Amount.Sub(d.Allocated)
+	return &d, nil
+}
+
+// monthStart returns the first day of t's month in UTC
+func monthStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"server/types"
+	"time"
+)
+
+var (
+	// ErrAccountNotFound is returned when the account does not exist
+	ErrAccountNotFound = errors.New("account not found")
+
+	// ErrBudgetNotFound is returned when the budget does not exist for the account
+	ErrBudgetNotFound = errors.New("budget not found")
+
+	// ErrBudgetExists is returned when the account already has a budget for the category
+	ErrBudgetExists = errors.New("a budget for this category already exists")
+
+	// ErrAllocationNotFound is returned when the allocation does not exist for the account
+	ErrAllocationNotFound = errors.New("allocation not found")
+
+	// ErrDepositNotFound is returned when the transaction is not an income deposit of the account
+	ErrDepositNotFound = errors.New("income deposit not found")
+
+	// ErrOverAllocated is returned when an allocation would assign more than a deposit holds
+	ErrOverAllocated = errors.New("allocation exceeds the unallocated amount of the deposit")
+)
+
+// Repository defines the interface for budget data operations
+type Repository interface {
+	// GetAccountCurrency retrieves the currency of the account's balance; it
+	// is empty when the account or its balance is unknown
+	GetAccountCurrency(ctx context.Context, accountID string) (string, error)
+
+	// ListBudgets retrieves the budgets of an account ordered by category
+	ListBudgets(ctx context.Context, accountID string) ([]types.Budget, error)
+
+	// GetBudget retrieves a single budget of an account
+	GetBudget(ctx context.Context, accountID string, budgetID int64) (*types.Budget, error)
+
+	// CreateBudget inserts a budget, setting its ID and timestamps
+	CreateBudget(ctx context.Context, budget *types.Budget) error
+
+	// UpdateBudget replaces a budget's category, limit and rollover
+	UpdateBudget(ctx context.Context, budget *types.Budget) error
+
+	// DeleteBudget removes a budget
+	DeleteBudget(ctx context.Context, accountID string, budgetID int64) error
+
+	// GetMonthlySpending retrieves the account's spending per month,
+	// category and original currency between from (inclusive) and to
+	// (exclusive). Months are keyed by their first day in UTC. Income and
+	// transfers are left out.
+	GetMonthlySpending(ctx context.Context, accountID string, from, to time.Time) (map[time.Time]map[string]types.Amounts, error)
+
+	// ListAllocations retrieves the allocations to the account's budgets for
+	// the months from (inclusive) to to (exclusive), ordered by month
+	ListAllocations(ctx context.Context, accountID string, from, to time.Time) ([]types.BudgetAllocation, error)
+
+	// GetAllocation retrieves a single allocation to one of the account's budgets
+	GetAllocation(ctx context.Context, accountID string, allocationID int64) (*types.BudgetAllocation, error)
+
+	// CreateAllocation inserts an allocation to one of the account's
+	// budgets, setting its ID. An allocation from a deposit fails with
+	// ErrOverAllocated when the deposit's allocations would exceed it.
+	CreateAllocation(ctx context.Context, accountID string, allocation *types.BudgetAllocation) error
+
+	// DeleteAllocation removes an allocation
+	DeleteAllocation(ctx context.Context, accountID string, allocationID int64) error
+
+	// ListDeposits retrieves the account's income between from (inclusive)
+	// and to (exclusive) with how much of each deposit is allocated
+	ListDeposits(ctx context.Context, accountID string, from, to time.Time) ([]types.IncomeDeposit, error)
+
+	// GetDeposit retrieves a single income deposit of the account
+	GetDeposit(ctx context.Context, accountID string, transactionID string) (*types.IncomeDeposit, error)
+}
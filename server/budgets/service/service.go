@@ -0,0 +1,420 @@
+package service
+
+import (
+	"context"
+	"math"
+	"server/budgets/repository"
+	categoriesService "server/categories/service"
+	"server/fx/rates"
+	"server/types"
+	"time"
+)
+
+// maxEnvelopeMonths limits how many months an envelope report may cover
+const maxEnvelopeMonths = 120
+
+type Service interface {
+	ListBudgets(ctx context.Context, accountID string) ([]types.Budget, error)
+	GetBudget(ctx context.Context, accountID string, budgetID int64) (*types.Budget, error)
+	CreateBudget(ctx context.Context, accountID string, input types.Budget) (*types.Budget, error)
+	UpdateBudget(ctx context.Context, accountID string, budgetID int64, input types.Budget) (*types.Budget, error)
+	DeleteBudget(ctx context.Context, accountID string, budgetID int64) error
+	GetStatus(ctx context.Context, accountID string, year int, month int) (*types.BudgetStatus, error)
+
+	ListAllocations(ctx context.Context, accountID string, year int, month int) ([]types.BudgetAllocation, error)
+	CreateAllocation(ctx context.Context, accountID string, input types.BudgetAllocation) (*types.BudgetAllocation, error)
+	DeleteAllocation(ctx context.Context, accountID string, allocationID int64) error
+	ListDeposits(ctx context.Context, accountID string, year int, month int) ([]types.IncomeDeposit, error)
+	GetEnvelopes(ctx context.Context, accountID string, from time.Time, to time.Time) (*types.EnvelopeReport, error)
+}
+
+type service struct {
+	repo       repository.Repository
+	categories categoriesService.Service
+	rates      rates.Source
+	now        func() time.Time
+}
+
+func NewService(repo repository.Repository, categories categoriesService.Service, source rates.Source) Service {
+	return &service{repo: repo, categories: categories, rates: source, now: time.Now}
+}
+
+func (s *service) ListBudgets(ctx context.Context, accountID string) ([]types.Budget, error) {
+	return s.repo.ListBudgets(ctx, accountID)
+}
+
+func (s *service) GetBudget(ctx context.Context, accountID string, budgetID int64) (*types.Budget, error) {
+	return s.repo.GetBudget(ctx, accountID, budgetID)
+}
+
+// CreateBudget validates the input and creates a budget for the account
+func (s *service) CreateBudget(ctx context.Context, accountID string, input types.Budget) (*types.Budget, error) {
+	categories, err := s.categories.Categories(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	if err := input.Validate(categories); err != nil {
+		return nil, err
+	}
+	input.AccountID = accountID
+
+	if err := s.repo.CreateBudget(ctx, &input); err != nil {
+		return nil, err
+	}
+	return &input, nil
+}
+
+// UpdateBudget replaces the budget's category, limit and rollover
+func (s *service) UpdateBudget(ctx context.Context, accountID string, budgetID int64, input types.Budget) (*types.Budget, error) {
+	categories, err := s.categories.Categories(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	if err := input.Validate(categories); err != nil {
+		return nil, err
+	}
+	input.AccountID = accountID
+	input.ID = budgetID
+
+	if err := s.repo.UpdateBudget(ctx, &input); err != nil {
+		return nil, err
+	}
+	return s.repo.GetBudget(ctx, accountID, budgetID)
+}
+
+func (s *service) DeleteBudget(ctx context.Context, accountID string, budgetID int64) error {
+	return s.repo.DeleteBudget(ctx, accountID, budgetID)
+}
+
+// GetStatus compares the month's spending in each budgeted category with
+// what the budget has available: its limit, the income allocated to it and,
+// for budgets that roll over, the balance carried from the previous month.
+// Spending in other currencies is converted into the account's currency at
+// the rates of each month's last day, or today for the current month.
+func (s *service) GetStatus(ctx context.Context, accountID string, year int, month int) (*types.BudgetStatus, error) {
+	start, err := monthOf(year, month)
+	if err != nil {
+		return nil, err
+	}
+	end := start.AddDate(0, 1, 0)
+
+	currency, err := s.currency(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	budgets, err := s.repo.ListBudgets(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	h, err := s.loadHistory(ctx, accountID, currency, budgets, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	zero := types.NewMoney(0, currency)
+	status := &types.BudgetStatus{
+		AccountID:      accountID,
+		Year:           year,
+		Month:          month,
+		Currency:       currency,
+		Categories:     make([]types.BudgetCategoryStatus, 0, len(budgets)),
+		TotalLimit:     zero,
+		TotalAvailable: zero,
+		TotalSpent:     zero,
+		TotalRemaining: zero,
+		Unbudgeted:     zero,
+	}
+
+	now := s.now()
+	budgeted := make(map[string]bool, len(budgets))
+	for _, b := range budgets {
+		budgeted[b.Category] = true
+		m := h.ledger(b, start, end, currency)[0]
+		available := m.CarriedIn.Add(m.Funded).Add(m.Allocated)
+		projected := project(m.Spent, start, end, now)
+
+		status.Categories = append(status.Categories, types.BudgetCategoryStatus{
+			BudgetID:       b.ID,
+			Category:       b.Category,
+			Limit:          m.Funded,
+			CarriedOver:    m.CarriedIn,
+			Allocated:      m.Allocated,
+			Available:      available,
+			Spent:          m.Spent,
+			Remaining:      m.Balance,
+			PercentUsed:    percent(m.Spent, available),
+			ProjectedSpend: projected,
+			OverBudget:     m.Spent.Cmp(available) > 0,
+			ProjectedOver:  projected.Cmp(available) > 0,
+		})
+		status.TotalLimit = status.TotalLimit.Add(m.Funded)
+		status.TotalAvailable = status.TotalAvailable.Add(available)
+		status.TotalSpent = status.TotalSpent.Add(m.Spent)
+		status.TotalRemaining = status.TotalRemaining.Add(m.Balance)
+	}
+
+	for category, amount := range h.spent[start] {
+		if !budgeted[category] {
+			status.Unbudgeted = status.Unbudgeted.Add(amount)
+		}
+	}
+	return status, nil
+}
+
+// ListAllocations lists the allocations to the account's budgets in a month
+func (s *service) ListAllocations(ctx context.Context, accountID string, year int, month int) ([]types.BudgetAllocation, error) {
+	start, err := monthOf(year, month)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.ListAllocations(ctx, accountID, start, start.AddDate(0, 1, 0))
+}
+
+// CreateAllocation assigns money to one of the account's budgets. Money taken
+// from an income deposit lands in the deposit's month and must be in the
+// account's currency.
+func (s *service) CreateAllocation(ctx context.Context, accountID string, input types.BudgetAllocation) (*types.BudgetAllocation, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+
+	if input.TransactionID != "" {
+		deposit, err := s.repo.GetDeposit(ctx, accountID, input.TransactionID)
+		if err != nil {
+			return nil, err
+		}
+		currency, err := s.currency(ctx, accountID)
+		if err != nil {
+			return nil, err
+		}
+		if deposit.Transaction.Currency != currency {
+			return nil, &types.ValidationError{Field: "transaction_id", Message: "deposit must be in the account's currency"}
+		}
+		if input.Amount.Cmp(deposit.Unallocated) > 0 {
+			return nil, repository.ErrOverAllocated
+		}
+		input.Year = deposit.Transaction.Date.Year()
+		input.Month = int(deposit.Transaction.Date.Month())
+	}
+
+	if err := s.repo.CreateAllocation(ctx, accountID, &input); err != nil {
+		return nil, err
+	}
+	return s.repo.GetAllocation(ctx, accountID, input.ID)
+}
+
+func (s *service) DeleteAllocation(ctx context.Context, accountID string, allocationID int64) error {
+	return s.repo.DeleteAllocation(ctx, accountID, allocationID)
+}
+
+// ListDeposits lists a month's income with how much of each deposit is
+// still unallocated
+func (s *service) ListDeposits(ctx context.Context, accountID string, year int, month int) ([]types.IncomeDeposit, error) {
+	start, err := monthOf(year, month)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.ListDeposits(ctx, accountID, start, start.AddDate(0, 1, 0))
+}
+
+// GetEnvelopes reports each budget's running balance for every month from
+// the month of from to the month of to. Balances of budgets that roll over
+// include everything carried since the budget was created, even when that
+// is before from.
+func (s *service) GetEnvelopes(ctx context.Context, accountID string, from time.Time, to time.Time) (*types.EnvelopeReport, error) {
+	from = monthStart(from)
+	to = monthStart(to)
+	if to.Before(from) {
+		return nil, &types.ValidationError{Field: "to", Message: "must not be before from"}
+	}
+	if to.After(from.AddDate(0, maxEnvelopeMonths-1, 0)) {
+		return nil, &types.ValidationError{Field: "to", Message: "must be at most 120 months after from"}
+	}
+	end := to.AddDate(0, 1, 0)
+
+	currency, err := s.currency(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	budgets, err := s.repo.ListBudgets(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	h, err := s.loadHistory(ctx, accountID, currency, budgets, from, end)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &types.EnvelopeReport{
+		AccountID: accountID,
+		Currency:  currency,
+		From:      from.Format("2006-01"),
+		To:        to.Format("2006-01"),
+		Envelopes: make([]types.Envelope, 0, len(budgets)),
+	}
+	for _, b := range budgets {
+		report.Envelopes = append(report.Envelopes, types.Envelope{
+			BudgetID:     b.ID,
+			Category:     b.Category,
+			MonthlyLimit: b.MonthlyLimit,
+			Rollover:     b.Rollover,
+			Months:       h.ledger(b, from, end, currency),
+		})
+	}
+	return report, nil
+}
+
+// history is the spending and allocations the envelope balances are built from
+type history struct {
+	// starts holds the month each budget's ledger begins: the month it was
+	// created, or an earlier month that already has an allocation
+	starts map[int64]time.Time
+
+	// spent holds the converted spending per month and category
+	spent map[time.Time]map[string]types.Money
+
+	// allocated holds the allocations per budget and month
+	allocated map[int64]map[time.Time]types.Money
+}
+
+// loadHistory loads what the ledgers of the budgets need up to end
+// (exclusive): from the earliest budget start or from, whichever is first
+func (s *service) loadHistory(ctx context.Context, accountID string, currency string, budgets []types.Budget, from, end time.Time) (*history, error) {
+	allocations, err := s.repo.ListAllocations(ctx, accountID, time.Time{}, end)
+	if err != nil {
+		return nil, err
+	}
+
+	h := &history{
+		starts:    make(map[int64]time.Time, len(budgets)),
+		spent:     make(map[time.Time]map[string]types.Money),
+		allocated: make(map[int64]map[time.Time]types.Money),
+	}
+	first := from
+	for _, b := range budgets {
+		h.starts[b.ID] = monthStart(b.CreatedAt)
+	}
+	for _, a := range allocations {
+		month := time.Date(a.Year, time.Month(a.Month), 1, 0, 0, 0, 0, time.UTC)
+		if start, ok := h.starts[a.BudgetID]; ok && month.Before(start) {
+			h.starts[a.BudgetID] = month
+		}
+		if h.allocated[a.BudgetID] == nil {
+			h.allocated[a.BudgetID] = make(map[time.Time]types.Money)
+		}
+		h.allocated[a.BudgetID][month] = h.allocated[a.BudgetID][month].Add(a.Amount)
+	}
+	for _, b := range budgets {
+		if b.Rollover && h.starts[b.ID].Before(first) {
+			first = h.starts[b.ID]
+		}
+	}
+
+	spending, err := s.repo.GetMonthlySpending(ctx, accountID, first, end)
+	if err != nil {
+		return nil, err
+	}
+	table, err := s.rates.Table(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now := s.now()
+	for month, categories := range spending {
+		rateDay := month.AddDate(0, 1, -1)
+		if now.Before(rateDay) {
+			rateDay = now
+		}
+		h.spent[month] = make(map[string]types.Money, len(categories))
+		for category, amounts := range categories {
+			if h.spent[month][category], err = table.ConvertAll(amounts, currency, rateDay); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return h, nil
+}
+
+// ledger returns a budget's months from from to end (exclusive). A budget
+// that rolls over carries each month's balance, positive or negative, into
+// the next from the month its ledger starts; any other budget starts every
+// month afresh.
+func (h *history) ledger(b types.Budget, from, end time.Time, currency string) []types.EnvelopeMonth {
+	zero := types.NewMoney(0, currency)
+	start := h.starts[b.ID]
+	first := from
+	if b.Rollover && start.Before(first) {
+		first = start
+	}
+
+	var months []types.EnvelopeMonth
+	carried := zero
+	for month := first; month.Before(end); month = month.AddDate(0, 1, 0) {
+		funded := zero.Add(b.MonthlyLimit)
+		allocated := zero.Add(h.allocated[b.ID][month])
+		spent := zero.Add(h.spent[month][b.Category])
+		balance := carried.Add(funded).Add(allocated).Sub(spent)
+
+		if !month.Before(from) {
+			months = append(months, types.EnvelopeMonth{
+				Year:      month.Year(),
+				Month:     int(month.Month()),
+				CarriedIn: carried,
+				Funded:    funded,
+				Allocated: allocated,
+				Spent:     spent,
+				Balance:   balance,
+			})
+		}
+
+		carried = zero
+		if b.Rollover && !month.Before(start) {
+			carried = balance
+		}
+	}
+	return months
+}
+
+// currency returns the account's currency, which budgets are kept in
+func (s *service) currency(ctx context.Context, accountID string) (string, error) {
+	accountCurrency, err := s.repo.GetAccountCurrency(ctx, accountID)
+	if err != nil {
+		return "", err
+	}
+	return rates.ReportingCurrency("", accountCurrency)
+}
+
+// monthOf validates a year and month and returns the month's first day in UTC
+func monthOf(year int, month int) (time.Time, error) {
+	if month < 1 || month > 12 {
+		return time.Time{}, &types.ValidationError{Field: "month", Message: "must be between 1 and 12"}
+	}
+	if year < 1900 || year > 9999 {
+		return time.Time{}, &types.ValidationError{Field: "year", Message: "is out of range"}
+	}
+	return time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC), nil
+}
+
+// monthStart returns the first day of t's month in UTC
+func monthStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// project extrapolates spending so far in the month [start, end) to the end
+// of the month at the same daily rate. Past months are returned as spent and
+// future months project nothing.
+func project(spent types.Money, start, end, now time.Time) types.Money {
+	switch {
+	case !now.Before(end):
+		return spent
+	case now.Before(start):
+		return types.NewMoney(0, spent.Currency)
+	}
+	days := int(end.Sub(start).Hours() / 24)
+	elapsed := int(now.Sub(start).Hours()/24) + 1
+	return types.NewMoney(spent.Cents*int64(days), spent.Currency).Div(elapsed)
+}
+
+// percent returns spent as a percentage of limit, rounded to one decimal place
+func percent(spent, limit types.Money) float64 {
+	return math.Round(spent.Ratio(limit)*1000) / 10
+}
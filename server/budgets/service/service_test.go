@@ -0,0 +1,136 @@
+package service
+
+import (
+	"server/types"
+	"testing"
+	"time"
+)
+
+func month(m time.Month) time.Time {
+	return time.Date(2025, m, 1, 0, 0, 0, 0, time.UTC)
+}
+
+func usd(cents int64) types.Money {
+	return types.NewMoney(cents, "USD")
+}
+
+func TestLedger(t *testing.T) {
+	// balance is what a month of the ledger should show
+	type balance struct {
+		carriedIn, allocated, spent, balance int64
+	}
+	tests := []struct {
+		name      string
+		rollover  bool
+		start     time.Time // the month the budget's ledger starts
+		spent     map[time.Time]int64
+		allocated map[time.Time]int64
+		from, end time.Time
+		want      []balance
+	}{
+		{
+			name:     "overspending carries a negative balance",
+			rollover: true,
+			start:    month(time.January),
+			spent:    map[time.Time]int64{month(time.January): 15000, month(time.February): 2000},
+			from:     month(time.January), end: month(time.April),
+			want: []balance{
+				{carriedIn: 0, spent: 15000, balance: -5000},
+				{carriedIn: -5000, spent: 2000, balance: 3000},
+				{carriedIn: 3000, balance: 13000},
+			},
+		},
+		{
+			name:      "carried from months before the requested range",
+			rollover:  true,
+			start:     month(time.January),
+			spent:     map[time.Time]int64{month(time.January): 4000},
+			allocated: map[time.Time]int64{month(time.February): 2500},
+			from:      month(time.March), end: month(time.April),
+			want: []balance{{carriedIn: 18500, balance: 28500}},
+		},
+		{
+			// An allocation to February moved the start of a budget created
+			// in March back to February. January's allocation is reported
+			// but, being before the ledger starts, not carried.
+			name:      "allocations before the budget was created",
+			rollover:  true,
+			start:     month(time.February),
+			allocated: map[time.Time]int64{month(time.January): 9900, month(time.February): 5000},
+			spent:     map[time.Time]int64{month(time.January): 1000},
+			from:      month(time.January), end: month(time.April),
+			want: []balance{
+				{carriedIn: 0, allocated: 9900, spent: 1000, balance: 18900},
+				{carriedIn: 0, allocated: 5000, balance: 15000},
+				{carriedIn: 15000, balance: 25000},
+			},
+		},
+		{
+			name:     "months without rollover start afresh",
+			rollover: false,
+			start:    month(time.January),
+			spent:    map[time.Time]int64{month(time.January): 15000, month(time.February): 2000},
+			from:     month(time.January), end: month(time.April),
+			want: []balance{
+				{carriedIn: 0, spent: 15000, balance: -5000},
+				{carriedIn: 0, spent: 2000, balance: 8000},
+				{carriedIn: 0, balance: 10000},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := types.Budget{ID: 1, Category: "Dining", MonthlyLimit: usd(10000), Rollover: tt.rollover}
+			h := &history{
+				starts:    map[int64]time.Time{b.ID: tt.start},
+				spent:     make(map[time.Time]map[string]types.Money),
+				allocated: map[int64]map[time.Time]types.Money{b.ID: {}},
+			}
+			for m, cents := range tt.spent {
+				h.spent[m] = map[string]types.Money{b.Category: usd(cents), "Groceries": usd(99999)}
+			}
+			for m, cents := range tt.allocated {
+				h.allocated[b.ID][m] = usd(cents)
+			}
+
+			months := h.ledger(b, tt.from, tt.end, "USD")
+			if len(months) != len(tt.want) {
+				t.Fatalf("ledger() returned %d months, want %d", len(months), len(tt.want))
+			}
+			for i, m := range months {
+				if want := tt.from.AddDate(0, i, 0); m.Year != want.Year() || m.Month != int(want.Month()) {
+					t.Errorf("month %d is %d-%02d, want %s", i, m.Year, m.Month, want.Format("2006-01"))
+				}
+				got := balance{m.CarriedIn.Cents, m.Allocated.Cents, m.Spent.Cents, m.Balance.Cents}
+				if got != tt.want[i] {
+					t.Errorf("%d-%02d = %+v, want %+v", m.Year, m.Month, got, tt.want[i])
+				}
+				if m.Funded.Cents != 10000 || m.Balance.Currency != "USD" {
+					t.Errorf("%d-%02d funded %s %s, want 100.00 USD", m.Year, m.Month, m.Funded, m.Balance.Currency)
+				}
+			}
+		})
+	}
+}
+
+func TestProject(t *testing.T) {
+	start, end := month(time.April), month(time.May)
+	tests := []struct {
+		name string
+		now  time.Time
+		want int64
+	}{
+		{"a past month is what was spent", time.Date(2025, time.May, 2, 0, 0, 0, 0, time.UTC), 12000},
+		{"a future month projects nothing", time.Date(2025, time.March, 31, 0, 0, 0, 0, time.UTC), 0},
+		{"the first day counts as a full day", time.Date(2025, time.April, 1, 8, 0, 0, 0, time.UTC), 360000},
+		{"the daily rate so far", time.Date(2025, time.April, 10, 23, 0, 0, 0, time.UTC), 36000},
+		{"the last day", time.Date(2025, time.April, 30, 12, 0, 0, 0, time.UTC), 12000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := project(usd(12000), start, end, tt.now); got.Cents != tt.want {
+				t.Errorf("project() = %d cents, want %d", got.Cents, tt.want)
+			}
+		})
+	}
+}
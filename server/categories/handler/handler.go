@@ -3,10 +3,16 @@ package handler
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"server/categories/repository"
 	"server/categories/service"
+	"server/fx/rates"
+	fxRepository "server/fx/repository"
+	fxService "server/fx/service"
+	"server/types"
+	"strconv"
 
 	"github.com/gorilla/mux"
 )
@@ -22,7 +28,8 @@ func NewHandler(service service.Service) *Handler {
 // SetupCategoryRoutes configures all the category-related routes
 func SetupCategoryRoutes(router *mux.Router, db *sql.DB) {
 	repo := repository.NewPostgresRepository(db)
-	svc := service.NewService(repo)
+	fx := fxService.NewService(fxRepository.NewPostgresRepository(db))
+	svc := service.NewService(repo, fx)
 	handler := NewHandler(svc)
 	handler.RegisterRoutes(router)
 }
@@ -31,6 +38,12 @@ func SetupCategoryRoutes(router *mux.Router, db *sql.DB) {
 func (h *Handler) RegisterRoutes(router *mux.Router) {
 	router.HandleFunc("/api/categories/{accountId}", h.HandleGetCategories).Methods("GET")
 	router.HandleFunc("/api/categories/{accountId}/totals", h.HandleGetCategoryTotals).Methods("GET")
+	router.HandleFunc("/api/categories/{accountId}/tags", h.HandleGetTagTotals).Methods("GET")
+	router.HandleFunc("/api/categories/{accountId}/definitions", h.HandleListDefinitions).Methods("GET")
+	router.HandleFunc("/api/categories/{accountId}/definitions", h.HandleCreateDefinition).Methods("POST")
+	router.HandleFunc("/api/categories/{accountId}/definitions/{categoryId:[0-9]+}", h.HandleGetDefinition).Methods("GET")
+	router.HandleFunc("/api/categories/{accountId}/definitions/{categoryId:[0-9]+}", h.HandleUpdateDefinition).Methods("PUT")
+	router.HandleFunc("/api/categories/{accountId}/definitions/{categoryId:[0-9]+}", h.HandleDeleteDefinition).Methods("DELETE")
 }
 
 // HandleGetCategories handles requests for categories
@@ -38,10 +51,9 @@ func (h *Handler) HandleGetCategories(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	accountID := vars["accountId"]
 
-	categories, err := h.service.GetCategories(r.Context(), accountID)
+	categories, err := h.service.GetCategories(r.Context(), accountID, r.URL.Query().Get("currency"))
 	if err != nil {
-		log.Printf("Error getting categories: %v", err)
-		http.Error(w, "Failed to get categories", http.StatusInternalServerError)
+		writeError(w, "Failed to get categories", err)
 		return
 	}
 
@@ -54,13 +66,135 @@ func (h *Handler) HandleGetCategoryTotals(w http.ResponseWriter, r *http.Request
 	vars := mux.Vars(r)
 	accountID := vars["accountId"]
 
-	totals, err := h.service.GetCategoryTotals(r.Context(), accountID)
+	totals, err := h.service.GetCategoryTotals(r.Context(), accountID, r.URL.Query().Get("currency"))
 	if err != nil {
-		log.Printf("Error getting category totals: %v", err)
-		http.Error(w, "Failed to get category totals", http.StatusInternalServerError)
+		writeError(w, "Failed to get category totals", err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(totals)
-} 
\ No newline at end of file
+}
+
+// HandleGetTagTotals handles requests for the spending per tag
+func (h *Handler) HandleGetTagTotals(w http.ResponseWriter, r *http.Request) {
+	accountID := mux.Vars(r)["accountId"]
+
+	totals, err := h.service.GetTagTotals(r.Context(), accountID, r.URL.Query().Get("currency"))
+	if err != nil {
+		writeError(w, "Failed to get tag totals", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(totals)
+}
+
+// HandleListDefinitions handles requests for the categories the account can
+// file transactions under: the built-in ones and its own
+func (h *Handler) HandleListDefinitions(w http.ResponseWriter, r *http.Request) {
+	accountID := mux.Vars(r)["accountId"]
+
+	categories, err := h.service.ListDefinitions(r.Context(), accountID)
+	if err != nil {
+		writeError(w, "Failed to get category definitions", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(categories)
+}
+
+// HandleCreateDefinition handles requests to create a category
+func (h *Handler) HandleCreateDefinition(w http.ResponseWriter, r *http.Request) {
+	accountID := mux.Vars(r)["accountId"]
+
+	var input types.CategoryDefinition
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	category, err := h.service.CreateDefinition(r.Context(), accountID, input)
+	if err != nil {
+		writeError(w, "Failed to create category", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(category)
+}
+
+// HandleGetDefinition handles requests for a single category
+func (h *Handler) HandleGetDefinition(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	categoryID, _ := strconv.ParseInt(vars["categoryId"], 10, 64)
+
+	category, err := h.service.GetDefinition(r.Context(), vars["accountId"], categoryID)
+	if err != nil {
+		writeError(w, "Failed to get category", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(category)
+}
+
+// HandleUpdateDefinition handles requests to replace a category
+func (h *Handler) HandleUpdateDefinition(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	categoryID, _ := strconv.ParseInt(vars["categoryId"], 10, 64)
+
+	var input types.CategoryDefinition
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	category, err := h.service.UpdateDefinition(r.Context(), vars["accountId"], categoryID, input)
+	if err != nil {
+		writeError(w, "Failed to update category", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(category)
+}
+
+// HandleDeleteDefinition handles requests to delete a category
+func (h *Handler) HandleDeleteDefinition(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	categoryID, _ := strconv.ParseInt(vars["categoryId"], 10, 64)
+
+	if err := h.service.DeleteDefinition(r.Context(), vars["accountId"], categoryID); err != nil {
+		writeError(w, "Failed to delete category", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeError converts service and repository errors into HTTP responses: an
+// invalid or unconvertible reporting currency is the client's error, and so
+// are category writes that conflict with the account's categories
+func writeError(w http.ResponseWriter, message string, err error) {
+	var validationErr *types.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		http.Error(w, validationErr.Error(), http.StatusBadRequest)
+	case errors.Is(err, rates.ErrNoRate):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, repository.ErrAccountNotFound):
+		http.Error(w, "Account not found", http.StatusNotFound)
+	case errors.Is(err, repository.ErrCategoryNotFound):
+		http.Error(w, "Category not found", http.StatusNotFound)
+	case errors.Is(err, repository.ErrCategoryExists),
+		errors.Is(err, repository.ErrCategoryInUse),
+		errors.Is(err, repository.ErrBuiltInCategory):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		log.Printf("%s: %v", message, err)
+		http.Error(w, message, http.StatusInternalServerError)
+	}
+}
@@ -3,9 +3,12 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"server/types"
+
+	"github.com/lib/pq"
 )
 
 type postgresRepo struct {
@@ -19,7 +22,25 @@ func NewPostgresRepository(db *sql.DB) Repository {
 	return &postgresRepo{db: db}
 }
 
-// GetCategories retrieves all categories for an account
+// GetAccountCurrency retrieves the currency of the account's balance; it is
+// empty when the account or its balance is unknown
+func (r *postgresRepo) GetAccountCurrency(ctx context.Context, accountID string) (string, error) {
+	var currency string
+	err := r.db.QueryRowContext(ctx,
+		`SELECT COALESCE(balance_currency, '') FROM users WHERE account_id = $1`, accountID,
+	).Scan(&currency)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		log.Printf("Error fetching account currency: %v", err)
+		return "", fmt.Errorf("failed to fetch account currency: %w", err)
+	}
+	return currency, nil
+}
+
+// GetCategories retrieves all categories for an account, counting each split
+// of a split transaction under its own category
 func (r *postgresRepo) GetCategories(ctx context.Context, accountID string) ([]types.Category, error) {
 	if accountID == "" {
 		return nil, fmt.Errorf("account ID is required")
@@ -32,12 +53,15 @@ func (r *postgresRepo) GetCategories(ctx context.Context, accountID string) ([]t
 			category as id,
 			category as name,
 			'' as description,
+			currency,
 			COALESCE(SUM(ABS(amount)), 0) as total_spent,
 			COUNT(*) as count
-		FROM transactions 
+		FROM transaction_lines 
 		WHERE account_id = $1 
-		GROUP BY category
-		ORDER BY total_spent DESC`
+		  AND transfer_id IS NULL
+		  AND category_type(account_id, category) IS DISTINCT FROM 'transfer'
+		GROUP BY category, currency
+		ORDER BY category`
 
 	rows, err := r.db.QueryContext(ctx, query, accountID)
 	if err != nil {
@@ -49,10 +73,21 @@ func (r *postgresRepo) GetCategories(ctx context.Context, accountID string) ([]t
 	var categories []types.Category
 	for rows.Next() {
 		var c types.Category
-		if err := rows.Scan(&c.ID, &c.Name, &c.Description, &c.TotalSpent, &c.Count); err != nil {
+		var currency string
+		var total types.Money
+		if err := rows.Scan(&c.ID, &c.Name, &c.Description, &currency, &total, &c.Count); err != nil {
 			log.Printf("Error scanning category: %v", err)
 			return nil, fmt.Errorf("failed to scan category: %w", err)
 		}
+
+		// Rows are ordered by category, one per currency
+		if n := len(categories); n > 0 && categories[n-1].ID == c.ID {
+			categories[n-1].Original.Add(currency, total)
+			categories[n-1].Count += c.Count
+			continue
+		}
+		c.Original = types.Amounts{}
+		c.Original.Add(currency, total)
 		categories = append(categories, c)
 	}
 
@@ -65,8 +100,9 @@ func (r *postgresRepo) GetCategories(ctx context.Context, accountID string) ([]t
 	return categories, nil
 }
 
-// GetCategoryTotals retrieves total spending by category
-func (r *postgresRepo) GetCategoryTotals(ctx context.Context, accountID string) (map[string]float64, error) {
+// GetCategoryTotals retrieves total spending by category. A split
+// transaction counts towards the category of each split.
+func (r *postgresRepo) GetCategoryTotals(ctx context.Context, accountID string) (map[string]types.Amounts, error) {
 	if accountID == "" {
 		return nil, fmt.Errorf("account ID is required")
 	}
@@ -74,10 +110,12 @@ func (r *postgresRepo) GetCategoryTotals(ctx context.Context, accountID string)
 	log.Printf("Fetching category totals for account %s", accountID)
 
 	query := `
-		SELECT category, COALESCE(SUM(ABS(amount)), 0) as total
-		FROM transactions 
+		SELECT category, currency, COALESCE(SUM(ABS(amount)), 0) as total
+		FROM transaction_lines 
 		WHERE account_id = $1 
-		GROUP BY category
+		  AND transfer_id IS NULL
+		  AND category_type(account_id, category) IS DISTINCT FROM 'transfer'
+		GROUP BY category, currency
 		ORDER BY total DESC`
 
 	rows, err := r.db.QueryContext(ctx, query, accountID)
@@ -87,15 +125,18 @@ func (r *postgresRepo) GetCategoryTotals(ctx context.Context, accountID string)
 	}
 	defer rows.Close()
 
-	totals := make(map[string]float64)
+	totals := make(map[string]types.Amounts)
 	for rows.Next() {
-		var category string
-		var total float64
-		if err := rows.Scan(&category, &total); err != nil {
+		var category, currency string
+		var total types.Money
+		if err := rows.Scan(&category, &currency, &total); err != nil {
 			log.Printf("Error scanning category total: %v", err)
 			return nil, fmt.Errorf("failed to scan category total: %w", err)
 		}
-		totals[category] = total
+		if totals[category] == nil {
+			totals[category] = make(types.Amounts)
+		}
+		totals[category].Add(currency, total)
 	}
 
 	if err = rows.Err(); err != nil {
@@ -105,4 +146,235 @@ func (r *postgresRepo) GetCategoryTotals(ctx context.Context, accountID string)
 
 	log.Printf("Found totals for %d categories", len(totals))
 	return totals, nil
-} 
\ No newline at end of file
+}
+
+// GetTagTotals retrieves total spending by tag. A transaction with several
+// tags counts towards each of them.
+func (r *postgresRepo) GetTagTotals(ctx context.Context, accountID string) (map[string]types.Amounts, error) {
+	if accountID == "" {
+		return nil, fmt.Errorf("account ID is required")
+	}
+
+	log.Printf("Fetching tag totals for account %s", accountID)
+
+	query := `
+		SELECT g.name, t.currency, COALESCE(SUM(ABS(t.amount)), 0) as total
+		FROM transaction_lines t
+		JOIN transaction_tags tt ON tt.transaction_id = t.transaction_id
+		JOIN tags g ON g.id = tt.tag_id
+		WHERE t.account_id = $1
+		  AND t.transfer_id IS NULL
+		  AND category_type(t.account_id, t.category) IN ('expense', 'bill')
+		GROUP BY g.name, t.currency
+		ORDER BY total DESC`
+
+	rows, err := r.db.QueryContext(ctx, query, accountID)
+	if err != nil {
+		log.Printf("Error querying tag totals: %v", err)
+		return nil, fmt.Errorf("failed to query tag totals: %w", err)
+	}
+	defer rows.Close()
+
+	totals := make(map[string]types.Amounts)
+	for rows.Next() {
+		var tag, currency string
+		var total types.Money
+		if err := rows.Scan(&tag, &currency, &total); err != nil {
+			log.Printf("Error scanning tag total: %v", err)
+			return nil, fmt.Errorf("failed to scan tag total: %w", err)
+		}
+		if totals[tag] == nil {
+			totals[tag] = make(types.Amounts)
+		}
+		totals[tag].Add(currency, total)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Printf("Error iterating tag totals: %v", err)
+		return nil, fmt.Errorf("error iterating tag totals: %w", err)
+	}
+
+	log.Printf("Found totals for %d tags", len(totals))
+	return totals, nil
+}
+
+// definitionColumns lists the columns of a category c
+const definitionColumns = `
+	c.id, COALESCE(c.account_id, ''), c.name, c.parent_id, c.type, c.account_id IS NULL,
+	c.created_at, c.updated_at`
+
+// ListDefinitions retrieves the built-in categories and the account's own,
+// built-in ones first
+func (r *postgresRepo) ListDefinitions(ctx context.Context, accountID string) ([]types.CategoryDefinition, error) {
+	query := `SELECT` + definitionColumns + `
+		FROM categories c
+		WHERE c.account_id IS NULL OR c.account_id = $1
+		ORDER BY c.account_id NULLS FIRST, c.name`
+
+	rows, err := r.db.QueryContext(ctx, query, accountID)
+	if err != nil {
+		log.Printf("Error querying category definitions: %v", err)
+		return nil, fmt.Errorf("failed to query category definitions: %w", err)
+	}
+	defer rows.Close()
+
+	categories := []types.CategoryDefinition{}
+	for rows.Next() {
+		category, err := scanDefinition(rows)
+		if err != nil {
+			log.Printf("Error scanning category definition: %v", err)
+			return nil, fmt.Errorf("failed to scan category definition: %w", err)
+		}
+		categories = append(categories, *category)
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("Error iterating category definitions: %v", err)
+		return nil, fmt.Errorf("error iterating category definitions: %w", err)
+	}
+	return categories, nil
+}
+
+// GetDefinition retrieves a built-in category or one of the account's
+func (r *postgresRepo) GetDefinition(ctx context.Context, accountID string, categoryID int64) (*types.CategoryDefinition, error) {
+	query := `SELECT` + definitionColumns + `
+		FROM categories c
+		WHERE c.id = $2 AND (c.account_id IS NULL OR c.account_id = $1)`
+
+	category, err := scanDefinition(r.db.QueryRowContext(ctx, query, accountID, categoryID))
+	if err == sql.ErrNoRows {
+		return nil, ErrCategoryNotFound
+	}
+	if err != nil {
+		log.Printf("Error fetching category %d: %v", categoryID, err)
+		return nil, fmt.Errorf("failed to fetch category: %w", err)
+	}
+	return category, nil
+}
+
+// CreateDefinition inserts a category of the account, setting its ID and
+// timestamps
+func (r *postgresRepo) CreateDefinition(ctx context.Context, category *types.CategoryDefinition) error {
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO categories (account_id, name, parent_id, type)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at, updated_at`,
+		category.AccountID, category.Name, category.ParentID, category.Type,
+	).Scan(&category.ID, &category.CreatedAt, &category.UpdatedAt)
+	if err != nil {
+		return translateError(err)
+	}
+	return nil
+}
+
+// renames lists the columns that refer to a category of account $1 by name
+var renames = []string{
+	`UPDATE transactions SET category = $3 WHERE account_id = $1 AND category = $2`,
+	`UPDATE transaction_splits s SET category = $3
+		FROM transactions t
+		WHERE t.transaction_id = s.transaction_id AND t.account_id = $1 AND s.category = $2`,
+	`UPDATE budgets SET category = $3 WHERE account_id = $1 AND category = $2`,
+	`UPDATE rules SET set_category = $3 WHERE account_id = $1 AND set_category = $2`,
+	`UPDATE merchants SET default_category = $3 WHERE account_id = $1 AND default_category = $2`,
+}
+
+// UpdateDefinition replaces the name, parent and type of one of the
+// account's categories in a single database transaction. A new name is
+// carried over to the transactions, budgets, rules and merchants that use
+// the old one.
+func (r *postgresRepo) UpdateDefinition(ctx context.Context, category *types.CategoryDefinition, oldName string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE categories SET name = $3, parent_id = $4, type = $5, updated_at = NOW()
+		WHERE account_id = $1 AND id = $2`,
+		category.AccountID, category.ID, category.Name, category.ParentID, category.Type,
+	)
+	if err != nil {
+		return translateError(err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrCategoryNotFound
+	}
+
+	if category.Name != oldName {
+		for _, query := range renames {
+			if _, err := tx.ExecContext(ctx, query, category.AccountID, oldName, category.Name); err != nil {
+				log.Printf("Error renaming category %q: %v", oldName, err)
+				return fmt.Errorf("failed to rename category: %w", err)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit category: %w", err)
+	}
+	return nil
+}
+
+// DeleteDefinition removes one of the account's categories
+func (r *postgresRepo) DeleteDefinition(ctx context.Context, accountID string, categoryID int64) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM categories WHERE account_id = $1 AND id = $2`, accountID, categoryID)
+	if err != nil {
+		return translateError(err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrCategoryNotFound
+	}
+	return nil
+}
+
+// CountUses counts the transactions, budgets, rules, merchants and
+// subcategories that use one of the account's categories
+func (r *postgresRepo) CountUses(ctx context.Context, accountID string, category *types.CategoryDefinition) (int, error) {
+	query := `
+		SELECT
+			(SELECT COUNT(*) FROM transactions WHERE account_id = $1 AND category = $2) +
+			(SELECT COUNT(*) FROM transaction_splits s
+				JOIN transactions t ON t.transaction_id = s.transaction_id
+				WHERE t.account_id = $1 AND s.category = $2) +
+			(SELECT COUNT(*) FROM budgets WHERE account_id = $1 AND category = $2) +
+			(SELECT COUNT(*) FROM rules WHERE account_id = $1 AND set_category = $2) +
+			(SELECT COUNT(*) FROM merchants WHERE account_id = $1 AND default_category = $2) +
+			(SELECT COUNT(*) FROM categories WHERE parent_id = $3)`
+
+	var uses int
+	if err := r.db.QueryRowContext(ctx, query, accountID, category.Name, category.ID).Scan(&uses); err != nil {
+		log.Printf("Error counting uses of category %d: %v", category.ID, err)
+		return 0, fmt.Errorf("failed to count category uses: %w", err)
+	}
+	return uses, nil
+}
+
+// translateError maps Postgres constraint violations onto the repository errors
+func translateError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code.Name() {
+		case "unique_violation":
+			return ErrCategoryExists
+		case "foreign_key_violation":
+			if pqErr.Constraint == "categories_parent_id_fkey" {
+				return ErrCategoryInUse
+			}
+			return ErrAccountNotFound
+		}
+	}
+	log.Printf("Error writing category: %v", err)
+	return fmt.Errorf("failed to write category: %w", err)
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanDefinition(row rowScanner) (*types.CategoryDefinition, error) {
+	var c types.CategoryDefinition
+	if err := row.Scan(&c.ID, &c.AccountID, &c.Name, &c.ParentID, &c.Type, &c.BuiltIn, &c.CreatedAt, &c.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
@@ -2,14 +2,64 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"server/types"
 )
 
+var (
+	// ErrAccountNotFound is returned when the account does not exist
+	ErrAccountNotFound = errors.New("account not found")
+
+	// ErrCategoryNotFound is returned when the category is neither built in
+	// nor one of the account's
+	ErrCategoryNotFound = errors.New("category not found")
+
+	// ErrCategoryExists is returned when the account or the built-in
+	// categories already have a category with the name
+	ErrCategoryExists = errors.New("a category with this name already exists")
+
+	// ErrCategoryInUse is returned when a category cannot be deleted or
+	// change type because transactions, budgets, rules, merchants or
+	// subcategories use it
+	ErrCategoryInUse = errors.New("category is in use")
+
+	// ErrBuiltInCategory is returned when a built-in category would be changed
+	ErrBuiltInCategory = errors.New("built-in categories cannot be changed")
+)
+
 // Repository defines the interface for category-related data operations
 type Repository interface {
-	// GetCategories retrieves all categories for an account
+	// GetAccountCurrency retrieves the currency of the account's balance
+	GetAccountCurrency(ctx context.Context, accountID string) (string, error)
+
+	// GetCategories retrieves all categories for an account with their
+	// spending per original currency in Original
 	GetCategories(ctx context.Context, accountID string) ([]types.Category, error)
 
-	// GetCategoryTotals retrieves total spending by category
-	GetCategoryTotals(ctx context.Context, accountID string) (map[string]float64, error)
+	// GetCategoryTotals retrieves total spending by category, per original currency
+	GetCategoryTotals(ctx context.Context, accountID string) (map[string]types.Amounts, error)
+
+	// GetTagTotals retrieves total spending by tag, per original currency
+	GetTagTotals(ctx context.Context, accountID string) (map[string]types.Amounts, error)
+
+	// ListDefinitions retrieves the built-in categories and the account's own
+	ListDefinitions(ctx context.Context, accountID string) ([]types.CategoryDefinition, error)
+
+	// GetDefinition retrieves a built-in category or one of the account's
+	GetDefinition(ctx context.Context, accountID string, categoryID int64) (*types.CategoryDefinition, error)
+
+	// CreateDefinition inserts a category of the account, setting its ID and timestamps
+	CreateDefinition(ctx context.Context, category *types.CategoryDefinition) error
+
+	// UpdateDefinition replaces the name, parent and type of one of the
+	// account's categories. A new name is carried over to the transactions,
+	// budgets, rules and merchants that use the old one.
+	UpdateDefinition(ctx context.Context, category *types.CategoryDefinition, oldName string) error
+
+	// DeleteDefinition removes one of the account's categories
+	DeleteDefinition(ctx context.Context, accountID string, categoryID int64) error
+
+	// CountUses counts the transactions, budgets, rules, merchants and
+	// subcategories that use one of the account's categories
+	CountUses(ctx context.Context, accountID string, category *types.CategoryDefinition) (int, error)
 } 
\ No newline at end of file
@@ -2,27 +2,282 @@ package service
 
 import (
 	"context"
+	"fmt"
 	"server/categories/repository"
+	"server/fx/rates"
 	"server/types"
+	"sort"
+	"time"
 )
 
 type Service interface {
-	GetCategories(ctx context.Context, accountID string) ([]types.Category, error)
-	GetCategoryTotals(ctx context.Context, accountID string) (map[string]float64, error)
+	GetCategories(ctx context.Context, accountID string, currency string) ([]types.Category, error)
+	GetCategoryTotals(ctx context.Context, accountID string, currency string) (map[string]types.Money, error)
+	GetTagTotals(ctx context.Context, accountID string, currency string) (map[string]types.Money, error)
+	ListDefinitions(ctx context.Context, accountID string) ([]types.CategoryDefinition, error)
+	GetDefinition(ctx context.Context, accountID string, categoryID int64) (*types.CategoryDefinition, error)
+	CreateDefinition(ctx context.Context, accountID string, input types.CategoryDefinition) (*types.CategoryDefinition, error)
+	UpdateDefinition(ctx context.Context, accountID string, categoryID int64, input types.CategoryDefinition) (*types.CategoryDefinition, error)
+	DeleteDefinition(ctx context.Context, accountID string, categoryID int64) error
+	Categories(ctx context.Context, accountID string) (types.Categories, error)
 }
 
 type service struct {
-	repo repository.Repository
+	repo  repository.Repository
+	rates rates.Source
 }
 
-func NewService(repo repository.Repository) Service {
-	return &service{repo: repo}
+func NewService(repo repository.Repository, source rates.Source) Service {
+	return &service{repo: repo, rates: source}
 }
 
-func (s *service) GetCategories(ctx context.Context, accountID string) ([]types.Category, error) {
-	return s.repo.GetCategories(ctx, accountID)
+// GetCategories returns the account's categories with their spending
+// converted into the reporting currency at the latest known rates, and the
+// type and parent of each
+func (s *service) GetCategories(ctx context.Context, accountID string, currency string) ([]types.Category, error) {
+	currency, table, err := s.conversion(ctx, accountID, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	categories, err := s.repo.GetCategories(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	definitions, err := s.repo.ListDefinitions(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	byName, byID := index(definitions)
+
+	now := time.Now()
+	for i := range categories {
+		c := &categories[i]
+		if c.TotalSpent, err = table.ConvertAll(c.Original, currency, now); err != nil {
+			return nil, err
+		}
+		c.Currency = currency
+
+		if definition, ok := byName[c.Name]; ok {
+			c.Type = definition.Type
+			if definition.ParentID != nil {
+				c.Parent = byID[*definition.ParentID].Name
+			}
+		}
+	}
+
+	sort.SliceStable(categories, func(i, j int) bool {
+		return categories[i].TotalSpent.Cmp(categories[j].TotalSpent) > 0
+	})
+	return categories, nil
+}
+
+// GetCategoryTotals returns the total spent per category in the reporting
+// currency at the latest known rates
+func (s *service) GetCategoryTotals(ctx context.Context, accountID string, currency string) (map[string]types.Money, error) {
+	currency, table, err := s.conversion(ctx, accountID, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	amounts, err := s.repo.GetCategoryTotals(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	return convertTotals(amounts, table, currency)
+}
+
+// GetTagTotals returns the total spent per tag in the reporting currency at
+// the latest known rates. Income and transfers are left out.
+func (s *service) GetTagTotals(ctx context.Context, accountID string, currency string) (map[string]types.Money, error) {
+	currency, table, err := s.conversion(ctx, accountID, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	amounts, err := s.repo.GetTagTotals(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	return convertTotals(amounts, table, currency)
+}
+
+// convertTotals converts each total into the currency at the latest rates
+func convertTotals(amounts map[string]types.Amounts, table *rates.Table, currency string) (map[string]types.Money, error) {
+	now := time.Now()
+	totals := make(map[string]types.Money, len(amounts))
+	for key, original := range amounts {
+		total, err := table.ConvertAll(original, currency, now)
+		if err != nil {
+			return nil, err
+		}
+		totals[key] = total
+	}
+	return totals, nil
+}
+
+// conversion resolves the reporting currency and loads the rates to convert with
+func (s *service) conversion(ctx context.Context, accountID string, requested string) (string, *rates.Table, error) {
+	accountCurrency, err := s.repo.GetAccountCurrency(ctx, accountID)
+	if err != nil {
+		return "", nil, err
+	}
+	currency, err := rates.ReportingCurrency(requested, accountCurrency)
+	if err != nil {
+		return "", nil, err
+	}
+	table, err := s.rates.Table(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+	return currency, table, nil
+}
+
+// ListDefinitions returns the built-in categories and the account's own
+func (s *service) ListDefinitions(ctx context.Context, accountID string) ([]types.CategoryDefinition, error) {
+	return s.repo.ListDefinitions(ctx, accountID)
+}
+
+func (s *service) GetDefinition(ctx context.Context, accountID string, categoryID int64) (*types.CategoryDefinition, error) {
+	return s.repo.GetDefinition(ctx, accountID, categoryID)
+}
+
+// CreateDefinition adds a category of the account's own. A subcategory
+// takes the type of its parent when it names none.
+func (s *service) CreateDefinition(ctx context.Context, accountID string, input types.CategoryDefinition) (*types.CategoryDefinition, error) {
+	input.ID = 0
+	input.AccountID = accountID
+	if err := s.check(ctx, &input); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.CreateDefinition(ctx, &input); err != nil {
+		return nil, err
+	}
+	return s.repo.GetDefinition(ctx, accountID, input.ID)
+}
+
+// UpdateDefinition replaces the name, parent and type of one of the
+// account's categories. Renaming a category renames it wherever it is used;
+// its type can only change while nothing uses it, as its transactions were
+// checked against the old one.
+func (s *service) UpdateDefinition(ctx context.Context, accountID string, categoryID int64, input types.CategoryDefinition) (*types.CategoryDefinition, error) {
+	existing, err := s.repo.GetDefinition(ctx, accountID, categoryID)
+	if err != nil {
+		return nil, err
+	}
+	if existing.BuiltIn {
+		return nil, repository.ErrBuiltInCategory
+	}
+
+	input.ID = categoryID
+	input.AccountID = accountID
+	if err := s.check(ctx, &input); err != nil {
+		return nil, err
+	}
+
+	if input.Type != existing.Type {
+		uses, err := s.repo.CountUses(ctx, accountID, existing)
+		if err != nil {
+			return nil, err
+		}
+		if uses > 0 {
+			return nil, fmt.Errorf("cannot change the type of %q: %w", existing.Name, repository.ErrCategoryInUse)
+		}
+	}
+
+	if err := s.repo.UpdateDefinition(ctx, &input, existing.Name); err != nil {
+		return nil, err
+	}
+	return s.repo.GetDefinition(ctx, accountID, categoryID)
+}
+
+// DeleteDefinition removes one of the account's categories that nothing uses
+func (s *service) DeleteDefinition(ctx context.Context, accountID string, categoryID int64) error {
+	existing, err := s.repo.GetDefinition(ctx, accountID, categoryID)
+	if err != nil {
+		return err
+	}
+	if existing.BuiltIn {
+		return repository.ErrBuiltInCategory
+	}
+
+	uses, err := s.repo.CountUses(ctx, accountID, existing)
+	if err != nil {
+		return err
+	}
+	if uses > 0 {
+		return fmt.Errorf("cannot delete %q: %w", existing.Name, repository.ErrCategoryInUse)
+	}
+	return s.repo.DeleteDefinition(ctx, accountID, categoryID)
+}
+
+// Categories returns the categories the account can file transactions
+// under, by name
+func (s *service) Categories(ctx context.Context, accountID string) (types.Categories, error) {
+	definitions, err := s.repo.ListDefinitions(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	categories, _ := index(definitions)
+	return categories, nil
+}
+
+// check validates a category against the account's other categories: its
+// name must be free, its parent must exist without making it its own
+// ancestor, and a subcategory has its parent's type
+func (s *service) check(ctx context.Context, input *types.CategoryDefinition) error {
+	if err := input.Validate(); err != nil {
+		return err
+	}
+
+	definitions, err := s.repo.ListDefinitions(ctx, input.AccountID)
+	if err != nil {
+		return err
+	}
+	byName, byID := index(definitions)
+
+	if other, ok := byName[input.Name]; ok && other.ID != input.ID {
+		return repository.ErrCategoryExists
+	}
+	if input.ParentID == nil {
+		return nil
+	}
+
+	parent, ok := byID[*input.ParentID]
+	if !ok {
+		return &types.ValidationError{Field: "parent_id", Message: "unknown category"}
+	}
+	for ancestor, ok := parent, true; ok; ancestor, ok = byID[derefID(ancestor.ParentID)] {
+		if ancestor.ID == input.ID {
+			return &types.ValidationError{Field: "parent_id", Message: "a category cannot be its own ancestor"}
+		}
+	}
+
+	switch input.Type {
+	case "":
+		input.Type = parent.Type
+	case parent.Type:
+	default:
+		return &types.ValidationError{Field: "type", Message: fmt.Sprintf("must match the parent's type %q", parent.Type)}
+	}
+	return nil
 }
 
-func (s *service) GetCategoryTotals(ctx context.Context, accountID string) (map[string]float64, error) {
-	return s.repo.GetCategoryTotals(ctx, accountID)
-} 
\ No newline at end of file
+// index maps category definitions by name and by ID
+func index(definitions []types.CategoryDefinition) (types.Categories, map[int64]types.CategoryDefinition) {
+	byName := make(types.Categories, len(definitions))
+	byID := make(map[int64]types.CategoryDefinition, len(definitions))
+	for _, d := range definitions {
+		byName[d.Name] = d
+		byID[d.ID] = d
+	}
+	return byName, byID
+}
+
+func derefID(id *int64) int64 {
+	if id == nil {
+		return 0
+	}
+	return *id
+}
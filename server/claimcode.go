@@ -0,0 +1,30 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	authRepository "server/auth/repository"
+	authService "server/auth/service"
+)
+
+const claimCodeUsage = "usage: server claim-code <account_id>"
+
+// runClaimCode implements the "claim-code" subcommand, which issues the code
+// an account holder links the account to their login with. Codes are only
+// ever issued here, never through the API.
+func runClaimCode(db *sql.DB, args []string) error {
+	if len(args) != 1 {
+		return errors.New(claimCodeUsage)
+	}
+
+	// The code is hashed like a password and needs no token secret
+	auth := authService.NewService(authRepository.NewPostgresRepository(db), authService.Config{Secret: []byte("unused")})
+	code, err := auth.IssueClaimCode(context.Background(), args[0])
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Claim code for account %s: %s\n", args[0], code)
+	return nil
+}
@@ -2,72 +2,17 @@ package crud
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"server/types"
+	"time"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
-// CreateTables initializes the database schema
-func CreateTables(db *sql.DB) error {
-	// Drop existing tables
-	dropTables := `
-		DROP TABLE IF EXISTS transactions;
-		DROP TABLE IF EXISTS bank_details;
-		DROP TABLE IF EXISTS users;`
-	
-	if _, err := db.Exec(dropTables); err != nil {
-		return fmt.Errorf("failed to drop tables: %w", err)
-	}
-
-	// Create users table
-	createUsers := `
-		CREATE TABLE users (
-			account_id VARCHAR(20) PRIMARY KEY,
-			account_name VARCHAR(50),
-			account_type VARCHAR(20),
-			account_number VARCHAR(20),
-			balance_current DECIMAL(10, 2),
-			balance_available DECIMAL(10, 2),
-			balance_currency VARCHAR(3),
-			owner_name VARCHAR(50)
-		)`
-	
-	if _, err := db.Exec(createUsers); err != nil {
-		return fmt.Errorf("failed to create users table: %w", err)
-	}
-
-	// Create bank_details table
-	createBankDetails := `
-		CREATE TABLE bank_details (
-			account_id VARCHAR(20) PRIMARY KEY REFERENCES users(account_id),
-			bank_name VARCHAR(50),
-			routing_number VARCHAR(20),
-			branch VARCHAR(100)
-		)`
-	
-	if _, err := db.Exec(createBankDetails); err != nil {
-		return fmt.Errorf("failed to create bank_details table: %w", err)
-	}
-
-	// Create transactions table
-	createTransactions := `
-		CREATE TABLE transactions (
-			transaction_id VARCHAR(20) PRIMARY KEY,
-			account_id VARCHAR(20) REFERENCES users(account_id),
-			date TIMESTAMP,
-			amount DECIMAL(10, 2),
-			category VARCHAR(50),
-			merchant VARCHAR(50),
-			location VARCHAR(100)
-		)`
-	
-	if _, err := db.Exec(createTransactions); err != nil {
-		return fmt.Errorf("failed to create transactions table: %w", err)
-	}
-
-	return nil
-}
+// ErrDuplicateTransaction is returned when a transaction with the same bank
+// FITID has already been imported for the account
+var ErrDuplicateTransaction = errors.New("transaction already imported")
 
 // createUserTx creates a user within a transaction
 func createUserTx(tx *sql.Tx, user *types.User) error {
@@ -105,14 +50,21 @@ func createUserTx(tx *sql.Tx, user *types.User) error {
 	return err
 }
 
-// insertTransactionTx inserts a transaction within a transaction
+// insertTransactionTx inserts a transaction within a transaction. A
+// transaction whose bank FITID was already imported for the account is
+// skipped and reported as ErrDuplicateTransaction.
 func insertTransactionTx(tx *sql.Tx, transaction *types.Transaction) error {
 	query := `
 		INSERT INTO transactions (
-			transaction_id, account_id, date, amount, category, merchant, location
-		) VALUES ($1, $2, $3, $4, $5, $6, $7)`
+			transaction_id, account_id, date, amount, category, merchant, location, fitid, currency, merchant_id
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''), COALESCE(
+			NULLIF($9, ''),
+			(SELECT NULLIF(balance_currency, '') FROM users WHERE account_id = $2),
+			'USD'
+		), $10)
+		ON CONFLICT (account_id, fitid) DO NOTHING`
 	
-	_, err := tx.Exec(query,
+	res, err := tx.Exec(query,
 		transaction.TransactionID,
 		transaction.AccountID,
 		transaction.Date,
@@ -120,14 +72,95 @@ func insertTransactionTx(tx *sql.Tx, transaction *types.Transaction) error {
 		transaction.Category,
 		transaction.Merchant,
 		transaction.Location,
+		transaction.FITID,
+		transaction.Currency,
+		transaction.MerchantID,
 	)
+	if err != nil {
+		return err
+	}
+
+	inserted, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if inserted == 0 {
+		return ErrDuplicateTransaction
+	}
+	return InsertTransactionTagsTx(tx, transaction.AccountID, transaction.TransactionID, transaction.Tags)
+}
+
+// InsertTransactionTagsTx links tags to a transaction within a transaction,
+// creating the account's tags that do not exist yet. Tags the transaction
+// already has are left as they are.
+func InsertTransactionTagsTx(tx *sql.Tx, accountID string, transactionID string, tags []string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+
+	_, err := tx.Exec(`
+		INSERT INTO tags (account_id, name)
+		SELECT $1, unnest($2::text[])
+		ON CONFLICT (account_id, name) DO NOTHING`,
+		accountID, pq.Array(tags))
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO transaction_tags (transaction_id, tag_id)
+		SELECT $1, id FROM tags WHERE account_id = $2 AND name = ANY($3)
+		ON CONFLICT DO NOTHING`,
+		transactionID, accountID, pq.Array(tags))
+	return err
+}
+
+// UpdateBalanceTx sets the account balance from an imported statement within
+// a transaction. Nil balances are left unchanged, and a statement older than
+// the last one applied does not overwrite the newer balance.
+func UpdateBalanceTx(tx *sql.Tx, accountID string, current, available *types.Money, asOf time.Time) error {
+	query := `
+		UPDATE users SET
+			balance_current = COALESCE($2, balance_current),
+			balance_available = COALESCE($3, balance_available),
+			balance_as_of = $4
+		WHERE account_id = $1
+		  AND (balance_as_of IS NULL OR balance_as_of <= $4)`
+
+	_, err := tx.Exec(query, accountID, current, available, asOf)
 	return err
 }
 
+// InsertTransactionsTx inserts a batch of transactions within a transaction.
+// Each row runs under its own savepoint so that a failing row is rolled back
+// on its own without aborting the batch; the returned slice holds one error
+// (or nil) per transaction.
+func InsertTransactionsTx(tx *sql.Tx, transactions []types.Transaction) ([]error, error) {
+	rowErrors := make([]error, len(transactions))
+	for i := range transactions {
+		if _, err := tx.Exec("SAVEPOINT insert_row"); err != nil {
+			return nil, fmt.Errorf("failed to create savepoint: %w", err)
+		}
+
+		if err := insertTransactionTx(tx, &transactions[i]); err != nil {
+			rowErrors[i] = err
+			if _, err := tx.Exec("ROLLBACK TO SAVEPOINT insert_row"); err != nil {
+				return nil, fmt.Errorf("failed to roll back to savepoint: %w", err)
+			}
+			continue
+		}
+
+		if _, err := tx.Exec("RELEASE SAVEPOINT insert_row"); err != nil {
+			return nil, fmt.Errorf("failed to release savepoint: %w", err)
+		}
+	}
+	return rowErrors, nil
+}
+
 // GetTransactions retrieves all transactions for a given account
 func GetTransactions(db *sql.DB, accountID string) ([]types.Transaction, error) {
 	query := `
-		SELECT transaction_id, account_id, date, amount, category, merchant, location
+		SELECT transaction_id, account_id, date, amount, category, merchant, location, currency
 		FROM transactions
 		WHERE account_id = $1
 		ORDER BY date DESC`
@@ -149,6 +182,7 @@ func GetTransactions(db *sql.DB, accountID string) ([]types.Transaction, error)
 			&t.Category,
 			&t.Merchant,
 			&t.Location,
+			&t.Currency,
 		); err != nil {
 			return nil, fmt.Errorf("failed to scan transaction: %w", err)
 		}
@@ -219,6 +253,8 @@ func GetUser(db *sql.DB, accountID string) (*types.User, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
+	user.Balance.Current.Currency = user.Balance.Currency
+	user.Balance.Available.Currency = user.Balance.Currency
 
 	return user, nil
 } 
\ No newline at end of file
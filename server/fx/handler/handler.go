@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"server/fx/repository"
+	"server/fx/service"
+	"server/types"
+
+	"github.com/gorilla/mux"
+)
+
+type Handler struct {
+	service service.Service
+}
+
+func NewHandler(service service.Service) *Handler {
+	return &Handler{service: service}
+}
+
+// SetupFXRoutes configures all the exchange-rate routes. The rates are shared
+// by every login, so the API only reads them; they are loaded on startup or
+// with the "server fx-rates" command.
+func SetupFXRoutes(router *mux.Router, db *sql.DB) {
+	repo := repository.NewPostgresRepository(db)
+	svc := service.NewService(repo)
+	handler := NewHandler(svc)
+	handler.RegisterRoutes(router)
+}
+
+// RegisterRoutes registers all exchange-rate routes
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/api/fx/rates", h.HandleListRates).Methods("GET")
+}
+
+// HandleListRates handles requests for the stored exchange rates, optionally
+// filtered with the "base" and "quote" query parameters
+func (h *Handler) HandleListRates(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	list, err := h.service.ListRates(r.Context(), query.Get("base"), query.Get("quote"))
+	if err != nil {
+		writeError(w, "Failed to get exchange rates", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+// writeError converts service errors into HTTP responses
+func writeError(w http.ResponseWriter, message string, err error) {
+	var validationErr *types.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		http.Error(w, validationErr.Error(), http.StatusBadRequest)
+	default:
+		log.Printf("%s: %v", message, err)
+		http.Error(w, message, http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,176 @@
+package rates
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"server/types"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// snapshot is the JSON shape used by common FX APIs such as Frankfurter and
+// exchangerate.host: the value of one unit of base in each quoted currency
+type snapshot struct {
+	Base  string             `json:"base"`
+	Date  string             `json:"date"`
+	Rates map[string]float64 `json:"rates"`
+}
+
+// Parse reads exchange rates from a file. JSON input is either a single
+// snapshot ({"base": "USD", "date": "2025-01-14", "rates": {"EUR": 0.97}})
+// or an array of them; anything else is read as CSV with a
+// date,base,quote,rate header.
+func Parse(r io.Reader, source string) ([]types.ExchangeRate, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rates: %w", err)
+	}
+
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 {
+		return nil, fmt.Errorf("rates file is empty")
+	}
+	switch trimmed[0] {
+	case '{':
+		var s snapshot
+		if err := json.Unmarshal(trimmed, &s); err != nil {
+			return nil, fmt.Errorf("invalid rates JSON: %w", err)
+		}
+		return s.exchangeRates(source)
+	case '[':
+		var list []snapshot
+		if err := json.Unmarshal(trimmed, &list); err != nil {
+			return nil, fmt.Errorf("invalid rates JSON: %w", err)
+		}
+		var all []types.ExchangeRate
+		for _, s := range list {
+			rates, err := s.exchangeRates(source)
+			if err != nil {
+				return nil, err
+			}
+			all = append(all, rates...)
+		}
+		return all, nil
+	default:
+		return parseCSV(trimmed, source)
+	}
+}
+
+// Fetch downloads the latest snapshot from an FX API returning the
+// {"base", "date", "rates"} JSON shape
+func Fetch(ctx context.Context, client *http.Client, url string) ([]types.ExchangeRate, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid rates URL: %w", err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch rates: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch rates: %s", resp.Status)
+	}
+
+	source := req.URL.Host
+	return Parse(io.LimitReader(resp.Body, 1<<20), source)
+}
+
+func (s snapshot) exchangeRates(source string) ([]types.ExchangeRate, error) {
+	base := strings.ToUpper(strings.TrimSpace(s.Base))
+	if !types.IsCurrencyCode(base) {
+		return nil, fmt.Errorf("invalid base currency %q", s.Base)
+	}
+	date, err := parseDate(s.Date)
+	if err != nil {
+		return nil, err
+	}
+
+	rates := make([]types.ExchangeRate, 0, len(s.Rates))
+	for quote, rate := range s.Rates {
+		r, err := newRate(base, quote, date, rate, source)
+		if err != nil {
+			return nil, err
+		}
+		rates = append(rates, r)
+	}
+	return rates, nil
+}
+
+func parseCSV(data []byte, source string) ([]types.ExchangeRate, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.TrimLeadingSpace = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid rates CSV: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("rates file is empty")
+	}
+
+	cols := map[string]int{"date": -1, "base": -1, "quote": -1, "rate": -1}
+	for i, name := range records[0] {
+		if _, ok := cols[strings.ToLower(strings.TrimSpace(name))]; ok {
+			cols[strings.ToLower(strings.TrimSpace(name))] = i
+		}
+	}
+	for name, idx := range cols {
+		if idx < 0 {
+			return nil, fmt.Errorf("rates CSV is missing the %q column", name)
+		}
+	}
+
+	var rates []types.ExchangeRate
+	for n, record := range records[1:] {
+		line := n + 2
+		if len(record) < len(cols) {
+			return nil, fmt.Errorf("line %d: expected date,base,quote,rate", line)
+		}
+		date, err := parseDate(record[cols["date"]])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		value, err := strconv.ParseFloat(strings.TrimSpace(record[cols["rate"]]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid rate %q", line, record[cols["rate"]])
+		}
+		r, err := newRate(record[cols["base"]], record[cols["quote"]], date, value, source)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		rates = append(rates, r)
+	}
+	return rates, nil
+}
+
+func newRate(base, quote string, date time.Time, rate float64, source string) (types.ExchangeRate, error) {
+	base = strings.ToUpper(strings.TrimSpace(base))
+	quote = strings.ToUpper(strings.TrimSpace(quote))
+	if !types.IsCurrencyCode(base) {
+		return types.ExchangeRate{}, fmt.Errorf("invalid base currency %q", base)
+	}
+	if !types.IsCurrencyCode(quote) {
+		return types.ExchangeRate{}, fmt.Errorf("invalid quote currency %q", quote)
+	}
+	if rate <= 0 {
+		return types.ExchangeRate{}, fmt.Errorf("rate for %s/%s must be positive", base, quote)
+	}
+	return types.ExchangeRate{Base: base, Quote: quote, Date: date, Rate: rate, Source: source}, nil
+}
+
+func parseDate(value string) (time.Time, error) {
+	date, err := time.Parse("2006-01-02", strings.TrimSpace(value))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q: expected YYYY-MM-DD", value)
+	}
+	return date, nil
+}
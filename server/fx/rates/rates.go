@@ -0,0 +1,166 @@
+package rates
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"server/types"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrNoRate is returned when no rate connects two currencies
+var ErrNoRate = errors.New("no exchange rate available")
+
+// Source provides the exchange rates used to convert amounts
+type Source interface {
+	// Table returns the currently known exchange rates
+	Table(ctx context.Context) (*Table, error)
+}
+
+type pair struct {
+	base, quote string
+}
+
+// Table answers conversions from a set of dated exchange rates. For each
+// conversion it uses the latest rate dated on or before the day of the
+// amount, falling back to the earliest known rate for older amounts.
+type Table struct {
+	rates      map[pair][]types.ExchangeRate
+	currencies []string
+}
+
+// NewTable indexes the given rates by currency pair
+func NewTable(rates []types.ExchangeRate) *Table {
+	t := &Table{rates: make(map[pair][]types.ExchangeRate)}
+	for _, r := range rates {
+		if r.Rate <= 0 {
+			continue
+		}
+		key := pair{r.Base, r.Quote}
+		t.rates[key] = append(t.rates[key], r)
+	}
+	seen := make(map[string]bool)
+	for key := range t.rates {
+		list := t.rates[key]
+		sort.Slice(list, func(i, j int) bool { return list[i].Date.Before(list[j].Date) })
+		for _, c := range []string{key.base, key.quote} {
+			if !seen[c] {
+				seen[c] = true
+				t.currencies = append(t.currencies, c)
+			}
+		}
+	}
+	sort.Strings(t.currencies)
+	return t
+}
+
+// Rate returns how many units of to one unit of from was worth on the given
+// day. It uses a direct rate, the inverse of the opposite rate, or a cross
+// rate through a third currency, in that order.
+func (t *Table) Rate(from, to string, on time.Time) (float64, error) {
+	if from == to {
+		return 1, nil
+	}
+	if rate, ok := t.direct(from, to, on); ok {
+		return rate, nil
+	}
+
+	for _, via := range t.currencies {
+		if via == from || via == to {
+			continue
+		}
+		first, ok := t.direct(from, via, on)
+		if !ok {
+			continue
+		}
+		if second, ok := t.direct(via, to, on); ok {
+			return first * second, nil
+		}
+	}
+	return 0, fmt.Errorf("%w from %s to %s", ErrNoRate, from, to)
+}
+
+// direct looks up from→to or the inverse of to→from
+func (t *Table) direct(from, to string, on time.Time) (float64, bool) {
+	if r, ok := pick(t.rates[pair{from, to}], on); ok {
+		return r, true
+	}
+	if r, ok := pick(t.rates[pair{to, from}], on); ok {
+		return 1 / r, true
+	}
+	return 0, false
+}
+
+// pick returns the latest rate dated on or before the given day, or the
+// earliest rate when all are later
+func pick(list []types.ExchangeRate, on time.Time) (float64, bool) {
+	if len(list) == 0 {
+		return 0, false
+	}
+	day := time.Date(on.Year(), on.Month(), on.Day(), 0, 0, 0, 0, time.UTC)
+	i := sort.Search(len(list), func(i int) bool { return list[i].Date.After(day) })
+	if i == 0 {
+		return list[0].Rate, true
+	}
+	return list[i-1].Rate, true
+}
+
+// Convert converts an amount in currency from into currency to at the rate
+// for the given day, rounding to the nearest cent
+func (t *Table) Convert(amount types.Money, from, to string, on time.Time) (types.Money, error) {
+	if from == to || from == "" {
+		return types.NewMoney(amount.Cents, to), nil
+	}
+	rate, err := t.Rate(from, to, on)
+	if err != nil {
+		return types.Money{}, err
+	}
+	return types.NewMoney(int64(math.Round(float64(amount.Cents)*rate)), to), nil
+}
+
+// ConvertAll converts and sums amounts held in several currencies
+func (t *Table) ConvertAll(amounts types.Amounts, to string, on time.Time) (types.Money, error) {
+	total := types.NewMoney(0, to)
+	for currency, amount := range amounts {
+		converted, err := t.Convert(amount, currency, to, on)
+		if err != nil {
+			return types.Money{}, err
+		}
+		total = total.Add(converted)
+	}
+	return total, nil
+}
+
+// ConvertTransactions sets ConvertedAmount and ConvertedCurrency on each
+// transaction, using the rate for the transaction's date
+func (t *Table) ConvertTransactions(transactions []types.Transaction, to string) error {
+	for i := range transactions {
+		tx := &transactions[i]
+		converted, err := t.Convert(tx.Amount, tx.Currency, to, tx.Date)
+		if err != nil {
+			return err
+		}
+		tx.ConvertedAmount = &converted
+		tx.ConvertedCurrency = to
+	}
+	return nil
+}
+
+// ReportingCurrency picks the currency amounts are reported in: the one
+// requested by the client, else the account's own currency, else USD
+func ReportingCurrency(requested string, accountCurrency string) (string, error) {
+	if requested != "" {
+		requested = strings.ToUpper(requested)
+		if !types.IsCurrencyCode(requested) {
+			return "", &types.ValidationError{Field: "currency", Message: "must be a three-letter ISO 4217 code"}
+		}
+		return requested, nil
+	}
+	if types.IsCurrencyCode(accountCurrency) {
+		return accountCurrency, nil
+	}
+	return "USD", nil
+}
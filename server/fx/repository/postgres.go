@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"server/types"
+)
+
+type postgresRepo struct {
+	db *sql.DB
+}
+
+func NewPostgresRepository(db *sql.DB) Repository {
+	if db == nil {
+		panic("database connection is required")
+	}
+	return &postgresRepo{db: db}
+}
+
+// ListRates retrieves stored rates, optionally filtered by base and quote currency
+func (r *postgresRepo) ListRates(ctx context.Context, base string, quote string) ([]types.ExchangeRate, error) {
+	query := `
+		SELECT base, quote, date, rate, source
+		FROM fx_rates
+		WHERE ($1 = '' OR base = $1)
+		  AND ($2 = '' OR quote = $2)
+		ORDER BY base, quote, date`
+
+	rows, err := r.db.QueryContext(ctx, query, base, quote)
+	if err != nil {
+		log.Printf("Error querying exchange rates: %v", err)
+		return nil, fmt.Errorf("failed to query exchange rates: %w", err)
+	}
+	defer rows.Close()
+
+	rates := []types.ExchangeRate{}
+	for rows.Next() {
+		var rate types.ExchangeRate
+		if err := rows.Scan(&rate.Base, &rate.Quote, &rate.Date, &rate.Rate, &rate.Source); err != nil {
+			log.Printf("Error scanning exchange rate: %v", err)
+			return nil, fmt.Errorf("failed to scan exchange rate: %w", err)
+		}
+		rates = append(rates, rate)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Printf("Error iterating exchange rates: %v", err)
+		return nil, fmt.Errorf("error iterating exchange rates: %w", err)
+	}
+
+	return rates, nil
+}
+
+// UpsertRates saves rates in a single database transaction, replacing any
+// existing rate for the same pair and date
+func (r *postgresRepo) UpsertRates(ctx context.Context, rates []types.ExchangeRate) error {
+	log.Printf("Saving %d exchange rates", len(rates))
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO fx_rates (base, quote, date, rate, source)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (base, quote, date)
+		DO UPDATE SET rate = EXCLUDED.rate, source = EXCLUDED.source, updated_at = NOW()`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare exchange rate insert: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, rate := range rates {
+		if _, err := stmt.ExecContext(ctx, rate.Base, rate.Quote, rate.Date, rate.Rate, rate.Source); err != nil {
+			log.Printf("Error saving exchange rate %s/%s: %v", rate.Base, rate.Quote, err)
+			return fmt.Errorf("failed to save exchange rate %s/%s: %w", rate.Base, rate.Quote, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit exchange rates: %w", err)
+	}
+	return nil
+}
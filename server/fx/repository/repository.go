@@ -0,0 +1,15 @@
+package repository
+
+import (
+	"context"
+	"server/types"
+)
+
+// Repository defines the interface for exchange-rate data operations
+type Repository interface {
+	// ListRates retrieves stored rates, optionally filtered by base and quote currency
+	ListRates(ctx context.Context, base string, quote string) ([]types.ExchangeRate, error)
+
+	// UpsertRates saves rates, replacing any existing rate for the same pair and date
+	UpsertRates(ctx context.Context, rates []types.ExchangeRate) error
+}
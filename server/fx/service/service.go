@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"server/fx/rates"
+	"server/fx/repository"
+	"server/types"
+	"strings"
+	"time"
+)
+
+// Service manages the local exchange-rate table. It also implements
+// rates.Source for the features that convert amounts.
+type Service interface {
+	rates.Source
+	ListRates(ctx context.Context, base string, quote string) ([]types.ExchangeRate, error)
+	ImportRates(ctx context.Context, data io.Reader, source string) (int, error)
+	LoadFile(ctx context.Context, path string) (int, error)
+	Refresh(ctx context.Context, url string) (int, error)
+}
+
+type service struct {
+	repo   repository.Repository
+	client *http.Client
+}
+
+func NewService(repo repository.Repository) Service {
+	return &service{repo: repo, client: &http.Client{Timeout: 15 * time.Second}}
+}
+
+func (s *service) Table(ctx context.Context) (*rates.Table, error) {
+	list, err := s.repo.ListRates(ctx, "", "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to load exchange rates: %w", err)
+	}
+	return rates.NewTable(list), nil
+}
+
+func (s *service) ListRates(ctx context.Context, base string, quote string) ([]types.ExchangeRate, error) {
+	return s.repo.ListRates(ctx, strings.ToUpper(base), strings.ToUpper(quote))
+}
+
+// ImportRates parses a rates file (JSON snapshot or CSV) and saves its rates
+func (s *service) ImportRates(ctx context.Context, data io.Reader, source string) (int, error) {
+	list, err := rates.Parse(data, source)
+	if err != nil {
+		return 0, &types.ValidationError{Field: "rates", Message: err.Error()}
+	}
+	if err := s.repo.UpsertRates(ctx, list); err != nil {
+		return 0, err
+	}
+	return len(list), nil
+}
+
+// LoadFile imports the rates file at path
+func (s *service) LoadFile(ctx context.Context, path string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open rates file: %w", err)
+	}
+	defer f.Close()
+
+	return s.ImportRates(ctx, f, filepath.Base(path))
+}
+
+// Refresh fetches the latest rates from an FX API and saves them
+func (s *service) Refresh(ctx context.Context, url string) (int, error) {
+	if url == "" {
+		return 0, &types.ValidationError{Field: "url", Message: "no FX rates API is configured"}
+	}
+
+	list, err := rates.Fetch(ctx, s.client, url)
+	if err != nil {
+		return 0, err
+	}
+	if err := s.repo.UpsertRates(ctx, list); err != nil {
+		return 0, err
+	}
+
+	log.Printf("Refreshed %d exchange rates from %s", len(list), url)
+	return len(list), nil
+}
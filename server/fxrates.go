@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	fxRepo "server/fx/repository"
+	fxService "server/fx/service"
+)
+
+const fxRatesUsage = "usage: server fx-rates import <file> | refresh [url]"
+
+// runFXRates implements the "fx-rates" subcommand. The rates are shared by
+// every login, so they are only ever written here and on startup, never
+// through the API.
+func runFXRates(db *sql.DB, args []string) error {
+	if len(args) == 0 {
+		return errors.New(fxRatesUsage)
+	}
+
+	ctx := context.Background()
+	fx := fxService.NewService(fxRepo.NewPostgresRepository(db))
+	switch {
+	case args[0] == "import" && len(args) == 2:
+		n, err := fx.LoadFile(ctx, args[1])
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Imported %d exchange rates from %s\n", n, args[1])
+	case args[0] == "refresh" && len(args) <= 2:
+		url := os.Getenv("FX_RATES_URL")
+		if len(args) == 2 {
+			url = args[1]
+		}
+		n, err := fx.Refresh(ctx, url)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Imported %d exchange rates from %s\n", n, url)
+	default:
+		return errors.New(fxRatesUsage)
+	}
+	return nil
+}
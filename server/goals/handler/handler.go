@@ -0,0 +1,239 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	analyticsRepository "server/analytics/repository"
+	"server/fx/rates"
+	fxRepository "server/fx/repository"
+	fxService "server/fx/service"
+	"server/goals/repository"
+	"server/goals/service"
+	"server/types"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+type Handler struct {
+	service service.Service
+}
+
+func NewHandler(service service.Service) *Handler {
+	return &Handler{service: service}
+}
+
+// SetupGoalRoutes configures all the savings goal routes
+func SetupGoalRoutes(router *mux.Router, db *sql.DB) {
+	repo := repository.NewPostgresRepository(db)
+	analytics := analyticsRepository.NewPostgresRepository(db)
+	fx := fxService.NewService(fxRepository.NewPostgresRepository(db))
+	svc := service.NewService(repo, analytics, fx)
+	handler := NewHandler(svc)
+	handler.RegisterRoutes(router)
+}
+
+// RegisterRoutes registers all goal routes
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/api/goals/{accountId}", h.HandleListGoals).Methods("GET")
+	router.HandleFunc("/api/goals/{accountId}", h.HandleCreateGoal).Methods("POST")
+	router.HandleFunc("/api/goals/{accountId}/progress", h.HandleListProgress).Methods("GET")
+	router.HandleFunc("/api/goals/{accountId}/{goalId:[0-9]+}", h.HandleGetGoal).Methods("GET")
+	router.HandleFunc("/api/goals/{accountId}/{goalId:[0-9]+}", h.HandleUpdateGoal).Methods("PUT")
+	router.HandleFunc("/api/goals/{accountId}/{goalId:[0-9]+}", h.HandleDeleteGoal).Methods("DELETE")
+	router.HandleFunc("/api/goals/{accountId}/{goalId:[0-9]+}/progress", h.HandleGetProgress).Methods("GET")
+	router.HandleFunc("/api/goals/{accountId}/{goalId:[0-9]+}/contributions", h.HandleListContributions).Methods("GET")
+	router.HandleFunc("/api/goals/{accountId}/{goalId:[0-9]+}/contributions", h.HandleCreateContribution).Methods("POST")
+	router.HandleFunc("/api/goals/{accountId}/{goalId:[0-9]+}/contributions/{contributionId:[0-9]+}", h.HandleDeleteContribution).Methods("DELETE")
+}
+
+// HandleListGoals handles requests for an account's goals
+func (h *Handler) HandleListGoals(w http.ResponseWriter, r *http.Request) {
+	accountID := mux.Vars(r)["accountId"]
+
+	goals, err := h.service.ListGoals(r.Context(), accountID)
+	if err != nil {
+		writeError(w, "Failed to get goals", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(goals)
+}
+
+// HandleCreateGoal handles requests to create a goal
+func (h *Handler) HandleCreateGoal(w http.ResponseWriter, r *http.Request) {
+	accountID := mux.Vars(r)["accountId"]
+
+	var input types.Goal
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	goal, err := h.service.CreateGoal(r.Context(), accountID, input)
+	if err != nil {
+		writeError(w, "Failed to create goal", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(goal)
+}
+
+// HandleGetGoal handles requests for a single goal
+func (h *Handler) HandleGetGoal(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	goalID, _ := strconv.ParseInt(vars["goalId"], 10, 64)
+
+	goal, err := h.service.GetGoal(r.Context(), vars["accountId"], goalID)
+	if err != nil {
+		writeError(w, "Failed to get goal", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(goal)
+}
+
+// HandleUpdateGoal handles requests to replace a goal
+func (h *Handler) HandleUpdateGoal(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	goalID, _ := strconv.ParseInt(vars["goalId"], 10, 64)
+
+	var input types.Goal
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	goal, err := h.service.UpdateGoal(r.Context(), vars["accountId"], goalID, input)
+	if err != nil {
+		writeError(w, "Failed to update goal", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(goal)
+}
+
+// HandleDeleteGoal handles requests to delete a goal
+func (h *Handler) HandleDeleteGoal(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	goalID, _ := strconv.ParseInt(vars["goalId"], 10, 64)
+
+	if err := h.service.DeleteGoal(r.Context(), vars["accountId"], goalID); err != nil {
+		writeError(w, "Failed to delete goal", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleGetProgress handles requests for a goal's progress and projection
+func (h *Handler) HandleGetProgress(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	goalID, _ := strconv.ParseInt(vars["goalId"], 10, 64)
+
+	progress, err := h.service.GetProgress(r.Context(), vars["accountId"], goalID)
+	if err != nil {
+		writeError(w, "Failed to get goal progress", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(progress)
+}
+
+// HandleListProgress handles requests for the progress of every goal
+func (h *Handler) HandleListProgress(w http.ResponseWriter, r *http.Request) {
+	accountID := mux.Vars(r)["accountId"]
+
+	progress, err := h.service.ListProgress(r.Context(), accountID)
+	if err != nil {
+		writeError(w, "Failed to get goal progress", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(progress)
+}
+
+// HandleListContributions handles requests for a goal's contributions
+func (h *Handler) HandleListContributions(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	goalID, _ := strconv.ParseInt(vars["goalId"], 10, 64)
+
+	contributions, err := h.service.ListContributions(r.Context(), vars["accountId"], goalID)
+	if err != nil {
+		writeError(w, "Failed to get contributions", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(contributions)
+}
+
+// HandleCreateContribution handles requests to add a contribution to a goal
+func (h *Handler) HandleCreateContribution(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	goalID, _ := strconv.ParseInt(vars["goalId"], 10, 64)
+
+	var input types.GoalContribution
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	contribution, err := h.service.CreateContribution(r.Context(), vars["accountId"], goalID, input)
+	if err != nil {
+		writeError(w, "Failed to create contribution", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(contribution)
+}
+
+// HandleDeleteContribution handles requests to remove a contribution
+func (h *Handler) HandleDeleteContribution(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	goalID, _ := strconv.ParseInt(vars["goalId"], 10, 64)
+	contributionID, _ := strconv.ParseInt(vars["contributionId"], 10, 64)
+
+	if err := h.service.DeleteContribution(r.Context(), vars["accountId"], goalID, contributionID); err != nil {
+		writeError(w, "Failed to delete contribution", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeError converts service and repository errors into HTTP responses
+func writeError(w http.ResponseWriter, message string, err error) {
+	var validationErr *types.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		http.Error(w, validationErr.Error(), http.StatusBadRequest)
+	case errors.Is(err, rates.ErrNoRate):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, repository.ErrAccountNotFound):
+		http.Error(w, "Account not found", http.StatusNotFound)
+	case errors.Is(err, repository.ErrGoalNotFound):
+		http.Error(w, "Goal not found", http.StatusNotFound)
+	case errors.Is(err, repository.ErrContributionNotFound):
+		http.Error(w, "Contribution not found", http.StatusNotFound)
+	case errors.Is(err, repository.ErrTransactionNotFound):
+		http.Error(w, "Transaction not found", http.StatusNotFound)
+	case errors.Is(err, repository.ErrTransactionLinked):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		log.Printf("%s: %v", message, err)
+		http.Error(w, message, http.StatusInternalServerError)
+	}
+}
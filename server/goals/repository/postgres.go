@@ -0,0 +1,261 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"server/types"
+
+	"github.com/lib/pq"
+)
+
+type postgresRepo struct {
+	db *sql.DB
+}
+
+func NewPostgresRepository(db *sql.DB) Repository {
+	if db == nil {
+		panic("database connection is required")
+	}
+	return &postgresRepo{db: db}
+}
+
+// GetAccountCurrency retrieves the currency of the account's balance; it is
+// empty when the account or its balance is unknown
+func (r *postgresRepo) GetAccountCurrency(ctx context.Context, accountID string) (string, error) {
+	var currency string
+	err := r.db.QueryRowContext(ctx,
+		`SELECT COALESCE(balance_currency, '') FROM users WHERE account_id = $1`, accountID,
+	).Scan(&currency)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		log.Printf("Error fetching account currency: %v", err)
+		return "", fmt.Errorf("failed to fetch account currency: %w", err)
+	}
+	return currency, nil
+}
+
+// goalColumns lists the goal columns of g together with its saved total
+const goalColumns = `
+	g.id, g.account_id, g.name, g.target_amount, g.target_date,
+	COALESCE((SELECT SUM(c.amount) FROM goal_contributions c WHERE c.goal_id = g.id), 0),
+	g.created_at, g.updated_at`
+
+// ListGoals retrieves the goals of an account with their saved totals
+func (r *postgresRepo) ListGoals(ctx context.Context, accountID string) ([]types.Goal, error) {
+	query := `SELECT` + goalColumns + ` FROM goals g WHERE g.account_id = $1 ORDER BY g.target_date NULLS LAST, g.id`
+
+	rows, err := r.db.QueryContext(ctx, query, accountID)
+	if err != nil {
+		log.Printf("Error querying goals: %v", err)
+		return nil, fmt.Errorf("failed to query goals: %w", err)
+	}
+	defer rows.Close()
+
+	goals := []types.Goal{}
+	for rows.Next() {
+		goal, err := scanGoal(rows)
+		if err != nil {
+			log.Printf("Error scanning goal: %v", err)
+			return nil, fmt.Errorf("failed to scan goal: %w", err)
+		}
+		goals = append(goals, *goal)
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("Error iterating goals: %v", err)
+		return nil, fmt.Errorf("error iterating goals: %w", err)
+	}
+	return goals, nil
+}
+
+// GetGoal retrieves a single goal of an account with its saved total
+func (r *postgresRepo) GetGoal(ctx context.Context, accountID string, goalID int64) (*types.Goal, error) {
+	query := `SELECT` + goalColumns + ` FROM goals g WHERE g.account_id = $1 AND g.id = $2`
+
+	goal, err := scanGoal(r.db.QueryRowContext(ctx, query, accountID, goalID))
+	if err == sql.ErrNoRows {
+		return nil, ErrGoalNotFound
+	}
+	if err != nil {
+		log.Printf("Error fetching goal %d: %v", goalID, err)
+		return nil, fmt.Errorf("failed to fetch goal: %w", err)
+	}
+	return goal, nil
+}
+
+// CreateGoal inserts a goal, setting its ID and timestamps
+func (r *postgresRepo) CreateGoal(ctx context.Context, goal *types.Goal) error {
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO goals (account_id, name, target_amount, target_date)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at, updated_at`,
+		goal.AccountID, goal.Name, goal.TargetAmount, goal.TargetDate,
+	).Scan(&goal.ID, &goal.CreatedAt, &goal.UpdatedAt)
+	if err != nil {
+		return translateError(err)
+	}
+	return nil
+}
+
+// UpdateGoal replaces a goal's name, target amount and target date
+func (r *postgresRepo) UpdateGoal(ctx context.Context, goal *types.Goal) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE goals SET name = $3, target_amount = $4, target_date = $5, updated_at = NOW()
+		WHERE account_id = $1 AND id = $2`,
+		goal.AccountID, goal.ID, goal.Name, goal.TargetAmount, goal.TargetDate,
+	)
+	if err != nil {
+		return translateError(err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrGoalNotFound
+	}
+	return nil
+}
+
+// DeleteGoal removes a goal and its contributions
+func (r *postgresRepo) DeleteGoal(ctx context.Context, accountID string, goalID int64) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM goals WHERE account_id = $1 AND id = $2`, accountID, goalID)
+	if err != nil {
+		log.Printf("Error deleting goal %d: %v", goalID, err)
+		return fmt.Errorf("failed to delete goal: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrGoalNotFound
+	}
+	return nil
+}
+
+// ListContributions retrieves a goal's contributions, newest first
+func (r *postgresRepo) ListContributions(ctx context.Context, accountID string, goalID int64) ([]types.GoalContribution, error) {
+	query := `
+		SELECT c.id, c.goal_id, c.date, c.amount, COALESCE(c.transaction_id, ''), c.note, c.created_at
+		FROM goal_contributions c
+		JOIN goals g ON g.id = c.goal_id
+		WHERE g.account_id = $1 AND g.id = $2
+		ORDER BY c.date DESC, c.id DESC`
+
+	rows, err := r.db.QueryContext(ctx, query, accountID, goalID)
+	if err != nil {
+		log.Printf("Error querying goal contributions: %v", err)
+		return nil, fmt.Errorf("failed to query contributions: %w", err)
+	}
+	defer rows.Close()
+
+	contributions := []types.GoalContribution{}
+	for rows.Next() {
+		var c types.GoalContribution
+		if err := rows.Scan(&c.ID, &c.GoalID, &c.Date, &c.Amount, &c.TransactionID, &c.Note, &c.CreatedAt); err != nil {
+			log.Printf("Error scanning contribution: %v", err)
+			return nil, fmt.Errorf("failed to scan contribution: %w", err)
+		}
+		contributions = append(contributions, c)
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("Error iterating contributions: %v", err)
+		return nil, fmt.Errorf("error iterating contributions: %w", err)
+	}
+	return contributions, nil
+}
+
+// CreateContribution inserts a contribution to one of the account's goals,
+// setting its ID
+func (r *postgresRepo) CreateContribution(ctx context.Context, accountID string, contribution *types.GoalContribution) error {
+	var transactionID interface{}
+	if contribution.TransactionID != "" {
+		transactionID = contribution.TransactionID
+	}
+
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO goal_contributions (goal_id, date, amount, transaction_id, note)
+		SELECT g.id, $3, $4, $5, $6 FROM goals g WHERE g.account_id = $1 AND g.id = $2
+		RETURNING id, created_at`,
+		accountID, contribution.GoalID, contribution.Date, contribution.Amount, transactionID, contribution.Note,
+	).Scan(&contribution.ID, &contribution.CreatedAt)
+	if err == sql.ErrNoRows {
+		return ErrGoalNotFound
+	}
+	if err != nil {
+		return translateError(err)
+	}
+	return nil
+}
+
+// DeleteContribution removes a contribution from a goal
+func (r *postgresRepo) DeleteContribution(ctx context.Context, accountID string, goalID int64, contributionID int64) error {
+	result, err := r.db.ExecContext(ctx, `
+		DELETE FROM goal_contributions c
+		USING goals g
+		WHERE g.id = c.goal_id AND g.account_id = $1 AND g.id = $2 AND c.id = $3`,
+		accountID, goalID, contributionID,
+	)
+	if err != nil {
+		log.Printf("Error deleting contribution %d: %v", contributionID, err)
+		return fmt.Errorf("failed to delete contribution: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrContributionNotFound
+	}
+	return nil
+}
+
+// GetTransaction retrieves a transaction of the account with its currency resolved
+func (r *postgresRepo) GetTransaction(ctx context.Context, accountID string, transactionID string) (*types.Transaction, error) {
+	query := `
+		SELECT t.transaction_id, t.account_id, t.date, t.amount, t.category, t.merchant, t.location,
+			COALESCE(NULLIF(t.currency, ''), NULLIF(u.balance_currency, ''), 'USD')
+		FROM transactions t
+		JOIN users u ON u.account_id = t.account_id
+		WHERE t.account_id = $1 AND t.transaction_id = $2`
+
+	var t types.Transaction
+	err := r.db.QueryRowContext(ctx, query, accountID, transactionID).Scan(
+		&t.TransactionID, &t.AccountID, &t.Date, &t.Amount, &t.Category, &t.Merchant, &t.Location, &t.Currency,
+	)
+	if err == sql.ErrNoRows {
+		return nil, ErrTransactionNotFound
+	}
+	if err != nil {
+		log.Printf("Error fetching transaction %s: %v", transactionID, err)
+		return nil, fmt.Errorf("failed to fetch transaction: %w", err)
+	}
+	return &t, nil
+}
+
+// translateError maps Postgres constraint violations onto the repository errors
+func translateError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code.Name() {
+		case "unique_violation":
+			return ErrTransactionLinked
+		case "foreign_key_violation":
+			if pqErr.Table == "goals" {
+				return ErrAccountNotFound
+			}
+			return ErrTransactionNotFound
+		}
+	}
+	log.Printf("Error writing goal: %v", err)
+	return fmt.Errorf("failed to write goal: %w", err)
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanGoal(row rowScanner) (*types.Goal, error) {
+	var g types.Goal
+	var targetDate sql.NullTime
+	if err := row.Scan(&g.ID, &g.AccountID, &g.Name, &g.TargetAmount, &targetDate, &g.Saved, &g.CreatedAt, &g.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if targetDate.Valid {
+		g.TargetDate = &targetDate.Time
+	}
+	return &g, nil
+}
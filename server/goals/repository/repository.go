@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"server/types"
+)
+
+var (
+	// ErrAccountNotFound is returned when the account does not exist
+	ErrAccountNotFound = errors.New("account not found")
+
+	// ErrGoalNotFound is returned when the goal does not exist for the account
+	ErrGoalNotFound = errors.New("goal not found")
+
+	// ErrContributionNotFound is returned when the contribution does not exist for the goal
+	ErrContributionNotFound = errors.New("contribution not found")
+
+	// ErrTransactionNotFound is returned when a transaction to link is not on the account
+	ErrTransactionNotFound = errors.New("transaction not found")
+
+	// ErrTransactionLinked is returned when a transaction already counts towards a goal
+	ErrTransactionLinked = errors.New("transaction is already linked to a goal")
+)
+
+// Repository defines the interface for savings goal data operations
+type Repository interface {
+	// GetAccountCurrency retrieves the currency of the account's balance; it
+	// is empty when the account or its balance is unknown
+	GetAccountCurrency(ctx context.Context, accountID string) (string, error)
+
+	// ListGoals retrieves the goals of an account with their saved totals
+	ListGoals(ctx context.Context, accountID string) ([]types.Goal, error)
+
+	// GetGoal retrieves a single goal of an account with its saved total
+	GetGoal(ctx context.Context, accountID string, goalID int64) (*types.Goal, error)
+
+	// CreateGoal inserts a goal, setting its ID and timestamps
+	CreateGoal(ctx context.Context, goal *types.Goal) error
+
+	// UpdateGoal replaces a goal's name, target amount and target date
+	UpdateGoal(ctx context.Context, goal *types.Goal) error
+
+	// DeleteGoal removes a goal and its contributions
+	DeleteGoal(ctx context.Context, accountID string, goalID int64) error
+
+	// ListContributions retrieves a goal's contributions, newest first
+	ListContributions(ctx context.Context, accountID string, goalID int64) ([]types.GoalContribution, error)
+
+	// CreateContribution inserts a contribution to one of the account's
+	// goals, setting its ID
+	CreateContribution(ctx context.Context, accountID string, contribution *types.GoalContribution) error
+
+	// DeleteContribution removes a contribution from a goal
+	DeleteContribution(ctx context.Context, accountID string, goalID int64, contributionID int64) error
+
+	// GetTransaction retrieves a transaction of the account with its currency resolved
+	GetTransaction(ctx context.Context, accountID string, transactionID string) (*types.Transaction, error)
+}
@@ -0,0 +1,271 @@
+package service
+
+import (
+	"context"
+	"math"
+	analyticsRepository "server/analytics/repository"
+	"server/fx/rates"
+	"server/goals/repository"
+	"server/types"
+	"time"
+)
+
+// trendMonths is how many complete months the savings trend averages over
+const trendMonths = 6
+
+// daysPerMonth is the average length of a month, for turning a monthly pace into dates
+const daysPerMonth = 30.44
+
+type Service interface {
+	ListGoals(ctx context.Context, accountID string) ([]types.Goal, error)
+	GetGoal(ctx context.Context, accountID string, goalID int64) (*types.Goal, error)
+	CreateGoal(ctx context.Context, accountID string, input types.Goal) (*types.Goal, error)
+	UpdateGoal(ctx context.Context, accountID string, goalID int64, input types.Goal) (*types.Goal, error)
+	DeleteGoal(ctx context.Context, accountID string, goalID int64) error
+	ListContributions(ctx context.Context, accountID string, goalID int64) ([]types.GoalContribution, error)
+	CreateContribution(ctx context.Context, accountID string, goalID int64, input types.GoalContribution) (*types.GoalContribution, error)
+	DeleteContribution(ctx context.Context, accountID string, goalID int64, contributionID int64) error
+	GetProgress(ctx context.Context, accountID string, goalID int64) (*types.GoalProgress, error)
+	ListProgress(ctx context.Context, accountID string) ([]types.GoalProgress, error)
+}
+
+type service struct {
+	repo      repository.Repository
+	analytics analyticsRepository.Repository
+	rates     rates.Source
+	now       func() time.Time
+}
+
+func NewService(repo repository.Repository, analytics analyticsRepository.Repository, source rates.Source) Service {
+	return &service{repo: repo, analytics: analytics, rates: source, now: time.Now}
+}
+
+func (s *service) ListGoals(ctx context.Context, accountID string) ([]types.Goal, error) {
+	return s.repo.ListGoals(ctx, accountID)
+}
+
+func (s *service) GetGoal(ctx context.Context, accountID string, goalID int64) (*types.Goal, error) {
+	return s.repo.GetGoal(ctx, accountID, goalID)
+}
+
+// CreateGoal validates the input and creates a goal for the account
+func (s *service) CreateGoal(ctx context.Context, accountID string, input types.Goal) (*types.Goal, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	input.AccountID = accountID
+
+	if err := s.repo.CreateGoal(ctx, &input); err != nil {
+		return nil, err
+	}
+	return s.repo.GetGoal(ctx, accountID, input.ID)
+}
+
+// UpdateGoal replaces the goal's name, target amount and target date
+func (s *service) UpdateGoal(ctx context.Context, accountID string, goalID int64, input types.Goal) (*types.Goal, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	input.AccountID = accountID
+	input.ID = goalID
+
+	if err := s.repo.UpdateGoal(ctx, &input); err != nil {
+		return nil, err
+	}
+	return s.repo.GetGoal(ctx, accountID, goalID)
+}
+
+func (s *service) DeleteGoal(ctx context.Context, accountID string, goalID int64) error {
+	return s.repo.DeleteGoal(ctx, accountID, goalID)
+}
+
+func (s *service) ListContributions(ctx context.Context, accountID string, goalID int64) ([]types.GoalContribution, error) {
+	if _, err := s.repo.GetGoal(ctx, accountID, goalID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListContributions(ctx, accountID, goalID)
+}
+
+// CreateContribution records money put towards a goal. A contribution
+// linked to a transaction takes the transaction's date and, unless an
+// amount is given, the size of the transaction.
+func (s *service) CreateContribution(ctx context.Context, accountID string, goalID int64, input types.GoalContribution) (*types.GoalContribution, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	input.GoalID = goalID
+
+	if input.TransactionID != "" {
+		t, err := s.repo.GetTransaction(ctx, accountID, input.TransactionID)
+		if err != nil {
+			return nil, err
+		}
+		currency, err := s.currency(ctx, accountID)
+		if err != nil {
+			return nil, err
+		}
+		if t.Currency != currency {
+			return nil, &types.ValidationError{Field: "transaction_id", Message: "transaction must be in the account's currency"}
+		}
+		input.Date = t.Date
+		if input.Amount.IsZero() {
+			input.Amount = t.Amount.Abs()
+		}
+	}
+
+	if err := s.repo.CreateContribution(ctx, accountID, &input); err != nil {
+		return nil, err
+	}
+	return &input, nil
+}
+
+func (s *service) DeleteContribution(ctx context.Context, accountID string, goalID int64, contributionID int64) error {
+	return s.repo.DeleteContribution(ctx, accountID, goalID, contributionID)
+}
+
+// GetProgress reports how far a goal is, what it takes to reach it by its
+// target date and when the account's savings trend will reach it
+func (s *service) GetProgress(ctx context.Context, accountID string, goalID int64) (*types.GoalProgress, error) {
+	goal, err := s.repo.GetGoal(ctx, accountID, goalID)
+	if err != nil {
+		return nil, err
+	}
+	currency, err := s.currency(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	trend, months, err := s.savingsTrend(ctx, accountID, currency)
+	if err != nil {
+		return nil, err
+	}
+	progress := progressOf(*goal, currency, trend, months, s.now())
+	return &progress, nil
+}
+
+// ListProgress reports the progress of every goal of the account. Each goal
+// is projected against the account's whole savings trend.
+func (s *service) ListProgress(ctx context.Context, accountID string) ([]types.GoalProgress, error) {
+	goals, err := s.repo.ListGoals(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	currency, err := s.currency(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	trend, months, err := s.savingsTrend(ctx, accountID, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	progress := make([]types.GoalProgress, 0, len(goals))
+	for _, goal := range goals {
+		progress = append(progress, progressOf(goal, currency, trend, months, now))
+	}
+	return progress, nil
+}
+
+// savingsTrend averages the account's income minus spending over the last
+// complete months that have any transactions, converted into currency. It
+// returns the average and how many months it covers.
+func (s *service) savingsTrend(ctx context.Context, accountID string, currency string) (types.Money, int, error) {
+	table, err := s.rates.Table(ctx)
+	if err != nil {
+		return types.Money{}, 0, err
+	}
+
+	now := s.now()
+	thisMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	total := types.NewMoney(0, currency)
+	months := 0
+	for i := 1; i <= trendMonths; i++ {
+		month := thisMonth.AddDate(0, -i, 0)
+		transactions, err := s.analytics.GetMonthlySpending(ctx, []string{accountID}, month.Year(), int(month.Month()))
+		if err != nil {
+			return types.Money{}, 0, err
+		}
+		if len(transactions) == 0 {
+			continue
+		}
+		if err := table.ConvertTransactions(transactions, currency); err != nil {
+			return types.Money{}, 0, err
+		}
+		for _, t := range transactions {
+			total = total.Add(*t.ConvertedAmount)
+		}
+		months++
+	}
+	return total.Div(months), months, nil
+}
+
+// progressOf computes a goal's progress against a monthly savings pace
+func progressOf(goal types.Goal, currency string, trend types.Money, trendMonths int, now time.Time) types.GoalProgress {
+	zero := types.NewMoney(0, currency)
+	saved := zero.Add(goal.Saved)
+	remaining := goal.TargetAmount.Sub(saved)
+	if remaining.Sign() < 0 {
+		remaining = zero
+	}
+
+	p := types.GoalProgress{
+		Goal:              goal,
+		Currency:          currency,
+		Saved:             saved,
+		Remaining:         remaining,
+		PercentComplete:   math.Min(100, math.Round(saved.Ratio(goal.TargetAmount)*1000)/10),
+		MonthlyNetSavings: trend,
+		TrendMonths:       trendMonths,
+	}
+
+	if goal.TargetDate != nil {
+		left := monthsUntil(now, *goal.TargetDate)
+		required := remaining
+		if left > 1 {
+			required = remaining.Div(left)
+		}
+		p.MonthsLeft = &left
+		p.RequiredMonthly = &required
+	}
+
+	switch {
+	case remaining.IsZero():
+		p.Status = types.GoalCompleted
+	case trend.Sign() <= 0:
+		p.Status = types.GoalStalled
+	default:
+		days := math.Ceil(remaining.Ratio(trend) * daysPerMonth)
+		projected := now.AddDate(0, 0, int(days))
+		p.ProjectedCompletion = &projected
+
+		p.Status = types.GoalOnTrack
+		if goal.TargetDate != nil && projected.After(endOfDay(*goal.TargetDate)) {
+			p.Status = types.GoalBehind
+		}
+	}
+	return p
+}
+
+// monthsUntil counts the whole months from now until the target date,
+// rounding a part month up; it is zero once the date has passed
+func monthsUntil(now, target time.Time) int {
+	days := endOfDay(target).Sub(now).Hours() / 24
+	if days <= 0 {
+		return 0
+	}
+	return int(math.Ceil(days / daysPerMonth))
+}
+
+// endOfDay returns the last instant of t's day
+func endOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 23, 59, 59, 0, t.Location())
+}
+
+// currency returns the account's currency, which goals are kept in
+func (s *service) currency(ctx context.Context, accountID string) (string, error) {
+	accountCurrency, err := s.repo.GetAccountCurrency(ctx, accountID)
+	if err != nil {
+		return "", err
+	}
+	return rates.ReportingCurrency("", accountCurrency)
+}
@@ -5,24 +5,55 @@ import (
 	"encoding/json"
 	"net/http"
 	analyticsHandler "server/analytics/handler"
+	attachmentsHandler "server/attachments/handler"
+	authHandler "server/auth/handler"
 	billsHandler "server/bills/handler"
+	budgetsHandler "server/budgets/handler"
 	categoriesHandler "server/categories/handler"
 	"server/crud"
+	fxHandler "server/fx/handler"
+	goalsHandler "server/goals/handler"
+	importsHandler "server/imports/handler"
 	incomeHandler "server/income/handler"
+	merchantsHandler "server/merchants/handler"
+	ownersHandler "server/owners/handler"
+	rulesHandler "server/rules/handler"
+	subscriptionsHandler "server/subscriptions/handler"
+	transactionsHandler "server/transactions/handler"
+	transfersHandler "server/transfers/handler"
 
 	"github.com/gorilla/mux"
 )
 
 // SetupRoutes configures all the routes for the API
 func SetupRoutes(router *mux.Router, db *sql.DB) {
+	// Register and login are the only public API routes
+	auth := authHandler.SetupAuthRoutes(router, db)
+
+	// Every other route needs a signed-in caller who owns the {accountId} or
+	// {ownerId} in the path
+	api := router.NewRoute().Subrouter()
+	api.Use(auth.Authorize)
+
 	// Setup routes from each package
-	analyticsHandler.SetupRoutes(router, db)
-	billsHandler.SetupBillRoutes(router, db)
-	categoriesHandler.SetupCategoryRoutes(router, db)
-	incomeHandler.SetupIncomeRoutes(router, db)
+	analyticsHandler.SetupRoutes(api, db)
+	billsHandler.SetupBillRoutes(api, db)
+	categoriesHandler.SetupCategoryRoutes(api, db)
+	incomeHandler.SetupIncomeRoutes(api, db)
+	transactionsHandler.SetupTransactionRoutes(api, db)
+	importsHandler.SetupImportRoutes(api, db)
+	fxHandler.SetupFXRoutes(api, db)
+	ownersHandler.SetupOwnerRoutes(api, db)
+	transfersHandler.SetupTransferRoutes(api, db)
+	budgetsHandler.SetupBudgetRoutes(api, db)
+	goalsHandler.SetupGoalRoutes(api, db)
+	rulesHandler.SetupRuleRoutes(api, db)
+	merchantsHandler.SetupMerchantRoutes(api, db)
+	attachmentsHandler.SetupAttachmentRoutes(api, db)
+	subscriptionsHandler.SetupSubscriptionRoutes(api, db)
 
 	// User route
-	router.HandleFunc("/api/user/{accountId}", func(w http.ResponseWriter, r *http.Request) {
+	api.HandleFunc("/api/user/{accountId}", func(w http.ResponseWriter, r *http.Request) {
 		vars := mux.Vars(r)
 		accountID := vars["accountId"]
 
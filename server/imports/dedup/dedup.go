@@ -0,0 +1,188 @@
+package dedup
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"server/types"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Config controls how close two transactions must be to count as duplicates
+type Config struct {
+	// DateWindow is how far apart the posting dates of two duplicates may be
+	DateWindow time.Duration
+
+	// MinMerchantScore is the lowest merchant similarity (0-1) that still counts as a match
+	MinMerchantScore float64
+}
+
+// DefaultConfig tolerates the few days of posting-date drift seen between a
+// pending card transaction and the statement that settles it
+var DefaultConfig = Config{
+	DateWindow:       3 * 24 * time.Hour,
+	MinMerchantScore: 0.6,
+}
+
+// Match describes an existing transaction that a candidate likely duplicates
+type Match struct {
+	Existing types.Transaction
+	Score    float64
+	Reason   string
+}
+
+// noiseTokens are words banks add to descriptions that say nothing about the merchant
+var noiseTokens = map[string]bool{
+	"pos": true, "purchase": true, "debit": true, "credit": true, "card": true,
+	"ach": true, "recurring": true, "www": true, "com": true, "inc": true,
+	"llc": true, "ltd": true, "co": true, "the": true, "sq": true, "tst": true,
+}
+
+// NormalizeMerchant reduces a merchant description to lowercase words,
+// dropping store numbers, reference codes, punctuation and bank noise words,
+// so that "STARBUCKS #1234" and "Starbucks" normalize to the same value
+func NormalizeMerchant(merchant string) string {
+	words := strings.FieldsFunc(strings.ToLower(merchant), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+
+	kept := words[:0]
+	for _, w := range words {
+		if len(w) < 2 || noiseTokens[w] {
+			continue
+		}
+		kept = append(kept, w)
+	}
+	return strings.Join(kept, " ")
+}
+
+// Fingerprint identifies a transaction by account, amount and normalized
+// merchant. The date is left out on purpose: duplicates are looked up by
+// fingerprint within a date window rather than on an exact date.
+func Fingerprint(t types.Transaction) string {
+	key := fmt.Sprintf("%s|%d|%s", t.AccountID, t.Amount.Cents, NormalizeMerchant(t.Merchant))
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:8])
+}
+
+// MerchantSimilarity scores how alike two merchant descriptions are, from 0
+// to 1. It takes the better of a bigram (Dice) score, which tolerates
+// abbreviations and typos, and a word-overlap score, which tolerates one
+// description carrying extra words such as a city name.
+func MerchantSimilarity(a, b string) float64 {
+	na, nb := NormalizeMerchant(a), NormalizeMerchant(b)
+	if na == "" || nb == "" {
+		return 0
+	}
+	if na == nb {
+		return 1
+	}
+	return math.Max(diceCoefficient(na, nb), wordOverlap(na, nb))
+}
+
+// FindDuplicate returns the existing transaction that candidate most likely
+// duplicates, or nil. Both transactions must have the same amount and
+// currency and fall within the date window. A shared bank FITID is an exact
+// match, while two different FITIDs mean the bank considers them separate
+// transactions.
+func (c Config) FindDuplicate(candidate types.Transaction, existing []types.Transaction) *Match {
+	var best *Match
+	for _, e := range existing {
+		if candidate.FITID != "" && candidate.FITID == e.FITID {
+			return &Match{Existing: e, Score: 1, Reason: "same bank transaction ID"}
+		}
+		if candidate.FITID != "" && e.FITID != "" {
+			continue
+		}
+		if candidate.Amount.Cents != e.Amount.Cents {
+			continue
+		}
+		if candidate.Currency != "" && e.Currency != "" && candidate.Currency != e.Currency {
+			continue
+		}
+
+		gap := candidate.Date.Sub(e.Date)
+		if gap < 0 {
+			gap = -gap
+		}
+		if gap > c.DateWindow {
+			continue
+		}
+
+		similarity := MerchantSimilarity(candidate.Merchant, e.Merchant)
+		if similarity < c.MinMerchantScore {
+			continue
+		}
+
+		closeness := 1.0
+		if c.DateWindow > 0 {
+			closeness = 1 - float64(gap)/float64(c.DateWindow)
+		}
+		score := 0.7*similarity + 0.3*closeness
+		if best == nil || score > best.Score {
+			best = &Match{
+				Existing: e,
+				Score:    math.Round(score*1000) / 1000,
+				Reason: fmt.Sprintf("same amount, %d day(s) apart, merchant similarity %.2f",
+					int(gap.Hours()/24), similarity),
+			}
+		}
+	}
+	return best
+}
+
+// diceCoefficient compares the character bigrams of two strings
+func diceCoefficient(a, b string) float64 {
+	ba, bb := bigrams(a), bigrams(b)
+	if len(ba) == 0 || len(bb) == 0 {
+		return 0
+	}
+
+	counts := make(map[string]int, len(ba))
+	for _, g := range ba {
+		counts[g]++
+	}
+	shared := 0
+	for _, g := range bb {
+		if counts[g] > 0 {
+			counts[g]--
+			shared++
+		}
+	}
+	return 2 * float64(shared) / float64(len(ba)+len(bb))
+}
+
+func bigrams(s string) []string {
+	runes := []rune(strings.ReplaceAll(s, " ", ""))
+	if len(runes) < 2 {
+		return nil
+	}
+	grams := make([]string, 0, len(runes)-1)
+	for i := 0; i < len(runes)-1; i++ {
+		grams = append(grams, string(runes[i:i+2]))
+	}
+	return grams
+}
+
+// wordOverlap is the share of the shorter description's words found in the other
+func wordOverlap(a, b string) float64 {
+	wa, wb := strings.Fields(a), strings.Fields(b)
+	if len(wa) > len(wb) {
+		wa, wb = wb, wa
+	}
+
+	set := make(map[string]bool, len(wb))
+	for _, w := range wb {
+		set[w] = true
+	}
+	shared := 0
+	for _, w := range wa {
+		if set[w] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(wa))
+}
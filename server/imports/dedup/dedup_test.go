@@ -0,0 +1,177 @@
+package dedup
+
+import (
+	"server/types"
+	"testing"
+	"time"
+)
+
+// tx is a checking account transaction posted on the given day of January 2025
+func tx(id, merchant string, cents int64, day int) types.Transaction {
+	return types.Transaction{
+		TransactionID: id,
+		AccountID:     "checking",
+		Merchant:      merchant,
+		Amount:        types.NewMoney(cents, ""),
+		Currency:      "USD",
+		Date:          time.Date(2025, time.January, day, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+func withFITID(t types.Transaction, fitid string) types.Transaction {
+	t.FITID = fitid
+	return t
+}
+
+func TestNormalizeMerchant(t *testing.T) {
+	tests := []struct {
+		merchant string
+		want     string
+	}{
+		{"STARBUCKS #1234", "starbucks"},
+		{"Starbucks", "starbucks"},
+		{"POS PURCHASE SQ *BLUE BOTTLE COFFEE", "blue bottle coffee"},
+		{"AMAZON.COM*2K4L1 AMZN.COM/BILL", "amazon amzn bill"},
+		{"ACH DEBIT 123456", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.merchant, func(t *testing.T) {
+			if got := NormalizeMerchant(tt.merchant); got != tt.want {
+				t.Errorf("NormalizeMerchant(%q) = %q, want %q", tt.merchant, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMerchantSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{"STARBUCKS #1234", "Starbucks", 1},
+		{"Whole Foods Market", "WHOLE FOODS MKT", 0.741},
+		{"Shell Oil 5741 Boston MA", "SHELL OIL", 1},
+		{"Chipotle", "Netflix", 0},
+		{"#1234", "Starbucks", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.a+"/"+tt.b, func(t *testing.T) {
+			got := MerchantSimilarity(tt.a, tt.b)
+			if diff := got - tt.want; diff > 0.001 || diff < -0.001 {
+				t.Errorf("MerchantSimilarity(%q, %q) = %.3f, want %.3f", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindDuplicate(t *testing.T) {
+	euro := tx("euro", "Starbucks", -475, 14)
+	euro.Currency = "EUR"
+
+	tests := []struct {
+		name      string
+		candidate types.Transaction
+		existing  []types.Transaction
+		want      string // ID of the matched transaction, "" for none
+		score     float64
+	}{
+		{
+			name:      "same merchant, amount and day",
+			candidate: tx("", "STARBUCKS #1234", -475, 14),
+			existing:  []types.Transaction{tx("a", "Starbucks", -475, 14)},
+			want:      "a", score: 1,
+		},
+		{
+			name:      "posted two days later",
+			candidate: tx("", "STARBUCKS #1234", -475, 16),
+			existing:  []types.Transaction{tx("a", "Starbucks", -475, 14)},
+			want:      "a", score: 0.8,
+		},
+		{
+			name:      "outside the date window",
+			candidate: tx("", "Starbucks", -475, 18),
+			existing:  []types.Transaction{tx("a", "Starbucks", -475, 14)},
+		},
+		{
+			name:      "different amount",
+			candidate: tx("", "Starbucks", -476, 14),
+			existing:  []types.Transaction{tx("a", "Starbucks", -475, 14)},
+		},
+		{
+			name:      "different currency",
+			candidate: tx("", "Starbucks", -475, 14),
+			existing:  []types.Transaction{euro},
+		},
+		{
+			name:      "dissimilar merchant",
+			candidate: tx("", "Chipotle", -475, 14),
+			existing:  []types.Transaction{tx("a", "Netflix", -475, 14)},
+		},
+		{
+			// An abbreviated merchant scores lower but still matches
+			name:      "near-duplicate merchant",
+			candidate: tx("", "WHOLE FOODS MKT", -8312, 15),
+			existing:  []types.Transaction{tx("a", "Whole Foods Market", -8312, 14)},
+			want:      "a", score: 0.719,
+		},
+		{
+			name:      "the closest of several",
+			candidate: tx("", "Starbucks", -475, 15),
+			existing: []types.Transaction{
+				tx("far", "Starbucks", -475, 17),
+				tx("near", "Starbucks", -475, 15),
+				tx("other", "Chipotle", -475, 15),
+			},
+			want: "near", score: 1,
+		},
+		{
+			name:      "same FITID",
+			candidate: withFITID(tx("", "Different name", -999, 24), "F1"),
+			existing:  []types.Transaction{withFITID(tx("a", "Starbucks", -475, 14), "F1")},
+			want:      "a", score: 1,
+		},
+		{
+			// The bank says they are separate transactions
+			name:      "different FITIDs",
+			candidate: withFITID(tx("", "Starbucks", -475, 14), "F2"),
+			existing:  []types.Transaction{withFITID(tx("a", "Starbucks", -475, 14), "F1")},
+		},
+		{
+			name:      "FITID against a row without one",
+			candidate: withFITID(tx("", "Starbucks", -475, 14), "F2"),
+			existing:  []types.Transaction{tx("a", "Starbucks", -475, 14)},
+			want:      "a", score: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			match := DefaultConfig.FindDuplicate(tt.candidate, tt.existing)
+			if tt.want == "" {
+				if match != nil {
+					t.Fatalf("FindDuplicate() = %s (%v), want no match", match.Existing.TransactionID, match.Score)
+				}
+				return
+			}
+			if match == nil {
+				t.Fatalf("FindDuplicate() = nil, want %s", tt.want)
+			}
+			if match.Existing.TransactionID != tt.want || match.Score != tt.score {
+				t.Errorf("FindDuplicate() = %s (%v), want %s (%v)", match.Existing.TransactionID, match.Score, tt.want, tt.score)
+			}
+		})
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	a := tx("a", "STARBUCKS #1234", -475, 14)
+	b := tx("b", "Starbucks", -475, 16)
+	if Fingerprint(a) != Fingerprint(b) {
+		t.Errorf("Fingerprint() differs for the same merchant and amount on different days")
+	}
+
+	c := tx("c", "Starbucks", -476, 14)
+	if Fingerprint(a) == Fingerprint(c) {
+		t.Errorf("Fingerprint() is the same for different amounts")
+	}
+}
@@ -0,0 +1,310 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	authHandler "server/auth/handler"
+	categoriesRepository "server/categories/repository"
+	categoriesService "server/categories/service"
+	fxRepository "server/fx/repository"
+	fxService "server/fx/service"
+	"server/imports/repository"
+	"server/imports/service"
+	merchantsRepository "server/merchants/repository"
+	merchantsService "server/merchants/service"
+	rulesRepository "server/rules/repository"
+	rulesService "server/rules/service"
+	"server/types"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// maxUploadSize limits the size of an uploaded statement file
+const maxUploadSize = 10 << 20
+
+type Handler struct {
+	service service.Service
+}
+
+func NewHandler(service service.Service) *Handler {
+	return &Handler{service: service}
+}
+
+// SetupImportRoutes configures all the statement import routes
+func SetupImportRoutes(router *mux.Router, db *sql.DB) {
+	repo := repository.NewPostgresRepository(db)
+	fx := fxService.NewService(fxRepository.NewPostgresRepository(db))
+	categories := categoriesService.NewService(categoriesRepository.NewPostgresRepository(db), fx)
+	merchants := merchantsService.NewService(merchantsRepository.NewPostgresRepository(db), categories, fx)
+	rules := rulesService.NewService(rulesRepository.NewPostgresRepository(db), categories)
+	svc := service.NewService(repo, categories, merchants, rules)
+	handler := NewHandler(svc)
+	handler.RegisterRoutes(router)
+}
+
+// RegisterRoutes registers all import routes
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/api/import/profiles", h.HandleListProfiles).Methods("GET")
+	router.HandleFunc("/api/import/profiles", h.HandleCreateProfile).Methods("POST")
+	router.HandleFunc("/api/import/profiles/{profileId:[0-9]+}", h.HandleGetProfile).Methods("GET")
+	router.HandleFunc("/api/import/profiles/{profileId:[0-9]+}", h.HandleUpdateProfile).Methods("PUT")
+	router.HandleFunc("/api/import/profiles/{profileId:[0-9]+}", h.HandleDeleteProfile).Methods("DELETE")
+	router.HandleFunc("/api/import/{accountId}/csv", h.HandleImportCSV).Methods("POST")
+	router.HandleFunc("/api/import/{accountId}/{format:ofx|qfx}", h.HandleImportOFX).Methods("POST")
+	router.HandleFunc("/api/import/{accountId}/duplicates", h.HandleListDuplicates).Methods("GET")
+	router.HandleFunc("/api/import/{accountId}/duplicates/{duplicateId:[0-9]+}", h.HandleGetDuplicate).Methods("GET")
+	router.HandleFunc("/api/import/{accountId}/duplicates/{duplicateId:[0-9]+}", h.HandleDismissDuplicate).Methods("DELETE")
+	router.HandleFunc("/api/import/{accountId}/duplicates/{duplicateId:[0-9]+}/merge", h.HandleMergeDuplicate).Methods("POST")
+	router.HandleFunc("/api/import/{accountId}/duplicates/{duplicateId:[0-9]+}/insert", h.HandleInsertDuplicate).Methods("POST")
+}
+
+// HandleListProfiles handles requests for the caller's column-mapping
+// profiles and the shared ones
+func (h *Handler) HandleListProfiles(w http.ResponseWriter, r *http.Request) {
+	loginID, _ := authHandler.LoginID(r.Context())
+	profiles, err := h.service.ListProfiles(r.Context(), loginID)
+	if err != nil {
+		writeError(w, "Failed to get import profiles", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(profiles)
+}
+
+// HandleGetProfile handles requests for a single profile
+func (h *Handler) HandleGetProfile(w http.ResponseWriter, r *http.Request) {
+	loginID, _ := authHandler.LoginID(r.Context())
+	id, _ := strconv.Atoi(mux.Vars(r)["profileId"])
+
+	profile, err := h.service.GetProfile(r.Context(), loginID, id)
+	if err != nil {
+		writeError(w, "Failed to get import profile", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(profile)
+}
+
+// HandleCreateProfile handles requests to save a new profile of the caller
+func (h *Handler) HandleCreateProfile(w http.ResponseWriter, r *http.Request) {
+	loginID, _ := authHandler.LoginID(r.Context())
+
+	var input types.ImportProfile
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	profile, err := h.service.CreateProfile(r.Context(), loginID, input)
+	if err != nil {
+		writeError(w, "Failed to create import profile", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(profile)
+}
+
+// HandleUpdateProfile handles requests to overwrite one of the caller's profiles
+func (h *Handler) HandleUpdateProfile(w http.ResponseWriter, r *http.Request) {
+	loginID, _ := authHandler.LoginID(r.Context())
+	id, _ := strconv.Atoi(mux.Vars(r)["profileId"])
+
+	var input types.ImportProfile
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	profile, err := h.service.UpdateProfile(r.Context(), loginID, id, input)
+	if err != nil {
+		writeError(w, "Failed to update import profile", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(profile)
+}
+
+// HandleDeleteProfile handles requests to delete one of the caller's profiles
+func (h *Handler) HandleDeleteProfile(w http.ResponseWriter, r *http.Request) {
+	loginID, _ := authHandler.LoginID(r.Context())
+	id, _ := strconv.Atoi(mux.Vars(r)["profileId"])
+
+	if err := h.service.DeleteProfile(r.Context(), loginID, id); err != nil {
+		writeError(w, "Failed to delete import profile", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleImportCSV handles CSV statement uploads. The file is sent either as
+// the raw request body or as the "file" field of a multipart form, and the
+// column mapping is selected with the "profile" query parameter.
+func (h *Handler) HandleImportCSV(w http.ResponseWriter, r *http.Request) {
+	loginID, _ := authHandler.LoginID(r.Context())
+	vars := mux.Vars(r)
+	accountID := vars["accountId"]
+	profile := r.URL.Query().Get("profile")
+
+	file, err := uploadedFile(w, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	result, err := h.service.ImportCSV(r.Context(), loginID, accountID, profile, file, importOptions(r))
+	if err != nil {
+		writeError(w, "Failed to import CSV", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// HandleImportOFX handles OFX and QFX statement uploads, sent either as the
+// raw request body or as the "file" field of a multipart form. When the file
+// holds statements for several bank accounts, the "bankAccountId" query
+// parameter selects one by its ACCTID.
+func (h *Handler) HandleImportOFX(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	accountID := vars["accountId"]
+	bankAccountID := r.URL.Query().Get("bankAccountId")
+
+	file, err := uploadedFile(w, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	result, err := h.service.ImportOFX(r.Context(), accountID, bankAccountID, file, importOptions(r))
+	if err != nil {
+		writeError(w, "Failed to import OFX", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// HandleListDuplicates handles requests for the rows held back as likely
+// duplicates, optionally filtered with the "status" query parameter
+func (h *Handler) HandleListDuplicates(w http.ResponseWriter, r *http.Request) {
+	accountID := mux.Vars(r)["accountId"]
+	status := r.URL.Query().Get("status")
+
+	duplicates, err := h.service.ListDuplicates(r.Context(), accountID, status)
+	if err != nil {
+		writeError(w, "Failed to get import duplicates", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(duplicates)
+}
+
+// HandleGetDuplicate handles requests for a single flagged row and the
+// transaction it matched
+func (h *Handler) HandleGetDuplicate(w http.ResponseWriter, r *http.Request) {
+	h.handleDuplicate(w, r, "Failed to get import duplicate", h.service.GetDuplicate)
+}
+
+// HandleMergeDuplicate handles requests to treat a flagged row as a
+// duplicate of the existing transaction
+func (h *Handler) HandleMergeDuplicate(w http.ResponseWriter, r *http.Request) {
+	h.handleDuplicate(w, r, "Failed to merge import duplicate", h.service.MergeDuplicate)
+}
+
+// HandleInsertDuplicate handles requests to import a flagged row as a new
+// transaction
+func (h *Handler) HandleInsertDuplicate(w http.ResponseWriter, r *http.Request) {
+	h.handleDuplicate(w, r, "Failed to insert import duplicate", h.service.InsertDuplicate)
+}
+
+// HandleDismissDuplicate handles requests to discard a flagged row
+func (h *Handler) HandleDismissDuplicate(w http.ResponseWriter, r *http.Request) {
+	h.handleDuplicate(w, r, "Failed to dismiss import duplicate", h.service.DismissDuplicate)
+}
+
+func (h *Handler) handleDuplicate(w http.ResponseWriter, r *http.Request, message string, action func(context.Context, string, int) (*types.ImportDuplicate, error)) {
+	vars := mux.Vars(r)
+	id, _ := strconv.Atoi(vars["duplicateId"])
+
+	duplicate, err := action(r.Context(), vars["accountId"], id)
+	if err != nil {
+		writeError(w, message, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(duplicate)
+}
+
+// importOptions reads the "dryRun" and "dedup" query parameters. Duplicate
+// detection is on unless dedup=false is given.
+func importOptions(r *http.Request) service.Options {
+	query := r.URL.Query()
+	dryRun, _ := strconv.ParseBool(query.Get("dryRun"))
+	opts := service.Options{DryRun: dryRun}
+	if dedup, err := strconv.ParseBool(query.Get("dedup")); err == nil && !dedup {
+		opts.SkipDedup = true
+	}
+	return opts
+}
+
+// uploadedFile returns the statement file from a multipart form or, for any
+// other content type, the request body itself
+func uploadedFile(w http.ResponseWriter, r *http.Request) (io.ReadCloser, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
+
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		return r.Body, nil
+	}
+
+	if err := r.ParseMultipartForm(maxUploadSize); err != nil {
+		return nil, errors.New("invalid multipart form")
+	}
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		return nil, errors.New("missing \"file\" form field")
+	}
+	return file, nil
+}
+
+// writeError converts service errors into HTTP responses
+func writeError(w http.ResponseWriter, message string, err error) {
+	var validationErr *types.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		http.Error(w, validationErr.Error(), http.StatusBadRequest)
+	case errors.Is(err, repository.ErrAccountNotFound):
+		http.Error(w, "Account not found", http.StatusNotFound)
+	case errors.Is(err, repository.ErrProfileNotFound):
+		http.Error(w, "Import profile not found", http.StatusNotFound)
+	case errors.Is(err, repository.ErrProfileExists):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, repository.ErrProfileShared):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, repository.ErrDuplicateNotFound):
+		http.Error(w, "Import duplicate not found", http.StatusNotFound)
+	case errors.Is(err, repository.ErrDuplicateResolved), errors.Is(err, repository.ErrAlreadyImported):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		log.Printf("%s: %v", message, err)
+		http.Error(w, message, http.StatusInternalServerError)
+	}
+}
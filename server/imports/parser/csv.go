@@ -0,0 +1,294 @@
+package parser
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"server/types"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Row is a single statement entry parsed into a transaction. Line is the
+// 1-based line in the source file where the entry starts, used in error
+// reports.
+type Row struct {
+	Line        int
+	Transaction types.Transaction
+	Err         error
+
+	// Fallback is the category to use when the statement's category is not
+	// one of the account's. The parser does not know the account's
+	// categories, so it keeps the statement's value and leaves that check to
+	// the importer. It is empty when the row was given a default category.
+	Fallback string
+}
+
+// columnMap holds the resolved column indexes of a profile; -1 means unmapped
+type columnMap struct {
+	date, amount, debit, credit, merchant, category, location int
+}
+
+// ParseCSV parses a bank CSV export using the given column-mapping profile.
+// Rows that cannot be parsed are returned with Err set so that they can be
+// reported individually; a non-nil error means the file itself is unusable.
+func ParseCSV(r io.Reader, profile types.ImportProfile) ([]Row, error) {
+	reader := csv.NewReader(r)
+	reader.Comma = []rune(profile.Delimiter)[0]
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	for i := 0; i < profile.SkipRows; i++ {
+		if _, err := reader.Read(); err != nil {
+			return nil, fmt.Errorf("failed to skip leading rows: %w", err)
+		}
+	}
+
+	var header []string
+	if profile.HasHeader {
+		record, err := reader.Read()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read header row: %w", err)
+		}
+		header = record
+	}
+
+	cols, err := resolveColumns(profile, header)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []Row
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			rows = append(rows, Row{Line: parseErr.StartLine, Err: fmt.Errorf("malformed CSV: %v", parseErr.Err)})
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CSV: %w", err)
+		}
+		if isBlank(record) {
+			continue
+		}
+		line, _ := reader.FieldPos(0)
+
+		t, err := parseRecord(record, cols, profile)
+		row := Row{Line: line, Transaction: t, Err: err}
+		if fallback := DefaultCategory(t.Amount, profile.DefaultCategory); t.Category != fallback {
+			row.Fallback = fallback
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+// resolveColumns maps the profile's column references onto record indexes
+func resolveColumns(profile types.ImportProfile, header []string) (columnMap, error) {
+	var cols columnMap
+	var err error
+	refs := []struct {
+		ref string
+		dst *int
+	}{
+		{profile.DateColumn, &cols.date},
+		{profile.AmountColumn, &cols.amount},
+		{profile.DebitColumn, &cols.debit},
+		{profile.CreditColumn, &cols.credit},
+		{profile.MerchantColumn, &cols.merchant},
+		{profile.CategoryColumn, &cols.category},
+		{profile.LocationColumn, &cols.location},
+	}
+	for _, c := range refs {
+		if *c.dst, err = resolveColumn(c.ref, header); err != nil {
+			return cols, err
+		}
+	}
+	return cols, nil
+}
+
+func resolveColumn(ref string, header []string) (int, error) {
+	if ref == "" {
+		return -1, nil
+	}
+	for i, name := range header {
+		if strings.EqualFold(strings.TrimSpace(name), strings.TrimSpace(ref)) {
+			return i, nil
+		}
+	}
+	if idx, err := strconv.Atoi(ref); err == nil && idx >= 0 {
+		return idx, nil
+	}
+	return -1, fmt.Errorf("column %q not found in header", ref)
+}
+
+// parseRecord converts one CSV record into a transaction
+func parseRecord(record []string, cols columnMap, profile types.ImportProfile) (types.Transaction, error) {
+	var t types.Transaction
+
+	dateValue, err := field(record, cols.date)
+	if err != nil {
+		return t, err
+	}
+	t.Date, err = time.Parse(profile.DateFormat, dateValue)
+	if err != nil {
+		return t, fmt.Errorf("invalid date %q: expected format %s", dateValue, profile.DateFormat)
+	}
+
+	if cols.amount >= 0 {
+		value, err := field(record, cols.amount)
+		if err != nil {
+			return t, err
+		}
+		t.Amount, err = ParseAmount(value, profile.DecimalSeparator)
+		if err != nil {
+			return t, err
+		}
+		if profile.AmountSign == types.AmountSignDebitPositive {
+			t.Amount = t.Amount.Neg()
+		}
+	} else {
+		debit, err := optionalAmount(record, cols.debit, profile.DecimalSeparator)
+		if err != nil {
+			return t, err
+		}
+		credit, err := optionalAmount(record, cols.credit, profile.DecimalSeparator)
+		if err != nil {
+			return t, err
+		}
+		if !debit.IsZero() && !credit.IsZero() {
+			return t, fmt.Errorf("row has both a debit and a credit amount")
+		}
+		t.Amount = credit.Abs().Sub(debit.Abs())
+	}
+
+	if t.Merchant, err = field(record, cols.merchant); err != nil {
+		return t, err
+	}
+	t.Merchant = truncate(t.Merchant, 50)
+	if cols.location >= 0 {
+		location, _ := field(record, cols.location)
+		t.Location = truncate(location, 100)
+	}
+
+	if cols.category >= 0 {
+		t.Category, _ = field(record, cols.category)
+	}
+	if t.Category == "" {
+		t.Category = DefaultCategory(t.Amount, profile.DefaultCategory)
+	}
+
+	return t, nil
+}
+
+// DefaultCategory picks the category for a row the statement did not
+// categorize: deposits are income, everything else falls back to the
+// profile's default category or Other
+func DefaultCategory(amount types.Money, fallback string) string {
+	if amount.Sign() > 0 {
+		return types.CategoryIncome
+	}
+	if fallback != "" {
+		return fallback
+	}
+	return types.CategoryOther
+}
+
+// ParseAmount parses a bank-formatted amount such as "$1,234.56", "-12.00"
+// or "(12.00)", where parentheses denote a negative value. decimal is the
+// decimal separator, "," for amounts such as "1.234,56" and "." otherwise.
+// The other separator may only group thousands, so "12,50" is an error
+// rather than 1250 when the point is the decimal separator.
+func ParseAmount(value string, decimal string) (types.Money, error) {
+	s := strings.TrimSpace(value)
+	negative := false
+	if strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")") {
+		negative = true
+		s = s[1 : len(s)-1]
+	}
+	s = strings.NewReplacer("$", "", " ", "").Replace(s)
+	if s == "" {
+		return types.Money{}, fmt.Errorf("amount is empty")
+	}
+
+	group := ","
+	if decimal == "," {
+		group = "."
+	}
+	whole, fraction, hasFraction := strings.Cut(s, decimal)
+	if strings.Contains(fraction, group) || !groupsThousands(whole, group) {
+		return types.Money{}, fmt.Errorf("invalid amount %q: expected %q as the decimal separator", value, decimal)
+	}
+	s = strings.ReplaceAll(whole, group, "")
+	if hasFraction {
+		s += "." + fraction
+	}
+
+	amount, err := types.ParseMoney(s, "")
+	if err != nil {
+		return types.Money{}, fmt.Errorf("invalid amount %q", value)
+	}
+	if negative {
+		amount = amount.Neg()
+	}
+	return amount, nil
+}
+
+// groupsThousands reports whether every separator in the whole part of an
+// amount separates a group of three digits
+func groupsThousands(whole string, separator string) bool {
+	groups := strings.Split(strings.TrimLeft(whole, "+-"), separator)
+	if len(groups) > 1 && groups[0] == "" {
+		return false
+	}
+	for _, g := range groups[1:] {
+		if len(g) != 3 {
+			return false
+		}
+	}
+	return true
+}
+
+func optionalAmount(record []string, idx int, decimal string) (types.Money, error) {
+	if idx < 0 || idx >= len(record) || strings.TrimSpace(record[idx]) == "" {
+		return types.Money{}, nil
+	}
+	return ParseAmount(record[idx], decimal)
+}
+
+func field(record []string, idx int) (string, error) {
+	if idx < 0 || idx >= len(record) {
+		return "", fmt.Errorf("row has %d columns, missing column %d", len(record), idx)
+	}
+	return strings.TrimSpace(record[idx]), nil
+}
+
+func isBlank(record []string) bool {
+	for _, v := range record {
+		if strings.TrimSpace(v) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// truncate shortens s to at most n bytes without splitting a character, so
+// long bank descriptions fit the merchant and location columns
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
@@ -0,0 +1,258 @@
+package parser
+
+import (
+	"server/types"
+	"strings"
+	"testing"
+)
+
+// row is the part of a parsed row the CSV tests check; an empty err means
+// the row parsed
+type row struct {
+	line     int
+	date     string
+	cents    int64
+	merchant string
+	category string
+	fallback string
+	err      string
+}
+
+func profile(p types.ImportProfile) types.ImportProfile {
+	p.Name = "test"
+	if p.DateFormat == "" {
+		p.DateFormat = "2006-01-02"
+	}
+	p.ApplyDefaults()
+	return p
+}
+
+func TestParseCSV(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile types.ImportProfile
+		input   string
+		want    []row
+		wantErr string
+	}{
+		{
+			name:    "signed amounts with a header",
+			profile: profile(types.ImportProfile{HasHeader: true, DateColumn: "Date", AmountColumn: "Amount", MerchantColumn: "Description"}),
+			input: "Date,Description,Amount\n" +
+				"2025-01-14,STARBUCKS #1234,-4.75\n" +
+				"2025-01-15,PAYROLL,\"$2,500.00\"\n",
+			want: []row{
+				{line: 2, date: "2025-01-14", cents: -475, merchant: "STARBUCKS #1234", category: types.CategoryOther},
+				{line: 3, date: "2025-01-15", cents: 250000, merchant: "PAYROLL", category: types.CategoryIncome},
+			},
+		},
+		{
+			name:    "debits exported as positive numbers",
+			profile: profile(types.ImportProfile{HasHeader: true, DateColumn: "Date", AmountColumn: "Amount", MerchantColumn: "Payee", AmountSign: types.AmountSignDebitPositive}),
+			input: "Date,Payee,Amount\n" +
+				"2025-01-14,Shell,40.00\n" +
+				"2025-01-15,Refund,(12.00)\n",
+			want: []row{
+				{line: 2, date: "2025-01-14", cents: -4000, merchant: "Shell", category: types.CategoryOther},
+				{line: 3, date: "2025-01-15", cents: 1200, merchant: "Refund", category: types.CategoryIncome},
+			},
+		},
+		{
+			// Parentheses mark a negative value whichever column it is in
+			name:    "debit and credit columns",
+			profile: profile(types.ImportProfile{HasHeader: true, DateColumn: "Date", DebitColumn: "Debit", CreditColumn: "Credit", MerchantColumn: "Memo"}),
+			input: "Date,Memo,Debit,Credit\n" +
+				"2025-01-14,Rent,1500.00,\n" +
+				"2025-01-15,Deposit,,300.00\n" +
+				"2025-01-16,Fee,(2.50),\n" +
+				"2025-01-17,Both,1.00,1.00\n",
+			want: []row{
+				{line: 2, date: "2025-01-14", cents: -150000, merchant: "Rent", category: types.CategoryOther},
+				{line: 3, date: "2025-01-15", cents: 30000, merchant: "Deposit", category: types.CategoryIncome},
+				{line: 4, date: "2025-01-16", cents: -250, merchant: "Fee", category: types.CategoryOther},
+				{line: 5, err: "row has both a debit and a credit amount"},
+			},
+		},
+		{
+			name:    "skip_rows before the header",
+			profile: profile(types.ImportProfile{SkipRows: 2, HasHeader: true, DateColumn: "Date", AmountColumn: "Amount", MerchantColumn: "Description"}),
+			input: "Account statement\n" +
+				"Generated 2025-02-01\n" +
+				"Date,Description,Amount\n" +
+				"2025-01-14,Chipotle,-12.50\n",
+			want: []row{
+				{line: 4, date: "2025-01-14", cents: -1250, merchant: "Chipotle", category: types.CategoryOther},
+			},
+		},
+		{
+			name:    "columns by index without a header",
+			profile: profile(types.ImportProfile{Delimiter: ";", DateColumn: "0", AmountColumn: "2", MerchantColumn: "1", DateFormat: "02.01.2006"}),
+			input:   "14.01.2025;Bakery;-3.20\n\n15.01.2025;Bakery;-2.80\n",
+			want: []row{
+				{line: 1, date: "2025-01-14", cents: -320, merchant: "Bakery", category: types.CategoryOther},
+				{line: 3, date: "2025-01-15", cents: -280, merchant: "Bakery", category: types.CategoryOther},
+			},
+		},
+		{
+			name:    "decimal comma",
+			profile: profile(types.ImportProfile{Delimiter: ";", DateColumn: "0", AmountColumn: "2", MerchantColumn: "1", DateFormat: "02.01.2006", DecimalSeparator: ","}),
+			input:   "14.01.2025;Bakery;-3,20\n15.01.2025;Landlord;-1.234,56\n16.01.2025;Bakery;-3.20\n",
+			want: []row{
+				{line: 1, date: "2025-01-14", cents: -320, merchant: "Bakery", category: types.CategoryOther},
+				{line: 2, date: "2025-01-15", cents: -123456, merchant: "Landlord", category: types.CategoryOther},
+				{line: 3, err: `invalid amount "-3.20": expected "," as the decimal separator`},
+			},
+		},
+		{
+			name:    "malformed date",
+			profile: profile(types.ImportProfile{HasHeader: true, DateColumn: "Date", AmountColumn: "Amount", MerchantColumn: "Description", DateFormat: "01/02/2006"}),
+			input: "Date,Description,Amount\n" +
+				"2025-01-14,Chipotle,-12.50\n" +
+				"01/15/2025,Chipotle,-9.00\n",
+			want: []row{
+				{line: 2, err: `invalid date "2025-01-14": expected format 01/02/2006`},
+				{line: 3, date: "2025-01-15", cents: -900, merchant: "Chipotle", category: types.CategoryOther},
+			},
+		},
+		{
+			name:    "malformed amount and missing column",
+			profile: profile(types.ImportProfile{HasHeader: true, DateColumn: "Date", AmountColumn: "Amount", MerchantColumn: "Description"}),
+			input: "Date,Description,Amount\n" +
+				"2025-01-14,Chipotle,twelve\n" +
+				"2025-01-15,Chipotle\n",
+			want: []row{
+				{line: 2, err: `invalid amount "twelve"`},
+				{line: 3, err: "row has 2 columns, missing column 2"},
+			},
+		},
+		{
+			// The statement's category is kept for the importer to check,
+			// with the default to fall back on
+			name:    "category column and default category",
+			profile: profile(types.ImportProfile{HasHeader: true, DateColumn: "Date", AmountColumn: "Amount", MerchantColumn: "Description", CategoryColumn: "Category", DefaultCategory: types.CategorySubscription}),
+			input: "Date,Description,Amount,Category\n" +
+				"2025-01-14,Chipotle,-12.50,Takeout\n" +
+				"2025-01-15,Cafe,-3.00,\n",
+			want: []row{
+				{line: 2, date: "2025-01-14", cents: -1250, merchant: "Chipotle", category: "Takeout", fallback: types.CategorySubscription},
+				{line: 3, date: "2025-01-15", cents: -300, merchant: "Cafe", category: types.CategorySubscription},
+			},
+		},
+		{
+			name:    "unknown header column",
+			profile: profile(types.ImportProfile{HasHeader: true, DateColumn: "Posted", AmountColumn: "Amount", MerchantColumn: "Description"}),
+			input:   "Date,Description,Amount\n",
+			wantErr: `column "Posted" not found in header`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := ParseCSV(strings.NewReader(tt.input), tt.profile)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("ParseCSV() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseCSV() error = %v", err)
+			}
+			checkRows(t, rows, tt.want)
+		})
+	}
+}
+
+// checkRows compares parsed rows with the expected ones
+func checkRows(t *testing.T, rows []Row, want []row) {
+	t.Helper()
+	if len(rows) != len(want) {
+		t.Fatalf("got %d rows, want %d", len(rows), len(want))
+	}
+	for i, w := range want {
+		r := rows[i]
+		if r.Line != w.line {
+			t.Errorf("row %d: Line = %d, want %d", i, r.Line, w.line)
+		}
+		if w.err != "" {
+			if r.Err == nil || r.Err.Error() != w.err {
+				t.Errorf("row %d: Err = %v, want %q", i, r.Err, w.err)
+			}
+			continue
+		}
+		if r.Err != nil {
+			t.Errorf("row %d: Err = %v", i, r.Err)
+			continue
+		}
+
+		tx := r.Transaction
+		if got := tx.Date.Format("2006-01-02"); got != w.date {
+			t.Errorf("row %d: Date = %s, want %s", i, got, w.date)
+		}
+		if tx.Amount.Cents != w.cents {
+			t.Errorf("row %d: Amount = %d cents, want %d", i, tx.Amount.Cents, w.cents)
+		}
+		if tx.Merchant != w.merchant {
+			t.Errorf("row %d: Merchant = %q, want %q", i, tx.Merchant, w.merchant)
+		}
+		if tx.Category != w.category || r.Fallback != w.fallback {
+			t.Errorf("row %d: Category, Fallback = %q, %q, want %q, %q", i, tx.Category, r.Fallback, w.category, w.fallback)
+		}
+	}
+}
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		value string
+		cents int64
+		ok    bool
+	}{
+		{"12.50", 1250, true},
+		{"-12.5", -1250, true},
+		{"$1,234.56", 123456, true},
+		{"(12.00)", -1200, true},
+		{" 7 ", 700, true},
+		{"", 0, false},
+		{"12.345", 1235, true},
+		{"abc", 0, false},
+		{"1,234,567", 123456700, true},
+		{"-1,234", -123400, true},
+		{"12,50", 0, false},
+		{"1.234,56", 0, false},
+		{"1,23.45", 0, false},
+		{",123", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseAmount(tt.value, ".")
+			if (err == nil) != tt.ok || got.Cents != tt.cents {
+				t.Errorf("ParseAmount(%q) = %d, %v, want %d, ok %v", tt.value, got.Cents, err, tt.cents, tt.ok)
+			}
+		})
+	}
+}
+
+func TestParseAmountDecimalComma(t *testing.T) {
+	tests := []struct {
+		value string
+		cents int64
+		ok    bool
+	}{
+		{"12,50", 1250, true},
+		{"1.234,56", 123456, true},
+		{"(1 234,56)", -123456, true},
+		{"-0,5", -50, true},
+		{"1.234", 123400, true},
+		{"1,234.56", 0, false},
+		{"12.50", 0, false},
+		{"1,2,3", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseAmount(tt.value, ",")
+			if (err == nil) != tt.ok || got.Cents != tt.cents {
+				t.Errorf("ParseAmount(%q, \",\") = %d, %v, want %d, ok %v", tt.value, got.Cents, err, tt.cents, tt.ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,316 @@
+package parser
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"io"
+	"server/types"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OFXBalance is a LEDGERBAL or AVAILBAL aggregate from an OFX statement
+type OFXBalance struct {
+	Amount types.Money
+	AsOf   time.Time
+}
+
+// OFXStatement is the parsed content of an OFX or QFX statement file
+type OFXStatement struct {
+	BankAccountID    string
+	Currency         string
+	Rows             []Row
+	LedgerBalance    *OFXBalance
+	AvailableBalance *OFXBalance
+}
+
+// element is a node of the OFX document tree. Aggregates have children,
+// leaf elements carry a value. line is the 1-based line of the opening tag.
+type element struct {
+	name     string
+	value    string
+	line     int
+	children []*element
+}
+
+// ParseOFX parses an OFX 1.x (SGML) or 2.x (XML) bank or credit card
+// statement. Both versions are read by the same tolerant tokenizer: SGML
+// leaf elements have no closing tag, so a leaf is recognised by the text
+// that follows its opening tag rather than by its end tag.
+//
+// A file may hold a statement for each of several accounts. bankAccountID
+// selects the statement whose ACCTID matches it; when it is empty the file
+// must contain exactly one statement.
+func ParseOFX(r io.Reader, bankAccountID string) (*OFXStatement, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read OFX file: %w", err)
+	}
+
+	root, err := parseOFXTree(data)
+	if err != nil {
+		return nil, err
+	}
+
+	statements := append(root.findAll("STMTRS"), root.findAll("CCSTMTRS")...)
+	if len(statements) == 0 {
+		return nil, fmt.Errorf("no bank or credit card statement found")
+	}
+
+	var selected *element
+	switch {
+	case bankAccountID != "":
+		for _, s := range statements {
+			if s.find("ACCTID").text() == bankAccountID {
+				selected = s
+				break
+			}
+		}
+		if selected == nil {
+			return nil, fmt.Errorf("no statement for bank account %q", bankAccountID)
+		}
+	case len(statements) > 1:
+		ids := make([]string, len(statements))
+		for i, s := range statements {
+			ids[i] = s.find("ACCTID").text()
+		}
+		return nil, fmt.Errorf("file holds statements for %d bank accounts (%s); select one by its ACCTID",
+			len(statements), strings.Join(ids, ", "))
+	default:
+		selected = statements[0]
+	}
+
+	return parseStatement(selected)
+}
+
+// parseStatement maps a STMTRS or CCSTMTRS aggregate onto a statement
+func parseStatement(node *element) (*OFXStatement, error) {
+	stmt := &OFXStatement{
+		BankAccountID: node.find("ACCTID").text(),
+		Currency:      node.find("CURDEF").text(),
+	}
+
+	for _, b := range []struct {
+		name string
+		dst  **OFXBalance
+	}{
+		{"LEDGERBAL", &stmt.LedgerBalance},
+		{"AVAILBAL", &stmt.AvailableBalance},
+	} {
+		balance := node.find(b.name)
+		if balance == nil {
+			continue
+		}
+		amount, err := parseOFXAmount(balance.find("BALAMT").text())
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", b.name, err)
+		}
+		asOf, _ := ParseOFXDate(balance.find("DTASOF").text())
+		*b.dst = &OFXBalance{Amount: amount, AsOf: asOf}
+	}
+
+	for _, trn := range node.findAll("STMTTRN") {
+		t, err := parseStatementTransaction(trn)
+		if t.Currency == "" {
+			t.Currency = strings.ToUpper(stmt.Currency)
+		}
+		stmt.Rows = append(stmt.Rows, Row{Line: trn.line, Transaction: t, Err: err})
+	}
+
+	return stmt, nil
+}
+
+// parseStatementTransaction maps a STMTTRN aggregate onto a transaction
+func parseStatementTransaction(trn *element) (types.Transaction, error) {
+	var t types.Transaction
+
+	t.FITID = trn.find("FITID").text()
+	if t.FITID == "" {
+		return t, fmt.Errorf("transaction has no FITID")
+	}
+	if len(t.FITID) > 255 {
+		return t, fmt.Errorf("FITID %q is longer than 255 characters", t.FITID)
+	}
+
+	var err error
+	if t.Date, err = ParseOFXDate(trn.find("DTPOSTED").text()); err != nil {
+		return t, fmt.Errorf("transaction %s: %w", t.FITID, err)
+	}
+	if t.Amount, err = parseOFXAmount(trn.find("TRNAMT").text()); err != nil {
+		return t, fmt.Errorf("transaction %s: %w", t.FITID, err)
+	}
+
+	merchant := trn.find("NAME").text()
+	if payee := trn.find("PAYEE"); payee != nil && merchant == "" {
+		merchant = payee.find("NAME").text()
+	}
+	if merchant == "" {
+		merchant = trn.find("MEMO").text()
+	}
+	t.Merchant = truncate(merchant, 50)
+	t.Category = DefaultCategory(t.Amount, "")
+
+	// A CURRENCY aggregate means the amount is not in the statement's CURDEF
+	if currency := trn.find("CURRENCY"); currency != nil {
+		t.Currency = strings.ToUpper(currency.find("CURSYM").text())
+	}
+
+	return t, nil
+}
+
+// parseOFXTree tokenizes the document body starting at the <OFX> element
+func parseOFXTree(data []byte) (*element, error) {
+	start := bytes.Index(bytes.ToUpper(data), []byte("<OFX>"))
+	if start < 0 {
+		return nil, fmt.Errorf("not an OFX document: missing <OFX> element")
+	}
+	body := string(data[start:])
+	line := 1 + bytes.Count(data[:start], []byte("\n"))
+
+	root := &element{}
+	stack := []*element{root}
+	for len(body) > 0 {
+		open := strings.IndexByte(body, '<')
+		if open < 0 {
+			break
+		}
+		end := strings.IndexByte(body[open:], '>')
+		if end < 0 {
+			return nil, fmt.Errorf("malformed OFX: unterminated tag")
+		}
+		tag := strings.TrimSpace(body[open+1 : open+end])
+		line += strings.Count(body[:open], "\n")
+		tagLine := line
+		line += strings.Count(body[open:open+end], "\n")
+		body = body[open+end+1:]
+
+		fields := strings.Fields(strings.TrimSuffix(tag, "/"))
+		switch {
+		case len(fields) == 0 || tag[0] == '?' || tag[0] == '!':
+			continue
+		case tag[0] == '/':
+			name := strings.ToUpper(strings.TrimSpace(tag[1:]))
+			for i := len(stack) - 1; i > 0; i-- {
+				if stack[i].name == name {
+					stack = stack[:i]
+					break
+				}
+			}
+			continue
+		}
+
+		selfClosing := strings.HasSuffix(tag, "/")
+		name := strings.ToUpper(fields[0])
+		el := &element{name: name, line: tagLine}
+		parent := stack[len(stack)-1]
+		parent.children = append(parent.children, el)
+		if selfClosing {
+			continue
+		}
+
+		next := strings.IndexByte(body, '<')
+		if next < 0 {
+			next = len(body)
+		}
+		if value := strings.TrimSpace(body[:next]); value != "" {
+			el.value = html.UnescapeString(value)
+			line += strings.Count(body[:next], "\n")
+			body = body[next:]
+			continue
+		}
+		stack = append(stack, el)
+	}
+
+	if len(root.children) == 0 {
+		return nil, fmt.Errorf("malformed OFX: empty document")
+	}
+	return root, nil
+}
+
+// find returns the first descendant with the given name
+func (e *element) find(name string) *element {
+	if e == nil {
+		return nil
+	}
+	for _, c := range e.children {
+		if c.name == name {
+			return c
+		}
+		if found := c.find(name); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// findAll returns every descendant with the given name in document order
+func (e *element) findAll(name string) []*element {
+	var found []*element
+	for _, c := range e.children {
+		if c.name == name {
+			found = append(found, c)
+			continue
+		}
+		found = append(found, c.findAll(name)...)
+	}
+	return found
+}
+
+func (e *element) text() string {
+	if e == nil {
+		return ""
+	}
+	return e.value
+}
+
+// ParseOFXDate parses an OFX datetime such as 20250114, 20250114150858 or
+// 20250114150858.000[-5:EST]. The wall-clock time is kept in the offset
+// given by the bank, or UTC when none is given.
+func ParseOFXDate(value string) (time.Time, error) {
+	s := strings.TrimSpace(value)
+	loc := time.UTC
+	if i := strings.IndexByte(s, '['); i >= 0 {
+		tz := strings.TrimSuffix(s[i+1:], "]")
+		s = s[:i]
+		offset := tz
+		if j := strings.IndexByte(tz, ':'); j >= 0 {
+			offset = tz[:j]
+		}
+		if hours, err := strconv.ParseFloat(offset, 64); err == nil {
+			loc = time.FixedZone(tz, int(hours*3600))
+		}
+	}
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		s = s[:i]
+	}
+
+	var layout string
+	switch len(s) {
+	case 8:
+		layout = "20060102"
+	case 12:
+		layout = "200601021504"
+	case 14:
+		layout = "20060102150405"
+	default:
+		return time.Time{}, fmt.Errorf("invalid OFX date %q", value)
+	}
+
+	t, err := time.ParseInLocation(layout, s, loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid OFX date %q", value)
+	}
+	return t, nil
+}
+
+// parseOFXAmount parses a TRNAMT or BALAMT value. OFX allows a comma as the
+// decimal separator, so a lone comma is treated as the decimal point.
+func parseOFXAmount(value string) (types.Money, error) {
+	decimal := "."
+	if strings.Count(value, ",") == 1 && !strings.Contains(value, ".") {
+		decimal = ","
+	}
+	return ParseAmount(value, decimal)
+}
@@ -0,0 +1,261 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// sgmlStatement is an OFX 1.x bank statement: a plain-text header and leaf
+// elements without closing tags
+const sgmlStatement = `OFXHEADER:100
+DATA:OFXSGML
+VERSION:102
+
+<OFX>
+<BANKMSGSRSV1>
+<STMTTRNRS>
+<STMTRS>
+<CURDEF>USD
+<BANKACCTFROM>
+<BANKID>121000248
+<ACCTID>1111
+<ACCTTYPE>CHECKING
+</BANKACCTFROM>
+<BANKTRANLIST>
+<STMTTRN>
+<TRNTYPE>DEBIT
+<DTPOSTED>20250114150858.000[-5:EST]
+<TRNAMT>-12.50
+<FITID>A1
+<NAME>CHIPOTLE &amp; CO
+</STMTTRN>
+<STMTTRN>
+<TRNTYPE>CREDIT
+<DTPOSTED>20250115
+<TRNAMT>2500,00
+<FITID>A2
+<MEMO>PAYROLL
+</STMTTRN>
+</BANKTRANLIST>
+<LEDGERBAL>
+<BALAMT>4321.09
+<DTASOF>20250116
+</LEDGERBAL>
+</STMTRS>
+</STMTTRNRS>
+</BANKMSGSRSV1>
+</OFX>
+`
+
+// xmlStatement is an OFX 2.x credit card statement
+const xmlStatement = `<?xml version="1.0" encoding="UTF-8"?>
+<?OFX OFXHEADER="200" VERSION="220"?>
+<OFX>
+  <CREDITCARDMSGSRSV1>
+    <CCSTMTTRNRS>
+      <CCSTMTRS>
+        <CURDEF>EUR</CURDEF>
+        <CCACCTFROM><ACCTID>4444</ACCTID></CCACCTFROM>
+        <BANKTRANLIST>
+          <STMTTRN>
+            <DTPOSTED>20250120</DTPOSTED>
+            <TRNAMT>-30.00</TRNAMT>
+            <FITID>C1</FITID>
+            <PAYEE><NAME>Hotel Lyon</NAME></PAYEE>
+            <CURRENCY><CURRATE>1.08</CURRATE><CURSYM>usd</CURSYM></CURRENCY>
+          </STMTTRN>
+          <STMTTRN>
+            <DTPOSTED>not-a-date</DTPOSTED>
+            <TRNAMT>-5.00</TRNAMT>
+            <FITID>C2</FITID>
+          </STMTTRN>
+          <STMTTRN>
+            <DTPOSTED>20250121</DTPOSTED>
+            <TRNAMT>-5.00</TRNAMT>
+          </STMTTRN>
+        </BANKTRANLIST>
+        <AVAILBAL><BALAMT>-765.43</BALAMT><DTASOF>20250122</DTASOF></AVAILBAL>
+      </CCSTMTRS>
+    </CCSTMTTRNRS>
+  </CREDITCARDMSGSRSV1>
+</OFX>
+`
+
+// multiStatement holds a checking and a savings statement in one file
+const multiStatement = `<OFX>
+<BANKMSGSRSV1>
+<STMTTRNRS>
+<STMTRS>
+<CURDEF>USD
+<BANKACCTFROM><ACCTID>1111</BANKACCTFROM>
+<BANKTRANLIST>
+<STMTTRN><DTPOSTED>20250110<TRNAMT>-100.00<FITID>CHK1<NAME>Transfer to savings</STMTTRN>
+</BANKTRANLIST>
+<LEDGERBAL><BALAMT>900.00<DTASOF>20250111</LEDGERBAL>
+</STMTRS>
+</STMTTRNRS>
+<STMTTRNRS>
+<STMTRS>
+<CURDEF>USD
+<BANKACCTFROM><ACCTID>2222</BANKACCTFROM>
+<BANKTRANLIST>
+<STMTTRN><DTPOSTED>20250110<TRNAMT>100.00<FITID>SAV1<NAME>Transfer from checking</STMTTRN>
+<STMTTRN><DTPOSTED>20250131<TRNAMT>0.42<FITID>SAV2<NAME>Interest</STMTTRN>
+</BANKTRANLIST>
+<LEDGERBAL><BALAMT>5100.42<DTASOF>20250131</LEDGERBAL>
+</STMTRS>
+</STMTTRNRS>
+</BANKMSGSRSV1>
+</OFX>
+`
+
+func TestParseOFX(t *testing.T) {
+	tests := []struct {
+		name          string
+		input         string
+		bankAccountID string
+
+		account  string
+		ledger   int64 // 0 when the statement has no LEDGERBAL
+		rows     []row
+		fitids   []string
+		currency []string
+		wantErr  string
+	}{
+		{
+			name:    "SGML bank statement",
+			input:   sgmlStatement,
+			account: "1111",
+			ledger:  432109,
+			rows: []row{
+				{line: 16, date: "2025-01-14", cents: -1250, merchant: "CHIPOTLE & CO", category: "Other"},
+				{line: 23, date: "2025-01-15", cents: 250000, merchant: "PAYROLL", category: "Income"},
+			},
+			fitids:   []string{"A1", "A2"},
+			currency: []string{"USD", "USD"},
+		},
+		{
+			// The second row has a bad date and the third no FITID
+			name:    "XML credit card statement",
+			input:   xmlStatement,
+			account: "4444",
+			rows: []row{
+				{line: 10, date: "2025-01-20", cents: -3000, merchant: "Hotel Lyon", category: "Other"},
+				{line: 17, err: `transaction C2: invalid OFX date "not-a-date"`},
+				{line: 22, err: "transaction has no FITID"},
+			},
+			fitids:   []string{"C1", "C2", ""},
+			currency: []string{"USD", "EUR", "EUR"},
+		},
+		{
+			name:          "one statement of several selected by ACCTID",
+			input:         multiStatement,
+			bankAccountID: "2222",
+			account:       "2222",
+			ledger:        510042,
+			rows: []row{
+				{line: 18, date: "2025-01-10", cents: 10000, merchant: "Transfer from checking", category: "Income"},
+				{line: 19, date: "2025-01-31", cents: 42, merchant: "Interest", category: "Income"},
+			},
+			fitids:   []string{"SAV1", "SAV2"},
+			currency: []string{"USD", "USD"},
+		},
+		{
+			name:    "several statements without a selection",
+			input:   multiStatement,
+			wantErr: "file holds statements for 2 bank accounts (1111, 2222); select one by its ACCTID",
+		},
+		{
+			name:          "no statement for the selected account",
+			input:         multiStatement,
+			bankAccountID: "3333",
+			wantErr:       `no statement for bank account "3333"`,
+		},
+		{
+			name:    "no statement",
+			input:   "<OFX><SIGNONMSGSRSV1></SIGNONMSGSRSV1></OFX>",
+			wantErr: "no bank or credit card statement found",
+		},
+		{
+			name:    "not an OFX document",
+			input:   "Date,Description,Amount\n",
+			wantErr: "not an OFX document: missing <OFX> element",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stmt, err := ParseOFX(strings.NewReader(tt.input), tt.bankAccountID)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("ParseOFX() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseOFX() error = %v", err)
+			}
+
+			if stmt.BankAccountID != tt.account {
+				t.Errorf("BankAccountID = %q, want %q", stmt.BankAccountID, tt.account)
+			}
+			switch {
+			case tt.ledger == 0 && stmt.LedgerBalance != nil:
+				t.Errorf("LedgerBalance = %s, want none", stmt.LedgerBalance.Amount)
+			case tt.ledger != 0 && (stmt.LedgerBalance == nil || stmt.LedgerBalance.Amount.Cents != tt.ledger):
+				t.Errorf("LedgerBalance = %+v, want %d cents", stmt.LedgerBalance, tt.ledger)
+			}
+			checkRows(t, stmt.Rows, tt.rows)
+			for i, r := range stmt.Rows {
+				if r.Transaction.FITID != tt.fitids[i] {
+					t.Errorf("row %d: FITID = %q, want %q", i, r.Transaction.FITID, tt.fitids[i])
+				}
+				if r.Transaction.Currency != tt.currency[i] {
+					t.Errorf("row %d: Currency = %q, want %q", i, r.Transaction.Currency, tt.currency[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseOFXBalances(t *testing.T) {
+	stmt, err := ParseOFX(strings.NewReader(xmlStatement), "")
+	if err != nil {
+		t.Fatalf("ParseOFX() error = %v", err)
+	}
+	b := stmt.AvailableBalance
+	if b == nil {
+		t.Fatal("AvailableBalance = nil")
+	}
+	if b.Amount.Cents != -76543 {
+		t.Errorf("AvailableBalance = %d cents, want -76543", b.Amount.Cents)
+	}
+	if want := time.Date(2025, 1, 22, 0, 0, 0, 0, time.UTC); !b.AsOf.Equal(want) {
+		t.Errorf("AvailableBalance.AsOf = %s, want %s", b.AsOf, want)
+	}
+}
+
+func TestParseOFXDate(t *testing.T) {
+	est := time.FixedZone("-5:EST", -5*3600)
+	tests := []struct {
+		value string
+		want  time.Time
+		ok    bool
+	}{
+		{"20250114", time.Date(2025, 1, 14, 0, 0, 0, 0, time.UTC), true},
+		{"202501141508", time.Date(2025, 1, 14, 15, 8, 0, 0, time.UTC), true},
+		{"20250114150858", time.Date(2025, 1, 14, 15, 8, 58, 0, time.UTC), true},
+		{"20250114150858.000[-5:EST]", time.Date(2025, 1, 14, 15, 8, 58, 0, est), true},
+		{"2025011", time.Time{}, false},
+		{"20251340", time.Time{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseOFXDate(tt.value)
+			if (err == nil) != tt.ok || !got.Equal(tt.want) {
+				t.Errorf("ParseOFXDate(%q) = %s, %v, want %s, ok %v", tt.value, got, err, tt.want, tt.ok)
+			}
+		})
+	}
+}
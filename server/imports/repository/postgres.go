@@ -0,0 +1,523 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"server/crud"
+	"server/types"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type postgresRepo struct {
+	db *sql.DB
+}
+
+func NewPostgresRepository(db *sql.DB) Repository {
+	if db == nil {
+		panic("database connection is required")
+	}
+	return &postgresRepo{db: db}
+}
+
+const profileColumns = `id, name, delimiter, has_header, skip_rows, date_column, date_format,
+	amount_column, debit_column, credit_column, amount_sign, decimal_separator,
+	merchant_column, category_column, location_column, default_category,
+	login_id IS NULL`
+
+// AccountExists reports whether the account exists
+func (r *postgresRepo) AccountExists(ctx context.Context, accountID string) (bool, error) {
+	if accountID == "" {
+		return false, fmt.Errorf("account ID is required")
+	}
+
+	var exists bool
+	err := r.db.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM users WHERE account_id = $1)`, accountID).Scan(&exists)
+	if err != nil {
+		log.Printf("Error checking account %s: %v", accountID, err)
+		return false, fmt.Errorf("failed to check account: %w", err)
+	}
+	return exists, nil
+}
+
+// ListProfiles retrieves the login's column-mapping profiles and the shared ones
+func (r *postgresRepo) ListProfiles(ctx context.Context, loginID int64) ([]types.ImportProfile, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+profileColumns+` FROM import_profiles
+		 WHERE login_id = $1 OR login_id IS NULL
+		 ORDER BY name, login_id NULLS LAST`, loginID)
+	if err != nil {
+		log.Printf("Error querying import profiles: %v", err)
+		return nil, fmt.Errorf("failed to query import profiles: %w", err)
+	}
+	defer rows.Close()
+
+	profiles := []types.ImportProfile{}
+	for rows.Next() {
+		p, err := scanProfile(rows)
+		if err != nil {
+			log.Printf("Error scanning import profile: %v", err)
+			return nil, fmt.Errorf("failed to scan import profile: %w", err)
+		}
+		profiles = append(profiles, *p)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Printf("Error iterating import profiles: %v", err)
+		return nil, fmt.Errorf("error iterating import profiles: %w", err)
+	}
+
+	return profiles, nil
+}
+
+// GetProfile retrieves a profile of the login, or a shared one, by its ID
+func (r *postgresRepo) GetProfile(ctx context.Context, loginID int64, id int) (*types.ImportProfile, error) {
+	row := r.db.QueryRowContext(ctx,
+		`SELECT `+profileColumns+` FROM import_profiles
+		 WHERE id = $2 AND (login_id = $1 OR login_id IS NULL)`, loginID, id)
+	return r.getProfile(row)
+}
+
+// GetProfileByName retrieves a profile by name, preferring the login's own
+// profile to a shared one
+func (r *postgresRepo) GetProfileByName(ctx context.Context, loginID int64, name string) (*types.ImportProfile, error) {
+	row := r.db.QueryRowContext(ctx,
+		`SELECT `+profileColumns+` FROM import_profiles
+		 WHERE name = $2 AND (login_id = $1 OR login_id IS NULL)
+		 ORDER BY login_id NULLS LAST
+		 LIMIT 1`, loginID, name)
+	return r.getProfile(row)
+}
+
+func (r *postgresRepo) getProfile(row *sql.Row) (*types.ImportProfile, error) {
+	p, err := scanProfile(row)
+	if err == sql.ErrNoRows {
+		return nil, ErrProfileNotFound
+	}
+	if err != nil {
+		log.Printf("Error fetching import profile: %v", err)
+		return nil, fmt.Errorf("failed to fetch import profile: %w", err)
+	}
+	return p, nil
+}
+
+// CreateProfile saves a new profile of the login and sets its ID
+func (r *postgresRepo) CreateProfile(ctx context.Context, loginID int64, p *types.ImportProfile) error {
+	log.Printf("Creating import profile %s for login %d", p.Name, loginID)
+
+	query := `
+		INSERT INTO import_profiles (
+			login_id, name, delimiter, has_header, skip_rows, date_column, date_format,
+			amount_column, debit_column, credit_column, amount_sign, decimal_separator,
+			merchant_column, category_column, location_column, default_category
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+		RETURNING id`
+
+	err := r.db.QueryRowContext(ctx, query, loginID,
+		p.Name, p.Delimiter, p.HasHeader, p.SkipRows, p.DateColumn, p.DateFormat,
+		p.AmountColumn, p.DebitColumn, p.CreditColumn, p.AmountSign, p.DecimalSeparator,
+		p.MerchantColumn, p.CategoryColumn, p.LocationColumn, p.DefaultCategory,
+	).Scan(&p.ID)
+	if err != nil {
+		log.Printf("Error creating import profile: %v", err)
+		return translateProfileError(err)
+	}
+	p.Shared = false
+	return nil
+}
+
+// UpdateProfile overwrites one of the login's profiles
+func (r *postgresRepo) UpdateProfile(ctx context.Context, loginID int64, p *types.ImportProfile) error {
+	log.Printf("Updating import profile %d", p.ID)
+
+	query := `
+		UPDATE import_profiles SET
+			name = $2, delimiter = $3, has_header = $4, skip_rows = $5,
+			date_column = $6, date_format = $7, amount_column = $8,
+			debit_column = $9, credit_column = $10, amount_sign = $11,
+			decimal_separator = $12, merchant_column = $13, category_column = $14,
+			location_column = $15, default_category = $16
+		WHERE id = $1 AND login_id = $17`
+
+	res, err := r.db.ExecContext(ctx, query, p.ID,
+		p.Name, p.Delimiter, p.HasHeader, p.SkipRows, p.DateColumn, p.DateFormat,
+		p.AmountColumn, p.DebitColumn, p.CreditColumn, p.AmountSign, p.DecimalSeparator,
+		p.MerchantColumn, p.CategoryColumn, p.LocationColumn, p.DefaultCategory, loginID,
+	)
+	if err != nil {
+		log.Printf("Error updating import profile: %v", err)
+		return translateProfileError(err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return r.unchangedProfile(ctx, loginID, p.ID)
+	}
+	p.Shared = false
+	return nil
+}
+
+// DeleteProfile removes one of the login's profiles
+func (r *postgresRepo) DeleteProfile(ctx context.Context, loginID int64, id int) error {
+	log.Printf("Deleting import profile %d", id)
+
+	res, err := r.db.ExecContext(ctx, `DELETE FROM import_profiles WHERE id = $1 AND login_id = $2`, id, loginID)
+	if err != nil {
+		log.Printf("Error deleting import profile: %v", err)
+		return fmt.Errorf("failed to delete import profile: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return r.unchangedProfile(ctx, loginID, id)
+	}
+	return nil
+}
+
+// unchangedProfile explains why a profile the login tried to change was left
+// alone: it is shared, or it is not one the login can see
+func (r *postgresRepo) unchangedProfile(ctx context.Context, loginID int64, id int) error {
+	p, err := r.GetProfile(ctx, loginID, id)
+	if err != nil {
+		return err
+	}
+	if p.Shared {
+		return ErrProfileShared
+	}
+	return ErrProfileNotFound
+}
+
+// FindTransactions retrieves the account's transactions dated within the range
+func (r *postgresRepo) FindTransactions(ctx context.Context, accountID string, from, to time.Time) ([]types.Transaction, error) {
+	query := `
+		SELECT transaction_id, account_id, date, amount, category, merchant, location, currency,
+			COALESCE(fitid, '')
+		FROM transactions
+		WHERE account_id = $1
+		  AND date >= $2
+		  AND date <= $3
+		ORDER BY date ASC`
+
+	rows, err := r.db.QueryContext(ctx, query, accountID, from, to)
+	if err != nil {
+		log.Printf("Error querying transactions for deduplication: %v", err)
+		return nil, fmt.Errorf("failed to query transactions: %w", err)
+	}
+	defer rows.Close()
+
+	var transactions []types.Transaction
+	for rows.Next() {
+		var t types.Transaction
+		if err := rows.Scan(
+			&t.TransactionID,
+			&t.AccountID,
+			&t.Date,
+			&t.Amount,
+			&t.Category,
+			&t.Merchant,
+			&t.Location,
+			&t.Currency,
+			&t.FITID,
+		); err != nil {
+			log.Printf("Error scanning transaction: %v", err)
+			return nil, fmt.Errorf("failed to scan transaction: %w", err)
+		}
+		transactions = append(transactions, t)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Printf("Error iterating transactions: %v", err)
+		return nil, fmt.Errorf("error iterating transactions: %w", err)
+	}
+
+	return transactions, nil
+}
+
+// ImportBatch inserts the rows through crud, stages the flagged duplicates
+// and applies the statement balance in a single database transaction
+func (r *postgresRepo) ImportBatch(ctx context.Context, batch Batch, dryRun bool) (*BatchResult, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rowErrors, err := crud.InsertTransactionsTx(tx, batch.Transactions)
+	if err != nil {
+		log.Printf("Error importing transactions: %v", err)
+		return nil, fmt.Errorf("failed to import transactions: %w", err)
+	}
+	for i, rowErr := range rowErrors {
+		if errors.Is(rowErr, crud.ErrDuplicateTransaction) {
+			rowErrors[i] = ErrAlreadyImported
+		}
+	}
+
+	duplicateIDs := make([]int, len(batch.Duplicates))
+	for i := range batch.Duplicates {
+		if duplicateIDs[i], err = stageDuplicateTx(ctx, tx, &batch.Duplicates[i]); err != nil {
+			log.Printf("Error staging import duplicate: %v", err)
+			return nil, fmt.Errorf("failed to stage import duplicate: %w", err)
+		}
+	}
+
+	if b := batch.Balance; b != nil {
+		log.Printf("Updating balance of account %s as of %s", batch.AccountID, b.AsOf.Format("2006-01-02"))
+		if err := crud.UpdateBalanceTx(tx, batch.AccountID, b.Current, b.Available, b.AsOf); err != nil {
+			log.Printf("Error updating balance: %v", err)
+			return nil, fmt.Errorf("failed to update balance: %w", err)
+		}
+	}
+
+	result := &BatchResult{RowErrors: rowErrors, DuplicateIDs: duplicateIDs}
+	if dryRun {
+		log.Printf("Dry run: rolling back import of %d transactions", len(batch.Transactions))
+		return result, nil
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit import: %w", err)
+	}
+	return result, nil
+}
+
+// stageDuplicateTx records a held-back row for review. A row that was
+// already flagged against the same transaction is not staged twice, so
+// re-importing a statement does not pile up identical review entries.
+func stageDuplicateTx(ctx context.Context, tx *sql.Tx, d *types.ImportDuplicate) (int, error) {
+	query := `
+		INSERT INTO import_duplicates (
+			account_id, existing_transaction_id, date, amount, category, merchant,
+			location, fitid, fingerprint, score, reason, currency, tags
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''), $9, $10, $11, $12, COALESCE($13, '{}'::text[]))
+		ON CONFLICT (existing_transaction_id, fingerprint, date) DO NOTHING
+		RETURNING id`
+
+	c := d.Candidate
+	var id int
+	err := tx.QueryRowContext(ctx, query,
+		d.AccountID, d.ExistingTransactionID, c.Date, c.Amount, c.Category, c.Merchant,
+		c.Location, c.FITID, d.Fingerprint, d.Score, d.Reason, c.Currency, pq.Array(c.Tags),
+	).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return id, err
+}
+
+const duplicateColumns = `
+	d.id, d.account_id, d.existing_transaction_id, d.date, d.amount, d.category,
+	d.merchant, d.location, COALESCE(d.fitid, ''), d.fingerprint, d.score,
+	COALESCE(d.reason, ''), d.status, d.created_at, d.resolved_at,
+	COALESCE(d.resolved_transaction_id, ''), d.currency, d.tags,
+	t.transaction_id, t.date, t.amount, t.category, t.merchant, t.location,
+	COALESCE(t.fitid, ''), t.currency`
+
+// ListDuplicates retrieves the account's flagged duplicates, optionally filtered by status
+func (r *postgresRepo) ListDuplicates(ctx context.Context, accountID string, status string) ([]types.ImportDuplicate, error) {
+	log.Printf("Fetching import duplicates for account %s", accountID)
+
+	query := `
+		SELECT ` + duplicateColumns + `
+		FROM import_duplicates d
+		JOIN transactions t ON t.transaction_id = d.existing_transaction_id
+		WHERE d.account_id = $1
+		  AND ($2 = '' OR d.status = $2)
+		ORDER BY d.created_at DESC, d.id`
+
+	rows, err := r.db.QueryContext(ctx, query, accountID, status)
+	if err != nil {
+		log.Printf("Error querying import duplicates: %v", err)
+		return nil, fmt.Errorf("failed to query import duplicates: %w", err)
+	}
+	defer rows.Close()
+
+	duplicates := []types.ImportDuplicate{}
+	for rows.Next() {
+		d, err := scanDuplicate(rows)
+		if err != nil {
+			log.Printf("Error scanning import duplicate: %v", err)
+			return nil, fmt.Errorf("failed to scan import duplicate: %w", err)
+		}
+		duplicates = append(duplicates, *d)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Printf("Error iterating import duplicates: %v", err)
+		return nil, fmt.Errorf("error iterating import duplicates: %w", err)
+	}
+
+	return duplicates, nil
+}
+
+// GetDuplicate retrieves a flagged duplicate together with the transaction it matched
+func (r *postgresRepo) GetDuplicate(ctx context.Context, accountID string, id int) (*types.ImportDuplicate, error) {
+	query := `
+		SELECT ` + duplicateColumns + `
+		FROM import_duplicates d
+		JOIN transactions t ON t.transaction_id = d.existing_transaction_id
+		WHERE d.account_id = $1 AND d.id = $2`
+
+	d, err := scanDuplicate(r.db.QueryRowContext(ctx, query, accountID, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrDuplicateNotFound
+	}
+	if err != nil {
+		log.Printf("Error fetching import duplicate %d: %v", id, err)
+		return nil, fmt.Errorf("failed to fetch import duplicate: %w", err)
+	}
+	return d, nil
+}
+
+// MergeDuplicate discards a pending duplicate, copying its bank FITID and
+// location onto the matched transaction when that has none, and adding the
+// tags the rules gave it
+func (r *postgresRepo) MergeDuplicate(ctx context.Context, accountID string, id int) error {
+	log.Printf("Merging import duplicate %d for account %s", id, accountID)
+
+	return r.resolveDuplicate(ctx, accountID, id, types.DuplicateMerged, func(tx *sql.Tx, d *types.ImportDuplicate) (string, error) {
+		query := `
+			UPDATE transactions t SET
+				fitid = CASE
+					WHEN t.fitid IS NULL AND NOT EXISTS (
+						SELECT 1 FROM transactions o WHERE o.account_id = t.account_id AND o.fitid = $2
+					) THEN NULLIF($2, '')
+					ELSE t.fitid
+				END,
+				location = CASE WHEN COALESCE(t.location, '') = '' THEN $3 ELSE t.location END
+			WHERE t.transaction_id = $1`
+
+		if _, err := tx.ExecContext(ctx, query, d.ExistingTransactionID, d.Candidate.FITID, d.Candidate.Location); err != nil {
+			return "", err
+		}
+		return d.ExistingTransactionID, crud.InsertTransactionTagsTx(tx, accountID, d.ExistingTransactionID, d.Candidate.Tags)
+	})
+}
+
+// InsertDuplicate inserts a pending duplicate as a new transaction with the
+// given ID, linked to merchantID when it is set
+func (r *postgresRepo) InsertDuplicate(ctx context.Context, accountID string, id int, transactionID string, merchantID *int64) error {
+	log.Printf("Force-inserting import duplicate %d for account %s", id, accountID)
+
+	return r.resolveDuplicate(ctx, accountID, id, types.DuplicateInserted, func(tx *sql.Tx, d *types.ImportDuplicate) (string, error) {
+		t := d.Candidate
+		t.TransactionID = transactionID
+		t.MerchantID = merchantID
+		rowErrors, err := crud.InsertTransactionsTx(tx, []types.Transaction{t})
+		if err != nil {
+			return "", err
+		}
+		if errors.Is(rowErrors[0], crud.ErrDuplicateTransaction) {
+			return "", ErrAlreadyImported
+		}
+		return transactionID, rowErrors[0]
+	})
+}
+
+// DismissDuplicate discards a pending duplicate without touching the matched transaction
+func (r *postgresRepo) DismissDuplicate(ctx context.Context, accountID string, id int) error {
+	log.Printf("Dismissing import duplicate %d for account %s", id, accountID)
+
+	return r.resolveDuplicate(ctx, accountID, id, types.DuplicateDismissed, func(tx *sql.Tx, d *types.ImportDuplicate) (string, error) {
+		return "", nil
+	})
+}
+
+// resolveDuplicate locks a pending duplicate, applies the resolution and
+// records the new status in one database transaction
+func (r *postgresRepo) resolveDuplicate(ctx context.Context, accountID string, id int, status string, apply func(*sql.Tx, *types.ImportDuplicate) (string, error)) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		SELECT ` + duplicateColumns + `
+		FROM import_duplicates d
+		JOIN transactions t ON t.transaction_id = d.existing_transaction_id
+		WHERE d.account_id = $1 AND d.id = $2
+		FOR UPDATE OF d`
+
+	d, err := scanDuplicate(tx.QueryRowContext(ctx, query, accountID, id))
+	if err == sql.ErrNoRows {
+		return ErrDuplicateNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to fetch import duplicate: %w", err)
+	}
+	if d.Status != types.DuplicatePending {
+		return ErrDuplicateResolved
+	}
+
+	resolvedID, err := apply(tx, d)
+	if err != nil {
+		log.Printf("Error resolving import duplicate %d: %v", id, err)
+		if errors.Is(err, ErrAlreadyImported) {
+			return err
+		}
+		return fmt.Errorf("failed to resolve import duplicate: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE import_duplicates
+		SET status = $2, resolved_at = NOW(), resolved_transaction_id = NULLIF($3, '')
+		WHERE id = $1`, id, status, resolvedID)
+	if err != nil {
+		return fmt.Errorf("failed to update import duplicate: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+func scanDuplicate(row rowScanner) (*types.ImportDuplicate, error) {
+	var d types.ImportDuplicate
+	var resolvedAt sql.NullTime
+	existing := &types.Transaction{}
+	err := row.Scan(
+		&d.ID, &d.AccountID, &d.ExistingTransactionID, &d.Candidate.Date, &d.Candidate.Amount,
+		&d.Candidate.Category, &d.Candidate.Merchant, &d.Candidate.Location, &d.Candidate.FITID,
+		&d.Fingerprint, &d.Score, &d.Reason, &d.Status, &d.CreatedAt, &resolvedAt,
+		&d.ResolvedTransactionID, &d.Candidate.Currency, (*pq.StringArray)(&d.Candidate.Tags),
+		&existing.TransactionID, &existing.Date, &existing.Amount, &existing.Category,
+		&existing.Merchant, &existing.Location, &existing.FITID, &existing.Currency,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	d.Candidate.AccountID = d.AccountID
+	existing.AccountID = d.AccountID
+	d.Existing = existing
+	if resolvedAt.Valid {
+		d.ResolvedAt = &resolvedAt.Time
+	}
+	return &d, nil
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanProfile(row rowScanner) (*types.ImportProfile, error) {
+	var p types.ImportProfile
+	err := row.Scan(
+		&p.ID, &p.Name, &p.Delimiter, &p.HasHeader, &p.SkipRows, &p.DateColumn, &p.DateFormat,
+		&p.AmountColumn, &p.DebitColumn, &p.CreditColumn, &p.AmountSign, &p.DecimalSeparator,
+		&p.MerchantColumn, &p.CategoryColumn, &p.LocationColumn, &p.DefaultCategory,
+		&p.Shared,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func translateProfileError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code.Name() == "unique_violation" {
+		return ErrProfileExists
+	}
+	return fmt.Errorf("failed to save import profile: %w", err)
+}
@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"server/types"
+	"time"
+)
+
+var (
+	// ErrAccountNotFound is returned when the account does not exist
+	ErrAccountNotFound = errors.New("account not found")
+
+	// ErrProfileNotFound is returned when the import profile does not exist
+	ErrProfileNotFound = errors.New("import profile not found")
+
+	// ErrProfileExists is returned when a profile with the same name already exists
+	ErrProfileExists = errors.New("import profile already exists")
+
+	// ErrProfileShared is returned when changing a profile shared by every login
+	ErrProfileShared = errors.New("shared import profiles cannot be changed")
+
+	// ErrAlreadyImported is reported for a row whose bank FITID was imported before
+	ErrAlreadyImported = errors.New("transaction already imported")
+
+	// ErrDuplicateNotFound is returned when the flagged duplicate does not exist for the account
+	ErrDuplicateNotFound = errors.New("import duplicate not found")
+
+	// ErrDuplicateResolved is returned when the flagged duplicate has already been reviewed
+	ErrDuplicateResolved = errors.New("import duplicate has already been resolved")
+)
+
+// Repository defines the interface for statement import data operations
+type Repository interface {
+	// AccountExists reports whether the account exists
+	AccountExists(ctx context.Context, accountID string) (bool, error)
+
+	// ListProfiles retrieves the login's column-mapping profiles and the
+	// shared ones
+	ListProfiles(ctx context.Context, loginID int64) ([]types.ImportProfile, error)
+
+	// GetProfile retrieves a profile of the login, or a shared one, by its ID
+	GetProfile(ctx context.Context, loginID int64, id int) (*types.ImportProfile, error)
+
+	// GetProfileByName retrieves a profile by name, preferring the login's
+	// own profile to a shared one of the same name
+	GetProfileByName(ctx context.Context, loginID int64, name string) (*types.ImportProfile, error)
+
+	// CreateProfile saves a new profile of the login and sets its ID
+	CreateProfile(ctx context.Context, loginID int64, profile *types.ImportProfile) error
+
+	// UpdateProfile overwrites one of the login's profiles
+	UpdateProfile(ctx context.Context, loginID int64, profile *types.ImportProfile) error
+
+	// DeleteProfile removes one of the login's profiles
+	DeleteProfile(ctx context.Context, loginID int64, id int) error
+
+	// FindTransactions retrieves the account's transactions dated within the range
+	FindTransactions(ctx context.Context, accountID string, from, to time.Time) ([]types.Transaction, error)
+
+	// ImportBatch writes the batch in a single database transaction. With
+	// dryRun set the database transaction is rolled back after the writes
+	// have been attempted.
+	ImportBatch(ctx context.Context, batch Batch, dryRun bool) (*BatchResult, error)
+
+	// ListDuplicates retrieves the account's flagged duplicates, optionally filtered by status
+	ListDuplicates(ctx context.Context, accountID string, status string) ([]types.ImportDuplicate, error)
+
+	// GetDuplicate retrieves a flagged duplicate together with the transaction it matched
+	GetDuplicate(ctx context.Context, accountID string, id int) (*types.ImportDuplicate, error)
+
+	// MergeDuplicate discards a pending duplicate, copying its bank FITID and
+	// location onto the matched transaction when that has none
+	MergeDuplicate(ctx context.Context, accountID string, id int) error
+
+	// InsertDuplicate inserts a pending duplicate as a new transaction with the
+	// given ID, linked to the given merchant if any
+	InsertDuplicate(ctx context.Context, accountID string, id int, transactionID string, merchantID *int64) error
+
+	// DismissDuplicate discards a pending duplicate without touching the matched transaction
+	DismissDuplicate(ctx context.Context, accountID string, id int) error
+}
+
+// Batch is the set of rows parsed from one statement file: the rows to
+// insert, the rows held back as likely duplicates, and the closing balance
+// reported by the statement when it has one
+type Batch struct {
+	AccountID    string
+	Transactions []types.Transaction
+	Duplicates   []types.ImportDuplicate
+	Balance      *StatementBalance
+}
+
+// BatchResult reports the outcome of each row of a Batch. RowErrors holds one
+// error (or nil) per transaction; DuplicateIDs holds the ID assigned to each
+// flagged duplicate, or 0 when the same duplicate had already been flagged.
+type BatchResult struct {
+	RowErrors    []error
+	DuplicateIDs []int
+}
+
+// StatementBalance is the account balance reported by a statement. Nil
+// amounts were not present in the file.
+type StatementBalance struct {
+	Current   *types.Money
+	Available *types.Money
+	AsOf      time.Time
+}
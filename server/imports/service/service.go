@@ -0,0 +1,394 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	categoriesService "server/categories/service"
+	"server/imports/dedup"
+	"server/imports/parser"
+	"server/imports/repository"
+	merchantsService "server/merchants/service"
+	rulesService "server/rules/service"
+	"server/types"
+	"strconv"
+	"time"
+)
+
+type Service interface {
+	ListProfiles(ctx context.Context, loginID int64) ([]types.ImportProfile, error)
+	GetProfile(ctx context.Context, loginID int64, id int) (*types.ImportProfile, error)
+	CreateProfile(ctx context.Context, loginID int64, profile types.ImportProfile) (*types.ImportProfile, error)
+	UpdateProfile(ctx context.Context, loginID int64, id int, profile types.ImportProfile) (*types.ImportProfile, error)
+	DeleteProfile(ctx context.Context, loginID int64, id int) error
+	ImportCSV(ctx context.Context, loginID int64, accountID string, profileRef string, data io.Reader, opts Options) (*types.ImportResult, error)
+	ImportOFX(ctx context.Context, accountID string, bankAccountID string, data io.Reader, opts Options) (*types.ImportResult, error)
+	ListDuplicates(ctx context.Context, accountID string, status string) ([]types.ImportDuplicate, error)
+	GetDuplicate(ctx context.Context, accountID string, id int) (*types.ImportDuplicate, error)
+	MergeDuplicate(ctx context.Context, accountID string, id int) (*types.ImportDuplicate, error)
+	InsertDuplicate(ctx context.Context, accountID string, id int) (*types.ImportDuplicate, error)
+	DismissDuplicate(ctx context.Context, accountID string, id int) (*types.ImportDuplicate, error)
+}
+
+// Options controls how an import is applied
+type Options struct {
+	// DryRun validates and reports without saving anything
+	DryRun bool
+
+	// SkipDedup inserts rows even when they look like duplicates of existing transactions
+	SkipDedup bool
+}
+
+type service struct {
+	repo       repository.Repository
+	categories categoriesService.Service
+	merchants  merchantsService.Service
+	rules      rulesService.Service
+	dedup      dedup.Config
+}
+
+func NewService(repo repository.Repository, categories categoriesService.Service, merchants merchantsService.Service, rules rulesService.Service) Service {
+	return &service{repo: repo, categories: categories, merchants: merchants, rules: rules, dedup: dedup.DefaultConfig}
+}
+
+// ListProfiles returns the login's profiles and the shared ones
+func (s *service) ListProfiles(ctx context.Context, loginID int64) ([]types.ImportProfile, error) {
+	return s.repo.ListProfiles(ctx, loginID)
+}
+
+func (s *service) GetProfile(ctx context.Context, loginID int64, id int) (*types.ImportProfile, error) {
+	return s.repo.GetProfile(ctx, loginID, id)
+}
+
+func (s *service) CreateProfile(ctx context.Context, loginID int64, profile types.ImportProfile) (*types.ImportProfile, error) {
+	profile.ApplyDefaults()
+	if err := profile.Validate(); err != nil {
+		return nil, err
+	}
+	if err := s.repo.CreateProfile(ctx, loginID, &profile); err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+func (s *service) UpdateProfile(ctx context.Context, loginID int64, id int, profile types.ImportProfile) (*types.ImportProfile, error) {
+	profile.ID = id
+	profile.ApplyDefaults()
+	if err := profile.Validate(); err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateProfile(ctx, loginID, &profile); err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+func (s *service) DeleteProfile(ctx context.Context, loginID int64, id int) error {
+	return s.repo.DeleteProfile(ctx, loginID, id)
+}
+
+// resolveProfile looks a profile the login can use up by numeric ID or by name
+func (s *service) resolveProfile(ctx context.Context, loginID int64, ref string) (*types.ImportProfile, error) {
+	if ref == "" {
+		return nil, &types.ValidationError{Field: "profile", Message: "is required"}
+	}
+	if id, err := strconv.Atoi(ref); err == nil {
+		return s.repo.GetProfile(ctx, loginID, id)
+	}
+	return s.repo.GetProfileByName(ctx, loginID, ref)
+}
+
+func (s *service) requireAccount(ctx context.Context, accountID string) error {
+	exists, err := s.repo.AccountExists(ctx, accountID)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return repository.ErrAccountNotFound
+	}
+	return nil
+}
+
+// ImportCSV parses a CSV statement with the named profile, one of the
+// login's or a shared one, and inserts every valid row. Rows that fail to
+// parse, validate or insert are reported individually and do not prevent the
+// remaining rows from being imported; rows that look like duplicates of
+// existing transactions are held back for review.
+func (s *service) ImportCSV(ctx context.Context, loginID int64, accountID string, profileRef string, data io.Reader, opts Options) (*types.ImportResult, error) {
+	if err := s.requireAccount(ctx, accountID); err != nil {
+		return nil, err
+	}
+
+	profile, err := s.resolveProfile(ctx, loginID, profileRef)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := parser.ParseCSV(data, *profile)
+	if err != nil {
+		return nil, &types.ValidationError{Field: "file", Message: err.Error()}
+	}
+
+	log.Printf("Importing %d CSV rows into account %s with profile %s", len(rows), accountID, profile.Name)
+	return s.importRows(ctx, accountID, "csv", rows, nil, opts)
+}
+
+// ImportOFX parses an OFX or QFX statement and inserts its transactions.
+// Transactions are keyed by the bank's FITID, so importing the same or an
+// overlapping statement again skips the rows that are already present. The
+// account balance is updated from the statement's LEDGERBAL and AVAILBAL.
+// A file with statements for several bank accounts is only accepted when
+// bankAccountID names the one to import.
+func (s *service) ImportOFX(ctx context.Context, accountID string, bankAccountID string, data io.Reader, opts Options) (*types.ImportResult, error) {
+	if err := s.requireAccount(ctx, accountID); err != nil {
+		return nil, err
+	}
+
+	stmt, err := parser.ParseOFX(data, bankAccountID)
+	if err != nil {
+		return nil, &types.ValidationError{Field: "file", Message: err.Error()}
+	}
+
+	log.Printf("Importing %d OFX transactions into account %s", len(stmt.Rows), accountID)
+	return s.importRows(ctx, accountID, "ofx", stmt.Rows, statementBalance(stmt), opts)
+}
+
+// statementBalance extracts the closing balance of an OFX statement
+func statementBalance(stmt *parser.OFXStatement) *repository.StatementBalance {
+	if stmt.LedgerBalance == nil && stmt.AvailableBalance == nil {
+		return nil
+	}
+
+	balance := &repository.StatementBalance{AsOf: time.Now()}
+	if b := stmt.AvailableBalance; b != nil {
+		balance.Available = &b.Amount
+		if !b.AsOf.IsZero() {
+			balance.AsOf = b.AsOf
+		}
+	}
+	if b := stmt.LedgerBalance; b != nil {
+		balance.Current = &b.Amount
+		if !b.AsOf.IsZero() {
+			balance.AsOf = b.AsOf
+		}
+	}
+	return balance
+}
+
+// importRows validates the parsed rows, runs the account's categorization
+// rules over them, links them to their merchants, holds back likely
+// duplicates and writes the rest together with the statement balance,
+// building the per-row report as it goes
+func (s *service) importRows(ctx context.Context, accountID string, format string, rows []parser.Row, balance *repository.StatementBalance, opts Options) (*types.ImportResult, error) {
+	result := &types.ImportResult{
+		AccountID:      accountID,
+		Format:         format,
+		DryRun:         opts.DryRun,
+		Rows:           len(rows),
+		TransactionIDs: []string{},
+		DuplicateIDs:   []int{},
+		Errors:         []types.ImportRowError{},
+	}
+
+	categories, err := s.categories.Categories(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	var valid []types.Transaction
+	var validLines []int
+	for _, row := range rows {
+		if row.Err != nil {
+			result.AddError(row.Line, row.Err)
+			continue
+		}
+
+		t := row.Transaction
+		t.AccountID = accountID
+		t.TransactionID = types.NewTransactionID()
+		if row.Fallback != "" && !categories.Has(t.Category) {
+			t.Category = row.Fallback
+		}
+		if err := t.Validate(categories); err != nil {
+			result.AddError(row.Line, err)
+			continue
+		}
+
+		valid = append(valid, t)
+		validLines = append(validLines, row.Line)
+	}
+
+	// Rules run before deduplication so that rows are compared with existing
+	// transactions under the same cleaned merchant names
+	if err := s.rules.Apply(ctx, accountID, valid); err != nil {
+		return nil, fmt.Errorf("failed to apply rules: %w", err)
+	}
+	if err := s.merchants.Normalize(ctx, accountID, valid); err != nil {
+		return nil, fmt.Errorf("failed to normalize merchants: %w", err)
+	}
+
+	batch := repository.Batch{AccountID: accountID, Balance: balance}
+	var pendingLines []int
+	if opts.SkipDedup {
+		batch.Transactions = valid
+		pendingLines = validLines
+	} else {
+		existing, err := s.findExisting(ctx, accountID, valid)
+		if err != nil {
+			return nil, err
+		}
+
+		for i, t := range valid {
+			match := s.dedup.FindDuplicate(t, existing)
+			if match == nil {
+				batch.Transactions = append(batch.Transactions, t)
+				pendingLines = append(pendingLines, validLines[i])
+				continue
+			}
+
+			// Each existing transaction can only absorb one imported row
+			existing = removeTransaction(existing, match.Existing.TransactionID)
+			if t.FITID != "" && t.FITID == match.Existing.FITID {
+				result.Skipped++
+				continue
+			}
+			batch.Duplicates = append(batch.Duplicates, types.ImportDuplicate{
+				AccountID:             accountID,
+				Candidate:             t,
+				ExistingTransactionID: match.Existing.TransactionID,
+				Fingerprint:           dedup.Fingerprint(t),
+				Score:                 match.Score,
+				Reason:                match.Reason,
+			})
+		}
+	}
+
+	if len(batch.Transactions) == 0 && len(batch.Duplicates) == 0 && balance == nil {
+		return result, nil
+	}
+
+	written, err := s.repo.ImportBatch(ctx, batch, opts.DryRun)
+	if err != nil {
+		return nil, fmt.Errorf("failed to import transactions: %w", err)
+	}
+	for i, rowErr := range written.RowErrors {
+		switch {
+		case errors.Is(rowErr, repository.ErrAlreadyImported):
+			result.Skipped++
+		case rowErr != nil:
+			result.AddError(pendingLines[i], rowErr)
+		default:
+			result.Imported++
+			result.TransactionIDs = append(result.TransactionIDs, batch.Transactions[i].TransactionID)
+		}
+	}
+	for _, id := range written.DuplicateIDs {
+		if id == 0 {
+			result.Skipped++
+			continue
+		}
+		result.Flagged++
+		result.DuplicateIDs = append(result.DuplicateIDs, id)
+	}
+
+	if balance != nil {
+		result.Balance = &types.UserBalance{}
+		if balance.Current != nil {
+			result.Balance.Current = *balance.Current
+		}
+		if balance.Available != nil {
+			result.Balance.Available = *balance.Available
+		}
+	}
+
+	log.Printf("Imported %d of %d rows into account %s (%d skipped, %d flagged, %d failed)",
+		result.Imported, result.Rows, accountID, result.Skipped, result.Flagged, result.Failed)
+	return result, nil
+}
+
+// findExisting loads the account's transactions that could be duplicated by
+// the imported rows, i.e. those within the dedup window of the rows' dates
+func (s *service) findExisting(ctx context.Context, accountID string, rows []types.Transaction) ([]types.Transaction, error) {
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	from, to := rows[0].Date, rows[0].Date
+	for _, t := range rows[1:] {
+		if t.Date.Before(from) {
+			from = t.Date
+		}
+		if t.Date.After(to) {
+			to = t.Date
+		}
+	}
+
+	existing, err := s.repo.FindTransactions(ctx, accountID, from.Add(-s.dedup.DateWindow), to.Add(s.dedup.DateWindow))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load transactions for deduplication: %w", err)
+	}
+	return existing, nil
+}
+
+func removeTransaction(transactions []types.Transaction, transactionID string) []types.Transaction {
+	for i, t := range transactions {
+		if t.TransactionID == transactionID {
+			return append(transactions[:i:i], transactions[i+1:]...)
+		}
+	}
+	return transactions
+}
+
+func (s *service) ListDuplicates(ctx context.Context, accountID string, status string) ([]types.ImportDuplicate, error) {
+	switch status {
+	case "", types.DuplicatePending, types.DuplicateMerged, types.DuplicateInserted, types.DuplicateDismissed:
+	default:
+		return nil, &types.ValidationError{Field: "status", Message: fmt.Sprintf("unknown status %q", status)}
+	}
+	if err := s.requireAccount(ctx, accountID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListDuplicates(ctx, accountID, status)
+}
+
+func (s *service) GetDuplicate(ctx context.Context, accountID string, id int) (*types.ImportDuplicate, error) {
+	return s.repo.GetDuplicate(ctx, accountID, id)
+}
+
+// MergeDuplicate resolves a flagged row as a true duplicate of the
+// transaction it matched, keeping the existing transaction
+func (s *service) MergeDuplicate(ctx context.Context, accountID string, id int) (*types.ImportDuplicate, error) {
+	if err := s.repo.MergeDuplicate(ctx, accountID, id); err != nil {
+		return nil, err
+	}
+	return s.repo.GetDuplicate(ctx, accountID, id)
+}
+
+// InsertDuplicate resolves a flagged row as a distinct transaction and
+// inserts it despite the match. Duplicates are held back without their
+// merchant link, so the row is linked before it is inserted.
+func (s *service) InsertDuplicate(ctx context.Context, accountID string, id int) (*types.ImportDuplicate, error) {
+	d, err := s.repo.GetDuplicate(ctx, accountID, id)
+	if err != nil {
+		return nil, err
+	}
+	linked := []types.Transaction{d.Candidate}
+	if err := s.merchants.Link(ctx, accountID, linked); err != nil {
+		return nil, fmt.Errorf("failed to link merchant: %w", err)
+	}
+
+	if err := s.repo.InsertDuplicate(ctx, accountID, id, types.NewTransactionID(), linked[0].MerchantID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetDuplicate(ctx, accountID, id)
+}
+
+// DismissDuplicate discards a flagged row without changing any transaction
+func (s *service) DismissDuplicate(ctx context.Context, accountID string, id int) (*types.ImportDuplicate, error) {
+	if err := s.repo.DismissDuplicate(ctx, accountID, id); err != nil {
+		return nil, err
+	}
+	return s.repo.GetDuplicate(ctx, accountID, id)
+}
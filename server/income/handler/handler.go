@@ -3,10 +3,18 @@ package handler
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
+	"server/fx/rates"
+	fxRepository "server/fx/repository"
+	fxService "server/fx/service"
 	"server/income/repository"
 	"server/income/service"
+	ownersHandler "server/owners/handler"
+	ownersRepository "server/owners/repository"
+	ownersService "server/owners/service"
+	"server/types"
 	"strconv"
 	"time"
 
@@ -15,17 +23,20 @@ import (
 
 type Handler struct {
 	service service.Service
+	owners  ownersService.Service
 }
 
-func NewHandler(service service.Service) *Handler {
-	return &Handler{service: service}
+func NewHandler(service service.Service, owners ownersService.Service) *Handler {
+	return &Handler{service: service, owners: owners}
 }
 
 // SetupIncomeRoutes configures all the income-related routes
 func SetupIncomeRoutes(router *mux.Router, db *sql.DB) {
 	repo := repository.NewPostgresRepository(db)
-	svc := service.NewService(repo)
-	handler := NewHandler(svc)
+	fx := fxService.NewService(fxRepository.NewPostgresRepository(db))
+	svc := service.NewService(repo, fx)
+	owners := ownersService.NewService(ownersRepository.NewPostgresRepository(db))
+	handler := NewHandler(svc, owners)
 	handler.RegisterRoutes(router)
 }
 
@@ -33,17 +44,31 @@ func SetupIncomeRoutes(router *mux.Router, db *sql.DB) {
 func (h *Handler) RegisterRoutes(router *mux.Router) {
 	router.HandleFunc("/api/income/{accountId}", h.HandleGetIncome).Methods("GET")
 	router.HandleFunc("/api/income/{accountId}/monthly", h.HandleGetMonthlyIncome).Methods("GET")
+
+	// The same views across every account linked to an owner
+	router.HandleFunc("/api/owners/{ownerId:[0-9]+}/income", h.HandleGetIncome).Methods("GET")
+	router.HandleFunc("/api/owners/{ownerId:[0-9]+}/income/monthly", h.HandleGetMonthlyIncome).Methods("GET")
 }
 
 // HandleGetIncome handles requests for income data
 func (h *Handler) HandleGetIncome(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	accountID := vars["accountId"]
+	accountIDs, currency, err := ownersHandler.RequestAccounts(r, h.owners)
+	if err != nil {
+		writeError(w, "Failed to get income", err)
+		return
+	}
+	if requested := r.URL.Query().Get("currency"); requested != "" {
+		currency = requested
+	}
+	tags, err := types.ParseTagFilter(r.URL.Query()["tag"])
+	if err != nil {
+		writeError(w, "Failed to get income", err)
+		return
+	}
 
-	income, err := h.service.GetIncome(r.Context(), accountID)
+	income, err := h.service.GetIncome(r.Context(), accountIDs, currency, tags)
 	if err != nil {
-		log.Printf("Error getting income: %v", err)
-		http.Error(w, "Failed to get income", http.StatusInternalServerError)
+		writeError(w, "Failed to get income", err)
 		return
 	}
 
@@ -53,8 +78,14 @@ func (h *Handler) HandleGetIncome(w http.ResponseWriter, r *http.Request) {
 
 // HandleGetMonthlyIncome handles requests for monthly income data
 func (h *Handler) HandleGetMonthlyIncome(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	accountID := vars["accountId"]
+	accountIDs, currency, err := ownersHandler.RequestAccounts(r, h.owners)
+	if err != nil {
+		writeError(w, "Failed to get monthly income", err)
+		return
+	}
+	if requested := r.URL.Query().Get("currency"); requested != "" {
+		currency = requested
+	}
 
 	year, _ := strconv.Atoi(r.URL.Query().Get("year"))
 	month, _ := strconv.Atoi(r.URL.Query().Get("month"))
@@ -64,14 +95,35 @@ func (h *Handler) HandleGetMonthlyIncome(w http.ResponseWriter, r *http.Request)
 		year = now.Year()
 		month = int(now.Month())
 	}
+	tags, err := types.ParseTagFilter(r.URL.Query()["tag"])
+	if err != nil {
+		writeError(w, "Failed to get monthly income", err)
+		return
+	}
 
-	income, err := h.service.GetMonthlyIncome(r.Context(), accountID, year, month)
+	income, err := h.service.GetMonthlyIncome(r.Context(), accountIDs, year, month, currency, tags)
 	if err != nil {
-		log.Printf("Error getting monthly income: %v", err)
-		http.Error(w, "Failed to get monthly income", http.StatusInternalServerError)
+		writeError(w, "Failed to get monthly income", err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(income)
-} 
\ No newline at end of file
+}
+
+// writeError converts service errors into HTTP responses: an invalid or
+// unconvertible reporting currency is the client's error, anything else is ours
+func writeError(w http.ResponseWriter, message string, err error) {
+	var validationErr *types.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		http.Error(w, validationErr.Error(), http.StatusBadRequest)
+	case errors.Is(err, rates.ErrNoRate):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, ownersRepository.ErrOwnerNotFound):
+		http.Error(w, "Owner not found", http.StatusNotFound)
+	default:
+		log.Printf("%s: %v", message, err)
+		http.Error(w, message, http.StatusInternalServerError)
+	}
+}
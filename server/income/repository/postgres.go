@@ -7,6 +7,8 @@ import (
 	"log"
 	"server/types"
 	"time"
+
+	"github.com/lib/pq"
 )
 
 type postgresRepo struct {
@@ -20,22 +22,42 @@ func NewPostgresRepository(db *sql.DB) Repository {
 	return &postgresRepo{db: db}
 }
 
-// GetIncome retrieves all income transactions for an account
-func (r *postgresRepo) GetIncome(ctx context.Context, accountID string) ([]types.Transaction, error) {
-	if accountID == "" {
-		return nil, fmt.Errorf("account ID is required")
+// GetAccountCurrency retrieves the currency of the account's balance; it is
+// empty when the account or its balance is unknown
+func (r *postgresRepo) GetAccountCurrency(ctx context.Context, accountID string) (string, error) {
+	var currency string
+	err := r.db.QueryRowContext(ctx,
+		`SELECT COALESCE(balance_currency, '') FROM users WHERE account_id = $1`, accountID,
+	).Scan(&currency)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		log.Printf("Error fetching account currency: %v", err)
+		return "", fmt.Errorf("failed to fetch account currency: %w", err)
+	}
+	return currency, nil
+}
+
+// GetIncome retrieves all income transactions for an account that carry
+// every tag of the filter
+func (r *postgresRepo) GetIncome(ctx context.Context, accountIDs []string, tags types.TagFilter) ([]types.Transaction, error) {
+	if len(accountIDs) == 0 {
+		return nil, fmt.Errorf("at least one account ID is required")
 	}
 
-	log.Printf("Fetching income transactions for account %s", accountID)
+	log.Printf("Fetching income transactions for accounts %v", accountIDs)
 
 	query := `
-		SELECT transaction_id, account_id, date, amount, category, merchant, location
+		SELECT transaction_id, account_id, date, amount, category, merchant, location, currency
 		FROM transactions 
-		WHERE account_id = $1 
-		  AND category = 'Income'
+		WHERE account_id = ANY($1) 
+		  AND transfer_id IS NULL
+		  AND category_type(account_id, category) = 'income'
+		  AND has_tags(transaction_id, $2)
 		ORDER BY date DESC`
 
-	rows, err := r.db.QueryContext(ctx, query, accountID)
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(accountIDs), pq.Array([]string(tags)))
 	if err != nil {
 		log.Printf("Error querying income transactions: %v", err)
 		return nil, fmt.Errorf("failed to query income transactions: %w", err)
@@ -53,6 +75,7 @@ func (r *postgresRepo) GetIncome(ctx context.Context, accountID string) ([]types
 			&t.Category,
 			&t.Merchant,
 			&t.Location,
+			&t.Currency,
 		); err != nil {
 			log.Printf("Error scanning income transaction: %v", err)
 			return nil, fmt.Errorf("failed to scan income transaction: %w", err)
@@ -65,31 +88,34 @@ func (r *postgresRepo) GetIncome(ctx context.Context, accountID string) ([]types
 		return nil, fmt.Errorf("error iterating income transactions: %w", err)
 	}
 
-	log.Printf("Found %d income transactions for account %s", len(transactions), accountID)
+	log.Printf("Found %d income transactions for accounts %v", len(transactions), accountIDs)
 	return transactions, nil
 }
 
-// GetMonthlyIncome retrieves income transactions for a specific month
-func (r *postgresRepo) GetMonthlyIncome(ctx context.Context, accountID string, year int, month int) ([]types.Transaction, error) {
-	if accountID == "" {
-		return nil, fmt.Errorf("account ID is required")
+// GetMonthlyIncome retrieves income transactions for a specific month that
+// carry every tag of the filter
+func (r *postgresRepo) GetMonthlyIncome(ctx context.Context, accountIDs []string, year int, month int, tags types.TagFilter) ([]types.Transaction, error) {
+	if len(accountIDs) == 0 {
+		return nil, fmt.Errorf("at least one account ID is required")
 	}
 
 	startDate := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
 	endDate := startDate.AddDate(0, 1, 0).Add(-time.Second)
 
-	log.Printf("Fetching income for account %s between %s and %s", accountID, startDate.Format("2006-01-02"), endDate.Format("2006-01-02"))
+	log.Printf("Fetching income for accounts %v between %s and %s", accountIDs, startDate.Format("2006-01-02"), endDate.Format("2006-01-02"))
 
 	query := `
-		SELECT transaction_id, account_id, date, amount, category, merchant, location
+		SELECT transaction_id, account_id, date, amount, category, merchant, location, currency
 		FROM transactions 
-		WHERE account_id = $1 
+		WHERE account_id = ANY($1) 
+		  AND transfer_id IS NULL
 		  AND date >= $2
 		  AND date <= $3
-		  AND category = 'Income'
+		  AND category_type(account_id, category) = 'income'
+		  AND has_tags(transaction_id, $4)
 		ORDER BY date ASC`
 
-	rows, err := r.db.QueryContext(ctx, query, accountID, startDate, endDate)
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(accountIDs), startDate, endDate, pq.Array([]string(tags)))
 	if err != nil {
 		log.Printf("Error querying monthly income: %v", err)
 		return nil, fmt.Errorf("failed to query monthly income: %w", err)
@@ -107,6 +133,7 @@ func (r *postgresRepo) GetMonthlyIncome(ctx context.Context, accountID string, y
 			&t.Category,
 			&t.Merchant,
 			&t.Location,
+			&t.Currency,
 		); err != nil {
 			log.Printf("Error scanning income transaction: %v", err)
 			return nil, fmt.Errorf("failed to scan income transaction: %w", err)
@@ -7,9 +7,14 @@ import (
 
 // Repository defines the interface for income-related data operations
 type Repository interface {
-	// GetIncome retrieves all income transactions for an account
-	GetIncome(ctx context.Context, accountID string) ([]types.Transaction, error)
+	// GetIncome retrieves all income transactions for an account that carry
+	// every tag of the filter
+	GetIncome(ctx context.Context, accountIDs []string, tags types.TagFilter) ([]types.Transaction, error)
 
 	// GetMonthlyIncome retrieves income transactions for a specific month
-	GetMonthlyIncome(ctx context.Context, accountID string, year int, month int) ([]types.Transaction, error)
+	// that carry every tag of the filter
+	GetMonthlyIncome(ctx context.Context, accountIDs []string, year int, month int, tags types.TagFilter) ([]types.Transaction, error)
+
+	// GetAccountCurrency retrieves the currency of the account's balance
+	GetAccountCurrency(ctx context.Context, accountID string) (string, error)
 } 
\ No newline at end of file
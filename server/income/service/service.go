@@ -2,27 +2,55 @@ package service
 
 import (
 	"context"
+	"server/fx/rates"
 	"server/income/repository"
 	"server/types"
 )
 
 type Service interface {
-	GetIncome(ctx context.Context, accountID string) ([]types.Transaction, error)
-	GetMonthlyIncome(ctx context.Context, accountID string, year int, month int) ([]types.Transaction, error)
+	GetIncome(ctx context.Context, accountIDs []string, currency string, tags types.TagFilter) ([]types.Transaction, error)
+	GetMonthlyIncome(ctx context.Context, accountIDs []string, year int, month int, currency string, tags types.TagFilter) ([]types.Transaction, error)
 }
 
 type service struct {
-	repo repository.Repository
+	repo  repository.Repository
+	rates rates.Source
 }
 
-func NewService(repo repository.Repository) Service {
-	return &service{repo: repo}
+func NewService(repo repository.Repository, source rates.Source) Service {
+	return &service{repo: repo, rates: source}
 }
 
-func (s *service) GetIncome(ctx context.Context, accountID string) ([]types.Transaction, error) {
-	return s.repo.GetIncome(ctx, accountID)
+func (s *service) GetIncome(ctx context.Context, accountIDs []string, currency string, tags types.TagFilter) ([]types.Transaction, error) {
+	income, err := s.repo.GetIncome(ctx, accountIDs, tags)
+	if err != nil {
+		return nil, err
+	}
+	return income, s.convert(ctx, accountIDs, currency, income)
 }
 
-func (s *service) GetMonthlyIncome(ctx context.Context, accountID string, year int, month int) ([]types.Transaction, error) {
-	return s.repo.GetMonthlyIncome(ctx, accountID, year, month)
-} 
\ No newline at end of file
+func (s *service) GetMonthlyIncome(ctx context.Context, accountIDs []string, year int, month int, currency string, tags types.TagFilter) ([]types.Transaction, error) {
+	income, err := s.repo.GetMonthlyIncome(ctx, accountIDs, year, month, tags)
+	if err != nil {
+		return nil, err
+	}
+	return income, s.convert(ctx, accountIDs, currency, income)
+}
+
+// convert fills in each transaction's amount in the reporting currency (the
+// first account's currency by default), at the rate for the day it was paid
+func (s *service) convert(ctx context.Context, accountIDs []string, requested string, income []types.Transaction) error {
+	accountCurrency, err := s.repo.GetAccountCurrency(ctx, accountIDs[0])
+	if err != nil {
+		return err
+	}
+	currency, err := rates.ReportingCurrency(requested, accountCurrency)
+	if err != nil {
+		return err
+	}
+	table, err := s.rates.Table(ctx)
+	if err != nil {
+		return err
+	}
+	return table.ConvertTransactions(income, currency)
+}
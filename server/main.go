@@ -1,14 +1,16 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"log"
 	"net/http"
 	"os"
-	analyticsHandler "server/analytics/handler"
-	analyticsRepo "server/analytics/repository"
-	analyticsService "server/analytics/service"
+	fxRepo "server/fx/repository"
+	fxService "server/fx/service"
 	"server/handlers"
+	"server/migrations"
+	"strings"
 	"time"
 
 	gorilla_handlers "github.com/gorilla/handlers"
@@ -51,6 +53,21 @@ func (rw *responseWriter) WriteHeader(statusCode int) {
 	rw.w.WriteHeader(statusCode)
 }
 
+// allowedOrigins reads the comma-separated CORS_ALLOWED_ORIGINS, defaulting to
+// the local frontend dev server
+func allowedOrigins() []string {
+	var origins []string
+	for _, origin := range strings.Split(os.Getenv("CORS_ALLOWED_ORIGINS"), ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			origins = append(origins, origin)
+		}
+	}
+	if len(origins) == 0 {
+		origins = []string{"http://localhost:5173"}
+	}
+	return origins
+}
+
 func main() {
 	// Set up logging with timestamp
 	log.SetFlags(log.LstdFlags | log.Lshortfile)
@@ -80,10 +97,52 @@ func main() {
 	}
 	log.Printf("Successfully connected to database")
 
-	// Initialize repositories and services
-	repo := analyticsRepo.NewPostgresRepository(db)
-	service := analyticsService.NewService(repo)
-	analyticsH := analyticsHandler.NewHandler(service)
+	// "server migrate ..." manages the schema and exits
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(db, os.Args[2:]); err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+		return
+	}
+
+	// Bring the schema up to date before serving requests
+	applied, err := migrations.Up(context.Background(), db)
+	if err != nil {
+		log.Fatalf("Error applying migrations: %v", err)
+	}
+	log.Printf("Database schema is up to date (%d migration(s) applied)", applied)
+
+	// "server claim-code <account_id>" issues an account's claim code and exits
+	if len(os.Args) > 1 && os.Args[1] == "claim-code" {
+		if err := runClaimCode(db, os.Args[2:]); err != nil {
+			log.Fatalf("Issuing claim code failed: %v", err)
+		}
+		return
+	}
+
+	// "server fx-rates ..." loads exchange rates and exits
+	if len(os.Args) > 1 && os.Args[1] == "fx-rates" {
+		if err := runFXRates(db, os.Args[2:]); err != nil {
+			log.Fatalf("Loading exchange rates failed: %v", err)
+		}
+		return
+	}
+
+	// Seed the exchange-rate table from a file and/or an FX API. Failures only
+	// warn: amounts in a single currency need no rates at all.
+	fx := fxService.NewService(fxRepo.NewPostgresRepository(db))
+	if path := os.Getenv("FX_RATES_FILE"); path != "" {
+		if n, err := fx.LoadFile(context.Background(), path); err != nil {
+			log.Printf("Warning: could not load exchange rates from %s: %v", path, err)
+		} else {
+			log.Printf("Loaded %d exchange rates from %s", n, path)
+		}
+	}
+	if url := os.Getenv("FX_RATES_URL"); url != "" {
+		if _, err := fx.Refresh(context.Background(), url); err != nil {
+			log.Printf("Warning: could not refresh exchange rates: %v", err)
+		}
+	}
 
 	// Set up router with middleware
 	router := mux.NewRouter()
@@ -97,15 +156,14 @@ func main() {
 
 	// Register all routes
 	handlers.SetupRoutes(router, db)
-	analyticsH.RegisterRoutes(router)
 
-	// Set up CORS
+	// Set up CORS for the frontend origins only. Tokens travel in the
+	// Authorization header, so no cookies (credentials) are allowed.
 	corsMiddleware := gorilla_handlers.CORS(
-		gorilla_handlers.AllowedOrigins([]string{"*"}),
-		gorilla_handlers.AllowedMethods([]string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}),
+		gorilla_handlers.AllowedOrigins(allowedOrigins()),
+		gorilla_handlers.AllowedMethods([]string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}),
 		gorilla_handlers.AllowedHeaders([]string{"Content-Type", "Authorization"}),
-		gorilla_handlers.ExposedHeaders([]string{"Content-Length"}),
-		gorilla_handlers.AllowCredentials(),
+		gorilla_handlers.ExposedHeaders([]string{"Content-Length", "Content-Disposition"}),
 		gorilla_handlers.MaxAge(3600),
 	)
 
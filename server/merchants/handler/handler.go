@@ -0,0 +1,199 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	categoriesRepository "server/categories/repository"
+	categoriesService "server/categories/service"
+	"server/fx/rates"
+	fxRepository "server/fx/repository"
+	fxService "server/fx/service"
+	"server/merchants/repository"
+	"server/merchants/service"
+	"server/types"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+type Handler struct {
+	service service.Service
+}
+
+func NewHandler(service service.Service) *Handler {
+	return &Handler{service: service}
+}
+
+// SetupMerchantRoutes configures all the merchant directory routes
+func SetupMerchantRoutes(router *mux.Router, db *sql.DB) {
+	repo := repository.NewPostgresRepository(db)
+	fx := fxService.NewService(fxRepository.NewPostgresRepository(db))
+	categories := categoriesService.NewService(categoriesRepository.NewPostgresRepository(db), fx)
+	svc := service.NewService(repo, categories, fx)
+	handler := NewHandler(svc)
+	handler.RegisterRoutes(router)
+}
+
+// RegisterRoutes registers all merchant routes
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/api/merchants/{accountId}", h.HandleListMerchants).Methods("GET")
+	router.HandleFunc("/api/merchants/{accountId}", h.HandleCreateMerchant).Methods("POST")
+	router.HandleFunc("/api/merchants/{accountId}/spend", h.HandleGetSpend).Methods("GET")
+	router.HandleFunc("/api/merchants/{accountId}/{merchantId:[0-9]+}", h.HandleGetMerchant).Methods("GET")
+	router.HandleFunc("/api/merchants/{accountId}/{merchantId:[0-9]+}", h.HandleUpdateMerchant).Methods("PUT")
+	router.HandleFunc("/api/merchants/{accountId}/{merchantId:[0-9]+}", h.HandleDeleteMerchant).Methods("DELETE")
+}
+
+// HandleListMerchants handles requests for an account's merchants
+func (h *Handler) HandleListMerchants(w http.ResponseWriter, r *http.Request) {
+	accountID := mux.Vars(r)["accountId"]
+
+	merchants, err := h.service.ListMerchants(r.Context(), accountID)
+	if err != nil {
+		writeError(w, "Failed to get merchants", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(merchants)
+}
+
+// HandleCreateMerchant handles requests to create a merchant
+func (h *Handler) HandleCreateMerchant(w http.ResponseWriter, r *http.Request) {
+	accountID := mux.Vars(r)["accountId"]
+
+	var input types.Merchant
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	merchant, err := h.service.CreateMerchant(r.Context(), accountID, input)
+	if err != nil {
+		writeError(w, "Failed to create merchant", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(merchant)
+}
+
+// HandleGetMerchant handles requests for a single merchant
+func (h *Handler) HandleGetMerchant(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	merchantID, _ := strconv.ParseInt(vars["merchantId"], 10, 64)
+
+	merchant, err := h.service.GetMerchant(r.Context(), vars["accountId"], merchantID)
+	if err != nil {
+		writeError(w, "Failed to get merchant", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(merchant)
+}
+
+// HandleUpdateMerchant handles requests to replace a merchant
+func (h *Handler) HandleUpdateMerchant(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	merchantID, _ := strconv.ParseInt(vars["merchantId"], 10, 64)
+
+	var input types.Merchant
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	merchant, err := h.service.UpdateMerchant(r.Context(), vars["accountId"], merchantID, input)
+	if err != nil {
+		writeError(w, "Failed to update merchant", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(merchant)
+}
+
+// HandleDeleteMerchant handles requests to delete a merchant
+func (h *Handler) HandleDeleteMerchant(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	merchantID, _ := strconv.ParseInt(vars["merchantId"], 10, 64)
+
+	if err := h.service.DeleteMerchant(r.Context(), vars["accountId"], merchantID); err != nil {
+		writeError(w, "Failed to delete merchant", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleGetSpend handles requests for the spending per merchant and month.
+// from and to are months (YYYY-MM) and default to the last twelve;
+// merchant_id limits the report to one merchant.
+func (h *Handler) HandleGetSpend(w http.ResponseWriter, r *http.Request) {
+	accountID := mux.Vars(r)["accountId"]
+	query := r.URL.Query()
+
+	to := time.Now()
+	if value := query.Get("to"); value != "" {
+		parsed, err := time.Parse("2006-01", value)
+		if err != nil {
+			http.Error(w, "Invalid to month: expected YYYY-MM", http.StatusBadRequest)
+			return
+		}
+		to = parsed
+	}
+	from := to.AddDate(0, -11, 0)
+	if value := query.Get("from"); value != "" {
+		parsed, err := time.Parse("2006-01", value)
+		if err != nil {
+			http.Error(w, "Invalid from month: expected YYYY-MM", http.StatusBadRequest)
+			return
+		}
+		from = parsed
+	}
+
+	var merchantID int64
+	if value := query.Get("merchant_id"); value != "" {
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || parsed <= 0 {
+			http.Error(w, "Invalid merchant_id", http.StatusBadRequest)
+			return
+		}
+		merchantID = parsed
+	}
+
+	report, err := h.service.GetSpend(r.Context(), accountID, from, to, query.Get("currency"), merchantID)
+	if err != nil {
+		writeError(w, "Failed to get merchant spending", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// writeError converts service and repository errors into HTTP responses
+func writeError(w http.ResponseWriter, message string, err error) {
+	var validationErr *types.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		http.Error(w, validationErr.Error(), http.StatusBadRequest)
+	case errors.Is(err, rates.ErrNoRate):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, repository.ErrAccountNotFound):
+		http.Error(w, "Account not found", http.StatusNotFound)
+	case errors.Is(err, repository.ErrMerchantNotFound):
+		http.Error(w, "Merchant not found", http.StatusNotFound)
+	case errors.Is(err, repository.ErrMerchantExists), errors.Is(err, repository.ErrAliasTaken):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		log.Printf("%s: %v", message, err)
+		http.Error(w, message, http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,81 @@
+package normalizer
+
+import (
+	"server/imports/dedup"
+	"server/types"
+	"sort"
+	"strings"
+)
+
+// Normalize reduces a merchant description to the lowercase words that
+// aliases are matched on, dropping store numbers, reference codes,
+// punctuation and bank noise words: "AMZN Mktp US*2K3" becomes
+// "amzn mktp us"
+func Normalize(description string) string {
+	return dedup.NormalizeMerchant(description)
+}
+
+// NormalizeAliases normalizes a merchant's aliases, dropping empty ones and
+// duplicates, and sorts them
+func NormalizeAliases(aliases []string) []string {
+	seen := make(map[string]bool, len(aliases))
+	normalized := make([]string, 0, len(aliases))
+	for _, alias := range aliases {
+		alias = Normalize(alias)
+		if alias == "" || seen[alias] {
+			continue
+		}
+		seen[alias] = true
+		normalized = append(normalized, alias)
+	}
+	sort.Strings(normalized)
+	return normalized
+}
+
+// Directory matches merchant descriptions to an account's canonical merchants
+type Directory struct {
+	keys []key
+}
+
+// key is a normalized alias or merchant name
+type key struct {
+	words    string
+	merchant *types.Merchant
+}
+
+// NewDirectory indexes the merchants by their aliases and by their own
+// normalized names
+func NewDirectory(merchants []types.Merchant) *Directory {
+	d := &Directory{}
+	for i := range merchants {
+		m := &merchants[i]
+		for _, words := range append([]string{Normalize(m.Name)}, m.Aliases...) {
+			if words != "" {
+				d.keys = append(d.keys, key{words: words, merchant: m})
+			}
+		}
+	}
+
+	// The longest key is tried first, so "amazon prime" can belong to a
+	// different merchant than "amazon"
+	sort.SliceStable(d.keys, func(i, j int) bool {
+		return len(d.keys[i].words) > len(d.keys[j].words)
+	})
+	return d
+}
+
+// Match returns the merchant a description belongs to, or nil. A key matches
+// a description whose normalized words are the key or begin with it, so the
+// alias "amzn mktp" covers "AMZN Mktp US*2K3" and "AMZN MKTP DE".
+func (d *Directory) Match(description string) *types.Merchant {
+	words := Normalize(description)
+	if words == "" {
+		return nil
+	}
+	for _, k := range d.keys {
+		if words == k.words || strings.HasPrefix(words, k.words+" ") {
+			return k.merchant
+		}
+	}
+	return nil
+}
@@ -0,0 +1,316 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"server/types"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type postgresRepo struct {
+	db *sql.DB
+}
+
+func NewPostgresRepository(db *sql.DB) Repository {
+	if db == nil {
+		panic("database connection is required")
+	}
+	return &postgresRepo{db: db}
+}
+
+// GetAccountCurrency retrieves the currency of the account's balance; it is
+// empty when the account or its balance is unknown
+func (r *postgresRepo) GetAccountCurrency(ctx context.Context, accountID string) (string, error) {
+	var currency string
+	err := r.db.QueryRowContext(ctx,
+		`SELECT COALESCE(balance_currency, '') FROM users WHERE account_id = $1`, accountID,
+	).Scan(&currency)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		log.Printf("Error fetching account currency: %v", err)
+		return "", fmt.Errorf("failed to fetch account currency: %w", err)
+	}
+	return currency, nil
+}
+
+// merchantColumns lists the merchant columns of m together with its aliases
+const merchantColumns = `
+	m.id, m.account_id, m.name, m.default_category,
+	ARRAY(SELECT a.alias FROM merchant_aliases a WHERE a.merchant_id = m.id ORDER BY a.alias),
+	m.created_at, m.updated_at`
+
+// ListMerchants retrieves an account's merchants with their aliases
+func (r *postgresRepo) ListMerchants(ctx context.Context, accountID string) ([]types.Merchant, error) {
+	query := `SELECT` + merchantColumns + ` FROM merchants m WHERE m.account_id = $1 ORDER BY m.name`
+
+	rows, err := r.db.QueryContext(ctx, query, accountID)
+	if err != nil {
+		log.Printf("Error querying merchants: %v", err)
+		return nil, fmt.Errorf("failed to query merchants: %w", err)
+	}
+	defer rows.Close()
+
+	merchants := []types.Merchant{}
+	for rows.Next() {
+		merchant, err := scanMerchant(rows)
+		if err != nil {
+			log.Printf("Error scanning merchant: %v", err)
+			return nil, fmt.Errorf("failed to scan merchant: %w", err)
+		}
+		merchants = append(merchants, *merchant)
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("Error iterating merchants: %v", err)
+		return nil, fmt.Errorf("error iterating merchants: %w", err)
+	}
+	return merchants, nil
+}
+
+// GetMerchant retrieves a single merchant of an account with its aliases
+func (r *postgresRepo) GetMerchant(ctx context.Context, accountID string, merchantID int64) (*types.Merchant, error) {
+	query := `SELECT` + merchantColumns + ` FROM merchants m WHERE m.account_id = $1 AND m.id = $2`
+
+	merchant, err := scanMerchant(r.db.QueryRowContext(ctx, query, accountID, merchantID))
+	if err == sql.ErrNoRows {
+		return nil, ErrMerchantNotFound
+	}
+	if err != nil {
+		log.Printf("Error fetching merchant %d: %v", merchantID, err)
+		return nil, fmt.Errorf("failed to fetch merchant: %w", err)
+	}
+	return merchant, nil
+}
+
+// CreateMerchant inserts a merchant and its aliases in a single database
+// transaction, setting its ID and timestamps
+func (r *postgresRepo) CreateMerchant(ctx context.Context, merchant *types.Merchant) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO merchants (account_id, name, default_category)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at, updated_at`,
+		merchant.AccountID, merchant.Name, merchant.DefaultCategory,
+	).Scan(&merchant.ID, &merchant.CreatedAt, &merchant.UpdatedAt)
+	if err != nil {
+		return translateError(err)
+	}
+	if err := insertAliasesTx(ctx, tx, merchant); err != nil {
+		return translateError(err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit merchant: %w", err)
+	}
+	return nil
+}
+
+// UpdateMerchant replaces a merchant's name, default category and aliases in
+// a single database transaction
+func (r *postgresRepo) UpdateMerchant(ctx context.Context, merchant *types.Merchant) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE merchants SET name = $3, default_category = $4, updated_at = NOW()
+		WHERE account_id = $1 AND id = $2`,
+		merchant.AccountID, merchant.ID, merchant.Name, merchant.DefaultCategory,
+	)
+	if err != nil {
+		return translateError(err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrMerchantNotFound
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM merchant_aliases WHERE merchant_id = $1`, merchant.ID); err != nil {
+		return translateError(err)
+	}
+	if err := insertAliasesTx(ctx, tx, merchant); err != nil {
+		return translateError(err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit merchant: %w", err)
+	}
+	return nil
+}
+
+func insertAliasesTx(ctx context.Context, tx *sql.Tx, merchant *types.Merchant) error {
+	if len(merchant.Aliases) == 0 {
+		return nil
+	}
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO merchant_aliases (merchant_id, account_id, alias)
+		SELECT $1, $2, unnest($3::text[])`,
+		merchant.ID, merchant.AccountID, pq.Array(merchant.Aliases),
+	)
+	return err
+}
+
+// DeleteMerchant removes a merchant; its transactions are unlinked by the
+// foreign key
+func (r *postgresRepo) DeleteMerchant(ctx context.Context, accountID string, merchantID int64) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM merchants WHERE account_id = $1 AND id = $2`, accountID, merchantID)
+	if err != nil {
+		log.Printf("Error deleting merchant %d: %v", merchantID, err)
+		return fmt.Errorf("failed to delete merchant: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrMerchantNotFound
+	}
+	return nil
+}
+
+// ListTransactionMerchants retrieves the ID, merchant description and linked
+// merchant of each of the account's transactions
+func (r *postgresRepo) ListTransactionMerchants(ctx context.Context, accountID string) ([]types.Transaction, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT transaction_id, account_id, merchant, merchant_id FROM transactions WHERE account_id = $1`,
+		accountID,
+	)
+	if err != nil {
+		log.Printf("Error querying transaction merchants: %v", err)
+		return nil, fmt.Errorf("failed to query transaction merchants: %w", err)
+	}
+	defer rows.Close()
+
+	transactions := []types.Transaction{}
+	for rows.Next() {
+		var t types.Transaction
+		if err := rows.Scan(&t.TransactionID, &t.AccountID, &t.Merchant, &t.MerchantID); err != nil {
+			log.Printf("Error scanning transaction merchant: %v", err)
+			return nil, fmt.Errorf("failed to scan transaction merchant: %w", err)
+		}
+		transactions = append(transactions, t)
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("Error iterating transaction merchants: %v", err)
+		return nil, fmt.Errorf("error iterating transaction merchants: %w", err)
+	}
+	return transactions, nil
+}
+
+// LinkTransactions saves the MerchantID of each transaction in a single
+// database transaction
+func (r *postgresRepo) LinkTransactions(ctx context.Context, accountID string, transactions []types.Transaction) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `UPDATE transactions SET merchant_id = $3 WHERE account_id = $1 AND transaction_id = $2`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare merchant link: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, t := range transactions {
+		if _, err := stmt.ExecContext(ctx, accountID, t.TransactionID, t.MerchantID); err != nil {
+			log.Printf("Error linking transaction %s: %v", t.TransactionID, err)
+			return fmt.Errorf("failed to link transaction: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit merchant links: %w", err)
+	}
+	log.Printf("Relinked %d transactions of account %s to merchants", len(transactions), accountID)
+	return nil
+}
+
+// ListSpending retrieves the account's spending between from and to,
+// excluding transfers, with linked transactions under their canonical
+// merchant name
+func (r *postgresRepo) ListSpending(ctx context.Context, accountID string, from, to time.Time, merchantID int64) ([]types.Transaction, error) {
+	query := `
+		SELECT t.transaction_id, t.account_id, t.date, t.amount, t.category,
+			COALESCE(m.name, t.merchant), t.location,
+			COALESCE(NULLIF(t.currency, ''), NULLIF(u.balance_currency, ''), 'USD'),
+			t.merchant_id
+		FROM transactions t
+		JOIN users u ON u.account_id = t.account_id
+		LEFT JOIN merchants m ON m.id = t.merchant_id
+		WHERE t.account_id = $1
+		  AND t.transfer_id IS NULL
+		  AND category_type(t.account_id, t.category) IN ('expense', 'bill')
+		  AND t.date >= $2
+		  AND t.date < $3
+		  AND ($4 = 0 OR t.merchant_id = $4)
+		ORDER BY t.date`
+
+	rows, err := r.db.QueryContext(ctx, query, accountID, from, to, merchantID)
+	if err != nil {
+		log.Printf("Error querying merchant spending: %v", err)
+		return nil, fmt.Errorf("failed to query merchant spending: %w", err)
+	}
+	defer rows.Close()
+
+	transactions := []types.Transaction{}
+	for rows.Next() {
+		var t types.Transaction
+		if err := rows.Scan(
+			&t.TransactionID, &t.AccountID, &t.Date, &t.Amount, &t.Category,
+			&t.Merchant, &t.Location, &t.Currency, &t.MerchantID,
+		); err != nil {
+			log.Printf("Error scanning transaction: %v", err)
+			return nil, fmt.Errorf("failed to scan transaction: %w", err)
+		}
+		transactions = append(transactions, t)
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("Error iterating transactions: %v", err)
+		return nil, fmt.Errorf("error iterating transactions: %w", err)
+	}
+	return transactions, nil
+}
+
+// translateError maps Postgres constraint violations onto the repository errors
+func translateError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code.Name() {
+		case "unique_violation":
+			if pqErr.Table == "merchant_aliases" {
+				return ErrAliasTaken
+			}
+			return ErrMerchantExists
+		case "foreign_key_violation":
+			return ErrAccountNotFound
+		}
+	}
+	log.Printf("Error writing merchant: %v", err)
+	return fmt.Errorf("failed to write merchant: %w", err)
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanMerchant(row rowScanner) (*types.Merchant, error) {
+	var m types.Merchant
+	var aliases pq.StringArray
+	if err := row.Scan(&m.ID, &m.AccountID, &m.Name, &m.DefaultCategory, &aliases, &m.CreatedAt, &m.UpdatedAt); err != nil {
+		return nil, err
+	}
+	m.Aliases = aliases
+	if m.Aliases == nil {
+		m.Aliases = []string{}
+	}
+	return &m, nil
+}
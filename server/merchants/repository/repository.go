@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"server/types"
+	"time"
+)
+
+var (
+	// ErrAccountNotFound is returned when the account does not exist
+	ErrAccountNotFound = errors.New("account not found")
+
+	// ErrMerchantNotFound is returned when the merchant does not exist for the account
+	ErrMerchantNotFound = errors.New("merchant not found")
+
+	// ErrMerchantExists is returned when the account already has a merchant with the name
+	ErrMerchantExists = errors.New("a merchant with this name already exists")
+
+	// ErrAliasTaken is returned when an alias already belongs to another merchant of the account
+	ErrAliasTaken = errors.New("alias already belongs to another merchant")
+)
+
+// Repository defines the interface for merchant directory data operations
+type Repository interface {
+	// GetAccountCurrency retrieves the currency of the account's balance; it
+	// is empty when the account or its balance is unknown
+	GetAccountCurrency(ctx context.Context, accountID string) (string, error)
+
+	// ListMerchants retrieves an account's merchants with their aliases
+	ListMerchants(ctx context.Context, accountID string) ([]types.Merchant, error)
+
+	// GetMerchant retrieves a single merchant of an account with its aliases
+	GetMerchant(ctx context.Context, accountID string, merchantID int64) (*types.Merchant, error)
+
+	// CreateMerchant inserts a merchant and its aliases, setting its ID and timestamps
+	CreateMerchant(ctx context.Context, merchant *types.Merchant) error
+
+	// UpdateMerchant replaces a merchant's name, default category and aliases
+	UpdateMerchant(ctx context.Context, merchant *types.Merchant) error
+
+	// DeleteMerchant removes a merchant; its transactions are unlinked
+	DeleteMerchant(ctx context.Context, accountID string, merchantID int64) error
+
+	// ListTransactionMerchants retrieves the ID, merchant description and
+	// linked merchant of each of the account's transactions
+	ListTransactionMerchants(ctx context.Context, accountID string) ([]types.Transaction, error)
+
+	// LinkTransactions saves the MerchantID of each transaction
+	LinkTransactions(ctx context.Context, accountID string, transactions []types.Transaction) error
+
+	// ListSpending retrieves the account's spending between from and to,
+	// excluding transfers, with the currency resolved. Linked transactions
+	// carry the canonical merchant name. A non-zero merchantID limits the
+	// result to that merchant.
+	ListSpending(ctx context.Context, accountID string, from, to time.Time, merchantID int64) ([]types.Transaction, error)
+}
@@ -0,0 +1,299 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	categoriesService "server/categories/service"
+	"server/fx/rates"
+	"server/merchants/normalizer"
+	"server/merchants/repository"
+	"server/types"
+	"sort"
+	"time"
+)
+
+// maxSpendMonths limits how many months a spend report may cover
+const maxSpendMonths = 120
+
+type Service interface {
+	ListMerchants(ctx context.Context, accountID string) ([]types.Merchant, error)
+	GetMerchant(ctx context.Context, accountID string, merchantID int64) (*types.Merchant, error)
+	CreateMerchant(ctx context.Context, accountID string, input types.Merchant) (*types.Merchant, error)
+	UpdateMerchant(ctx context.Context, accountID string, merchantID int64, input types.Merchant) (*types.Merchant, error)
+	DeleteMerchant(ctx context.Context, accountID string, merchantID int64) error
+	GetSpend(ctx context.Context, accountID string, from, to time.Time, currency string, merchantID int64) (*types.MerchantSpendReport, error)
+	Link(ctx context.Context, accountID string, transactions []types.Transaction) error
+	Relink(ctx context.Context, accountID string) error
+	Normalize(ctx context.Context, accountID string, transactions []types.Transaction) error
+}
+
+type service struct {
+	repo       repository.Repository
+	categories categoriesService.Service
+	rates      rates.Source
+}
+
+func NewService(repo repository.Repository, categories categoriesService.Service, source rates.Source) Service {
+	return &service{repo: repo, categories: categories, rates: source}
+}
+
+func (s *service) ListMerchants(ctx context.Context, accountID string) ([]types.Merchant, error) {
+	return s.repo.ListMerchants(ctx, accountID)
+}
+
+func (s *service) GetMerchant(ctx context.Context, accountID string, merchantID int64) (*types.Merchant, error) {
+	return s.repo.GetMerchant(ctx, accountID, merchantID)
+}
+
+// CreateMerchant adds a canonical merchant and links the account's existing
+// transactions that match it
+func (s *service) CreateMerchant(ctx context.Context, accountID string, input types.Merchant) (*types.Merchant, error) {
+	categories, err := s.categories.Categories(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	if err := input.Validate(categories); err != nil {
+		return nil, err
+	}
+	input.AccountID = accountID
+	input.Aliases = normalizer.NormalizeAliases(input.Aliases)
+
+	if err := s.repo.CreateMerchant(ctx, &input); err != nil {
+		return nil, err
+	}
+	if err := s.Relink(ctx, accountID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetMerchant(ctx, accountID, input.ID)
+}
+
+// UpdateMerchant replaces a merchant's name, default category and aliases and
+// relinks the account's transactions
+func (s *service) UpdateMerchant(ctx context.Context, accountID string, merchantID int64, input types.Merchant) (*types.Merchant, error) {
+	categories, err := s.categories.Categories(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	if err := input.Validate(categories); err != nil {
+		return nil, err
+	}
+	input.AccountID = accountID
+	input.ID = merchantID
+	input.Aliases = normalizer.NormalizeAliases(input.Aliases)
+
+	if err := s.repo.UpdateMerchant(ctx, &input); err != nil {
+		return nil, err
+	}
+	if err := s.Relink(ctx, accountID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetMerchant(ctx, accountID, merchantID)
+}
+
+// DeleteMerchant removes a merchant. Its transactions are relinked, so those
+// that match another merchant move to it.
+func (s *service) DeleteMerchant(ctx context.Context, accountID string, merchantID int64) error {
+	if err := s.repo.DeleteMerchant(ctx, accountID, merchantID); err != nil {
+		return err
+	}
+	return s.Relink(ctx, accountID)
+}
+
+// Relink matches every transaction of the account against the merchant
+// directory again and saves the links that changed
+func (s *service) Relink(ctx context.Context, accountID string) error {
+	directory, err := s.directory(ctx, accountID)
+	if err != nil {
+		return err
+	}
+	transactions, err := s.repo.ListTransactionMerchants(ctx, accountID)
+	if err != nil {
+		return err
+	}
+
+	var changed []types.Transaction
+	for _, t := range transactions {
+		var merchantID *int64
+		if m := directory.Match(t.Merchant); m != nil {
+			merchantID = &m.ID
+		}
+		if !sameID(t.MerchantID, merchantID) {
+			t.MerchantID = merchantID
+			changed = append(changed, t)
+		}
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+	if err := s.repo.LinkTransactions(ctx, accountID, changed); err != nil {
+		return fmt.Errorf("failed to relink transactions: %w", err)
+	}
+	return nil
+}
+
+// Link sets the MerchantID of transactions that are about to be saved from
+// their merchant descriptions, clearing it where no merchant matches
+func (s *service) Link(ctx context.Context, accountID string, transactions []types.Transaction) error {
+	_, err := s.link(ctx, accountID, transactions)
+	return err
+}
+
+// Normalize links transactions that are about to be inserted to their
+// merchants and gives those filed under Other the merchant's default
+// category, when it fits the sign of the amount
+func (s *service) Normalize(ctx context.Context, accountID string, transactions []types.Transaction) error {
+	merchants, err := s.link(ctx, accountID, transactions)
+	if err != nil {
+		return err
+	}
+
+	var categories types.Categories
+	for i, m := range merchants {
+		t := &transactions[i]
+		if m == nil || m.DefaultCategory == "" || t.Category != types.CategoryOther {
+			continue
+		}
+		if categories == nil {
+			if categories, err = s.categories.Categories(ctx, accountID); err != nil {
+				return err
+			}
+		}
+		if categories.FitsSign(m.DefaultCategory, t.Amount) {
+			t.Category = m.DefaultCategory
+		}
+	}
+	return nil
+}
+
+// link sets the MerchantID of each transaction and returns the merchant each
+// one matched, if any
+func (s *service) link(ctx context.Context, accountID string, transactions []types.Transaction) ([]*types.Merchant, error) {
+	if len(transactions) == 0 {
+		return nil, nil
+	}
+	directory, err := s.directory(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	matched := make([]*types.Merchant, len(transactions))
+	for i := range transactions {
+		t := &transactions[i]
+		t.MerchantID = nil
+		if m := directory.Match(t.Merchant); m != nil {
+			id := m.ID
+			t.MerchantID = &id
+			matched[i] = m
+		}
+	}
+	return matched, nil
+}
+
+func (s *service) directory(ctx context.Context, accountID string) (*normalizer.Directory, error) {
+	merchants, err := s.repo.ListMerchants(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	return normalizer.NewDirectory(merchants), nil
+}
+
+// GetSpend reports the account's spending per merchant for each month from
+// the month of from through the month of to, converted into the reporting
+// currency at the rate for each transaction's date. Merchants are ordered by
+// total spending, largest first.
+func (s *service) GetSpend(ctx context.Context, accountID string, from, to time.Time, currency string, merchantID int64) (*types.MerchantSpendReport, error) {
+	from = time.Date(from.Year(), from.Month(), 1, 0, 0, 0, 0, time.UTC)
+	to = time.Date(to.Year(), to.Month(), 1, 0, 0, 0, 0, time.UTC)
+	if to.Before(from) {
+		return nil, &types.ValidationError{Field: "to", Message: "must not be before from"}
+	}
+	months := (to.Year()-from.Year())*12 + int(to.Month()-from.Month()) + 1
+	if months > maxSpendMonths {
+		return nil, &types.ValidationError{Field: "from", Message: fmt.Sprintf("a report covers at most %d months", maxSpendMonths)}
+	}
+
+	if merchantID != 0 {
+		if _, err := s.repo.GetMerchant(ctx, accountID, merchantID); err != nil {
+			return nil, err
+		}
+	}
+	accountCurrency, err := s.repo.GetAccountCurrency(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	currency, err = rates.ReportingCurrency(currency, accountCurrency)
+	if err != nil {
+		return nil, err
+	}
+	table, err := s.rates.Table(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	transactions, err := s.repo.ListSpending(ctx, accountID, from, to.AddDate(0, 1, 0), merchantID)
+	if err != nil {
+		return nil, err
+	}
+	if err := table.ConvertTransactions(transactions, currency); err != nil {
+		return nil, err
+	}
+
+	report := &types.MerchantSpendReport{
+		AccountID: accountID,
+		Currency:  currency,
+		From:      from.Format("2006-01"),
+		To:        to.Format("2006-01"),
+		Merchants: []types.MerchantSpend{},
+	}
+	index := map[string]int{}
+	for _, t := range transactions {
+		key := "name:" + t.Merchant
+		if t.MerchantID != nil {
+			key = fmt.Sprintf("id:%d", *t.MerchantID)
+		}
+		i, ok := index[key]
+		if !ok {
+			i = len(report.Merchants)
+			index[key] = i
+			report.Merchants = append(report.Merchants, newMerchantSpend(t, from, months, currency))
+		}
+
+		spend := &report.Merchants[i]
+		amount := t.ConvertedAmount.Abs()
+		spend.Total = spend.Total.Add(amount)
+		spend.Count++
+		month := &spend.Months[(t.Date.Year()-from.Year())*12+int(t.Date.Month()-from.Month())]
+		month.Total = month.Total.Add(amount)
+		month.Count++
+	}
+
+	sort.SliceStable(report.Merchants, func(i, j int) bool {
+		return report.Merchants[i].Total.Cmp(report.Merchants[j].Total) > 0
+	})
+	return report, nil
+}
+
+// newMerchantSpend starts the spending of the transaction's merchant with an
+// empty entry for every month of the report
+func newMerchantSpend(t types.Transaction, from time.Time, months int, currency string) types.MerchantSpend {
+	spend := types.MerchantSpend{
+		MerchantID: t.MerchantID,
+		Merchant:   t.Merchant,
+		Total:      types.NewMoney(0, currency),
+		Months:     make([]types.MerchantMonth, months),
+	}
+	for i := range spend.Months {
+		spend.Months[i] = types.MerchantMonth{
+			Month: from.AddDate(0, i, 0).Format("2006-01"),
+			Total: types.NewMoney(0, currency),
+		}
+	}
+	return spend
+}
+
+func sameID(a, b *int64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
@@ -0,0 +1,258 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed sql/*.sql
+var files embed.FS
+
+// lockID is the Postgres advisory lock key held while a migration runs, so
+// that several server instances starting at once apply each step only once
+const lockID = 72_000_001
+
+// Migration is a single versioned schema change, read from a pair of
+// sql/NNNN_name.up.sql and sql/NNNN_name.down.sql files
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status reports whether a migration has been applied to the database
+type Status struct {
+	Version   int
+	Name      string
+	AppliedAt *time.Time
+}
+
+// Load returns the embedded migrations ordered by version
+func Load() ([]Migration, error) {
+	entries, err := fs.ReadDir(files, "sql")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		name := entry.Name()
+		base, direction, ok := splitFilename(name)
+		if !ok {
+			return nil, fmt.Errorf("invalid migration filename %q: expected NNNN_name.up.sql or NNNN_name.down.sql", name)
+		}
+
+		prefix, label, _ := strings.Cut(base, "_")
+		version, err := strconv.Atoi(prefix)
+		if err != nil || version <= 0 || label == "" {
+			return nil, fmt.Errorf("invalid migration filename %q: expected NNNN_name.up.sql or NNNN_name.down.sql", name)
+		}
+
+		body, err := fs.ReadFile(files, path.Join("sql", name))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", name, err)
+		}
+
+		m := byVersion[version]
+		if m == nil {
+			m = &Migration{Version: version, Name: label}
+			byVersion[version] = m
+		}
+		if m.Name != label {
+			return nil, fmt.Errorf("migration %d has two names: %q and %q", version, m.Name, label)
+		}
+		if direction == "up" {
+			m.Up = string(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if strings.TrimSpace(m.Up) == "" || strings.TrimSpace(m.Down) == "" {
+			return nil, fmt.Errorf("migration %04d_%s needs both an up and a down file", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+func splitFilename(name string) (base, direction string, ok bool) {
+	for _, direction := range []string{"up", "down"} {
+		suffix := "." + direction + ".sql"
+		if strings.HasSuffix(name, suffix) {
+			return strings.TrimSuffix(name, suffix), direction, true
+		}
+	}
+	return "", "", false
+}
+
+// Up applies every pending migration in version order and returns how many
+// were applied. Each migration runs in its own database transaction, so a
+// failing step leaves the schema at the last successful version.
+func Up(ctx context.Context, db *sql.DB) (int, error) {
+	migrations, err := Load()
+	if err != nil {
+		return 0, err
+	}
+	if err := ensureTable(ctx, db); err != nil {
+		return 0, err
+	}
+
+	applied := 0
+	for _, m := range migrations {
+		ran, err := run(ctx, db, m, true)
+		if err != nil {
+			return applied, err
+		}
+		if ran {
+			applied++
+		}
+	}
+	return applied, nil
+}
+
+// Down rolls back the given number of applied migrations, newest first, and
+// returns how many were rolled back
+func Down(ctx context.Context, db *sql.DB, steps int) (int, error) {
+	migrations, err := Load()
+	if err != nil {
+		return 0, err
+	}
+	if err := ensureTable(ctx, db); err != nil {
+		return 0, err
+	}
+
+	statuses, err := status(ctx, db, migrations)
+	if err != nil {
+		return 0, err
+	}
+
+	rolledBack := 0
+	for i := len(migrations) - 1; i >= 0 && rolledBack < steps; i-- {
+		if statuses[i].AppliedAt == nil {
+			continue
+		}
+		ran, err := run(ctx, db, migrations[i], false)
+		if err != nil {
+			return rolledBack, err
+		}
+		if ran {
+			rolledBack++
+		}
+	}
+	return rolledBack, nil
+}
+
+// List reports every known migration and when it was applied
+func List(ctx context.Context, db *sql.DB) ([]Status, error) {
+	migrations, err := Load()
+	if err != nil {
+		return nil, err
+	}
+	if err := ensureTable(ctx, db); err != nil {
+		return nil, err
+	}
+	return status(ctx, db, migrations)
+}
+
+func ensureTable(ctx context.Context, db *sql.DB) error {
+	query := `
+		CREATE TABLE IF NOT EXISTS migrations (
+			version INTEGER PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			applied_at TIMESTAMP NOT NULL DEFAULT NOW()
+		)`
+
+	if _, err := db.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("failed to create migrations table: %w", err)
+	}
+	return nil
+}
+
+func status(ctx context.Context, db *sql.DB, migrations []Migration) ([]Status, error) {
+	rows, err := db.QueryContext(ctx, `SELECT version, applied_at FROM migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan migration: %w", err)
+		}
+		applied[version] = appliedAt
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating migrations: %w", err)
+	}
+
+	statuses := make([]Status, len(migrations))
+	for i, m := range migrations {
+		statuses[i] = Status{Version: m.Version, Name: m.Name}
+		if at, ok := applied[m.Version]; ok {
+			statuses[i].AppliedAt = &at
+		}
+	}
+	return statuses, nil
+}
+
+// run applies (up) or rolls back (down) a single migration together with its
+// bookkeeping row. It reports false when another instance got there first.
+func run(ctx context.Context, db *sql.DB, m Migration, up bool) (bool, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, lockID); err != nil {
+		return false, fmt.Errorf("failed to lock migrations: %w", err)
+	}
+
+	var exists bool
+	err = tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM migrations WHERE version = $1)`, m.Version).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check migration %d: %w", m.Version, err)
+	}
+	if exists == up {
+		return false, nil
+	}
+
+	if up {
+		log.Printf("Applying migration %04d_%s", m.Version, m.Name)
+		if _, err := tx.ExecContext(ctx, m.Up); err != nil {
+			return false, fmt.Errorf("failed to apply migration %04d_%s: %w", m.Version, m.Name, err)
+		}
+		_, err = tx.ExecContext(ctx, `INSERT INTO migrations (version, name) VALUES ($1, $2)`, m.Version, m.Name)
+	} else {
+		log.Printf("Rolling back migration %04d_%s", m.Version, m.Name)
+		if _, err := tx.ExecContext(ctx, m.Down); err != nil {
+			return false, fmt.Errorf("failed to roll back migration %04d_%s: %w", m.Version, m.Name, err)
+		}
+		_, err = tx.ExecContext(ctx, `DELETE FROM migrations WHERE version = $1`, m.Version)
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to record migration %d: %w", m.Version, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit migration %d: %w", m.Version, err)
+	}
+	return true, nil
+}
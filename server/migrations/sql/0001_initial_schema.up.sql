@@ -1,10 +1,6 @@
--- Drop tables if they exist
-DROP TABLE IF EXISTS transactions;
-DROP TABLE IF EXISTS bank_details;
-DROP TABLE IF EXISTS users;
-
--- Create users table
-CREATE TABLE users (
+-- Accounts, bank details and transactions. IF NOT EXISTS lets this run
+-- against databases that were created from the old init.sql script.
+CREATE TABLE IF NOT EXISTS users (
     account_id VARCHAR(20) PRIMARY KEY,
     account_name VARCHAR(50),
     account_type VARCHAR(20),
@@ -15,16 +11,14 @@ CREATE TABLE users (
     owner_name VARCHAR(50)
 );
 
--- Create bank_details table
-CREATE TABLE bank_details (
+CREATE TABLE IF NOT EXISTS bank_details (
     account_id VARCHAR(20) PRIMARY KEY REFERENCES users(account_id),
     bank_name VARCHAR(50),
     routing_number VARCHAR(20),
     branch VARCHAR(100)
 );
 
--- Create transactions table
-CREATE TABLE transactions (
+CREATE TABLE IF NOT EXISTS transactions (
     transaction_id VARCHAR(20) PRIMARY KEY,
     account_id VARCHAR(20) REFERENCES users(account_id),
     date TIMESTAMP,
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS import_profiles (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(50) UNIQUE NOT NULL,
+    delimiter VARCHAR(1) NOT NULL DEFAULT ',',
+    has_header BOOLEAN NOT NULL DEFAULT TRUE,
+    skip_rows INTEGER NOT NULL DEFAULT 0,
+    date_column VARCHAR(50) NOT NULL,
+    date_format VARCHAR(50) NOT NULL,
+    amount_column VARCHAR(50) NOT NULL DEFAULT '',
+    debit_column VARCHAR(50) NOT NULL DEFAULT '',
+    credit_column VARCHAR(50) NOT NULL DEFAULT '',
+    amount_sign VARCHAR(20) NOT NULL DEFAULT 'debit_negative',
+    merchant_column VARCHAR(50) NOT NULL,
+    category_column VARCHAR(50) NOT NULL DEFAULT '',
+    location_column VARCHAR(50) NOT NULL DEFAULT '',
+    default_category VARCHAR(50) NOT NULL DEFAULT ''
+);
@@ -0,0 +1,5 @@
+ALTER TABLE users DROP COLUMN IF EXISTS balance_as_of;
+
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS transactions_account_id_fitid_key;
+DROP INDEX IF EXISTS transactions_account_id_fitid_key;
+ALTER TABLE transactions DROP COLUMN IF EXISTS fitid;
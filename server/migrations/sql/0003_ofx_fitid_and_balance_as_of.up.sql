@@ -0,0 +1,6 @@
+-- Bank transaction IDs from OFX imports, unique per account, and the date
+-- of the statement balance last applied to the account
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS fitid VARCHAR(255);
+CREATE UNIQUE INDEX IF NOT EXISTS transactions_account_id_fitid_key ON transactions (account_id, fitid);
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS balance_as_of TIMESTAMP;
@@ -0,0 +1,20 @@
+-- Imported rows held back as likely duplicates of existing transactions
+CREATE TABLE IF NOT EXISTS import_duplicates (
+    id SERIAL PRIMARY KEY,
+    account_id VARCHAR(20) NOT NULL REFERENCES users(account_id),
+    existing_transaction_id VARCHAR(20) NOT NULL REFERENCES transactions(transaction_id) ON DELETE CASCADE,
+    date TIMESTAMP NOT NULL,
+    amount DECIMAL(10, 2) NOT NULL,
+    category VARCHAR(50) NOT NULL,
+    merchant VARCHAR(50) NOT NULL,
+    location VARCHAR(100) NOT NULL DEFAULT '',
+    fitid VARCHAR(255),
+    fingerprint VARCHAR(64) NOT NULL,
+    score DECIMAL(4, 3) NOT NULL,
+    reason VARCHAR(100),
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    resolved_at TIMESTAMP,
+    resolved_transaction_id VARCHAR(20),
+    UNIQUE (existing_transaction_id, fingerprint, date)
+);
@@ -0,0 +1,5 @@
+ALTER TABLE import_duplicates DROP COLUMN IF EXISTS currency;
+
+DROP TABLE IF EXISTS fx_rates;
+
+ALTER TABLE transactions DROP COLUMN IF EXISTS currency;
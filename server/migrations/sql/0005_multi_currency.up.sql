@@ -0,0 +1,28 @@
+-- Per-transaction currency, defaulting to the account's currency, and the
+-- local exchange-rate table used to convert amounts for reporting
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS currency VARCHAR(3);
+
+UPDATE transactions t
+SET currency = COALESCE(NULLIF(u.balance_currency, ''), 'USD')
+FROM users u
+WHERE u.account_id = t.account_id AND t.currency IS NULL;
+
+UPDATE transactions SET currency = 'USD' WHERE currency IS NULL;
+
+ALTER TABLE transactions ALTER COLUMN currency SET DEFAULT 'USD';
+ALTER TABLE transactions ALTER COLUMN currency SET NOT NULL;
+
+-- One unit of base is worth rate units of quote on the given date
+CREATE TABLE IF NOT EXISTS fx_rates (
+    base VARCHAR(3) NOT NULL,
+    quote VARCHAR(3) NOT NULL,
+    date DATE NOT NULL,
+    rate DECIMAL(20, 10) NOT NULL CHECK (rate > 0),
+    source VARCHAR(100) NOT NULL DEFAULT '',
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (base, quote, date)
+);
+
+-- Rows held back by the import deduplication keep their currency too;
+-- empty means the account's currency
+ALTER TABLE import_duplicates ADD COLUMN IF NOT EXISTS currency VARCHAR(3) NOT NULL DEFAULT '';
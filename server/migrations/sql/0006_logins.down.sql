@@ -0,0 +1,14 @@
+-- Profile names are unique again, so the per-login profiles are dropped
+DELETE FROM import_profiles WHERE login_id IS NOT NULL;
+
+DROP INDEX IF EXISTS import_profiles_login_name_idx;
+ALTER TABLE import_profiles DROP COLUMN IF EXISTS login_id;
+ALTER TABLE import_profiles ADD CONSTRAINT import_profiles_name_key UNIQUE (name);
+
+ALTER TABLE users DROP COLUMN IF EXISTS claim_code_hash;
+
+DROP INDEX IF EXISTS users_login_id_idx;
+
+ALTER TABLE users DROP COLUMN IF EXISTS login_id;
+
+DROP TABLE IF EXISTS logins;
@@ -0,0 +1,26 @@
+-- People who sign in to the API. Each account (users row) belongs to at most
+-- one login; accounts without a login cannot be read through the API.
+CREATE TABLE IF NOT EXISTS logins (
+    id SERIAL PRIMARY KEY,
+    username VARCHAR(255) NOT NULL UNIQUE,
+    password_hash VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS login_id INTEGER REFERENCES logins(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS users_login_id_idx ON users (login_id);
+
+-- A claim code proves the caller holds an account when linking it to a
+-- login. Only its bcrypt hash is stored; the code is issued by an operator
+-- with "server claim-code" and is cleared once the account is claimed.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS claim_code_hash TEXT;
+
+-- Import profiles belong to the login that created them, and names only need
+-- to be unique per login. Profiles with no login, such as those created
+-- before logins existed, are shared: every login can import with them, but
+-- they cannot be changed through the API.
+ALTER TABLE import_profiles ADD COLUMN IF NOT EXISTS login_id INTEGER REFERENCES logins(id) ON DELETE CASCADE;
+
+ALTER TABLE import_profiles DROP CONSTRAINT IF EXISTS import_profiles_name_key;
+CREATE UNIQUE INDEX IF NOT EXISTS import_profiles_login_name_idx ON import_profiles (COALESCE(login_id, 0), name);
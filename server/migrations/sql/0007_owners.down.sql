@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS users_owner_id_idx;
+
+ALTER TABLE users DROP COLUMN IF EXISTS owner_id;
+
+DROP TABLE IF EXISTS owners;
@@ -0,0 +1,15 @@
+-- An owner is a person or household whose accounts are viewed together.
+-- Owners belong to a login, and each account links to at most one owner.
+CREATE TABLE IF NOT EXISTS owners (
+    id SERIAL PRIMARY KEY,
+    login_id INTEGER NOT NULL REFERENCES logins(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    currency VARCHAR(3) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS owners_login_id_idx ON owners (login_id);
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS owner_id INTEGER REFERENCES owners(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS users_owner_id_idx ON users (owner_id);
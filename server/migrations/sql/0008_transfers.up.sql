@@ -0,0 +1,25 @@
+-- Money moved between two accounts of the same owner: the outgoing and the
+-- incoming side of one movement. Matched transactions point at their
+-- transfer and are left out of spending, income, category and bill analytics.
+-- Rejected pairs are kept so that detection does not propose them again.
+CREATE TABLE IF NOT EXISTS transfers (
+    id SERIAL PRIMARY KEY,
+    owner_id INTEGER NOT NULL REFERENCES owners(id) ON DELETE CASCADE,
+    from_transaction_id VARCHAR(20) NOT NULL REFERENCES transactions(transaction_id) ON DELETE CASCADE,
+    to_transaction_id VARCHAR(20) NOT NULL REFERENCES transactions(transaction_id) ON DELETE CASCADE,
+    score DECIMAL(4, 3) NOT NULL DEFAULT 1,
+    source VARCHAR(20) NOT NULL DEFAULT 'detected',
+    status VARCHAR(20) NOT NULL DEFAULT 'matched',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CHECK (from_transaction_id <> to_transaction_id)
+);
+
+CREATE INDEX IF NOT EXISTS transfers_owner_id_idx ON transfers (owner_id, status);
+
+-- A transaction is the side of at most one matched transfer
+CREATE UNIQUE INDEX IF NOT EXISTS transfers_matched_from_key ON transfers (from_transaction_id) WHERE status = 'matched';
+CREATE UNIQUE INDEX IF NOT EXISTS transfers_matched_to_key ON transfers (to_transaction_id) WHERE status = 'matched';
+
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS transfer_id INTEGER REFERENCES transfers(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS transactions_transfer_id_idx ON transactions (transfer_id);
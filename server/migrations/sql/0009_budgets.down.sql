@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS transactions_account_date_idx;
+
+DROP TABLE IF EXISTS budgets;
@@ -0,0 +1,13 @@
+-- Monthly spending limits per category of an account, in the account's currency
+CREATE TABLE IF NOT EXISTS budgets (
+    id SERIAL PRIMARY KEY,
+    account_id VARCHAR(20) NOT NULL REFERENCES users(account_id) ON DELETE CASCADE,
+    category VARCHAR(50) NOT NULL,
+    monthly_limit DECIMAL(10, 2) NOT NULL CHECK (monthly_limit > 0),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (account_id, category)
+);
+
+-- Budget status sums a single month of an account's spending
+CREATE INDEX IF NOT EXISTS transactions_account_date_idx ON transactions (account_id, date);
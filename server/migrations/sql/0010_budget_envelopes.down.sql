@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS budget_allocations;
+
+-- Envelope-only budgets have no fixed limit and cannot be kept
+DELETE FROM budgets WHERE monthly_limit = 0;
+
+ALTER TABLE budgets DROP CONSTRAINT IF EXISTS budgets_monthly_limit_check;
+ALTER TABLE budgets ADD CONSTRAINT budgets_monthly_limit_check CHECK (monthly_limit > 0);
+
+ALTER TABLE budgets DROP COLUMN IF EXISTS rollover;
@@ -0,0 +1,22 @@
+-- Envelope budgeting: a budget can carry its unspent or overspent balance
+-- into the next month, and can be funded by assigning income to it on top of
+-- (or instead of) its fixed monthly limit
+ALTER TABLE budgets ADD COLUMN IF NOT EXISTS rollover BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE budgets DROP CONSTRAINT IF EXISTS budgets_monthly_limit_check;
+ALTER TABLE budgets ADD CONSTRAINT budgets_monthly_limit_check CHECK (monthly_limit >= 0);
+
+-- Money assigned to a budget for one month, optionally taken from an income
+-- deposit. The allocations of a deposit never add up to more than the deposit.
+CREATE TABLE IF NOT EXISTS budget_allocations (
+    id SERIAL PRIMARY KEY,
+    budget_id INTEGER NOT NULL REFERENCES budgets(id) ON DELETE CASCADE,
+    month DATE NOT NULL,
+    amount DECIMAL(10, 2) NOT NULL CHECK (amount <> 0),
+    transaction_id VARCHAR(20) REFERENCES transactions(transaction_id) ON DELETE CASCADE,
+    note VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS budget_allocations_budget_month_idx ON budget_allocations (budget_id, month);
+CREATE INDEX IF NOT EXISTS budget_allocations_transaction_id_idx ON budget_allocations (transaction_id);
@@ -0,0 +1,26 @@
+-- Savings goals of an account, in the account's currency
+CREATE TABLE IF NOT EXISTS goals (
+    id SERIAL PRIMARY KEY,
+    account_id VARCHAR(20) NOT NULL REFERENCES users(account_id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    target_amount DECIMAL(10, 2) NOT NULL CHECK (target_amount > 0),
+    target_date DATE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS goals_account_id_idx ON goals (account_id);
+
+-- Money put towards (or taken from) a goal, entered by hand or linked to a
+-- transaction. A transaction counts towards at most one goal.
+CREATE TABLE IF NOT EXISTS goal_contributions (
+    id SERIAL PRIMARY KEY,
+    goal_id INTEGER NOT NULL REFERENCES goals(id) ON DELETE CASCADE,
+    date DATE NOT NULL,
+    amount DECIMAL(10, 2) NOT NULL CHECK (amount <> 0),
+    transaction_id VARCHAR(20) UNIQUE REFERENCES transactions(transaction_id) ON DELETE CASCADE,
+    note VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS goal_contributions_goal_id_idx ON goal_contributions (goal_id, date);
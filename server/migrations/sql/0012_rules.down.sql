@@ -0,0 +1,4 @@
+ALTER TABLE import_duplicates DROP COLUMN IF EXISTS tags;
+DROP TABLE IF EXISTS rules;
+DROP TABLE IF EXISTS transaction_tags;
+DROP TABLE IF EXISTS tags;
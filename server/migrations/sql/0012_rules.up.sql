@@ -0,0 +1,45 @@
+-- Labels on transactions. Tags belong to an account and are linked to any
+-- number of its transactions.
+CREATE TABLE IF NOT EXISTS tags (
+    id SERIAL PRIMARY KEY,
+    account_id VARCHAR(20) NOT NULL REFERENCES users(account_id) ON DELETE CASCADE,
+    name VARCHAR(50) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (account_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS transaction_tags (
+    transaction_id VARCHAR(20) NOT NULL REFERENCES transactions(transaction_id) ON DELETE CASCADE,
+    tag_id INTEGER NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    PRIMARY KEY (transaction_id, tag_id)
+);
+
+CREATE INDEX IF NOT EXISTS transaction_tags_tag_id_idx ON transaction_tags (tag_id);
+
+-- Categorization rules of an account. Every condition that is set must hold
+-- for a rule to match; rules run in priority order, lowest first.
+CREATE TABLE IF NOT EXISTS rules (
+    id SERIAL PRIMARY KEY,
+    account_id VARCHAR(20) NOT NULL REFERENCES users(account_id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    priority INTEGER NOT NULL DEFAULT 0,
+    disabled BOOLEAN NOT NULL DEFAULT FALSE,
+    merchant_pattern VARCHAR(255) NOT NULL DEFAULT '',
+    merchant_match VARCHAR(10) NOT NULL DEFAULT 'contains',
+    location_pattern VARCHAR(100) NOT NULL DEFAULT '',
+    min_amount DECIMAL(10, 2),
+    max_amount DECIMAL(10, 2),
+    day_from SMALLINT CHECK (day_from BETWEEN 1 AND 31),
+    day_to SMALLINT CHECK (day_to BETWEEN 1 AND 31),
+    set_category VARCHAR(50) NOT NULL DEFAULT '',
+    set_merchant VARCHAR(50) NOT NULL DEFAULT '',
+    add_tags TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS rules_account_id_idx ON rules (account_id, priority);
+
+-- Held-back rows keep the tags the rules added at import, so that a row
+-- inserted after review is tagged like the rest of its statement
+ALTER TABLE import_duplicates ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}';
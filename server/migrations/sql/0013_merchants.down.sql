@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS transactions_merchant_id_idx;
+ALTER TABLE transactions DROP COLUMN IF EXISTS merchant_id;
+
+DROP TABLE IF EXISTS merchant_aliases;
+DROP TABLE IF EXISTS merchants;
@@ -0,0 +1,27 @@
+-- Canonical merchants of an account. Transactions are linked to one when
+-- their merchant description matches one of its aliases, so that
+-- "AMZN Mktp US*2K3" and "Amazon.com" count as the same merchant.
+CREATE TABLE IF NOT EXISTS merchants (
+    id SERIAL PRIMARY KEY,
+    account_id VARCHAR(20) NOT NULL REFERENCES users(account_id) ON DELETE CASCADE,
+    name VARCHAR(50) NOT NULL,
+    default_category VARCHAR(50) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (account_id, name)
+);
+
+-- Aliases are stored normalized (lowercase words without store numbers or
+-- punctuation) and belong to at most one merchant of the account
+CREATE TABLE IF NOT EXISTS merchant_aliases (
+    id SERIAL PRIMARY KEY,
+    merchant_id INTEGER NOT NULL REFERENCES merchants(id) ON DELETE CASCADE,
+    account_id VARCHAR(20) NOT NULL REFERENCES users(account_id) ON DELETE CASCADE,
+    alias VARCHAR(100) NOT NULL,
+    UNIQUE (account_id, alias)
+);
+
+CREATE INDEX IF NOT EXISTS merchant_aliases_merchant_id_idx ON merchant_aliases (merchant_id);
+
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS merchant_id INTEGER REFERENCES merchants(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS transactions_merchant_id_idx ON transactions (merchant_id);
@@ -0,0 +1,2 @@
+DROP FUNCTION IF EXISTS category_type(VARCHAR, VARCHAR);
+DROP TABLE IF EXISTS categories;
@@ -0,0 +1,53 @@
+-- Categories transactions are filed under. Built-in categories have no
+-- account and are shared by every account; an account can add its own,
+-- optionally as subcategories of another. Names are unique among an
+-- account's categories and the built-in ones, and transactions, budgets,
+-- rules and merchants refer to a category by name.
+--
+-- The type decides how a category's transactions are counted: expenses and
+-- bills are spending, income is earnings, and transfers are left out of both.
+CREATE TABLE IF NOT EXISTS categories (
+    id SERIAL PRIMARY KEY,
+    account_id VARCHAR(20) REFERENCES users(account_id) ON DELETE CASCADE,
+    name VARCHAR(50) NOT NULL,
+    parent_id INTEGER REFERENCES categories(id),
+    type VARCHAR(10) NOT NULL CHECK (type IN ('expense', 'income', 'bill', 'transfer')),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS categories_account_name_idx ON categories (COALESCE(account_id, ''), name);
+CREATE INDEX IF NOT EXISTS categories_parent_id_idx ON categories (parent_id);
+
+-- The built-in categories, as listed in types.BuiltInCategories
+INSERT INTO categories (account_id, name, type) VALUES
+    (NULL, 'Income', 'income'),
+    (NULL, 'Bill Payment', 'bill'),
+    (NULL, 'Subscription', 'bill'),
+    (NULL, 'Transfer', 'transfer'),
+    (NULL, 'Other', 'expense'),
+    (NULL, 'Rent', 'expense'),
+    (NULL, 'Utilities', 'expense'),
+    (NULL, 'Groceries', 'expense'),
+    (NULL, 'Dining', 'expense'),
+    (NULL, 'Fast Food', 'expense'),
+    (NULL, 'Food Delivery', 'expense'),
+    (NULL, 'Transportation', 'expense'),
+    (NULL, 'Entertainment', 'expense'),
+    (NULL, 'Shopping', 'expense'),
+    (NULL, 'Home', 'expense'),
+    (NULL, 'Books', 'expense'),
+    (NULL, 'Electronics', 'expense'),
+    (NULL, 'Clothing', 'expense')
+ON CONFLICT DO NOTHING;
+
+-- category_type resolves the type of a transaction's category: the
+-- account's own category of that name or else the built-in one. Queries
+-- filter on it instead of on category names. It is NULL for a name that is
+-- no category, so filters that exclude a type use IS DISTINCT FROM.
+CREATE OR REPLACE FUNCTION category_type(account VARCHAR, category VARCHAR) RETURNS VARCHAR AS $$
+    SELECT c.type FROM categories c
+    WHERE c.name = category AND (c.account_id = account OR c.account_id IS NULL)
+    ORDER BY c.account_id NULLS LAST
+    LIMIT 1
+$$ LANGUAGE SQL STABLE;
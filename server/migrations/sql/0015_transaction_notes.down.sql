@@ -0,0 +1,3 @@
+DROP FUNCTION IF EXISTS has_tags(VARCHAR, TEXT[]);
+
+ALTER TABLE transactions DROP COLUMN IF EXISTS notes;
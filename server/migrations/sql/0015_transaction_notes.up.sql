@@ -0,0 +1,13 @@
+-- Free-form notes on transactions
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS notes VARCHAR(1000) NOT NULL DEFAULT '';
+
+-- has_tags reports whether a transaction carries every one of the wanted
+-- tags. An empty or NULL list matches every transaction, so endpoints that
+-- return transactions filter on it whether or not a tag filter was given.
+CREATE OR REPLACE FUNCTION has_tags(transaction VARCHAR, wanted TEXT[]) RETURNS BOOLEAN AS $$
+    SELECT COALESCE(cardinality(wanted), 0) = 0 OR (
+        SELECT COUNT(*) FROM transaction_tags tt
+        JOIN tags g ON g.id = tt.tag_id
+        WHERE tt.transaction_id = transaction AND g.name = ANY(wanted)
+    ) = cardinality(wanted)
+$$ LANGUAGE SQL STABLE;
@@ -0,0 +1,31 @@
+-- Allocations of a transaction to several categories. The splits of a
+-- transaction add up to its amount and carry the same sign.
+CREATE TABLE IF NOT EXISTS transaction_splits (
+    id SERIAL PRIMARY KEY,
+    transaction_id VARCHAR(20) NOT NULL REFERENCES transactions(transaction_id) ON DELETE CASCADE,
+    category VARCHAR(50) NOT NULL,
+    amount DECIMAL(10, 2) NOT NULL CHECK (amount <> 0),
+    memo VARCHAR(100) NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS transaction_splits_transaction_id_idx ON transaction_splits (transaction_id);
+
+-- transaction_lines holds one row per split of a split transaction and one
+-- row for every other transaction, with the category and amount of the split.
+-- Category totals and budgets read it instead of transactions so that a
+-- split transaction counts towards each of its categories.
+CREATE OR REPLACE VIEW transaction_lines AS
+SELECT
+    t.transaction_id,
+    t.account_id,
+    t.date,
+    COALESCE(s.amount, t.amount) AS amount,
+    COALESCE(s.category, t.category) AS category,
+    t.merchant,
+    t.location,
+    t.currency,
+    t.transfer_id,
+    t.merchant_id,
+    s.id AS split_id
+FROM transactions t
+LEFT JOIN transaction_splits s ON s.transaction_id = t.transaction_id;
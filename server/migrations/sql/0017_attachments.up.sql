@@ -0,0 +1,15 @@
+-- Receipts and other files attached to transactions. The files themselves
+-- live in the blob store under storage_key; this table holds their metadata.
+CREATE TABLE IF NOT EXISTS attachments (
+    id SERIAL PRIMARY KEY,
+    account_id VARCHAR(20) NOT NULL REFERENCES users(account_id) ON DELETE CASCADE,
+    transaction_id VARCHAR(20) NOT NULL REFERENCES transactions(transaction_id) ON DELETE CASCADE,
+    filename VARCHAR(255) NOT NULL,
+    content_type VARCHAR(100) NOT NULL,
+    size BIGINT NOT NULL CHECK (size > 0),
+    sha256 CHAR(64) NOT NULL,
+    storage_key VARCHAR(255) NOT NULL UNIQUE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS attachments_transaction_id_idx ON attachments (transaction_id);
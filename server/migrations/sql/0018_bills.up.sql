@@ -0,0 +1,19 @@
+-- Bills an account declares itself, so that they are tracked before any
+-- payment has been seen. Amounts are in the account's currency; a variable
+-- bill's amount is an estimate.
+CREATE TABLE IF NOT EXISTS bills (
+    id SERIAL PRIMARY KEY,
+    account_id VARCHAR(20) NOT NULL REFERENCES users(account_id) ON DELETE CASCADE,
+    payee VARCHAR(100) NOT NULL,
+    category VARCHAR(50) NOT NULL,
+    amount DECIMAL(10, 2) NOT NULL CHECK (amount > 0),
+    amount_type VARCHAR(10) NOT NULL DEFAULT 'fixed' CHECK (amount_type IN ('fixed', 'variable')),
+    frequency VARCHAR(10) NOT NULL CHECK (frequency IN ('weekly', 'biweekly', 'monthly', 'quarterly', 'semiannual', 'annual')),
+    due_day SMALLINT NOT NULL CHECK (due_day BETWEEN 1 AND 31),
+    start_date DATE NOT NULL,
+    autopay BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS bills_account_id_idx ON bills (account_id);
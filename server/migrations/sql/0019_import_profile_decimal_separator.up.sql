@@ -0,0 +1,3 @@
+-- The decimal separator of a profile's amounts; the other of "." and "," may
+-- only group thousands
+ALTER TABLE import_profiles ADD COLUMN IF NOT EXISTS decimal_separator VARCHAR(1) NOT NULL DEFAULT '.';
@@ -0,0 +1,195 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	authHandler "server/auth/handler"
+	"server/owners/repository"
+	"server/owners/service"
+	"server/types"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+type Handler struct {
+	service service.Service
+}
+
+func NewHandler(service service.Service) *Handler {
+	return &Handler{service: service}
+}
+
+// SetupOwnerRoutes configures all the owner-related routes
+func SetupOwnerRoutes(router *mux.Router, db *sql.DB) {
+	repo := repository.NewPostgresRepository(db)
+	svc := service.NewService(repo)
+	handler := NewHandler(svc)
+	handler.RegisterRoutes(router)
+}
+
+// RegisterRoutes registers all owner routes. The combined analytics, bills
+// and income views under /api/owners/{ownerId} are registered by those packages.
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/api/owners", h.HandleListOwners).Methods("GET")
+	router.HandleFunc("/api/owners", h.HandleCreateOwner).Methods("POST")
+	router.HandleFunc("/api/owners/{ownerId:[0-9]+}", h.HandleGetOwner).Methods("GET")
+	router.HandleFunc("/api/owners/{ownerId:[0-9]+}", h.HandleUpdateOwner).Methods("PUT")
+	router.HandleFunc("/api/owners/{ownerId:[0-9]+}", h.HandleDeleteOwner).Methods("DELETE")
+	router.HandleFunc("/api/owners/{ownerId:[0-9]+}/accounts/{accountId}", h.HandleLinkAccount).Methods("PUT")
+	router.HandleFunc("/api/owners/{ownerId:[0-9]+}/accounts/{accountId}", h.HandleUnlinkAccount).Methods("DELETE")
+}
+
+// RequestAccounts returns the accounts a request covers and their default
+// reporting currency: the {accountId} in the path, or every account linked
+// to the {ownerId} in the path. It lets one handler serve both the
+// per-account route and its /api/owners/{ownerId} variant.
+func RequestAccounts(r *http.Request, owners service.Service) ([]string, string, error) {
+	vars := mux.Vars(r)
+	if accountID, ok := vars["accountId"]; ok {
+		return []string{accountID}, "", nil
+	}
+
+	ownerID, err := strconv.ParseInt(vars["ownerId"], 10, 64)
+	if err != nil {
+		return nil, "", repository.ErrOwnerNotFound
+	}
+	owner, err := owners.GetOwner(r.Context(), ownerID)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(owner.AccountIDs) == 0 {
+		return nil, "", &types.ValidationError{Field: "owner", Message: "has no linked accounts"}
+	}
+	return owner.AccountIDs, owner.Currency, nil
+}
+
+// HandleListOwners handles requests for the caller's owners
+func (h *Handler) HandleListOwners(w http.ResponseWriter, r *http.Request) {
+	loginID, _ := authHandler.LoginID(r.Context())
+
+	owners, err := h.service.ListOwners(r.Context(), loginID)
+	if err != nil {
+		writeError(w, "Failed to get owners", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(owners)
+}
+
+// HandleCreateOwner handles requests to create an owner
+func (h *Handler) HandleCreateOwner(w http.ResponseWriter, r *http.Request) {
+	loginID, _ := authHandler.LoginID(r.Context())
+
+	var input types.Owner
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	owner, err := h.service.CreateOwner(r.Context(), loginID, input)
+	if err != nil {
+		writeError(w, "Failed to create owner", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(owner)
+}
+
+// HandleGetOwner handles requests for a single owner
+func (h *Handler) HandleGetOwner(w http.ResponseWriter, r *http.Request) {
+	ownerID, _ := strconv.ParseInt(mux.Vars(r)["ownerId"], 10, 64)
+
+	owner, err := h.service.GetOwner(r.Context(), ownerID)
+	if err != nil {
+		writeError(w, "Failed to get owner", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(owner)
+}
+
+// HandleUpdateOwner handles requests to replace an owner
+func (h *Handler) HandleUpdateOwner(w http.ResponseWriter, r *http.Request) {
+	ownerID, _ := strconv.ParseInt(mux.Vars(r)["ownerId"], 10, 64)
+
+	var input types.Owner
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	owner, err := h.service.UpdateOwner(r.Context(), ownerID, input)
+	if err != nil {
+		writeError(w, "Failed to update owner", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(owner)
+}
+
+// HandleDeleteOwner handles requests to delete an owner
+func (h *Handler) HandleDeleteOwner(w http.ResponseWriter, r *http.Request) {
+	ownerID, _ := strconv.ParseInt(mux.Vars(r)["ownerId"], 10, 64)
+
+	if err := h.service.DeleteOwner(r.Context(), ownerID); err != nil {
+		writeError(w, "Failed to delete owner", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleLinkAccount handles requests to link an account to an owner
+func (h *Handler) HandleLinkAccount(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	ownerID, _ := strconv.ParseInt(vars["ownerId"], 10, 64)
+
+	owner, err := h.service.LinkAccount(r.Context(), ownerID, vars["accountId"])
+	if err != nil {
+		writeError(w, "Failed to link account", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(owner)
+}
+
+// HandleUnlinkAccount handles requests to remove an account from an owner
+func (h *Handler) HandleUnlinkAccount(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	ownerID, _ := strconv.ParseInt(vars["ownerId"], 10, 64)
+
+	owner, err := h.service.UnlinkAccount(r.Context(), ownerID, vars["accountId"])
+	if err != nil {
+		writeError(w, "Failed to unlink account", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(owner)
+}
+
+// writeError converts service and repository errors into HTTP responses
+func writeError(w http.ResponseWriter, message string, err error) {
+	var validationErr *types.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		http.Error(w, validationErr.Error(), http.StatusBadRequest)
+	case errors.Is(err, repository.ErrOwnerNotFound):
+		http.Error(w, "Owner not found", http.StatusNotFound)
+	case errors.Is(err, repository.ErrAccountNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		log.Printf("%s: %v", message, err)
+		http.Error(w, message, http.StatusInternalServerError)
+	}
+}
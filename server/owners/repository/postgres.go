@@ -0,0 +1,216 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"server/types"
+
+	"github.com/lib/pq"
+)
+
+type postgresRepo struct {
+	db *sql.DB
+}
+
+func NewPostgresRepository(db *sql.DB) Repository {
+	if db == nil {
+		panic("database connection is required")
+	}
+	return &postgresRepo{db: db}
+}
+
+// ownerColumns lists the owner columns together with its linked accounts
+const ownerColumns = `
+	o.id, o.login_id, o.name, o.currency, o.created_at,
+	COALESCE(ARRAY(SELECT u.account_id FROM users u WHERE u.owner_id = o.id ORDER BY u.account_id), '{}')`
+
+// ListOwners retrieves the owners of a login
+func (r *postgresRepo) ListOwners(ctx context.Context, loginID int64) ([]types.Owner, error) {
+	query := `SELECT` + ownerColumns + ` FROM owners o WHERE o.login_id = $1 ORDER BY o.id`
+
+	rows, err := r.db.QueryContext(ctx, query, loginID)
+	if err != nil {
+		log.Printf("Error querying owners: %v", err)
+		return nil, fmt.Errorf("failed to query owners: %w", err)
+	}
+	defer rows.Close()
+
+	owners := []types.Owner{}
+	for rows.Next() {
+		owner, err := scanOwner(rows)
+		if err != nil {
+			log.Printf("Error scanning owner: %v", err)
+			return nil, fmt.Errorf("failed to scan owner: %w", err)
+		}
+		owners = append(owners, *owner)
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("Error iterating owners: %v", err)
+		return nil, fmt.Errorf("error iterating owners: %w", err)
+	}
+	return owners, nil
+}
+
+// GetOwner retrieves an owner and its linked accounts
+func (r *postgresRepo) GetOwner(ctx context.Context, ownerID int64) (*types.Owner, error) {
+	query := `SELECT` + ownerColumns + ` FROM owners o WHERE o.id = $1`
+
+	owner, err := scanOwner(r.db.QueryRowContext(ctx, query, ownerID))
+	if err == sql.ErrNoRows {
+		return nil, ErrOwnerNotFound
+	}
+	if err != nil {
+		log.Printf("Error fetching owner %d: %v", ownerID, err)
+		return nil, fmt.Errorf("failed to fetch owner: %w", err)
+	}
+	return owner, nil
+}
+
+// CreateOwner inserts an owner and links its accounts, setting its ID
+func (r *postgresRepo) CreateOwner(ctx context.Context, owner *types.Owner) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx,
+		`INSERT INTO owners (login_id, name, currency) VALUES ($1, $2, $3) RETURNING id, created_at`,
+		owner.LoginID, owner.Name, owner.Currency,
+	).Scan(&owner.ID, &owner.CreatedAt)
+	if err != nil {
+		log.Printf("Error creating owner: %v", err)
+		return fmt.Errorf("failed to create owner: %w", err)
+	}
+
+	if err := linkAccountsTx(ctx, tx, owner.ID, owner.AccountIDs); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit owner: %w", err)
+	}
+	return nil
+}
+
+// UpdateOwner replaces an owner's name, currency and linked accounts
+func (r *postgresRepo) UpdateOwner(ctx context.Context, owner *types.Owner) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx,
+		`UPDATE owners SET name = $2, currency = $3 WHERE id = $1`,
+		owner.ID, owner.Name, owner.Currency,
+	)
+	if err != nil {
+		log.Printf("Error updating owner %d: %v", owner.ID, err)
+		return fmt.Errorf("failed to update owner: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrOwnerNotFound
+	}
+
+	// An empty (not NULL) array unlinks every account
+	keep := append([]string{}, owner.AccountIDs...)
+	_, err = tx.ExecContext(ctx,
+		`UPDATE users SET owner_id = NULL WHERE owner_id = $1 AND NOT (account_id = ANY($2))`,
+		owner.ID, pq.Array(keep),
+	)
+	if err != nil {
+		log.Printf("Error unlinking accounts of owner %d: %v", owner.ID, err)
+		return fmt.Errorf("failed to unlink accounts: %w", err)
+	}
+	if err := linkAccountsTx(ctx, tx, owner.ID, owner.AccountIDs); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit owner: %w", err)
+	}
+	return nil
+}
+
+// DeleteOwner removes an owner; its accounts are unlinked, not deleted
+func (r *postgresRepo) DeleteOwner(ctx context.Context, ownerID int64) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM owners WHERE id = $1`, ownerID)
+	if err != nil {
+		log.Printf("Error deleting owner %d: %v", ownerID, err)
+		return fmt.Errorf("failed to delete owner: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrOwnerNotFound
+	}
+	return nil
+}
+
+// LinkAccount links an account of the owner's login to the owner
+func (r *postgresRepo) LinkAccount(ctx context.Context, ownerID int64, accountID string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := linkAccountsTx(ctx, tx, ownerID, []string{accountID}); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit account link: %w", err)
+	}
+	return nil
+}
+
+// UnlinkAccount removes an account from the owner
+func (r *postgresRepo) UnlinkAccount(ctx context.Context, ownerID int64, accountID string) error {
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE users SET owner_id = NULL WHERE owner_id = $1 AND account_id = $2`, ownerID, accountID)
+	if err != nil {
+		log.Printf("Error unlinking account %s from owner %d: %v", accountID, ownerID, err)
+		return fmt.Errorf("failed to unlink account: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrAccountNotFound
+	}
+	return nil
+}
+
+// linkAccountsTx links accounts to an owner. Only accounts that belong to
+// the owner's login can be linked; an account linked to another owner of
+// the same login moves to this one.
+func linkAccountsTx(ctx context.Context, tx *sql.Tx, ownerID int64, accountIDs []string) error {
+	for _, accountID := range accountIDs {
+		result, err := tx.ExecContext(ctx, `
+			UPDATE users u SET owner_id = o.id
+			FROM owners o
+			WHERE o.id = $1 AND u.account_id = $2 AND u.login_id = o.login_id`,
+			ownerID, accountID,
+		)
+		if err != nil {
+			log.Printf("Error linking account %s to owner %d: %v", accountID, ownerID, err)
+			return fmt.Errorf("failed to link account: %w", err)
+		}
+		if n, err := result.RowsAffected(); err == nil && n == 0 {
+			return fmt.Errorf("%w: %s", ErrAccountNotFound, accountID)
+		}
+	}
+	return nil
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanOwner(row rowScanner) (*types.Owner, error) {
+	var owner types.Owner
+	var accountIDs pq.StringArray
+	if err := row.Scan(&owner.ID, &owner.LoginID, &owner.Name, &owner.Currency, &owner.CreatedAt, &accountIDs); err != nil {
+		return nil, err
+	}
+	owner.AccountIDs = []string(accountIDs)
+	return &owner, nil
+}
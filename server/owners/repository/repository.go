@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"server/types"
+)
+
+var (
+	// ErrOwnerNotFound is returned when the owner does not exist
+	ErrOwnerNotFound = errors.New("owner not found")
+
+	// ErrAccountNotFound is returned when an account to link does not belong
+	// to the owner's login
+	ErrAccountNotFound = errors.New("account not found")
+)
+
+// Repository defines the interface for owner data operations
+type Repository interface {
+	// ListOwners retrieves the owners of a login
+	ListOwners(ctx context.Context, loginID int64) ([]types.Owner, error)
+
+	// GetOwner retrieves an owner and its linked accounts
+	GetOwner(ctx context.Context, ownerID int64) (*types.Owner, error)
+
+	// CreateOwner inserts an owner and links its accounts, setting its ID
+	CreateOwner(ctx context.Context, owner *types.Owner) error
+
+	// UpdateOwner replaces an owner's name, currency and linked accounts
+	UpdateOwner(ctx context.Context, owner *types.Owner) error
+
+	// DeleteOwner removes an owner; its accounts are unlinked, not deleted
+	DeleteOwner(ctx context.Context, ownerID int64) error
+
+	// LinkAccount links an account of the owner's login to the owner
+	LinkAccount(ctx context.Context, ownerID int64, accountID string) error
+
+	// UnlinkAccount removes an account from the owner
+	UnlinkAccount(ctx context.Context, ownerID int64, accountID string) error
+}
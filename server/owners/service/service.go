@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"server/owners/repository"
+	"server/types"
+	"strings"
+)
+
+type Service interface {
+	ListOwners(ctx context.Context, loginID int64) ([]types.Owner, error)
+	GetOwner(ctx context.Context, ownerID int64) (*types.Owner, error)
+	CreateOwner(ctx context.Context, loginID int64, input types.Owner) (*types.Owner, error)
+	UpdateOwner(ctx context.Context, ownerID int64, input types.Owner) (*types.Owner, error)
+	DeleteOwner(ctx context.Context, ownerID int64) error
+	LinkAccount(ctx context.Context, ownerID int64, accountID string) (*types.Owner, error)
+	UnlinkAccount(ctx context.Context, ownerID int64, accountID string) (*types.Owner, error)
+}
+
+type service struct {
+	repo repository.Repository
+}
+
+func NewService(repo repository.Repository) Service {
+	return &service{repo: repo}
+}
+
+func (s *service) ListOwners(ctx context.Context, loginID int64) ([]types.Owner, error) {
+	return s.repo.ListOwners(ctx, loginID)
+}
+
+func (s *service) GetOwner(ctx context.Context, ownerID int64) (*types.Owner, error) {
+	return s.repo.GetOwner(ctx, ownerID)
+}
+
+// CreateOwner validates the input and creates an owner for the login
+func (s *service) CreateOwner(ctx context.Context, loginID int64, input types.Owner) (*types.Owner, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	input.LoginID = loginID
+	input.AccountIDs = uniqueAccountIDs(input.AccountIDs)
+
+	if err := s.repo.CreateOwner(ctx, &input); err != nil {
+		return nil, err
+	}
+	return s.repo.GetOwner(ctx, input.ID)
+}
+
+// UpdateOwner replaces the owner's name, currency and linked accounts
+func (s *service) UpdateOwner(ctx context.Context, ownerID int64, input types.Owner) (*types.Owner, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	input.ID = ownerID
+	input.AccountIDs = uniqueAccountIDs(input.AccountIDs)
+
+	if err := s.repo.UpdateOwner(ctx, &input); err != nil {
+		return nil, err
+	}
+	return s.repo.GetOwner(ctx, ownerID)
+}
+
+func (s *service) DeleteOwner(ctx context.Context, ownerID int64) error {
+	return s.repo.DeleteOwner(ctx, ownerID)
+}
+
+func (s *service) LinkAccount(ctx context.Context, ownerID int64, accountID string) (*types.Owner, error) {
+	if err := s.repo.LinkAccount(ctx, ownerID, accountID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetOwner(ctx, ownerID)
+}
+
+func (s *service) UnlinkAccount(ctx context.Context, ownerID int64, accountID string) (*types.Owner, error) {
+	if err := s.repo.UnlinkAccount(ctx, ownerID, accountID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetOwner(ctx, ownerID)
+}
+
+// uniqueAccountIDs trims the IDs and drops blanks and repeats
+func uniqueAccountIDs(ids []string) []string {
+	seen := make(map[string]bool, len(ids))
+	unique := []string{}
+	for _, id := range ids {
+		id = strings.TrimSpace(id)
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		unique = append(unique, id)
+	}
+	return unique
+}
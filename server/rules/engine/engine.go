@@ -0,0 +1,161 @@
+package engine
+
+import (
+	"regexp"
+	"server/types"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Engine evaluates an account's rules against transactions
+type Engine struct {
+	rules      []rule
+	categories types.Categories
+}
+
+// rule is a types.Rule with its patterns prepared for matching
+type rule struct {
+	types.Rule
+	merchant string
+	location string
+	pattern  *regexp.Regexp
+}
+
+// New prepares the enabled rules for matching, in priority order. Rules with
+// the same priority run in the order they were created. categories are the
+// account's categories, whose types decide which amounts a category fits.
+func New(rules []types.Rule, categories types.Categories) (*Engine, error) {
+	e := &Engine{categories: categories}
+	for _, r := range rules {
+		if r.Disabled {
+			continue
+		}
+
+		compiled := rule{
+			Rule:     r,
+			merchant: strings.ToLower(r.MerchantPattern),
+			location: strings.ToLower(r.LocationPattern),
+		}
+		if r.MerchantMatch == types.MerchantRegex && r.MerchantPattern != "" {
+			pattern, err := regexp.Compile("(?i)" + r.MerchantPattern)
+			if err != nil {
+				return nil, &types.ValidationError{Field: "merchant_pattern", Message: "is not a valid regular expression"}
+			}
+			compiled.pattern = pattern
+		}
+		e.rules = append(e.rules, compiled)
+	}
+
+	sort.SliceStable(e.rules, func(i, j int) bool {
+		if e.rules[i].Priority != e.rules[j].Priority {
+			return e.rules[i].Priority < e.rules[j].Priority
+		}
+		return e.rules[i].ID < e.rules[j].ID
+	})
+	return e, nil
+}
+
+// Len returns how many enabled rules the engine runs
+func (e *Engine) Len() int {
+	return len(e.rules)
+}
+
+// Apply runs the rules against t and updates it in place. Every rule is
+// matched against the transaction as it came in, so a rule that cleans the
+// merchant name does not change which later rules match.
+//
+// The first matching rule that sets a category decides the category and the
+// first that sets a merchant decides the merchant; tags of every matching
+// rule are added. A category that contradicts the sign of the amount (income
+// on spending or the other way round) or that the account no longer has is
+// passed over. Apply returns the IDs of the matching rules in the order they
+// ran.
+func (e *Engine) Apply(t *types.Transaction) []int64 {
+	in := *t
+	var matched []int64
+	category, merchant := "", ""
+	tags := append([]string{}, t.Tags...)
+
+	for _, r := range e.rules {
+		if !r.matches(in) {
+			continue
+		}
+		matched = append(matched, r.ID)
+
+		if category == "" && r.Category != "" && e.categories.FitsSign(r.Category, in.Amount) {
+			category = r.Category
+		}
+		if merchant == "" && r.Merchant != "" {
+			merchant = r.Merchant
+		}
+		tags = append(tags, r.Tags...)
+	}
+	if len(matched) == 0 {
+		return nil
+	}
+
+	if category != "" {
+		t.Category = category
+	}
+	if merchant != "" {
+		t.Merchant = merchant
+	}
+	if normalized, err := types.NormalizeTags("tags", tags); err == nil {
+		t.Tags = normalized
+	}
+	return matched
+}
+
+// matches reports whether every condition the rule sets holds for t
+func (r rule) matches(t types.Transaction) bool {
+	if r.MerchantPattern != "" && !r.matchesMerchant(t.Merchant) {
+		return false
+	}
+	if r.location != "" && !strings.Contains(strings.ToLower(t.Location), r.location) {
+		return false
+	}
+
+	size := t.Amount.Abs()
+	if r.MinAmount != nil && size.Cents < r.MinAmount.Cents {
+		return false
+	}
+	if r.MaxAmount != nil && size.Cents > r.MaxAmount.Cents {
+		return false
+	}
+
+	if r.DayFrom != nil && r.DayTo != nil {
+		// Days past the end of a short month stand for its last day
+		day, last := t.Date.Day(), daysIn(t.Date)
+		from, to := min(*r.DayFrom, last), min(*r.DayTo, last)
+		if from <= to && (day < from || day > to) {
+			return false
+		}
+		// A range such as 28 to 3 wraps around the end of the month
+		if from > to && day < from && day > to {
+			return false
+		}
+	}
+	return true
+}
+
+func (r rule) matchesMerchant(merchant string) bool {
+	if r.pattern != nil {
+		return r.pattern.MatchString(merchant)
+	}
+
+	merchant = strings.ToLower(strings.TrimSpace(merchant))
+	switch r.MerchantMatch {
+	case types.MerchantExact:
+		return merchant == r.merchant
+	case types.MerchantPrefix:
+		return strings.HasPrefix(merchant, r.merchant)
+	default:
+		return strings.Contains(merchant, r.merchant)
+	}
+}
+
+// daysIn returns the number of days in t's month
+func daysIn(t time.Time) int {
+	return time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
@@ -0,0 +1,145 @@
+package engine
+
+import (
+	"reflect"
+	"server/types"
+	"testing"
+	"time"
+)
+
+func days(from, to int) (*int, *int) {
+	return &from, &to
+}
+
+// spend is a card payment at merchant on the given date
+func spend(merchant string, cents int64, year int, month time.Month, day int) types.Transaction {
+	return types.Transaction{
+		Merchant: merchant,
+		Amount:   types.NewMoney(cents, ""),
+		Date:     time.Date(year, month, day, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestMatchesDays(t *testing.T) {
+	payday := types.Rule{ID: 1}
+	payday.DayFrom, payday.DayTo = days(28, 3)
+	monthEnd := types.Rule{ID: 2}
+	monthEnd.DayFrom, monthEnd.DayTo = days(30, 31)
+	middle := types.Rule{ID: 3}
+	middle.DayFrom, middle.DayTo = days(10, 20)
+
+	tests := []struct {
+		name string
+		rule types.Rule
+		date time.Time
+		want bool
+	}{
+		{"wrapping range before the month end", payday, time.Date(2025, time.January, 30, 0, 0, 0, 0, time.UTC), true},
+		{"wrapping range after the month start", payday, time.Date(2025, time.February, 3, 0, 0, 0, 0, time.UTC), true},
+		{"outside a wrapping range", payday, time.Date(2025, time.January, 15, 0, 0, 0, 0, time.UTC), false},
+		{"the day after a wrapping range", payday, time.Date(2025, time.February, 4, 0, 0, 0, 0, time.UTC), false},
+		{"the 31st clamped to a 30-day month", monthEnd, time.Date(2025, time.April, 30, 0, 0, 0, 0, time.UTC), true},
+		{"both ends clamped to February", monthEnd, time.Date(2025, time.February, 28, 0, 0, 0, 0, time.UTC), true},
+		{"before clamped ends", monthEnd, time.Date(2025, time.February, 27, 0, 0, 0, 0, time.UTC), false},
+		{"clamping follows leap years", monthEnd, time.Date(2024, time.February, 28, 0, 0, 0, 0, time.UTC), false},
+		{"inside a plain range", middle, time.Date(2025, time.March, 20, 0, 0, 0, 0, time.UTC), true},
+		{"outside a plain range", middle, time.Date(2025, time.March, 21, 0, 0, 0, 0, time.UTC), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := (rule{Rule: tt.rule}).matches(types.Transaction{Date: tt.date}); got != tt.want {
+				t.Errorf("matches(%s) = %v, want %v", tt.date.Format("2006-01-02"), got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	coffee := spend("STARBUCKS #1234", -475, 2025, time.March, 14)
+	coffee.Tags = []string{"Work"}
+
+	tests := []struct {
+		name  string
+		rules []types.Rule
+		tx    types.Transaction
+
+		matched  []int64
+		category string
+		merchant string
+		tags     []string
+	}{
+		{
+			name: "lowest priority first, then lowest ID",
+			rules: []types.Rule{
+				{ID: 4, Priority: 2, MerchantPattern: "starbucks", Category: "Groceries"},
+				{ID: 3, Priority: 1, MerchantPattern: "starbucks", Merchant: "Starbucks"},
+				{ID: 2, Priority: 1, MerchantPattern: "starbucks", Category: "Dining"},
+				{ID: 1, Priority: 5, MerchantPattern: "starbucks", Merchant: "Coffee"},
+			},
+			tx:       spend("STARBUCKS #1234", -475, 2025, time.March, 14),
+			matched:  []int64{2, 3, 4, 1},
+			category: "Dining", merchant: "Starbucks", tags: []string{},
+		},
+		{
+			// Income does not fit spending, so the next category wins
+			name: "first category that fits the sign",
+			rules: []types.Rule{
+				{ID: 1, Priority: 1, MerchantPattern: "starbucks", Category: types.CategoryIncome},
+				{ID: 2, Priority: 2, MerchantPattern: "starbucks", Category: "Dining"},
+				{ID: 3, Priority: 3, MerchantPattern: "starbucks", Category: "Groceries"},
+			},
+			tx:       spend("STARBUCKS #1234", -475, 2025, time.March, 14),
+			matched:  []int64{1, 2, 3},
+			category: "Dining", merchant: "STARBUCKS #1234", tags: []string{},
+		},
+		{
+			name: "no category fits the sign",
+			rules: []types.Rule{
+				{ID: 1, MerchantPattern: "payroll", Category: "Dining"},
+			},
+			tx:       spend("ACME PAYROLL", 250000, 2025, time.March, 14),
+			matched:  []int64{1},
+			category: "", merchant: "ACME PAYROLL", tags: []string{},
+		},
+		{
+			// Later rules match the merchant as it came in, not as renamed
+			name: "tags of every matching rule",
+			rules: []types.Rule{
+				{ID: 1, MerchantPattern: "starbucks", Merchant: "Starbucks", Tags: []string{"coffee", "work"}},
+				{ID: 2, MerchantPattern: "STARBUCKS #", MerchantMatch: types.MerchantPrefix, Tags: []string{"Travel"}},
+				{ID: 3, MerchantPattern: "Starbucks", MerchantMatch: types.MerchantExact, Tags: []string{"exact"}},
+			},
+			tx:       coffee,
+			matched:  []int64{1, 2},
+			category: "", merchant: "Starbucks", tags: []string{"coffee", "travel", "work"},
+		},
+		{
+			name: "disabled and unmatched rules change nothing",
+			rules: []types.Rule{
+				{ID: 1, MerchantPattern: "starbucks", Category: "Dining", Disabled: true},
+				{ID: 2, MerchantPattern: "chipotle", Category: "Dining"},
+			},
+			tx:       coffee,
+			category: "", merchant: "STARBUCKS #1234", tags: []string{"Work"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := New(tt.rules, types.BuiltInCategories)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			tx := tt.tx
+			matched := e.Apply(&tx)
+			if !reflect.DeepEqual(matched, tt.matched) {
+				t.Errorf("Apply() matched %v, want %v", matched, tt.matched)
+			}
+			if tx.Category != tt.category || tx.Merchant != tt.merchant {
+				t.Errorf("Apply() category, merchant = %q, %q, want %q, %q", tx.Category, tx.Merchant, tt.category, tt.merchant)
+			}
+			if tags := append([]string{}, tx.Tags...); !reflect.DeepEqual(tags, tt.tags) {
+				t.Errorf("Apply() tags = %v, want %v", tx.Tags, tt.tags)
+			}
+		})
+	}
+}
@@ -0,0 +1,197 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	categoriesRepository "server/categories/repository"
+	categoriesService "server/categories/service"
+	fxRepository "server/fx/repository"
+	fxService "server/fx/service"
+	"server/rules/repository"
+	"server/rules/service"
+	"server/types"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+type Handler struct {
+	service service.Service
+}
+
+func NewHandler(service service.Service) *Handler {
+	return &Handler{service: service}
+}
+
+// SetupRuleRoutes configures all the categorization rule routes
+func SetupRuleRoutes(router *mux.Router, db *sql.DB) {
+	repo := repository.NewPostgresRepository(db)
+	fx := fxService.NewService(fxRepository.NewPostgresRepository(db))
+	categories := categoriesService.NewService(categoriesRepository.NewPostgresRepository(db), fx)
+	svc := service.NewService(repo, categories)
+	handler := NewHandler(svc)
+	handler.RegisterRoutes(router)
+}
+
+// RegisterRoutes registers all rule routes
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/api/rules/{accountId}", h.HandleListRules).Methods("GET")
+	router.HandleFunc("/api/rules/{accountId}", h.HandleCreateRule).Methods("POST")
+	router.HandleFunc("/api/rules/{accountId}/preview", h.HandlePreview).Methods("POST")
+	router.HandleFunc("/api/rules/{accountId}/run", h.HandleRun).Methods("POST")
+	router.HandleFunc("/api/rules/{accountId}/{ruleId:[0-9]+}", h.HandleGetRule).Methods("GET")
+	router.HandleFunc("/api/rules/{accountId}/{ruleId:[0-9]+}", h.HandleUpdateRule).Methods("PUT")
+	router.HandleFunc("/api/rules/{accountId}/{ruleId:[0-9]+}", h.HandleDeleteRule).Methods("DELETE")
+}
+
+// HandleListRules handles requests for an account's rules
+func (h *Handler) HandleListRules(w http.ResponseWriter, r *http.Request) {
+	accountID := mux.Vars(r)["accountId"]
+
+	rules, err := h.service.ListRules(r.Context(), accountID)
+	if err != nil {
+		writeError(w, "Failed to get rules", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rules)
+}
+
+// HandleCreateRule handles requests to create a rule
+func (h *Handler) HandleCreateRule(w http.ResponseWriter, r *http.Request) {
+	accountID := mux.Vars(r)["accountId"]
+
+	var input types.Rule
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	rule, err := h.service.CreateRule(r.Context(), accountID, input)
+	if err != nil {
+		writeError(w, "Failed to create rule", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(rule)
+}
+
+// HandleGetRule handles requests for a single rule
+func (h *Handler) HandleGetRule(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	ruleID, _ := strconv.ParseInt(vars["ruleId"], 10, 64)
+
+	rule, err := h.service.GetRule(r.Context(), vars["accountId"], ruleID)
+	if err != nil {
+		writeError(w, "Failed to get rule", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rule)
+}
+
+// HandleUpdateRule handles requests to replace a rule
+func (h *Handler) HandleUpdateRule(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	ruleID, _ := strconv.ParseInt(vars["ruleId"], 10, 64)
+
+	var input types.Rule
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	rule, err := h.service.UpdateRule(r.Context(), vars["accountId"], ruleID, input)
+	if err != nil {
+		writeError(w, "Failed to update rule", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rule)
+}
+
+// HandleDeleteRule handles requests to delete a rule
+func (h *Handler) HandleDeleteRule(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	ruleID, _ := strconv.ParseInt(vars["ruleId"], 10, 64)
+
+	if err := h.service.DeleteRule(r.Context(), vars["accountId"], ruleID); err != nil {
+		writeError(w, "Failed to delete rule", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandlePreview handles requests to see what the rules would change. Without
+// a body the account's rules are previewed; with a rule in the body only that
+// rule is, without saving it.
+func (h *Handler) HandlePreview(w http.ResponseWriter, r *http.Request) {
+	accountID := mux.Vars(r)["accountId"]
+
+	var draft *types.Rule
+	var input types.Rule
+	switch err := json.NewDecoder(r.Body).Decode(&input); {
+	case err == io.EOF:
+	case err != nil:
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	default:
+		draft = &input
+	}
+
+	run, err := h.service.Preview(r.Context(), accountID, draft)
+	if err != nil {
+		writeError(w, "Failed to preview rules", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(run)
+}
+
+// HandleRun handles requests to apply the rules to the account's existing
+// transactions. With dryRun=true nothing is saved.
+func (h *Handler) HandleRun(w http.ResponseWriter, r *http.Request) {
+	accountID := mux.Vars(r)["accountId"]
+
+	var run *types.RuleRun
+	var err error
+	if dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dryRun")); dryRun {
+		run, err = h.service.Preview(r.Context(), accountID, nil)
+	} else {
+		run, err = h.service.Run(r.Context(), accountID)
+	}
+	if err != nil {
+		writeError(w, "Failed to run rules", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(run)
+}
+
+// writeError converts service and repository errors into HTTP responses
+func writeError(w http.ResponseWriter, message string, err error) {
+	var validationErr *types.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		http.Error(w, validationErr.Error(), http.StatusBadRequest)
+	case errors.Is(err, repository.ErrAccountNotFound):
+		http.Error(w, "Account not found", http.StatusNotFound)
+	case errors.Is(err, repository.ErrRuleNotFound):
+		http.Error(w, "Rule not found", http.StatusNotFound)
+	default:
+		log.Printf("%s: %v", message, err)
+		http.Error(w, message, http.StatusInternalServerError)
+	}
+}
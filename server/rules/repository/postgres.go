@@ -0,0 +1,222 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"server/crud"
+	"server/types"
+
+	"github.com/lib/pq"
+)
+
+type postgresRepo struct {
+	db *sql.DB
+}
+
+func NewPostgresRepository(db *sql.DB) Repository {
+	if db == nil {
+		panic("database connection is required")
+	}
+	return &postgresRepo{db: db}
+}
+
+const ruleColumns = `
+	id, account_id, name, priority, disabled, merchant_pattern, merchant_match, location_pattern,
+	min_amount, max_amount, day_from, day_to, set_category, set_merchant, add_tags, created_at, updated_at`
+
+// ListRules retrieves an account's rules in the order they run
+func (r *postgresRepo) ListRules(ctx context.Context, accountID string) ([]types.Rule, error) {
+	query := `SELECT` + ruleColumns + ` FROM rules WHERE account_id = $1 ORDER BY priority, id`
+
+	rows, err := r.db.QueryContext(ctx, query, accountID)
+	if err != nil {
+		log.Printf("Error querying rules: %v", err)
+		return nil, fmt.Errorf("failed to query rules: %w", err)
+	}
+	defer rows.Close()
+
+	rules := []types.Rule{}
+	for rows.Next() {
+		rule, err := scanRule(rows)
+		if err != nil {
+			log.Printf("Error scanning rule: %v", err)
+			return nil, fmt.Errorf("failed to scan rule: %w", err)
+		}
+		rules = append(rules, *rule)
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("Error iterating rules: %v", err)
+		return nil, fmt.Errorf("error iterating rules: %w", err)
+	}
+	return rules, nil
+}
+
+// GetRule retrieves a single rule of an account
+func (r *postgresRepo) GetRule(ctx context.Context, accountID string, ruleID int64) (*types.Rule, error) {
+	query := `SELECT` + ruleColumns + ` FROM rules WHERE account_id = $1 AND id = $2`
+
+	rule, err := scanRule(r.db.QueryRowContext(ctx, query, accountID, ruleID))
+	if err == sql.ErrNoRows {
+		return nil, ErrRuleNotFound
+	}
+	if err != nil {
+		log.Printf("Error fetching rule %d: %v", ruleID, err)
+		return nil, fmt.Errorf("failed to fetch rule: %w", err)
+	}
+	return rule, nil
+}
+
+// CreateRule inserts a rule, setting its ID and timestamps
+func (r *postgresRepo) CreateRule(ctx context.Context, rule *types.Rule) error {
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO rules (
+			account_id, name, priority, disabled, merchant_pattern, merchant_match, location_pattern,
+			min_amount, max_amount, day_from, day_to, set_category, set_merchant, add_tags
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+		RETURNING id, created_at, updated_at`,
+		rule.AccountID, rule.Name, rule.Priority, rule.Disabled, rule.MerchantPattern, rule.MerchantMatch,
+		rule.LocationPattern, rule.MinAmount, rule.MaxAmount, rule.DayFrom, rule.DayTo,
+		rule.Category, rule.Merchant, pq.Array(rule.Tags),
+	).Scan(&rule.ID, &rule.CreatedAt, &rule.UpdatedAt)
+	if err != nil {
+		return translateError(err)
+	}
+	return nil
+}
+
+// UpdateRule replaces a rule's settings
+func (r *postgresRepo) UpdateRule(ctx context.Context, rule *types.Rule) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE rules SET
+			name = $3, priority = $4, disabled = $5, merchant_pattern = $6, merchant_match = $7,
+			location_pattern = $8, min_amount = $9, max_amount = $10, day_from = $11, day_to = $12,
+			set_category = $13, set_merchant = $14, add_tags = $15, updated_at = NOW()
+		WHERE account_id = $1 AND id = $2`,
+		rule.AccountID, rule.ID, rule.Name, rule.Priority, rule.Disabled, rule.MerchantPattern, rule.MerchantMatch,
+		rule.LocationPattern, rule.MinAmount, rule.MaxAmount, rule.DayFrom, rule.DayTo,
+		rule.Category, rule.Merchant, pq.Array(rule.Tags),
+	)
+	if err != nil {
+		return translateError(err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrRuleNotFound
+	}
+	return nil
+}
+
+// DeleteRule removes a rule
+func (r *postgresRepo) DeleteRule(ctx context.Context, accountID string, ruleID int64) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM rules WHERE account_id = $1 AND id = $2`, accountID, ruleID)
+	if err != nil {
+		log.Printf("Error deleting rule %d: %v", ruleID, err)
+		return fmt.Errorf("failed to delete rule: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrRuleNotFound
+	}
+	return nil
+}
+
+// ListTransactions retrieves all transactions of an account with their tags,
+// oldest first
+func (r *postgresRepo) ListTransactions(ctx context.Context, accountID string) ([]types.Transaction, error) {
+	query := `
+		SELECT t.transaction_id, t.account_id, t.date, t.amount, t.category, t.merchant, t.location,
+			ARRAY(
+				SELECT g.name FROM transaction_tags tt JOIN tags g ON g.id = tt.tag_id
+				WHERE tt.transaction_id = t.transaction_id ORDER BY g.name
+			)
+		FROM transactions t
+		WHERE t.account_id = $1
+		ORDER BY t.date, t.transaction_id`
+
+	rows, err := r.db.QueryContext(ctx, query, accountID)
+	if err != nil {
+		log.Printf("Error querying transactions for rules: %v", err)
+		return nil, fmt.Errorf("failed to query transactions: %w", err)
+	}
+	defer rows.Close()
+
+	transactions := []types.Transaction{}
+	for rows.Next() {
+		var t types.Transaction
+		if err := rows.Scan(
+			&t.TransactionID, &t.AccountID, &t.Date, &t.Amount, &t.Category, &t.Merchant, &t.Location,
+			(*pq.StringArray)(&t.Tags),
+		); err != nil {
+			log.Printf("Error scanning transaction: %v", err)
+			return nil, fmt.Errorf("failed to scan transaction: %w", err)
+		}
+		transactions = append(transactions, t)
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("Error iterating transactions: %v", err)
+		return nil, fmt.Errorf("error iterating transactions: %w", err)
+	}
+	return transactions, nil
+}
+
+// ApplyChanges saves the new category, merchant and tags of each changed
+// transaction in a single database transaction
+func (r *postgresRepo) ApplyChanges(ctx context.Context, accountID string, changes []types.RuleChange) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, c := range changes {
+		_, err := tx.ExecContext(ctx, `
+			UPDATE transactions SET category = $3, merchant = $4
+			WHERE account_id = $1 AND transaction_id = $2`,
+			accountID, c.TransactionID, c.NewCategory, c.NewMerchant,
+		)
+		if err != nil {
+			log.Printf("Error recategorizing transaction %s: %v", c.TransactionID, err)
+			return fmt.Errorf("failed to recategorize transaction: %w", err)
+		}
+		if err := crud.InsertTransactionTagsTx(tx, accountID, c.TransactionID, c.NewTags); err != nil {
+			log.Printf("Error tagging transaction %s: %v", c.TransactionID, err)
+			return fmt.Errorf("failed to tag transaction: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit rule changes: %w", err)
+	}
+	log.Printf("Rules changed %d transactions of account %s", len(changes), accountID)
+	return nil
+}
+
+// translateError maps Postgres constraint violations onto the repository errors
+func translateError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code.Name() == "foreign_key_violation" {
+		return ErrAccountNotFound
+	}
+	log.Printf("Error writing rule: %v", err)
+	return fmt.Errorf("failed to write rule: %w", err)
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanRule(row rowScanner) (*types.Rule, error) {
+	var r types.Rule
+	var tags pq.StringArray
+	err := row.Scan(
+		&r.ID, &r.AccountID, &r.Name, &r.Priority, &r.Disabled, &r.MerchantPattern, &r.MerchantMatch,
+		&r.LocationPattern, &r.MinAmount, &r.MaxAmount, &r.DayFrom, &r.DayTo,
+		&r.Category, &r.Merchant, &tags, &r.CreatedAt, &r.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	r.Tags = tags
+	return &r, nil
+}
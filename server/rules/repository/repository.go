@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"server/types"
+)
+
+var (
+	// ErrAccountNotFound is returned when the account does not exist
+	ErrAccountNotFound = errors.New("account not found")
+
+	// ErrRuleNotFound is returned when the rule does not exist for the account
+	ErrRuleNotFound = errors.New("rule not found")
+)
+
+// Repository defines the interface for categorization rule data operations
+type Repository interface {
+	// ListRules retrieves an account's rules in the order they run
+	ListRules(ctx context.Context, accountID string) ([]types.Rule, error)
+
+	// GetRule retrieves a single rule of an account
+	GetRule(ctx context.Context, accountID string, ruleID int64) (*types.Rule, error)
+
+	// CreateRule inserts a rule, setting its ID and timestamps
+	CreateRule(ctx context.Context, rule *types.Rule) error
+
+	// UpdateRule replaces a rule's settings
+	UpdateRule(ctx context.Context, rule *types.Rule) error
+
+	// DeleteRule removes a rule
+	DeleteRule(ctx context.Context, accountID string, ruleID int64) error
+
+	// ListTransactions retrieves all transactions of an account with their tags
+	ListTransactions(ctx context.Context, accountID string) ([]types.Transaction, error)
+
+	// ApplyChanges saves the new category, merchant and tags of each changed
+	// transaction. Either every change is saved or none is.
+	ApplyChanges(ctx context.Context, accountID string, changes []types.RuleChange) error
+}
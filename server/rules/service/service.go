@@ -0,0 +1,208 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	categoriesService "server/categories/service"
+	"server/rules/engine"
+	"server/rules/repository"
+	"server/types"
+	"slices"
+)
+
+type Service interface {
+	ListRules(ctx context.Context, accountID string) ([]types.Rule, error)
+	GetRule(ctx context.Context, accountID string, ruleID int64) (*types.Rule, error)
+	CreateRule(ctx context.Context, accountID string, input types.Rule) (*types.Rule, error)
+	UpdateRule(ctx context.Context, accountID string, ruleID int64, input types.Rule) (*types.Rule, error)
+	DeleteRule(ctx context.Context, accountID string, ruleID int64) error
+	Preview(ctx context.Context, accountID string, draft *types.Rule) (*types.RuleRun, error)
+	Run(ctx context.Context, accountID string) (*types.RuleRun, error)
+	Apply(ctx context.Context, accountID string, transactions []types.Transaction) error
+}
+
+type service struct {
+	repo       repository.Repository
+	categories categoriesService.Service
+}
+
+func NewService(repo repository.Repository, categories categoriesService.Service) Service {
+	return &service{repo: repo, categories: categories}
+}
+
+func (s *service) ListRules(ctx context.Context, accountID string) ([]types.Rule, error) {
+	return s.repo.ListRules(ctx, accountID)
+}
+
+func (s *service) GetRule(ctx context.Context, accountID string, ruleID int64) (*types.Rule, error) {
+	return s.repo.GetRule(ctx, accountID, ruleID)
+}
+
+// CreateRule validates the input and creates a rule for the account. The
+// rule applies to transactions added from now on; Run applies it to the
+// existing ones.
+func (s *service) CreateRule(ctx context.Context, accountID string, input types.Rule) (*types.Rule, error) {
+	categories, err := s.categories.Categories(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	if err := input.Validate(categories); err != nil {
+		return nil, err
+	}
+	input.AccountID = accountID
+
+	if err := s.repo.CreateRule(ctx, &input); err != nil {
+		return nil, err
+	}
+	return s.repo.GetRule(ctx, accountID, input.ID)
+}
+
+// UpdateRule replaces every setting of a rule
+func (s *service) UpdateRule(ctx context.Context, accountID string, ruleID int64, input types.Rule) (*types.Rule, error) {
+	categories, err := s.categories.Categories(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	if err := input.Validate(categories); err != nil {
+		return nil, err
+	}
+	input.AccountID = accountID
+	input.ID = ruleID
+
+	if err := s.repo.UpdateRule(ctx, &input); err != nil {
+		return nil, err
+	}
+	return s.repo.GetRule(ctx, accountID, ruleID)
+}
+
+func (s *service) DeleteRule(ctx context.Context, accountID string, ruleID int64) error {
+	return s.repo.DeleteRule(ctx, accountID, ruleID)
+}
+
+// Preview reports what running the rules over the account's transactions
+// would change without saving anything. With a draft it runs only that rule,
+// so a rule can be tried out before it is created.
+func (s *service) Preview(ctx context.Context, accountID string, draft *types.Rule) (*types.RuleRun, error) {
+	var rules []types.Rule
+	if draft != nil {
+		categories, err := s.categories.Categories(ctx, accountID)
+		if err != nil {
+			return nil, err
+		}
+		if err := draft.Validate(categories); err != nil {
+			return nil, err
+		}
+		draft.Disabled = false
+		rules = []types.Rule{*draft}
+	} else {
+		var err error
+		if rules, err = s.repo.ListRules(ctx, accountID); err != nil {
+			return nil, err
+		}
+	}
+	return s.run(ctx, accountID, rules, true)
+}
+
+// Run applies the account's rules to all of its existing transactions. A
+// transaction's category and merchant are overwritten where a rule matches,
+// and the tags of matching rules are added to the ones it has.
+func (s *service) Run(ctx context.Context, accountID string) (*types.RuleRun, error) {
+	rules, err := s.repo.ListRules(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	return s.run(ctx, accountID, rules, false)
+}
+
+func (s *service) run(ctx context.Context, accountID string, rules []types.Rule, dryRun bool) (*types.RuleRun, error) {
+	e, err := s.engine(ctx, accountID, rules)
+	if err != nil {
+		return nil, err
+	}
+	transactions, err := s.repo.ListTransactions(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &types.RuleRun{
+		AccountID: accountID,
+		DryRun:    dryRun,
+		Scanned:   len(transactions),
+		Changes:   []types.RuleChange{},
+	}
+	for _, t := range transactions {
+		if t.Tags == nil {
+			t.Tags = []string{}
+		}
+		updated := t
+		matched := e.Apply(&updated)
+		if len(matched) == 0 || !changed(t, updated) {
+			continue
+		}
+		result.Changes = append(result.Changes, types.RuleChange{
+			TransactionID: t.TransactionID,
+			Date:          t.Date,
+			Amount:        t.Amount,
+			Category:      t.Category,
+			Merchant:      t.Merchant,
+			Tags:          t.Tags,
+			NewCategory:   updated.Category,
+			NewMerchant:   updated.Merchant,
+			NewTags:       updated.Tags,
+			RuleIDs:       matched,
+		})
+	}
+	result.Changed = len(result.Changes)
+
+	if dryRun || result.Changed == 0 {
+		return result, nil
+	}
+	if err := s.repo.ApplyChanges(ctx, accountID, result.Changes); err != nil {
+		return nil, fmt.Errorf("failed to apply rules: %w", err)
+	}
+	return result, nil
+}
+
+// Apply runs the account's rules over transactions that are about to be
+// inserted, updating them in place
+func (s *service) Apply(ctx context.Context, accountID string, transactions []types.Transaction) error {
+	if len(transactions) == 0 {
+		return nil
+	}
+	rules, err := s.repo.ListRules(ctx, accountID)
+	if err != nil || len(rules) == 0 {
+		return err
+	}
+	e, err := s.engine(ctx, accountID, rules)
+	if err != nil {
+		return err
+	}
+	if e.Len() == 0 {
+		return nil
+	}
+
+	for i := range transactions {
+		e.Apply(&transactions[i])
+	}
+	return nil
+}
+
+// engine prepares the rules to run against the account's categories
+func (s *service) engine(ctx context.Context, accountID string, rules []types.Rule) (*engine.Engine, error) {
+	categories, err := s.categories.Categories(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	return engine.New(rules, categories)
+}
+
+// changed reports whether the rules changed anything about the transaction.
+// The rules sort the tags, so the tags it had are sorted the same way before
+// they are compared.
+func changed(before, after types.Transaction) bool {
+	tags := slices.Clone(before.Tags)
+	slices.Sort(tags)
+	return before.Category != after.Category ||
+		before.Merchant != after.Merchant ||
+		!slices.Equal(tags, after.Tags)
+}
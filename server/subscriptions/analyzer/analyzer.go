@@ -0,0 +1,206 @@
+package analyzer
+
+import (
+	"math"
+	"server/bills/recurrence"
+	"server/types"
+	"sort"
+	"time"
+)
+
+// Config controls what the analyzer reports and flags
+type Config struct {
+	// NewDays is how recently a subscription must have started to be new.
+	// New subscriptions are reported even before their cycle is known.
+	NewDays int
+
+	// AlertDays is how recently a price must have gone up to be flagged
+	AlertDays int
+
+	// IdleDays is how long a subscription's merchant may go without any
+	// other activity before the subscription is flagged as unused
+	IdleDays int
+
+	// PriceTolerance is the fraction a charge may differ from the price
+	// before it counts as a new price, so that rounding in currency
+	// conversion or tax does not look like a price change
+	PriceTolerance float64
+}
+
+// DefaultConfig flags price increases and inactivity over the last three
+// months and subscriptions started in the last two
+var DefaultConfig = Config{NewDays: 60, AlertDays: 90, IdleDays: 90, PriceTolerance: 0.01}
+
+// charge is one payment of a subscription; payments on the same day count as one
+type charge struct {
+	date     time.Time
+	amount   types.Money
+	currency string
+}
+
+// activity is what else happened at a merchant
+type activity struct {
+	count int
+	last  time.Time
+}
+
+// Analyze audits subscriptions from their charges and the other transactions
+// at the same merchants. A merchant's charges are a subscription when the
+// recurrence detector finds a cycle in them, or when they started less than
+// NewDays before today; one too new for its cycle to be known is taken to be
+// monthly. The result is ordered by monthly cost, largest first.
+func (c Config) Analyze(charges []types.Transaction, related []types.Transaction, today time.Time) []types.Subscription {
+	today = day(today)
+
+	recurring := make(map[string]types.RecurringBill)
+	for _, bill := range recurrence.DefaultConfig.Detect(charges, today) {
+		recurring[bill.Merchant] = bill
+	}
+
+	byMerchant := make(map[string][]types.Transaction)
+	var merchants []string
+	for _, t := range charges {
+		if t.Amount.Sign() >= 0 {
+			continue
+		}
+		if _, ok := byMerchant[t.Merchant]; !ok {
+			merchants = append(merchants, t.Merchant)
+		}
+		byMerchant[t.Merchant] = append(byMerchant[t.Merchant], t)
+	}
+
+	activities := make(map[string]*activity)
+	for _, t := range related {
+		a, ok := activities[t.Merchant]
+		if !ok {
+			a = &activity{}
+			activities[t.Merchant] = a
+		}
+		a.count++
+		if d := day(t.Date); d.After(a.last) {
+			a.last = d
+		}
+	}
+
+	var subscriptions []types.Subscription
+	for _, merchant := range merchants {
+		history := collapse(byMerchant[merchant])
+		first, last := history[0], history[len(history)-1]
+		bill, detected := recurring[merchant]
+		isNew := daysBetween(first.date, today) < c.NewDays
+		if !detected && !isNew {
+			continue
+		}
+
+		sub := types.Subscription{
+			Merchant:     merchant,
+			Currency:     last.currency,
+			Frequency:    types.BillMonthly,
+			Price:        last.amount,
+			Charges:      len(history),
+			FirstCharge:  first.date,
+			LastCharge:   last.date,
+			NextDueDate:  last.date.AddDate(0, 1, 0),
+			PriceHistory: c.prices(history),
+			Flags:        []string{},
+		}
+		if detected {
+			sub.Frequency = bill.Frequency
+			sub.Confidence = bill.Confidence
+			sub.NextDueDate = bill.NextDueDate
+		}
+		sub.MonthlyCost = monthlyCost(sub.Price, sub.Frequency)
+
+		if n := len(sub.PriceHistory); n > 1 {
+			from, to := sub.PriceHistory[n-2], sub.PriceHistory[n-1]
+			sub.PriceChange = &types.SubscriptionPriceChange{
+				From:    from.Amount,
+				To:      to.Amount,
+				Date:    to.Since,
+				Percent: round(100*float64(to.Amount.Cents-from.Amount.Cents)/float64(from.Amount.Cents), 1),
+			}
+			if to.Amount.Cmp(from.Amount) > 0 && daysBetween(to.Since, today) <= c.AlertDays {
+				sub.Flags = append(sub.Flags, types.SubscriptionPriceIncrease)
+			}
+		}
+		if isNew {
+			sub.Flags = append(sub.Flags, types.SubscriptionNew)
+		}
+
+		if a, ok := activities[merchant]; ok {
+			latest := a.last
+			sub.RelatedTransactions = a.count
+			sub.LastActivity = &latest
+		}
+		if sub.LastActivity == nil || daysBetween(*sub.LastActivity, today) > c.IdleDays {
+			sub.Flags = append(sub.Flags, types.SubscriptionUnused)
+		}
+		subscriptions = append(subscriptions, sub)
+	}
+
+	sort.SliceStable(subscriptions, func(i, j int) bool {
+		return subscriptions[i].MonthlyCost.Cmp(subscriptions[j].MonthlyCost) > 0
+	})
+	return subscriptions
+}
+
+// prices splits the charges into runs at the same price, oldest first
+func (c Config) prices(history []charge) []types.SubscriptionPrice {
+	var prices []types.SubscriptionPrice
+	for _, ch := range history {
+		if n := len(prices); n > 0 {
+			current := &prices[n-1]
+			diff := math.Abs(float64(ch.amount.Cents - current.Amount.Cents))
+			if diff <= c.PriceTolerance*float64(current.Amount.Cents) {
+				current.Charges++
+				continue
+			}
+		}
+		prices = append(prices, types.SubscriptionPrice{Amount: ch.amount, Since: ch.date, Charges: 1})
+	}
+	return prices
+}
+
+// monthlyCost spreads a price charged at the given frequency over a month
+func monthlyCost(price types.Money, frequency string) types.Money {
+	switch frequency {
+	case types.BillWeekly:
+		return types.NewMoney(price.Cents*52, price.Currency).Div(12)
+	case types.BillBiweekly:
+		return types.NewMoney(price.Cents*26, price.Currency).Div(12)
+	}
+	if months := types.BillFrequencies[frequency]; months > 1 {
+		return price.Div(months)
+	}
+	return price
+}
+
+// collapse sorts the charges by date and merges those on the same day
+func collapse(transactions []types.Transaction) []charge {
+	sorted := append([]types.Transaction(nil), transactions...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Date.Before(sorted[j].Date) })
+
+	var charges []charge
+	for _, t := range sorted {
+		date := day(t.Date)
+		if n := len(charges); n > 0 && charges[n-1].date.Equal(date) {
+			charges[n-1].amount = charges[n-1].amount.Add(t.Amount.Abs())
+			continue
+		}
+		charges = append(charges, charge{date: date, amount: t.Amount.Abs(), currency: t.Currency})
+	}
+	return charges
+}
+
+func day(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func daysBetween(a, b time.Time) int {
+	return int(math.Round(b.Sub(a).Hours() / 24))
+}
+
+func round(value float64, places int) float64 {
+	scale := math.Pow(10, float64(places))
+	return math.Round(value*scale) / scale
+}
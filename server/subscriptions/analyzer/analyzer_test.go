@@ -0,0 +1,158 @@
+package analyzer
+
+import (
+	"reflect"
+	"server/types"
+	"testing"
+	"time"
+)
+
+func on(month time.Month, day int) time.Time {
+	return time.Date(2025, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// charges returns a charge of cents by merchant on each of the dates
+func charges(merchant string, cents int64, dates ...time.Time) []types.Transaction {
+	transactions := make([]types.Transaction, len(dates))
+	for i, d := range dates {
+		transactions[i] = types.Transaction{
+			Merchant: merchant,
+			Amount:   types.NewMoney(-cents, ""),
+			Category: types.CategorySubscription,
+			Currency: "USD",
+			Date:     d,
+		}
+	}
+	return transactions
+}
+
+func TestPrices(t *testing.T) {
+	// run is a price, the month its first charge was in and its charges
+	type run struct {
+		cents   int64
+		since   time.Month
+		charges int
+	}
+	tests := []struct {
+		name      string
+		tolerance float64
+		cents     []int64 // one charge a month from January
+		want      []run
+	}{
+		{"one price", 0.01, []int64{1299, 1299, 1299}, []run{{1299, time.January, 3}}},
+		{"within the tolerance", 0.01, []int64{1000, 1005, 1010, 995}, []run{{1000, time.January, 4}}},
+		{"drift is measured from the run's first price", 0.01, []int64{1000, 1010, 1020}, []run{{1000, time.January, 2}, {1020, time.March, 1}}},
+		{"an increase and a decrease", 0.01, []int64{1299, 1599, 1599, 1299}, []run{{1299, time.January, 1}, {1599, time.February, 2}, {1299, time.April, 1}}},
+		{"no tolerance", 0, []int64{1000, 1001}, []run{{1000, time.January, 1}, {1001, time.February, 1}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			history := make([]charge, len(tt.cents))
+			for i, cents := range tt.cents {
+				history[i] = charge{date: on(time.Month(i+1), 5), amount: types.NewMoney(cents, "USD")}
+			}
+			var got []run
+			for _, p := range (Config{PriceTolerance: tt.tolerance}).prices(history) {
+				got = append(got, run{p.Amount.Cents, p.Since.Month(), p.Charges})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("prices() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMonthlyCost(t *testing.T) {
+	tests := []struct {
+		frequency string
+		cents     int64
+		want      int64
+	}{
+		{types.BillWeekly, 1000, 4333},
+		{types.BillBiweekly, 1000, 2167},
+		{types.BillMonthly, 1299, 1299},
+		{types.BillQuarterly, 3000, 1000},
+		{types.BillSemiannual, 5999, 1000},
+		{types.BillAnnual, 11900, 992},
+	}
+	for _, tt := range tests {
+		if got := monthlyCost(types.NewMoney(tt.cents, "USD"), tt.frequency); got.Cents != tt.want || got.Currency != "USD" {
+			t.Errorf("monthlyCost(%d, %s) = %d %s, want %d USD", tt.cents, tt.frequency, got.Cents, got.Currency, tt.want)
+		}
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	today := on(time.May, 20)
+
+	var subscriptions []types.Transaction
+	// Monthly since January, up from 12.99 to 15.99 in April
+	subscriptions = append(subscriptions, charges("Streaming", 1299, on(time.January, 5), on(time.February, 5), on(time.March, 5))...)
+	subscriptions = append(subscriptions, charges("Streaming", 1599, on(time.April, 5), on(time.May, 5))...)
+	// First charged this month, too recently for a cycle
+	subscriptions = append(subscriptions, charges("Gym", 4500, on(time.May, 1))...)
+	// Neither recurring nor new
+	subscriptions = append(subscriptions, charges("App Store", 299, on(time.January, 9), on(time.March, 2))...)
+	// Refunds are not charges
+	subscriptions = append(subscriptions, charges("Gym", -4500, on(time.May, 2))...)
+
+	related := []types.Transaction{
+		{Merchant: "Streaming", Date: on(time.January, 20)},
+		{Merchant: "Gym", Date: on(time.May, 3)},
+		{Merchant: "Gym", Date: on(time.May, 15)},
+	}
+
+	type want struct {
+		merchant    string
+		frequency   string
+		monthlyCost int64
+		prices      int
+		nextDue     time.Time
+		related     int
+		flags       []string
+	}
+	// Largest monthly cost first
+	expected := []want{
+		{"Gym", types.BillMonthly, 4500, 1, on(time.June, 1), 2, []string{types.SubscriptionNew}},
+		{"Streaming", types.BillMonthly, 1599, 2, on(time.June, 5), 1, []string{types.SubscriptionPriceIncrease, types.SubscriptionUnused}},
+	}
+
+	got := DefaultConfig.Analyze(subscriptions, related, today)
+	if len(got) != len(expected) {
+		t.Fatalf("Analyze() returned %d subscriptions, want %d: %+v", len(got), len(expected), got)
+	}
+	for i, w := range expected {
+		s := got[i]
+		if s.Merchant != w.merchant || s.Frequency != w.frequency {
+			t.Errorf("subscription %d = %s %s, want %s %s", i, s.Merchant, s.Frequency, w.merchant, w.frequency)
+		}
+		if s.MonthlyCost.Cents != w.monthlyCost || len(s.PriceHistory) != w.prices {
+			t.Errorf("%s: monthly cost %s with %d prices, want %d cents with %d", s.Merchant, s.MonthlyCost, len(s.PriceHistory), w.monthlyCost, w.prices)
+		}
+		if !s.NextDueDate.Equal(w.nextDue) {
+			t.Errorf("%s: NextDueDate = %s, want %s", s.Merchant, s.NextDueDate.Format("2006-01-02"), w.nextDue.Format("2006-01-02"))
+		}
+		if s.RelatedTransactions != w.related {
+			t.Errorf("%s: RelatedTransactions = %d, want %d", s.Merchant, s.RelatedTransactions, w.related)
+		}
+		if !reflect.DeepEqual(s.Flags, w.flags) {
+			t.Errorf("%s: Flags = %v, want %v", s.Merchant, s.Flags, w.flags)
+		}
+	}
+
+	change := got[1].PriceChange
+	if change == nil || change.From.Cents != 1299 || change.To.Cents != 1599 || change.Percent != 23.1 || !change.Date.Equal(on(time.April, 5)) {
+		t.Errorf("Streaming: PriceChange = %+v, want 12.99 to 15.99 (23.1%%) on 2025-04-05", change)
+	}
+	if got[0].Confidence != 0 || got[0].LastActivity == nil || !got[0].LastActivity.Equal(on(time.May, 15)) {
+		t.Errorf("Gym: Confidence = %v, LastActivity = %v, want 0 and 2025-05-15", got[0].Confidence, got[0].LastActivity)
+	}
+
+	// A lower alert window no longer flags the April increase, and a longer
+	// idle window keeps Streaming in use
+	quiet := Config{NewDays: 10, AlertDays: 30, IdleDays: 150, PriceTolerance: 0.01}
+	got = quiet.Analyze(subscriptions, related, today)
+	if len(got) != 1 || got[0].Merchant != "Streaming" || len(got[0].Flags) != 0 {
+		t.Errorf("Analyze() with %+v = %+v, want Streaming without flags", quiet, got)
+	}
+}
@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	ownersHandler "server/owners/handler"
+	ownersRepository "server/owners/repository"
+	ownersService "server/owners/service"
+	"server/subscriptions/repository"
+	"server/subscriptions/service"
+	"server/types"
+
+	"github.com/gorilla/mux"
+)
+
+type Handler struct {
+	service service.Service
+	owners  ownersService.Service
+}
+
+func NewHandler(service service.Service, owners ownersService.Service) *Handler {
+	return &Handler{service: service, owners: owners}
+}
+
+// SetupSubscriptionRoutes configures all the subscription-related routes
+func SetupSubscriptionRoutes(router *mux.Router, db *sql.DB) {
+	repo := repository.NewPostgresRepository(db)
+	svc := service.NewService(repo)
+	owners := ownersService.NewService(ownersRepository.NewPostgresRepository(db))
+	handler := NewHandler(svc, owners)
+	handler.RegisterRoutes(router)
+}
+
+// RegisterRoutes registers all subscription routes
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/api/subscriptions/{accountId}", h.HandleGetSubscriptions).Methods("GET")
+
+	// The same view across every account linked to an owner
+	router.HandleFunc("/api/owners/{ownerId:[0-9]+}/subscriptions", h.HandleGetSubscriptions).Methods("GET")
+}
+
+// HandleGetSubscriptions handles requests for the subscription audit
+func (h *Handler) HandleGetSubscriptions(w http.ResponseWriter, r *http.Request) {
+	accountIDs, _, err := ownersHandler.RequestAccounts(r, h.owners)
+	if err != nil {
+		writeError(w, "Failed to get subscriptions", err)
+		return
+	}
+
+	report, err := h.service.GetSubscriptions(r.Context(), accountIDs)
+	if err != nil {
+		writeError(w, "Failed to get subscriptions", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// writeError converts errors resolving the accounts of a request into HTTP
+// responses; anything else is ours
+func writeError(w http.ResponseWriter, message string, err error) {
+	var validationErr *types.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		http.Error(w, validationErr.Error(), http.StatusBadRequest)
+	case errors.Is(err, ownersRepository.ErrOwnerNotFound):
+		http.Error(w, "Owner not found", http.StatusNotFound)
+	default:
+		log.Printf("%s: %v", message, err)
+		http.Error(w, message, http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"server/types"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type postgresRepo struct {
+	db *sql.DB
+}
+
+func NewPostgresRepository(db *sql.DB) Repository {
+	if db == nil {
+		panic("database connection is required")
+	}
+	return &postgresRepo{db: db}
+}
+
+// canonicalMerchant is the canonical name of a transaction's merchant, or
+// its raw description when it is not linked to one. Queries that use it join
+// transactions t with merchants m.
+const canonicalMerchant = `COALESCE(m.name, t.merchant)`
+
+// ListCharges retrieves the subscription charges of the accounts
+func (r *postgresRepo) ListCharges(ctx context.Context, accountIDs []string, from, to time.Time) ([]types.Transaction, error) {
+	if len(accountIDs) == 0 {
+		return nil, fmt.Errorf("at least one account ID is required")
+	}
+
+	log.Printf("Fetching subscription charges for accounts %v between %s and %s", accountIDs, from.Format("2006-01-02"), to.Format("2006-01-02"))
+
+	query := `
+		SELECT t.transaction_id, t.account_id, t.date, t.amount, t.category,
+			` + canonicalMerchant + `, t.location, t.currency, t.merchant_id
+		FROM transactions t
+		LEFT JOIN merchants m ON m.id = t.merchant_id
+		WHERE t.account_id = ANY($1)
+		  AND t.transfer_id IS NULL
+		  AND t.amount < 0
+		  AND t.category = $2
+		  AND t.date >= $3
+		  AND t.date < $4
+		ORDER BY t.date, t.transaction_id`
+
+	return r.queryTransactions(ctx, query, pq.Array(accountIDs), types.CategorySubscription, from, to)
+}
+
+// ListRelated retrieves the transactions outside the Subscription category
+// at the subscriptions' merchants, whether spending or refunds
+func (r *postgresRepo) ListRelated(ctx context.Context, accountIDs []string, merchants []string, from, to time.Time) ([]types.Transaction, error) {
+	if len(accountIDs) == 0 {
+		return nil, fmt.Errorf("at least one account ID is required")
+	}
+	if len(merchants) == 0 {
+		return nil, nil
+	}
+
+	query := `
+		SELECT t.transaction_id, t.account_id, t.date, t.amount, t.category,
+			` + canonicalMerchant + `, t.location, t.currency, t.merchant_id
+		FROM transactions t
+		LEFT JOIN merchants m ON m.id = t.merchant_id
+		WHERE t.account_id = ANY($1)
+		  AND t.transfer_id IS NULL
+		  AND t.category IS DISTINCT FROM $2
+		  AND ` + canonicalMerchant + ` = ANY($3)
+		  AND t.date >= $4
+		  AND t.date < $5
+		ORDER BY t.date, t.transaction_id`
+
+	return r.queryTransactions(ctx, query, pq.Array(accountIDs), types.CategorySubscription, pq.Array(merchants), from, to)
+}
+
+// queryTransactions runs a query for transactions with their canonical merchant
+func (r *postgresRepo) queryTransactions(ctx context.Context, query string, args ...interface{}) ([]types.Transaction, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		log.Printf("Error querying subscription transactions: %v", err)
+		return nil, fmt.Errorf("failed to query subscription transactions: %w", err)
+	}
+	defer rows.Close()
+
+	var transactions []types.Transaction
+	for rows.Next() {
+		var t types.Transaction
+		if err := rows.Scan(
+			&t.TransactionID,
+			&t.AccountID,
+			&t.Date,
+			&t.Amount,
+			&t.Category,
+			&t.Merchant,
+			&t.Location,
+			&t.Currency,
+			&t.MerchantID,
+		); err != nil {
+			log.Printf("Error scanning transaction: %v", err)
+			return nil, fmt.Errorf("failed to scan transaction: %w", err)
+		}
+		transactions = append(transactions, t)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Printf("Error iterating transactions: %v", err)
+		return nil, fmt.Errorf("error iterating transactions: %w", err)
+	}
+	return transactions, nil
+}
@@ -0,0 +1,19 @@
+package repository
+
+import (
+	"context"
+	"server/types"
+	"time"
+)
+
+// Repository defines the interface for subscription data operations
+type Repository interface {
+	// ListCharges retrieves the accounts' outgoing transactions in the
+	// Subscription category between from and to, excluding transfers, with
+	// their canonical merchant, by date
+	ListCharges(ctx context.Context, accountIDs []string, from, to time.Time) ([]types.Transaction, error)
+
+	// ListRelated retrieves the accounts' other transactions at the given
+	// canonical merchants between from and to, excluding transfers, by date
+	ListRelated(ctx context.Context, accountIDs []string, merchants []string, from, to time.Time) ([]types.Transaction, error)
+}
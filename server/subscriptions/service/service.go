@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"server/subscriptions/analyzer"
+	"server/subscriptions/repository"
+	"server/types"
+	"time"
+)
+
+// historyMonths is how far back subscription charges are audited: long
+// enough to see an annual subscription renew
+const historyMonths = 25
+
+type Service interface {
+	GetSubscriptions(ctx context.Context, accountIDs []string) (*types.SubscriptionReport, error)
+}
+
+type service struct {
+	repo repository.Repository
+}
+
+func NewService(repo repository.Repository) Service {
+	return &service{repo: repo}
+}
+
+// GetSubscriptions audits the accounts' subscriptions over the last
+// historyMonths months: their price history, what they cost a month, and
+// which of them went up in price, are new or see no other activity
+func (s *service) GetSubscriptions(ctx context.Context, accountIDs []string) (*types.SubscriptionReport, error) {
+	now := time.Now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	from := today.AddDate(0, -historyMonths, 0)
+	to := today.AddDate(0, 0, 1)
+
+	charges, err := s.repo.ListCharges(ctx, accountIDs, from, to)
+	if err != nil {
+		return nil, err
+	}
+	var merchants []string
+	seen := make(map[string]bool)
+	for _, t := range charges {
+		if !seen[t.Merchant] {
+			seen[t.Merchant] = true
+			merchants = append(merchants, t.Merchant)
+		}
+	}
+	related, err := s.repo.ListRelated(ctx, accountIDs, merchants, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &types.SubscriptionReport{
+		From:          from.Format("2006-01-02"),
+		To:            today.Format("2006-01-02"),
+		Subscriptions: analyzer.DefaultConfig.Analyze(charges, related, today),
+		MonthlyCost:   make(types.Amounts),
+		Counts:        map[string]int{},
+	}
+	if report.Subscriptions == nil {
+		report.Subscriptions = []types.Subscription{}
+	}
+	for _, sub := range report.Subscriptions {
+		report.MonthlyCost.Add(sub.Currency, sub.MonthlyCost)
+		for _, flag := range sub.Flags {
+			report.Counts[flag]++
+		}
+	}
+	return report, nil
+}
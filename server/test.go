@@ -91,34 +91,34 @@ func TestGetTransactions(t *testing.T) {
 	ctx := context.Background()
 
 	tests := []struct {
-		name      string
-		accountID string
-		timeRange string
-		wantErr   bool
+		name       string
+		accountIDs []string
+		timeRange  string
+		wantErr    bool
 	}{
 		{
-			name:      "Valid transactions last month",
-			accountID: "test_account_1",
-			timeRange: "1 month",
-			wantErr:   false,
+			name:       "Valid transactions last month",
+			accountIDs: []string{"test_account_1"},
+			timeRange:  "1 month",
+			wantErr:    false,
 		},
 		{
-			name:      "Empty account ID",
-			accountID: "",
-			timeRange: "1 month",
-			wantErr:   true,
+			name:       "No account IDs",
+			accountIDs: nil,
+			timeRange:  "1 month",
+			wantErr:    true,
 		},
 		{
-			name:      "Invalid time range",
-			accountID: "test_account_1",
-			timeRange: "invalid",
-			wantErr:   true,
+			name:       "Invalid time range",
+			accountIDs: []string{"test_account_1"},
+			timeRange:  "invalid",
+			wantErr:    true,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			transactions, err := repo.GetTransactions(ctx, tt.accountID, tt.timeRange)
+			transactions, err := repo.GetTransactions(ctx, tt.accountIDs, tt.timeRange)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("GetTransactions() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -138,28 +138,28 @@ func TestGetCategoryTotals(t *testing.T) {
 	ctx := context.Background()
 
 	tests := []struct {
-		name      string
-		accountID string
-		timeRange string
-		wantErr   bool
+		name       string
+		accountIDs []string
+		timeRange  string
+		wantErr    bool
 	}{
 		{
-			name:      "Valid category totals",
-			accountID: "test_account_1",
-			timeRange: "1 month",
-			wantErr:   false,
+			name:       "Valid category totals",
+			accountIDs: []string{"test_account_1"},
+			timeRange:  "1 month",
+			wantErr:    false,
 		},
 		{
-			name:      "Empty account ID",
-			accountID: "",
-			timeRange: "1 month",
-			wantErr:   true,
+			name:       "No account IDs",
+			accountIDs: nil,
+			timeRange:  "1 month",
+			wantErr:    true,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			totals, err := repo.GetCategoryTotals(ctx, tt.accountID, tt.timeRange)
+			totals, err := repo.GetCategoryTotals(ctx, tt.accountIDs, tt.timeRange)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("GetCategoryTotals() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -0,0 +1,393 @@
+package classifier
+
+import (
+	"context"
+	"math"
+	"server/imports/dedup"
+	"server/types"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// DefaultMaxAge is how long a trained model is used before it is retrained
+// from the database, which picks up imports and rule runs that did not go
+// through the transaction API
+const DefaultMaxAge = time.Hour
+
+// maxAlternatives limits how many runner-up categories a suggestion lists
+const maxAlternatives = 3
+
+// amountBuckets are the upper bounds, in cents, of the amount sizes the
+// classifier tells apart
+var amountBuckets = []int64{500, 1000, 2500, 5000, 10000, 25000, 50000, 100000, 250000}
+
+// Source loads the transactions a model is trained on
+type Source interface {
+	ListTransactions(ctx context.Context, accountID string) ([]types.Transaction, error)
+}
+
+// Classifier suggests categories for transactions with a naive Bayes model
+// per account, trained on the categories of the account's transactions. A
+// model is trained on first use and then kept up to date with Learn and
+// Forget as transactions are created, recategorized and deleted.
+type Classifier struct {
+	source Source
+	maxAge time.Duration
+	now    func() time.Time
+
+	mu     sync.Mutex
+	models map[string]*model
+}
+
+// New returns a classifier that trains on the transactions of source
+func New(source Source) *Classifier {
+	return &Classifier{
+		source: source,
+		maxAge: DefaultMaxAge,
+		now:    time.Now,
+		models: map[string]*model{},
+	}
+}
+
+// model holds the counts of a multinomial naive Bayes model
+type model struct {
+	trainedAt  time.Time
+	docs       map[string]int            // transactions per category
+	positive   map[string]int            // transactions per category with a positive amount
+	features   map[string]map[string]int // feature counts per category
+	totals     map[string]int            // feature count per category
+	vocabulary map[string]int            // categories each feature occurs in
+	size       int                       // transactions trained on
+
+	// trained holds the transactions the counts include, by ID, as they were
+	// when they were added, so that they are removed the same way. Rows
+	// written outside the transaction API are only included once the model
+	// is retrained.
+	trained map[string]types.Transaction
+}
+
+func newModel(now time.Time) *model {
+	return &model{
+		trainedAt:  now,
+		docs:       map[string]int{},
+		positive:   map[string]int{},
+		features:   map[string]map[string]int{},
+		totals:     map[string]int{},
+		vocabulary: map[string]int{},
+		trained:    map[string]types.Transaction{},
+	}
+}
+
+// Suggest returns the most likely category of t with its confidence, the
+// posterior probability of the category under the model. Only categories
+// that fit the sign of the amount are considered. A transaction the model
+// was trained on is scored with its own counts left out, so that it does not
+// vote for its own category.
+func (c *Classifier) Suggest(ctx context.Context, t types.Transaction) (*types.CategorySuggestion, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	m, err := c.model(ctx, t.AccountID)
+	if err != nil {
+		return nil, err
+	}
+
+	var ex exclusion
+	if previous, ok := m.trained[t.TransactionID]; ok {
+		ex = m.exclude(previous)
+	}
+	return m.suggest(t, ex), nil
+}
+
+// Learn adds a transaction to its account's model, if the model is loaded
+func (c *Classifier) Learn(t types.Transaction) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if m := c.models[t.AccountID]; m != nil {
+		m.learn(t)
+	}
+}
+
+// Forget removes a transaction from its account's model, if the model is loaded
+func (c *Classifier) Forget(t types.Transaction) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if m := c.models[t.AccountID]; m != nil {
+		m.forget(t)
+	}
+}
+
+// model returns the account's model, training it when it is missing or
+// older than maxAge. The caller holds c.mu.
+func (c *Classifier) model(ctx context.Context, accountID string) (*model, error) {
+	now := c.now()
+	if m := c.models[accountID]; m != nil && now.Sub(m.trainedAt) < c.maxAge {
+		return m, nil
+	}
+
+	transactions, err := c.source.ListTransactions(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	m := newModel(now)
+	for _, t := range transactions {
+		m.learn(t)
+	}
+	c.models[accountID] = m
+	return m, nil
+}
+
+// learn adds a transaction to the model, replacing the version of it the
+// model holds, if any
+func (m *model) learn(t types.Transaction) {
+	if t.TransactionID != "" {
+		m.forget(t)
+		m.trained[t.TransactionID] = t
+	}
+	m.update(t, 1)
+}
+
+// forget removes a transaction from the model as it was added. A transaction
+// the model was not trained on is left alone.
+func (m *model) forget(t types.Transaction) {
+	if t.TransactionID == "" {
+		m.update(t, -1)
+		return
+	}
+	previous, ok := m.trained[t.TransactionID]
+	if !ok {
+		return
+	}
+	delete(m.trained, t.TransactionID)
+	m.update(previous, -1)
+}
+
+// exclusion holds the counts a single trained transaction contributes to
+// the model, so that it can be scored as if the model had not seen it
+// without copying the model. The zero value excludes nothing.
+type exclusion struct {
+	category   string
+	positive   int
+	features   map[string]int // feature counts under category
+	total      int            // sum of features
+	vocabulary int            // features only this transaction contributes
+}
+
+// exclude works out the counts update(t, -1) would remove from the model,
+// leaving the model itself untouched
+func (m *model) exclude(t types.Transaction) exclusion {
+	if t.Category == "" || m.docs[t.Category] == 0 {
+		return exclusion{}
+	}
+
+	ex := exclusion{category: t.Category, features: map[string]int{}}
+	if t.Amount.Sign() > 0 && m.positive[t.Category] > 0 {
+		ex.positive = 1
+	}
+	counts := m.features[t.Category]
+	for _, f := range Features(t) {
+		if ex.features[f] < counts[f] {
+			ex.features[f]++
+			ex.total++
+		}
+	}
+	for f, n := range ex.features {
+		if counts[f] == n && m.vocabulary[f] <= 1 {
+			ex.vocabulary++
+		}
+	}
+	return ex
+}
+
+// docs returns the transactions per category left after the exclusion
+func (ex exclusion) docs(m *model, category string) int {
+	if category == ex.category {
+		return m.docs[category] - 1
+	}
+	return m.docs[category]
+}
+
+// update adds (delta 1) or removes (delta -1) a transaction's features
+// under its category
+func (m *model) update(t types.Transaction, delta int) {
+	category := t.Category
+	if category == "" {
+		return
+	}
+	if delta < 0 && m.docs[category] == 0 {
+		return
+	}
+
+	m.docs[category] += delta
+	m.size += delta
+	if t.Amount.Sign() > 0 && (delta > 0 || m.positive[category] > 0) {
+		m.positive[category] += delta
+	}
+	counts := m.features[category]
+	if counts == nil {
+		counts = map[string]int{}
+		m.features[category] = counts
+	}
+	for _, f := range Features(t) {
+		switch {
+		case delta > 0:
+			if counts[f] == 0 {
+				m.vocabulary[f]++
+			}
+			counts[f]++
+			m.totals[category]++
+		case counts[f] > 0:
+			counts[f]--
+			m.totals[category]--
+			if counts[f] == 0 {
+				delete(counts, f)
+				if m.vocabulary[f]--; m.vocabulary[f] <= 0 {
+					delete(m.vocabulary, f)
+				}
+			}
+		}
+	}
+	if m.docs[category] <= 0 {
+		delete(m.docs, category)
+		delete(m.positive, category)
+		delete(m.features, category)
+		delete(m.totals, category)
+	}
+}
+
+// suggest scores every category that fits the sign of t's amount and turns
+// the log-likelihoods into probabilities. Counts are smoothed with add-one
+// (Laplace) smoothing so that unseen features do not rule a category out.
+func (m *model) suggest(t types.Transaction, ex exclusion) *types.CategorySuggestion {
+	trainedOn := m.size
+	if ex.category != "" {
+		trainedOn--
+	}
+	suggestion := &types.CategorySuggestion{
+		TransactionID: t.TransactionID,
+		TrainedOn:     trainedOn,
+		Alternatives:  []types.CategoryScore{},
+	}
+
+	features := Features(t)
+	vocabulary := float64(len(m.vocabulary) - ex.vocabulary + 1)
+	var candidates []types.CategoryScore
+	var categoryDocs int
+	for category := range m.docs {
+		if m.fitsSign(category, t.Amount, ex) {
+			categoryDocs += ex.docs(m, category)
+		}
+	}
+	for category := range m.docs {
+		if !m.fitsSign(category, t.Amount, ex) {
+			continue
+		}
+		score := math.Log(float64(ex.docs(m, category)) / float64(categoryDocs))
+		counts := m.features[category]
+		total := float64(m.totals[category])
+		if category == ex.category {
+			total -= float64(ex.total)
+		}
+		for _, f := range features {
+			count := counts[f]
+			if category == ex.category {
+				count -= ex.features[f]
+			}
+			score += math.Log((float64(count) + 1) / (total + vocabulary))
+		}
+		candidates = append(candidates, types.CategoryScore{Category: category, Confidence: score})
+	}
+	if len(candidates) == 0 {
+		return suggestion
+	}
+
+	// Softmax over the log scores, shifted by the best one to stay in range
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].Confidence != candidates[j].Confidence {
+			return candidates[i].Confidence > candidates[j].Confidence
+		}
+		return candidates[i].Category < candidates[j].Category
+	})
+	best := candidates[0].Confidence
+	var sum float64
+	for i := range candidates {
+		candidates[i].Confidence = math.Exp(candidates[i].Confidence - best)
+		sum += candidates[i].Confidence
+	}
+	for i := range candidates {
+		candidates[i].Confidence = math.Round(candidates[i].Confidence/sum*1000) / 1000
+	}
+
+	suggestion.Category = candidates[0].Category
+	suggestion.Confidence = candidates[0].Confidence
+	for _, alt := range candidates[1:] {
+		if len(suggestion.Alternatives) == maxAlternatives {
+			break
+		}
+		suggestion.Alternatives = append(suggestion.Alternatives, alt)
+	}
+	return suggestion
+}
+
+// Features returns the tokens a transaction is classified by: the words of
+// its normalized merchant and the merchant as a whole, the words of its
+// location, and the size bucket of its amount
+func Features(t types.Transaction) []string {
+	var features []string
+	if merchant := dedup.NormalizeMerchant(t.Merchant); merchant != "" {
+		features = append(features, "merchant:"+merchant)
+		for _, word := range strings.Fields(merchant) {
+			features = append(features, "word:"+word)
+		}
+	}
+
+	location := strings.FieldsFunc(strings.ToLower(t.Location), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	for _, word := range location {
+		if len(word) >= 2 {
+			features = append(features, "location:"+word)
+		}
+	}
+
+	if !t.Amount.IsZero() {
+		features = append(features, "amount:"+amountBucket(t.Amount))
+	}
+	return features
+}
+
+// amountBucket names the size range an amount falls in
+func amountBucket(amount types.Money) string {
+	size := amount.Abs().Cents
+	for _, bound := range amountBuckets {
+		if size < bound {
+			return "<" + types.NewMoney(bound, "").String()
+		}
+	}
+	return ">=" + types.NewMoney(amountBuckets[len(amountBuckets)-1], "").String()
+}
+
+// fitsSign reports whether a transaction of the given amount may be filed
+// under category, judging by the signs of the transactions the category was
+// trained on. Categories are then held to the sign convention of their type
+// without the model knowing the account's categories. A zero amount, as in a
+// draft without one, fits every category that is left after the exclusion.
+func (m *model) fitsSign(category string, amount types.Money, ex exclusion) bool {
+	docs, positive := ex.docs(m, category), m.positive[category]
+	if category == ex.category {
+		positive -= ex.positive
+	}
+	switch {
+	case docs <= 0:
+		return false
+	case amount.IsZero():
+		return true
+	case amount.Sign() > 0:
+		return positive > 0
+	default:
+		return docs > positive
+	}
+}
@@ -0,0 +1,138 @@
+package classifier
+
+import (
+	"context"
+	"reflect"
+	"server/types"
+	"testing"
+	"time"
+)
+
+// source serves a fixed set of transactions to train on
+type source []types.Transaction
+
+func (s source) ListTransactions(ctx context.Context, accountID string) ([]types.Transaction, error) {
+	return s, nil
+}
+
+func tx(id, category, merchant string, cents int64) types.Transaction {
+	return types.Transaction{
+		TransactionID: id,
+		AccountID:     "checking",
+		Category:      category,
+		Merchant:      merchant,
+		Location:      "Boston, MA",
+		Amount:        types.NewMoney(cents, ""),
+	}
+}
+
+var history = source{
+	tx("1", "Dining", "Chipotle", -1250),
+	tx("2", "Dining", "CHIPOTLE #42", -1100),
+	tx("3", "Dining", "Blue Bottle Coffee", -450),
+	tx("4", "Groceries", "Whole Foods Market", -8312),
+	tx("5", "Groceries", "Trader Joe's", -5420),
+	tx("6", "Income", "ACME Corp Payroll", 250000),
+	tx("7", "Income", "Interest", 42),
+}
+
+func TestLearnForget(t *testing.T) {
+	m := newModel(time.Now())
+	for _, h := range history {
+		m.learn(h)
+	}
+	if m.size != len(history) || len(m.trained) != len(history) {
+		t.Fatalf("size, trained = %d, %d, want %d", m.size, len(m.trained), len(history))
+	}
+
+	// Learning a recategorized transaction replaces the version the model
+	// holds, and forgetting it removes the new version
+	moved := history[2]
+	moved.Category = "Groceries"
+	m.learn(moved)
+	if m.docs["Dining"] != 2 || m.docs["Groceries"] != 3 || m.size != len(history) {
+		t.Errorf("docs = %v, size = %d after recategorizing", m.docs, m.size)
+	}
+	m.forget(moved)
+
+	// Forgetting what the model was never trained on changes nothing
+	m.forget(tx("8", "Dining", "Chipotle", -1250))
+
+	for _, h := range history {
+		if h.TransactionID != moved.TransactionID {
+			m.forget(h)
+		}
+	}
+	empty := newModel(m.trainedAt)
+	if !reflect.DeepEqual(m, empty) {
+		t.Errorf("model after forgetting everything = %+v, want an empty model", m)
+	}
+}
+
+func TestSuggestLeavesOutTrainedTransaction(t *testing.T) {
+	c := New(history)
+	for _, h := range history {
+		got, err := c.Suggest(context.Background(), h)
+		if err != nil {
+			t.Fatalf("Suggest(%s) error = %v", h.TransactionID, err)
+		}
+
+		// The suggestion must be the one a model that never saw the
+		// transaction would make
+		var others source
+		for _, o := range history {
+			if o.TransactionID != h.TransactionID {
+				others = append(others, o)
+			}
+		}
+		want, err := New(others).Suggest(context.Background(), types.Transaction{
+			AccountID: h.AccountID, Merchant: h.Merchant, Location: h.Location, Amount: h.Amount,
+		})
+		if err != nil {
+			t.Fatalf("Suggest() error = %v", err)
+		}
+		want.TransactionID = h.TransactionID
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Suggest(%s) = %+v, want %+v", h.TransactionID, got, want)
+		}
+	}
+
+	// Without its own vote, the only Chipotle transaction left points the
+	// other one to Dining
+	got, _ := c.Suggest(context.Background(), history[0])
+	if got.Category != "Dining" || got.TrainedOn != len(history)-1 {
+		t.Errorf("Suggest(1) = %s trained on %d, want Dining trained on %d", got.Category, got.TrainedOn, len(history)-1)
+	}
+}
+
+func TestSuggestSign(t *testing.T) {
+	c := New(history)
+	tests := []struct {
+		name   string
+		cents  int64
+		allow  map[string]bool
+		expect string
+	}{
+		{"a deposit is only income", 250000, map[string]bool{"Income": true}, "Income"},
+		{"a payment is never income", -250000, map[string]bool{"Dining": true, "Groceries": true}, ""},
+		{"a draft without an amount fits every category", 0, map[string]bool{"Dining": true, "Groceries": true, "Income": true}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := c.Suggest(context.Background(), tx("", "", "ACME Corp Payroll", tt.cents))
+			if err != nil {
+				t.Fatalf("Suggest() error = %v", err)
+			}
+			seen := map[string]bool{got.Category: true}
+			for _, alt := range got.Alternatives {
+				seen[alt.Category] = true
+			}
+			if !reflect.DeepEqual(seen, tt.allow) {
+				t.Errorf("Suggest() considered %v, want %v", seen, tt.allow)
+			}
+			if tt.expect != "" && got.Category != tt.expect {
+				t.Errorf("Suggest() = %s, want %s", got.Category, tt.expect)
+			}
+		})
+	}
+}
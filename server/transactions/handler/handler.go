@@ -0,0 +1,315 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"server/attachments/blob"
+	attachmentsRepository "server/attachments/repository"
+	attachmentsService "server/attachments/service"
+	categoriesRepository "server/categories/repository"
+	categoriesService "server/categories/service"
+	fxRepository "server/fx/repository"
+	fxService "server/fx/service"
+	merchantsRepository "server/merchants/repository"
+	merchantsService "server/merchants/service"
+	rulesRepository "server/rules/repository"
+	rulesService "server/rules/service"
+	"server/transactions/classifier"
+	"server/transactions/repository"
+	"server/transactions/service"
+	"server/types"
+
+	"github.com/gorilla/mux"
+)
+
+type Handler struct {
+	service service.Service
+}
+
+func NewHandler(service service.Service) *Handler {
+	return &Handler{service: service}
+}
+
+// SetupTransactionRoutes configures all the transaction-related routes
+func SetupTransactionRoutes(router *mux.Router, db *sql.DB) {
+	repo := repository.NewPostgresRepository(db)
+	fx := fxService.NewService(fxRepository.NewPostgresRepository(db))
+	categories := categoriesService.NewService(categoriesRepository.NewPostgresRepository(db), fx)
+	merchants := merchantsService.NewService(merchantsRepository.NewPostgresRepository(db), categories, fx)
+	rules := rulesService.NewService(rulesRepository.NewPostgresRepository(db), categories)
+	store, err := blob.FromEnv()
+	if err != nil {
+		log.Fatalf("Error configuring attachment storage: %v", err)
+	}
+	attachments := attachmentsService.NewService(attachmentsRepository.NewPostgresRepository(db), store)
+	svc := service.NewService(repo, categories, merchants, rules, attachments, classifier.New(repo))
+	handler := NewHandler(svc)
+	handler.RegisterRoutes(router)
+}
+
+// RegisterRoutes registers all transaction routes
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/api/transactions/{accountId}", h.HandleListTransactions).Methods("GET")
+	router.HandleFunc("/api/transactions/{accountId}", h.HandleCreateTransaction).Methods("POST")
+	router.HandleFunc("/api/transactions/{accountId}/suggestion", h.HandleSuggestDraftCategory).Methods("POST")
+	router.HandleFunc("/api/transactions/{accountId}/tags/add", h.HandleTagTransactions).Methods("POST")
+	router.HandleFunc("/api/transactions/{accountId}/tags/remove", h.HandleUntagTransactions).Methods("POST")
+	router.HandleFunc("/api/transactions/{accountId}/{transactionId}", h.HandleGetTransaction).Methods("GET")
+	router.HandleFunc("/api/transactions/{accountId}/{transactionId}", h.HandleReplaceTransaction).Methods("PUT")
+	router.HandleFunc("/api/transactions/{accountId}/{transactionId}", h.HandlePatchTransaction).Methods("PATCH")
+	router.HandleFunc("/api/transactions/{accountId}/{transactionId}", h.HandleDeleteTransaction).Methods("DELETE")
+	router.HandleFunc("/api/transactions/{accountId}/{transactionId}/suggestion", h.HandleSuggestCategory).Methods("GET")
+	router.HandleFunc("/api/transactions/{accountId}/{transactionId}/tags/{tag}", h.HandleTagTransaction).Methods("PUT")
+	router.HandleFunc("/api/transactions/{accountId}/{transactionId}/tags/{tag}", h.HandleUntagTransaction).Methods("DELETE")
+}
+
+// HandleListTransactions handles requests for all transactions of an
+// account. tag limits them to the transactions that carry every tag given.
+func (h *Handler) HandleListTransactions(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	accountID := vars["accountId"]
+
+	tags, err := types.ParseTagFilter(r.URL.Query()["tag"])
+	if err != nil {
+		writeError(w, "Failed to get transactions", err)
+		return
+	}
+
+	transactions, err := h.service.ListTransactions(r.Context(), accountID, tags)
+	if err != nil {
+		writeError(w, "Failed to get transactions", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(transactions)
+}
+
+// HandleGetTransaction handles requests for a single transaction
+func (h *Handler) HandleGetTransaction(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	accountID := vars["accountId"]
+	transactionID := vars["transactionId"]
+
+	transaction, err := h.service.GetTransaction(r.Context(), accountID, transactionID)
+	if err != nil {
+		writeError(w, "Failed to get transaction", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(transaction)
+}
+
+// HandleCreateTransaction handles requests to create a transaction
+func (h *Handler) HandleCreateTransaction(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	accountID := vars["accountId"]
+
+	var input types.Transaction
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	transaction, err := h.service.CreateTransaction(r.Context(), accountID, input)
+	if err != nil {
+		writeError(w, "Failed to create transaction", err)
+		return
+	}
+
+	log.Printf("Created transaction %s for account %s", transaction.TransactionID, accountID)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/api/transactions/"+accountID+"/"+transaction.TransactionID)
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(transaction)
+}
+
+// HandleReplaceTransaction handles requests to replace a transaction
+func (h *Handler) HandleReplaceTransaction(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	accountID := vars["accountId"]
+	transactionID := vars["transactionId"]
+
+	var input types.Transaction
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	transaction, err := h.service.ReplaceTransaction(r.Context(), accountID, transactionID, input)
+	if err != nil {
+		writeError(w, "Failed to replace transaction", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(transaction)
+}
+
+// HandlePatchTransaction handles requests to partially update a transaction
+func (h *Handler) HandlePatchTransaction(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	accountID := vars["accountId"]
+	transactionID := vars["transactionId"]
+
+	var patch types.TransactionPatch
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&patch); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	transaction, err := h.service.PatchTransaction(r.Context(), accountID, transactionID, patch)
+	if err != nil {
+		writeError(w, "Failed to update transaction", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(transaction)
+}
+
+// HandleDeleteTransaction handles requests to delete a transaction
+func (h *Handler) HandleDeleteTransaction(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	accountID := vars["accountId"]
+	transactionID := vars["transactionId"]
+
+	if err := h.service.DeleteTransaction(r.Context(), accountID, transactionID); err != nil {
+		writeError(w, "Failed to delete transaction", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleTagTransaction handles requests to add a tag to a transaction
+func (h *Handler) HandleTagTransaction(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	transaction, err := h.service.TagTransaction(r.Context(), vars["accountId"], vars["transactionId"], vars["tag"])
+	if err != nil {
+		writeError(w, "Failed to tag transaction", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(transaction)
+}
+
+// HandleUntagTransaction handles requests to remove a tag from a transaction
+func (h *Handler) HandleUntagTransaction(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	transaction, err := h.service.UntagTransaction(r.Context(), vars["accountId"], vars["transactionId"], vars["tag"])
+	if err != nil {
+		writeError(w, "Failed to untag transaction", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(transaction)
+}
+
+// HandleTagTransactions handles requests to add tags to several transactions
+func (h *Handler) HandleTagTransactions(w http.ResponseWriter, r *http.Request) {
+	accountID := mux.Vars(r)["accountId"]
+
+	var change types.TagChange
+	if err := json.NewDecoder(r.Body).Decode(&change); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.service.TagTransactions(r.Context(), accountID, change)
+	if err != nil {
+		writeError(w, "Failed to tag transactions", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// HandleUntagTransactions handles requests to remove tags from several
+// transactions
+func (h *Handler) HandleUntagTransactions(w http.ResponseWriter, r *http.Request) {
+	accountID := mux.Vars(r)["accountId"]
+
+	var change types.TagChange
+	if err := json.NewDecoder(r.Body).Decode(&change); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.service.UntagTransactions(r.Context(), accountID, change)
+	if err != nil {
+		writeError(w, "Failed to untag transactions", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// HandleSuggestCategory handles requests for the category the classifier
+// expects an existing transaction to have
+func (h *Handler) HandleSuggestCategory(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	accountID := vars["accountId"]
+	transactionID := vars["transactionId"]
+
+	suggestion, err := h.service.SuggestCategory(r.Context(), accountID, transactionID)
+	if err != nil {
+		writeError(w, "Failed to suggest category", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(suggestion)
+}
+
+// HandleSuggestDraftCategory handles requests for the category the
+// classifier expects a transaction that is about to be created to have
+func (h *Handler) HandleSuggestDraftCategory(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	accountID := vars["accountId"]
+
+	var draft types.Transaction
+	if err := json.NewDecoder(r.Body).Decode(&draft); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	suggestion, err := h.service.SuggestDraftCategory(r.Context(), accountID, draft)
+	if err != nil {
+		writeError(w, "Failed to suggest category", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(suggestion)
+}
+
+// writeError converts service errors into HTTP responses
+func writeError(w http.ResponseWriter, message string, err error) {
+	var validationErr *types.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		http.Error(w, validationErr.Error(), http.StatusBadRequest)
+	case errors.Is(err, repository.ErrAccountNotFound):
+		http.Error(w, "Account not found", http.StatusNotFound)
+	case errors.Is(err, repository.ErrTransactionNotFound):
+		http.Error(w, "Transaction not found", http.StatusNotFound)
+	case errors.Is(err, repository.ErrConflict):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		log.Printf("%s: %v", message, err)
+		http.Error(w, message, http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,465 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"server/crud"
+	"server/types"
+
+	"github.com/lib/pq"
+)
+
+type postgresRepo struct {
+	db *sql.DB
+}
+
+func NewPostgresRepository(db *sql.DB) Repository {
+	if db == nil {
+		panic("database connection is required")
+	}
+	return &postgresRepo{db: db}
+}
+
+// AccountExists reports whether the account exists
+func (r *postgresRepo) AccountExists(ctx context.Context, accountID string) (bool, error) {
+	if accountID == "" {
+		return false, fmt.Errorf("account ID is required")
+	}
+
+	var exists bool
+	err := r.db.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM users WHERE account_id = $1)`, accountID).Scan(&exists)
+	if err != nil {
+		log.Printf("Error checking account %s: %v", accountID, err)
+		return false, fmt.Errorf("failed to check account: %w", err)
+	}
+	return exists, nil
+}
+
+// tagNames selects the names of the transaction's tags, in order
+const tagNames = `ARRAY(
+	SELECT g.name FROM transaction_tags tt JOIN tags g ON g.id = tt.tag_id
+	WHERE tt.transaction_id = transactions.transaction_id ORDER BY g.name)`
+
+// ListTransactions retrieves all transactions for an account
+func (r *postgresRepo) ListTransactions(ctx context.Context, accountID string) ([]types.Transaction, error) {
+	return r.FilterTransactions(ctx, accountID, nil)
+}
+
+// FilterTransactions retrieves the transactions of an account that carry
+// every tag of the filter
+func (r *postgresRepo) FilterTransactions(ctx context.Context, accountID string, tags types.TagFilter) ([]types.Transaction, error) {
+	if accountID == "" {
+		return nil, fmt.Errorf("account ID is required")
+	}
+
+	log.Printf("Fetching transactions for account %s with tags %v", accountID, tags)
+
+	query := `
+		SELECT transaction_id, account_id, date, amount, category, merchant, location, currency,
+			COALESCE(fitid, ''), transfer_id, merchant_id, ` + tagNames + `, notes
+		FROM transactions
+		WHERE account_id = $1
+		  AND has_tags(transaction_id, $2)
+		ORDER BY date DESC`
+
+	rows, err := r.db.QueryContext(ctx, query, accountID, pq.Array([]string(tags)))
+	if err != nil {
+		log.Printf("Error querying transactions: %v", err)
+		return nil, fmt.Errorf("failed to query transactions: %w", err)
+	}
+	defer rows.Close()
+
+	transactions := []types.Transaction{}
+	for rows.Next() {
+		var t types.Transaction
+		if err := rows.Scan(
+			&t.TransactionID,
+			&t.AccountID,
+			&t.Date,
+			&t.Amount,
+			&t.Category,
+			&t.Merchant,
+			&t.Location,
+			&t.Currency,
+			&t.FITID,
+			&t.TransferID,
+			&t.MerchantID,
+			(*pq.StringArray)(&t.Tags),
+			&t.Notes,
+		); err != nil {
+			log.Printf("Error scanning transaction: %v", err)
+			return nil, fmt.Errorf("failed to scan transaction: %w", err)
+		}
+		transactions = append(transactions, t)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Printf("Error iterating transactions: %v", err)
+		return nil, fmt.Errorf("error iterating transactions: %w", err)
+	}
+	if err := r.loadSplits(ctx, accountID, transactions); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Found %d transactions for account %s", len(transactions), accountID)
+	return transactions, nil
+}
+
+// GetTransaction retrieves a single transaction for an account
+func (r *postgresRepo) GetTransaction(ctx context.Context, accountID string, transactionID string) (*types.Transaction, error) {
+	if accountID == "" || transactionID == "" {
+		return nil, fmt.Errorf("account ID and transaction ID are required")
+	}
+
+	query := `
+		SELECT transaction_id, account_id, date, amount, category, merchant, location, currency,
+			COALESCE(fitid, ''), transfer_id, merchant_id, ` + tagNames + `, notes
+		FROM transactions
+		WHERE account_id = $1 AND transaction_id = $2`
+
+	var t types.Transaction
+	err := r.db.QueryRowContext(ctx, query, accountID, transactionID).Scan(
+		&t.TransactionID,
+		&t.AccountID,
+		&t.Date,
+		&t.Amount,
+		&t.Category,
+		&t.Merchant,
+		&t.Location,
+		&t.Currency,
+		&t.FITID,
+		&t.TransferID,
+		&t.MerchantID,
+		(*pq.StringArray)(&t.Tags),
+		&t.Notes,
+	)
+	if err == sql.ErrNoRows {
+		return nil, ErrTransactionNotFound
+	}
+	if err != nil {
+		log.Printf("Error fetching transaction %s: %v", transactionID, err)
+		return nil, fmt.Errorf("failed to fetch transaction: %w", err)
+	}
+
+	transactions := []types.Transaction{t}
+	if err := r.loadSplits(ctx, accountID, transactions); err != nil {
+		return nil, err
+	}
+	return &transactions[0], nil
+}
+
+// loadSplits fills in the splits of the account's transactions
+func (r *postgresRepo) loadSplits(ctx context.Context, accountID string, transactions []types.Transaction) error {
+	if len(transactions) == 0 {
+		return nil
+	}
+
+	ids := make([]string, len(transactions))
+	index := make(map[string]int, len(transactions))
+	for i, t := range transactions {
+		ids[i] = t.TransactionID
+		index[t.TransactionID] = i
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT s.transaction_id, s.id, s.category, s.amount, s.memo, t.currency
+		FROM transaction_splits s
+		JOIN transactions t ON t.transaction_id = s.transaction_id
+		WHERE t.account_id = $1 AND s.transaction_id = ANY($2)
+		ORDER BY s.id`,
+		accountID, pq.Array(ids))
+	if err != nil {
+		log.Printf("Error querying splits: %v", err)
+		return fmt.Errorf("failed to query splits: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var transactionID string
+		var split types.TransactionSplit
+		if err := rows.Scan(&transactionID, &split.ID, &split.Category, &split.Amount, &split.Memo, &split.Amount.Currency); err != nil {
+			log.Printf("Error scanning split: %v", err)
+			return fmt.Errorf("failed to scan split: %w", err)
+		}
+		t := &transactions[index[transactionID]]
+		t.Splits = append(t.Splits, split)
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("Error iterating splits: %v", err)
+		return fmt.Errorf("error iterating splits: %w", err)
+	}
+	return nil
+}
+
+// accountCurrency is the SQL fallback for a transaction without a currency:
+// the currency of the account it belongs to
+const accountCurrency = `COALESCE((SELECT NULLIF(balance_currency, '') FROM users WHERE account_id = $1), 'USD')`
+
+// CreateTransaction inserts a new transaction together with its tags. A
+// transaction without a currency takes the account's currency, which is
+// written back to t.
+func (r *postgresRepo) CreateTransaction(ctx context.Context, t *types.Transaction) error {
+	log.Printf("Creating transaction %s for account %s", t.TransactionID, t.AccountID)
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO transactions (
+			account_id, transaction_id, date, amount, category, merchant, location, currency, merchant_id, notes
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, COALESCE(NULLIF($8, ''), ` + accountCurrency + `), $9, $10)
+		RETURNING currency`
+
+	err = tx.QueryRowContext(ctx, query,
+		t.AccountID,
+		t.TransactionID,
+		t.Date,
+		t.Amount,
+		t.Category,
+		t.Merchant,
+		t.Location,
+		t.Currency,
+		t.MerchantID,
+		t.Notes,
+	).Scan(&t.Currency)
+	if err != nil {
+		log.Printf("Error creating transaction: %v", err)
+		return translateError(err)
+	}
+
+	if err := crud.InsertTransactionTagsTx(tx, t.AccountID, t.TransactionID, t.Tags); err != nil {
+		log.Printf("Error tagging transaction: %v", err)
+		return fmt.Errorf("failed to tag transaction: %w", err)
+	}
+	if err := insertSplits(ctx, tx, t); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// UpdateTransaction replaces the mutable fields, the tags and the splits of an
+// existing transaction. A transaction without a currency takes the account's
+// currency.
+func (r *postgresRepo) UpdateTransaction(ctx context.Context, t *types.Transaction) error {
+	log.Printf("Updating transaction %s for account %s", t.TransactionID, t.AccountID)
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE transactions
+		SET date = $3, amount = $4, category = $5, merchant = $6, location = $7,
+			currency = COALESCE(NULLIF($8, ''), ` + accountCurrency + `), merchant_id = $9, notes = $10
+		WHERE account_id = $1 AND transaction_id = $2
+		RETURNING currency`
+
+	err = tx.QueryRowContext(ctx, query,
+		t.AccountID,
+		t.TransactionID,
+		t.Date,
+		t.Amount,
+		t.Category,
+		t.Merchant,
+		t.Location,
+		t.Currency,
+		t.MerchantID,
+		t.Notes,
+	).Scan(&t.Currency)
+	if err == sql.ErrNoRows {
+		return ErrTransactionNotFound
+	}
+	if err != nil {
+		log.Printf("Error updating transaction: %v", err)
+		return translateError(err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM transaction_tags WHERE transaction_id = $1`, t.TransactionID); err != nil {
+		log.Printf("Error removing tags: %v", err)
+		return fmt.Errorf("failed to remove tags: %w", err)
+	}
+	if err := crud.InsertTransactionTagsTx(tx, t.AccountID, t.TransactionID, t.Tags); err != nil {
+		log.Printf("Error tagging transaction: %v", err)
+		return fmt.Errorf("failed to tag transaction: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM transaction_splits WHERE transaction_id = $1`, t.TransactionID); err != nil {
+		log.Printf("Error removing splits: %v", err)
+		return fmt.Errorf("failed to remove splits: %w", err)
+	}
+	if err := insertSplits(ctx, tx, t); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// insertSplits saves the splits of a transaction, setting their IDs and
+// giving them the transaction's currency
+func insertSplits(ctx context.Context, tx *sql.Tx, t *types.Transaction) error {
+	for i := range t.Splits {
+		split := &t.Splits[i]
+		err := tx.QueryRowContext(ctx, `
+			INSERT INTO transaction_splits (transaction_id, category, amount, memo)
+			VALUES ($1, $2, $3, $4)
+			RETURNING id`,
+			t.TransactionID, split.Category, split.Amount, split.Memo,
+		).Scan(&split.ID)
+		if err != nil {
+			log.Printf("Error inserting split: %v", err)
+			return fmt.Errorf("failed to insert split: %w", err)
+		}
+		split.Amount.Currency = t.Currency
+	}
+	return nil
+}
+
+// DeleteTransaction removes a transaction from an account
+func (r *postgresRepo) DeleteTransaction(ctx context.Context, accountID string, transactionID string) error {
+	log.Printf("Deleting transaction %s for account %s", transactionID, accountID)
+
+	res, err := r.db.ExecContext(ctx,
+		`DELETE FROM transactions WHERE account_id = $1 AND transaction_id = $2`,
+		accountID, transactionID)
+	if err != nil {
+		log.Printf("Error deleting transaction: %v", err)
+		return translateError(err)
+	}
+	return expectOneRow(res)
+}
+
+// TagTransactions adds tags to transactions of an account, creating the
+// account's tags that do not exist yet, and returns how many tags were
+// added. Tags a transaction already has are left as they are.
+func (r *postgresRepo) TagTransactions(ctx context.Context, accountID string, transactionIDs []string, tags []string) (int, error) {
+	log.Printf("Tagging %d transactions of account %s with %v", len(transactionIDs), accountID, tags)
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := requireTransactions(ctx, tx, accountID, transactionIDs); err != nil {
+		return 0, err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO tags (account_id, name)
+		SELECT $1, unnest($2::text[])
+		ON CONFLICT (account_id, name) DO NOTHING`,
+		accountID, pq.Array(tags))
+	if err != nil {
+		log.Printf("Error creating tags: %v", err)
+		return 0, fmt.Errorf("failed to create tags: %w", err)
+	}
+
+	res, err := tx.ExecContext(ctx, `
+		INSERT INTO transaction_tags (transaction_id, tag_id)
+		SELECT t.transaction_id, g.id
+		FROM transactions t
+		JOIN tags g ON g.account_id = t.account_id
+		WHERE t.account_id = $1 AND t.transaction_id = ANY($2) AND g.name = ANY($3)
+		ON CONFLICT DO NOTHING`,
+		accountID, pq.Array(transactionIDs), pq.Array(tags))
+	if err != nil {
+		log.Printf("Error tagging transactions: %v", err)
+		return 0, fmt.Errorf("failed to tag transactions: %w", err)
+	}
+	added, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read affected rows: %w", err)
+	}
+	return int(added), tx.Commit()
+}
+
+// UntagTransactions removes tags from transactions of an account and
+// returns how many tags were removed
+func (r *postgresRepo) UntagTransactions(ctx context.Context, accountID string, transactionIDs []string, tags []string) (int, error) {
+	log.Printf("Untagging %d transactions of account %s from %v", len(transactionIDs), accountID, tags)
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := requireTransactions(ctx, tx, accountID, transactionIDs); err != nil {
+		return 0, err
+	}
+
+	res, err := tx.ExecContext(ctx, `
+		DELETE FROM transaction_tags tt
+		USING tags g
+		WHERE g.id = tt.tag_id
+		  AND g.account_id = $1
+		  AND tt.transaction_id = ANY($2)
+		  AND g.name = ANY($3)`,
+		accountID, pq.Array(transactionIDs), pq.Array(tags))
+	if err != nil {
+		log.Printf("Error untagging transactions: %v", err)
+		return 0, fmt.Errorf("failed to untag transactions: %w", err)
+	}
+	removed, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read affected rows: %w", err)
+	}
+	return int(removed), tx.Commit()
+}
+
+// requireTransactions returns ErrTransactionNotFound unless every one of the
+// distinct transaction IDs belongs to the account
+func requireTransactions(ctx context.Context, tx *sql.Tx, accountID string, transactionIDs []string) error {
+	var found int
+	err := tx.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM transactions WHERE account_id = $1 AND transaction_id = ANY($2)`,
+		accountID, pq.Array(transactionIDs),
+	).Scan(&found)
+	if err != nil {
+		log.Printf("Error checking transactions: %v", err)
+		return fmt.Errorf("failed to check transactions: %w", err)
+	}
+	if found != len(transactionIDs) {
+		return ErrTransactionNotFound
+	}
+	return nil
+}
+
+// expectOneRow maps an update or delete that touched no rows to ErrTransactionNotFound
+func expectOneRow(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to read affected rows: %w", err)
+	}
+	if n == 0 {
+		return ErrTransactionNotFound
+	}
+	return nil
+}
+
+// translateError maps Postgres constraint violations onto the repository errors
+func translateError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code.Name() {
+		case "unique_violation":
+			return ErrConflict
+		case "foreign_key_violation":
+			// A violation raised on the transactions table itself means the
+			// account is missing; anywhere else another row still references
+			// the transaction being changed.
+			if pqErr.Table == "transactions" {
+				return ErrAccountNotFound
+			}
+			return ErrConflict
+		}
+	}
+	return fmt.Errorf("failed to write transaction: %w", err)
+}
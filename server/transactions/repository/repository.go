@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"server/types"
+)
+
+var (
+	// ErrAccountNotFound is returned when the account does not exist
+	ErrAccountNotFound = errors.New("account not found")
+
+	// ErrTransactionNotFound is returned when the transaction does not exist for the account
+	ErrTransactionNotFound = errors.New("transaction not found")
+
+	// ErrConflict is returned when a write collides with an existing record
+	ErrConflict = errors.New("transaction conflicts with an existing record")
+)
+
+// Repository defines the interface for transaction write operations
+type Repository interface {
+	// AccountExists reports whether the account exists
+	AccountExists(ctx context.Context, accountID string) (bool, error)
+
+	// ListTransactions retrieves all transactions for an account
+	ListTransactions(ctx context.Context, accountID string) ([]types.Transaction, error)
+
+	// FilterTransactions retrieves the transactions of an account that carry
+	// every tag of the filter
+	FilterTransactions(ctx context.Context, accountID string, tags types.TagFilter) ([]types.Transaction, error)
+
+	// GetTransaction retrieves a single transaction for an account
+	GetTransaction(ctx context.Context, accountID string, transactionID string) (*types.Transaction, error)
+
+	// CreateTransaction inserts a new transaction
+	CreateTransaction(ctx context.Context, transaction *types.Transaction) error
+
+	// UpdateTransaction replaces the mutable fields, tags and splits of an
+	// existing transaction
+	UpdateTransaction(ctx context.Context, transaction *types.Transaction) error
+
+	// DeleteTransaction removes a transaction from an account
+	DeleteTransaction(ctx context.Context, accountID string, transactionID string) error
+
+	// TagTransactions adds tags to transactions of an account and returns how
+	// many were added. It returns ErrTransactionNotFound, and changes nothing,
+	// when one of the transactions is not the account's.
+	TagTransactions(ctx context.Context, accountID string, transactionIDs []string, tags []string) (int, error)
+
+	// UntagTransactions removes tags from transactions of an account and
+	// returns how many were removed
+	UntagTransactions(ctx context.Context, accountID string, transactionIDs []string, tags []string) (int, error)
+}
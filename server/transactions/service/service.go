@@ -0,0 +1,322 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	attachmentsService "server/attachments/service"
+	categoriesService "server/categories/service"
+	merchantsService "server/merchants/service"
+	rulesService "server/rules/service"
+	"server/transactions/classifier"
+	"server/transactions/repository"
+	"server/types"
+)
+
+type Service interface {
+	ListTransactions(ctx context.Context, accountID string, tags types.TagFilter) ([]types.Transaction, error)
+	GetTransaction(ctx context.Context, accountID string, transactionID string) (*types.Transaction, error)
+	CreateTransaction(ctx context.Context, accountID string, transaction types.Transaction) (*types.Transaction, error)
+	ReplaceTransaction(ctx context.Context, accountID string, transactionID string, transaction types.Transaction) (*types.Transaction, error)
+	PatchTransaction(ctx context.Context, accountID string, transactionID string, patch types.TransactionPatch) (*types.Transaction, error)
+	DeleteTransaction(ctx context.Context, accountID string, transactionID string) error
+	TagTransaction(ctx context.Context, accountID string, transactionID string, tag string) (*types.Transaction, error)
+	UntagTransaction(ctx context.Context, accountID string, transactionID string, tag string) (*types.Transaction, error)
+	TagTransactions(ctx context.Context, accountID string, change types.TagChange) (*types.TagChangeResult, error)
+	UntagTransactions(ctx context.Context, accountID string, change types.TagChange) (*types.TagChangeResult, error)
+	SuggestCategory(ctx context.Context, accountID string, transactionID string) (*types.CategorySuggestion, error)
+	SuggestDraftCategory(ctx context.Context, accountID string, draft types.Transaction) (*types.CategorySuggestion, error)
+}
+
+type service struct {
+	repo        repository.Repository
+	categories  categoriesService.Service
+	merchants   merchantsService.Service
+	rules       rulesService.Service
+	attachments attachmentsService.Service
+	classifier  *classifier.Classifier
+}
+
+func NewService(repo repository.Repository, categories categoriesService.Service, merchants merchantsService.Service, rules rulesService.Service, attachments attachmentsService.Service, classifier *classifier.Classifier) Service {
+	return &service{repo: repo, categories: categories, merchants: merchants, rules: rules, attachments: attachments, classifier: classifier}
+}
+
+// validate checks the transaction against the account's categories
+func (s *service) validate(ctx context.Context, accountID string, t *types.Transaction) error {
+	categories, err := s.categories.Categories(ctx, accountID)
+	if err != nil {
+		return err
+	}
+	return t.Validate(categories)
+}
+
+// requireAccount returns ErrAccountNotFound when the account does not exist
+func (s *service) requireAccount(ctx context.Context, accountID string) error {
+	exists, err := s.repo.AccountExists(ctx, accountID)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return repository.ErrAccountNotFound
+	}
+	return nil
+}
+
+// ListTransactions returns the account's transactions that carry every tag
+// of the filter
+func (s *service) ListTransactions(ctx context.Context, accountID string, tags types.TagFilter) ([]types.Transaction, error) {
+	if err := s.requireAccount(ctx, accountID); err != nil {
+		return nil, err
+	}
+	return s.repo.FilterTransactions(ctx, accountID, tags)
+}
+
+func (s *service) GetTransaction(ctx context.Context, accountID string, transactionID string) (*types.Transaction, error) {
+	if err := s.requireAccount(ctx, accountID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetTransaction(ctx, accountID, transactionID)
+}
+
+// CreateTransaction validates the transaction, assigns it a server-generated
+// ID, runs the account's categorization rules over it, links it to its
+// merchant and stores it under the given account
+func (s *service) CreateTransaction(ctx context.Context, accountID string, t types.Transaction) (*types.Transaction, error) {
+	if t.TransactionID != "" {
+		return nil, &types.ValidationError{Field: "transaction_id", Message: "is assigned by the server"}
+	}
+	if err := checkAccountID(accountID, &t); err != nil {
+		return nil, err
+	}
+	if err := s.validate(ctx, accountID, &t); err != nil {
+		return nil, err
+	}
+	if err := s.requireAccount(ctx, accountID); err != nil {
+		return nil, err
+	}
+
+	tags, err := types.NormalizeTags("tags", t.Tags)
+	if err != nil {
+		return nil, err
+	}
+	t.Tags = tags
+
+	// Rules run first so that a merchant they rename is linked under its new
+	// name, and a category they set takes precedence over the merchant's
+	created := []types.Transaction{t}
+	if err := s.rules.Apply(ctx, accountID, created); err != nil {
+		return nil, fmt.Errorf("failed to apply rules: %w", err)
+	}
+	if err := s.merchants.Normalize(ctx, accountID, created); err != nil {
+		return nil, fmt.Errorf("failed to normalize merchant: %w", err)
+	}
+	t = created[0]
+
+	t.TransactionID = types.NewTransactionID()
+	if err := s.repo.CreateTransaction(ctx, &t); err != nil {
+		return nil, fmt.Errorf("failed to create transaction: %w", err)
+	}
+	s.classifier.Learn(t)
+	return &t, nil
+}
+
+// ReplaceTransaction overwrites every mutable field of an existing
+// transaction, including its tags and splits. Categorization rules only run
+// when a transaction is created, so the client's category, merchant and tags
+// are saved as given; rules are re-applied to existing transactions only by
+// an explicit rule run.
+func (s *service) ReplaceTransaction(ctx context.Context, accountID string, transactionID string, t types.Transaction) (*types.Transaction, error) {
+	if t.TransactionID != "" && t.TransactionID != transactionID {
+		return nil, &types.ValidationError{Field: "transaction_id", Message: "does not match the request path"}
+	}
+	if err := checkAccountID(accountID, &t); err != nil {
+		return nil, err
+	}
+	t.TransactionID = transactionID
+	if err := s.validate(ctx, accountID, &t); err != nil {
+		return nil, err
+	}
+	if err := s.requireAccount(ctx, accountID); err != nil {
+		return nil, err
+	}
+
+	tags, err := types.NormalizeTags("tags", t.Tags)
+	if err != nil {
+		return nil, err
+	}
+	t.Tags = tags
+
+	old, err := s.repo.GetTransaction(ctx, accountID, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.link(ctx, accountID, &t); err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateTransaction(ctx, &t); err != nil {
+		return nil, fmt.Errorf("failed to replace transaction: %w", err)
+	}
+	s.relearn(*old, t)
+	return &t, nil
+}
+
+// PatchTransaction applies a partial update to an existing transaction and
+// validates the result before saving it. Like ReplaceTransaction, it does not
+// run the categorization rules.
+func (s *service) PatchTransaction(ctx context.Context, accountID string, transactionID string, patch types.TransactionPatch) (*types.Transaction, error) {
+	if err := s.requireAccount(ctx, accountID); err != nil {
+		return nil, err
+	}
+
+	t, err := s.repo.GetTransaction(ctx, accountID, transactionID)
+	if err != nil {
+		return nil, err
+	}
+
+	old := *t
+	patch.Apply(t)
+	if err := s.validate(ctx, accountID, t); err != nil {
+		return nil, err
+	}
+	if err := s.link(ctx, accountID, t); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.UpdateTransaction(ctx, t); err != nil {
+		return nil, fmt.Errorf("failed to update transaction: %w", err)
+	}
+	s.relearn(old, *t)
+	return t, nil
+}
+
+// link points t at the merchant its description belongs to
+func (s *service) link(ctx context.Context, accountID string, t *types.Transaction) error {
+	linked := []types.Transaction{*t}
+	if err := s.merchants.Link(ctx, accountID, linked); err != nil {
+		return fmt.Errorf("failed to link merchant: %w", err)
+	}
+	t.MerchantID = linked[0].MerchantID
+	return nil
+}
+
+// DeleteTransaction removes a transaction. Its attachments go with it, and
+// their content is then deleted from the blob store.
+func (s *service) DeleteTransaction(ctx context.Context, accountID string, transactionID string) error {
+	if err := s.requireAccount(ctx, accountID); err != nil {
+		return err
+	}
+	t, err := s.repo.GetTransaction(ctx, accountID, transactionID)
+	if err != nil {
+		return err
+	}
+	attachments, err := s.attachments.ListAttachments(ctx, accountID, transactionID)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.DeleteTransaction(ctx, accountID, transactionID); err != nil {
+		return fmt.Errorf("failed to delete transaction: %w", err)
+	}
+	s.attachments.DeleteContent(ctx, attachments)
+	s.classifier.Forget(*t)
+	return nil
+}
+
+// TagTransaction adds a tag to a transaction and returns the transaction
+func (s *service) TagTransaction(ctx context.Context, accountID string, transactionID string, tag string) (*types.Transaction, error) {
+	change := types.TagChange{TransactionIDs: []string{transactionID}, Tags: []string{tag}}
+	if _, err := s.TagTransactions(ctx, accountID, change); err != nil {
+		return nil, err
+	}
+	return s.repo.GetTransaction(ctx, accountID, transactionID)
+}
+
+// UntagTransaction removes a tag from a transaction and returns the
+// transaction. Removing a tag the transaction does not have is not an error.
+func (s *service) UntagTransaction(ctx context.Context, accountID string, transactionID string, tag string) (*types.Transaction, error) {
+	change := types.TagChange{TransactionIDs: []string{transactionID}, Tags: []string{tag}}
+	if _, err := s.UntagTransactions(ctx, accountID, change); err != nil {
+		return nil, err
+	}
+	return s.repo.GetTransaction(ctx, accountID, transactionID)
+}
+
+// TagTransactions adds every tag of the change to every one of its
+// transactions. Nothing is tagged when one of them is not the account's.
+func (s *service) TagTransactions(ctx context.Context, accountID string, change types.TagChange) (*types.TagChangeResult, error) {
+	if err := change.Validate(); err != nil {
+		return nil, err
+	}
+	if err := s.requireAccount(ctx, accountID); err != nil {
+		return nil, err
+	}
+
+	added, err := s.repo.TagTransactions(ctx, accountID, change.TransactionIDs, change.Tags)
+	if err != nil {
+		return nil, err
+	}
+	return &types.TagChangeResult{Transactions: len(change.TransactionIDs), Changed: added}, nil
+}
+
+// UntagTransactions removes every tag of the change from every one of its
+// transactions
+func (s *service) UntagTransactions(ctx context.Context, accountID string, change types.TagChange) (*types.TagChangeResult, error) {
+	if err := change.Validate(); err != nil {
+		return nil, err
+	}
+	if err := s.requireAccount(ctx, accountID); err != nil {
+		return nil, err
+	}
+
+	removed, err := s.repo.UntagTransactions(ctx, accountID, change.TransactionIDs, change.Tags)
+	if err != nil {
+		return nil, err
+	}
+	return &types.TagChangeResult{Transactions: len(change.TransactionIDs), Changed: removed}, nil
+}
+
+// SuggestCategory suggests a category for an existing transaction from how
+// the account's other transactions are categorized
+func (s *service) SuggestCategory(ctx context.Context, accountID string, transactionID string) (*types.CategorySuggestion, error) {
+	if err := s.requireAccount(ctx, accountID); err != nil {
+		return nil, err
+	}
+	t, err := s.repo.GetTransaction(ctx, accountID, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	return s.classifier.Suggest(ctx, *t)
+}
+
+// SuggestDraftCategory suggests a category for a transaction that has not
+// been created yet. The draft needs a merchant or a location; its amount is
+// optional but narrows the suggestion to income or spending.
+func (s *service) SuggestDraftCategory(ctx context.Context, accountID string, draft types.Transaction) (*types.CategorySuggestion, error) {
+	if err := checkAccountID(accountID, &draft); err != nil {
+		return nil, err
+	}
+	if draft.Merchant == "" && draft.Location == "" {
+		return nil, &types.ValidationError{Field: "merchant", Message: "a merchant or location is required"}
+	}
+	if err := s.requireAccount(ctx, accountID); err != nil {
+		return nil, err
+	}
+
+	draft.TransactionID = ""
+	return s.classifier.Suggest(ctx, draft)
+}
+
+// relearn moves a changed transaction in the classifier from what it was to
+// what it is now, so that recategorizing it teaches the classifier at once
+func (s *service) relearn(old, updated types.Transaction) {
+	s.classifier.Forget(old)
+	s.classifier.Learn(updated)
+}
+
+// checkAccountID fills in the account ID from the request path and rejects a
+// body that names a different account
+func checkAccountID(accountID string, t *types.Transaction) error {
+	if t.AccountID != "" && t.AccountID != accountID {
+		return &types.ValidationError{Field: "account_id", Message: "does not match the request path"}
+	}
+	t.AccountID = accountID
+	return nil
+}
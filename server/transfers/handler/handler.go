@@ -0,0 +1,155 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	ownersRepository "server/owners/repository"
+	ownersService "server/owners/service"
+	"server/transfers/repository"
+	"server/transfers/service"
+	"server/types"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+type Handler struct {
+	service service.Service
+}
+
+func NewHandler(service service.Service) *Handler {
+	return &Handler{service: service}
+}
+
+// SetupTransferRoutes configures all the transfer-related routes
+func SetupTransferRoutes(router *mux.Router, db *sql.DB) {
+	repo := repository.NewPostgresRepository(db)
+	owners := ownersService.NewService(ownersRepository.NewPostgresRepository(db))
+	svc := service.NewService(repo, owners)
+	handler := NewHandler(svc)
+	handler.RegisterRoutes(router)
+}
+
+// RegisterRoutes registers all transfer routes
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/api/owners/{ownerId:[0-9]+}/transfers", h.HandleListTransfers).Methods("GET")
+	router.HandleFunc("/api/owners/{ownerId:[0-9]+}/transfers", h.HandleCreateTransfer).Methods("POST")
+	router.HandleFunc("/api/owners/{ownerId:[0-9]+}/transfers/detect", h.HandleDetectTransfers).Methods("POST")
+	router.HandleFunc("/api/owners/{ownerId:[0-9]+}/transfers/{transferId:[0-9]+}", h.HandleGetTransfer).Methods("GET")
+	router.HandleFunc("/api/owners/{ownerId:[0-9]+}/transfers/{transferId:[0-9]+}", h.HandleRejectTransfer).Methods("DELETE")
+}
+
+// HandleListTransfers handles requests for an owner's transfers
+func (h *Handler) HandleListTransfers(w http.ResponseWriter, r *http.Request) {
+	ownerID, _ := strconv.ParseInt(mux.Vars(r)["ownerId"], 10, 64)
+
+	transfers, err := h.service.ListTransfers(r.Context(), ownerID, r.URL.Query().Get("status"))
+	if err != nil {
+		writeError(w, "Failed to get transfers", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(transfers)
+}
+
+// HandleGetTransfer handles requests for a single transfer
+func (h *Handler) HandleGetTransfer(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	ownerID, _ := strconv.ParseInt(vars["ownerId"], 10, 64)
+	transferID, _ := strconv.ParseInt(vars["transferId"], 10, 64)
+
+	transfer, err := h.service.GetTransfer(r.Context(), ownerID, transferID)
+	if err != nil {
+		writeError(w, "Failed to get transfer", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(transfer)
+}
+
+// HandleDetectTransfers handles requests to pair up transfers between the
+// owner's accounts over the last "months" months, three unless given
+func (h *Handler) HandleDetectTransfers(w http.ResponseWriter, r *http.Request) {
+	ownerID, _ := strconv.ParseInt(mux.Vars(r)["ownerId"], 10, 64)
+
+	months := 3
+	if value := r.URL.Query().Get("months"); value != "" {
+		var err error
+		months, err = strconv.Atoi(value)
+		if err != nil {
+			http.Error(w, "Invalid months", http.StatusBadRequest)
+			return
+		}
+	}
+
+	transfers, err := h.service.DetectTransfers(r.Context(), ownerID, months)
+	if err != nil {
+		writeError(w, "Failed to detect transfers", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(transfers)
+}
+
+// HandleCreateTransfer handles requests to mark two transactions as a transfer
+func (h *Handler) HandleCreateTransfer(w http.ResponseWriter, r *http.Request) {
+	ownerID, _ := strconv.ParseInt(mux.Vars(r)["ownerId"], 10, 64)
+
+	var input types.Transfer
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	transfer, err := h.service.CreateTransfer(r.Context(), ownerID, input)
+	if err != nil {
+		writeError(w, "Failed to create transfer", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(transfer)
+}
+
+// HandleRejectTransfer handles requests to undo a transfer
+func (h *Handler) HandleRejectTransfer(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	ownerID, _ := strconv.ParseInt(vars["ownerId"], 10, 64)
+	transferID, _ := strconv.ParseInt(vars["transferId"], 10, 64)
+
+	transfer, err := h.service.RejectTransfer(r.Context(), ownerID, transferID)
+	if err != nil {
+		writeError(w, "Failed to reject transfer", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(transfer)
+}
+
+// writeError converts service and repository errors into HTTP responses
+func writeError(w http.ResponseWriter, message string, err error) {
+	var validationErr *types.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		http.Error(w, validationErr.Error(), http.StatusBadRequest)
+	case errors.Is(err, ownersRepository.ErrOwnerNotFound):
+		http.Error(w, "Owner not found", http.StatusNotFound)
+	case errors.Is(err, repository.ErrTransferNotFound):
+		http.Error(w, "Transfer not found", http.StatusNotFound)
+	case errors.Is(err, repository.ErrTransactionNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, repository.ErrAlreadyMatched):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		log.Printf("%s: %v", message, err)
+		http.Error(w, message, http.StatusInternalServerError)
+	}
+}
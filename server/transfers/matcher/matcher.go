@@ -0,0 +1,157 @@
+package matcher
+
+import (
+	"math"
+	"server/types"
+	"sort"
+	"time"
+)
+
+// Config controls how close the two sides of a transfer must be
+type Config struct {
+	// DateWindow is how far apart the outgoing and incoming sides may post
+	DateWindow time.Duration
+
+	// AmountTolerance is the share of the larger amount by which the two
+	// sides may differ, to allow for small transfer fees
+	AmountTolerance float64
+}
+
+// DefaultConfig allows for the few days an interbank transfer takes to
+// settle and for a fee of up to 1% taken from the amount received
+var DefaultConfig = Config{
+	DateWindow:      3 * 24 * time.Hour,
+	AmountTolerance: 0.01,
+}
+
+// Key identifies a candidate pair by its outgoing and incoming transaction
+type Key struct {
+	From, To string
+}
+
+// Pair is a likely transfer: money leaving one account in From and arriving
+// in another in To
+type Pair struct {
+	From  types.Transaction
+	To    types.Transaction
+	Score float64
+}
+
+// Match pairs outgoing transactions with incoming transactions on another
+// account that have the same currency, a close enough amount and a close
+// enough date. Each transaction ends up in at most one pair: the best scoring
+// pairs are taken first. Pairs listed in skip, such as transfers a user
+// rejected, are never proposed.
+//
+// Both sides are sorted by date, so each outgoing transaction is only
+// compared with the incoming ones within DateWindow of it.
+func (c Config) Match(transactions []types.Transaction, skip map[Key]bool) []Pair {
+	var outgoing, incoming []types.Transaction
+	for _, t := range transactions {
+		switch t.Amount.Sign() {
+		case -1:
+			outgoing = append(outgoing, t)
+		case 1:
+			incoming = append(incoming, t)
+		}
+	}
+	byDate := func(s []types.Transaction) func(i, j int) bool {
+		return func(i, j int) bool { return s[i].Date.Before(s[j].Date) }
+	}
+	sort.SliceStable(outgoing, byDate(outgoing))
+	sort.SliceStable(incoming, byDate(incoming))
+
+	var candidates []Pair
+	start := 0
+	for _, from := range outgoing {
+		earliest, latest := from.Date.Add(-c.DateWindow), from.Date.Add(c.DateWindow)
+		for start < len(incoming) && incoming[start].Date.Before(earliest) {
+			start++
+		}
+		for _, to := range incoming[start:] {
+			if to.Date.After(latest) {
+				break
+			}
+			if skip[Key{from.TransactionID, to.TransactionID}] {
+				continue
+			}
+			if score, ok := c.Score(from, to); ok {
+				candidates = append(candidates, Pair{From: from, To: to, Score: score})
+			}
+		}
+	}
+
+	// Best score first; ties go to the earlier transfer and then to the
+	// lower IDs so that repeated runs give the same pairs
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if !a.From.Date.Equal(b.From.Date) {
+			return a.From.Date.Before(b.From.Date)
+		}
+		if a.From.TransactionID != b.From.TransactionID {
+			return a.From.TransactionID < b.From.TransactionID
+		}
+		return a.To.TransactionID < b.To.TransactionID
+	})
+
+	used := make(map[string]bool)
+	pairs := []Pair{}
+	for _, p := range candidates {
+		if used[p.From.TransactionID] || used[p.To.TransactionID] {
+			continue
+		}
+		used[p.From.TransactionID] = true
+		used[p.To.TransactionID] = true
+		pairs = append(pairs, p)
+	}
+	return pairs
+}
+
+// Score reports whether from and to can be the two sides of one transfer
+// and, if so, how closely they match from 0 to 1. Amount closeness weighs
+// more than date closeness, as transfers between the same two accounts often
+// repeat the same amount on different days.
+func (c Config) Score(from, to types.Transaction) (float64, bool) {
+	if from.Amount.Sign() >= 0 || to.Amount.Sign() <= 0 {
+		return 0, false
+	}
+	if from.AccountID == to.AccountID || from.TransactionID == to.TransactionID {
+		return 0, false
+	}
+	if from.Currency != to.Currency {
+		return 0, false
+	}
+
+	sent, received := from.Amount.Abs(), to.Amount
+	larger := sent
+	if received.Cmp(larger) > 0 {
+		larger = received
+	}
+	diff := sent.Sub(received).Abs()
+	allowed := c.AmountTolerance * float64(larger.Cents)
+	if float64(diff.Cents) > allowed {
+		return 0, false
+	}
+
+	gap := to.Date.Sub(from.Date)
+	if gap < 0 {
+		gap = -gap
+	}
+	if gap > c.DateWindow {
+		return 0, false
+	}
+
+	amountCloseness := 1.0
+	if allowed > 0 {
+		amountCloseness = 1 - float64(diff.Cents)/allowed
+	}
+	dateCloseness := 1.0
+	if c.DateWindow > 0 {
+		dateCloseness = 1 - float64(gap)/float64(c.DateWindow)
+	}
+	score := 0.6*amountCloseness + 0.4*dateCloseness
+	return math.Round(score*1000) / 1000, true
+}
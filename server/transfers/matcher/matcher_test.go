@@ -0,0 +1,142 @@
+package matcher
+
+import (
+	"server/types"
+	"testing"
+	"time"
+)
+
+// tx is a transaction of the account posted on the given day of March 2025
+func tx(id, account string, cents int64, day int) types.Transaction {
+	return types.Transaction{
+		TransactionID: id,
+		AccountID:     account,
+		Amount:        types.NewMoney(cents, ""),
+		Currency:      "USD",
+		Date:          time.Date(2025, time.March, day, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestScore(t *testing.T) {
+	euro := tx("in", "savings", 10000, 10)
+	euro.Currency = "EUR"
+
+	tests := []struct {
+		name     string
+		from, to types.Transaction
+		want     float64
+		ok       bool
+	}{
+		{"same amount on the same day", tx("out", "checking", -10000, 10), tx("in", "savings", 10000, 10), 1, true},
+		{"a day apart", tx("out", "checking", -10000, 10), tx("in", "savings", 10000, 11), 0.867, true},
+		{"received before it was sent", tx("out", "checking", -10000, 11), tx("in", "savings", 10000, 10), 0.867, true},
+		{"at the edge of the date window", tx("out", "checking", -10000, 10), tx("in", "savings", 10000, 13), 0.6, true},
+		{"outside the date window", tx("out", "checking", -10000, 10), tx("in", "savings", 10000, 14), 0, false},
+		{"a fee of half the tolerance", tx("out", "checking", -10000, 10), tx("in", "savings", 9950, 10), 0.7, true},
+		{"more received than sent", tx("out", "checking", -10000, 10), tx("in", "savings", 10050, 10), 0.701, true},
+		{"a fee above the tolerance", tx("out", "checking", -10000, 10), tx("in", "savings", 9890, 10), 0, false},
+		{"the same account", tx("out", "checking", -10000, 10), tx("in", "checking", 10000, 10), 0, false},
+		{"different currencies", tx("out", "checking", -10000, 10), euro, 0, false},
+		{"both outgoing", tx("out", "checking", -10000, 10), tx("in", "savings", -10000, 10), 0, false},
+		{"the wrong way round", tx("in", "savings", 10000, 10), tx("out", "checking", -10000, 10), 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := DefaultConfig.Score(tt.from, tt.to)
+			if ok != tt.ok || got != tt.want {
+				t.Errorf("Score() = %v, %v, want %v, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		name         string
+		transactions []types.Transaction
+		skip         map[Key]bool
+		want         []Key
+	}{
+		{
+			name: "the closest incoming side wins",
+			transactions: []types.Transaction{
+				tx("out", "checking", -10000, 10),
+				tx("late", "savings", 10000, 12),
+				tx("same-day", "brokerage", 10000, 10),
+			},
+			want: []Key{{"out", "same-day"}},
+		},
+		{
+			name: "a rejected pair is never proposed",
+			transactions: []types.Transaction{
+				tx("out", "checking", -10000, 10),
+				tx("late", "savings", 10000, 12),
+				tx("same-day", "brokerage", 10000, 10),
+			},
+			skip: map[Key]bool{{"out", "same-day"}: true},
+			want: []Key{{"out", "late"}},
+		},
+		{
+			// Identical transfers pair up by ID, each side used once
+			name: "repeated transfers",
+			transactions: []types.Transaction{
+				tx("out-b", "checking", -5000, 10),
+				tx("in-b", "savings", 5000, 10),
+				tx("out-a", "checking", -5000, 10),
+				tx("in-a", "savings", 5000, 10),
+			},
+			want: []Key{{"out-a", "in-a"}, {"out-b", "in-b"}},
+		},
+		{
+			// The better pair is taken even though the other outgoing side
+			// could also have used the incoming one
+			name: "best score first",
+			transactions: []types.Transaction{
+				tx("out-1", "checking", -10000, 10),
+				tx("out-2", "card", -10000, 12),
+				tx("in", "savings", 10000, 12),
+			},
+			want: []Key{{"out-2", "in"}},
+		},
+		{
+			// Out of date order, with sides just inside and just outside the
+			// window on either side of each outgoing transaction
+			name: "unsorted input",
+			transactions: []types.Transaction{
+				tx("in-late", "savings", 2000, 24),
+				tx("out-late", "checking", -2000, 21),
+				tx("in-too-late", "savings", 1000, 14),
+				tx("in-early", "savings", 1000, 7),
+				tx("out-early", "checking", -1000, 10),
+				tx("in-too-early", "savings", 2000, 17),
+			},
+			want: []Key{{"out-early", "in-early"}, {"out-late", "in-late"}},
+		},
+		{
+			name: "nothing to pair",
+			transactions: []types.Transaction{
+				tx("out", "checking", -10000, 10),
+				tx("in", "checking", 10000, 10),
+			},
+			want: []Key{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pairs := DefaultConfig.Match(tt.transactions, tt.skip)
+			if pairs == nil {
+				t.Fatal("Match() = nil, want an empty slice")
+			}
+			if len(pairs) != len(tt.want) {
+				t.Fatalf("Match() returned %d pairs, want %d", len(pairs), len(tt.want))
+			}
+			for i, want := range tt.want {
+				got := Key{pairs[i].From.TransactionID, pairs[i].To.TransactionID}
+				if got != want {
+					t.Errorf("pair %d = %+v, want %+v", i, got, want)
+				}
+			}
+		})
+	}
+}
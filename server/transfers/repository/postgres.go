@@ -0,0 +1,269 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"server/types"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type postgresRepo struct {
+	db *sql.DB
+}
+
+func NewPostgresRepository(db *sql.DB) Repository {
+	if db == nil {
+		panic("database connection is required")
+	}
+	return &postgresRepo{db: db}
+}
+
+// transactionColumns selects a transaction t with its currency resolved
+// against its account u, so that both sides of a transfer compare alike
+const transactionColumns = `
+	t.transaction_id, t.account_id, t.date, t.amount, t.category, t.merchant, t.location,
+	COALESCE(NULLIF(t.currency, ''), NULLIF(u.balance_currency, ''), 'USD'), t.transfer_id`
+
+// ListCandidates retrieves the transactions of the accounts dated from
+// startDate up to but excluding endDate that are not part of a transfer yet,
+// with their currency resolved
+func (r *postgresRepo) ListCandidates(ctx context.Context, accountIDs []string, startDate, endDate time.Time) ([]types.Transaction, error) {
+	query := `
+		SELECT` + transactionColumns + `
+		FROM transactions t
+		JOIN users u ON u.account_id = t.account_id
+		WHERE t.account_id = ANY($1)
+		  AND t.date >= $2 AND t.date < $3
+		  AND t.transfer_id IS NULL
+		ORDER BY t.date, t.transaction_id`
+
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(accountIDs), startDate, endDate)
+	if err != nil {
+		log.Printf("Error querying transfer candidates: %v", err)
+		return nil, fmt.Errorf("failed to query transactions: %w", err)
+	}
+	defer rows.Close()
+
+	transactions := []types.Transaction{}
+	for rows.Next() {
+		t, err := scanTransaction(rows)
+		if err != nil {
+			log.Printf("Error scanning transaction: %v", err)
+			return nil, fmt.Errorf("failed to scan transaction: %w", err)
+		}
+		transactions = append(transactions, *t)
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("Error iterating transactions: %v", err)
+		return nil, fmt.Errorf("error iterating transactions: %w", err)
+	}
+	return transactions, nil
+}
+
+// GetTransaction retrieves a single transaction from one of the accounts
+func (r *postgresRepo) GetTransaction(ctx context.Context, accountIDs []string, transactionID string) (*types.Transaction, error) {
+	query := `
+		SELECT` + transactionColumns + `
+		FROM transactions t
+		JOIN users u ON u.account_id = t.account_id
+		WHERE t.account_id = ANY($1) AND t.transaction_id = $2`
+
+	t, err := scanTransaction(r.db.QueryRowContext(ctx, query, pq.Array(accountIDs), transactionID))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w: %s", ErrTransactionNotFound, transactionID)
+	}
+	if err != nil {
+		log.Printf("Error fetching transaction %s: %v", transactionID, err)
+		return nil, fmt.Errorf("failed to fetch transaction: %w", err)
+	}
+	return t, nil
+}
+
+// transferQuery selects transfers tr together with their outgoing (f) and
+// incoming (t) transactions
+const transferQuery = `
+	SELECT tr.id, tr.owner_id, tr.score, tr.source, tr.status, tr.created_at,
+		f.transaction_id, f.account_id, f.date, f.amount, f.category, f.merchant, f.location,
+		COALESCE(NULLIF(f.currency, ''), NULLIF(fu.balance_currency, ''), 'USD'), f.transfer_id,
+		t.transaction_id, t.account_id, t.date, t.amount, t.category, t.merchant, t.location,
+		COALESCE(NULLIF(t.currency, ''), NULLIF(tu.balance_currency, ''), 'USD'), t.transfer_id
+	FROM transfers tr
+	JOIN transactions f ON f.transaction_id = tr.from_transaction_id
+	JOIN users fu ON fu.account_id = f.account_id
+	JOIN transactions t ON t.transaction_id = tr.to_transaction_id
+	JOIN users tu ON tu.account_id = t.account_id`
+
+// ListTransfers retrieves an owner's transfers with both transactions,
+// newest first. An empty status lists every transfer.
+func (r *postgresRepo) ListTransfers(ctx context.Context, ownerID int64, status string) ([]types.Transfer, error) {
+	query := transferQuery + `
+		WHERE tr.owner_id = $1 AND ($2::text = '' OR tr.status = $2)
+		ORDER BY t.date DESC, tr.id DESC`
+
+	rows, err := r.db.QueryContext(ctx, query, ownerID, status)
+	if err != nil {
+		log.Printf("Error querying transfers: %v", err)
+		return nil, fmt.Errorf("failed to query transfers: %w", err)
+	}
+	defer rows.Close()
+
+	transfers := []types.Transfer{}
+	for rows.Next() {
+		transfer, err := scanTransfer(rows)
+		if err != nil {
+			log.Printf("Error scanning transfer: %v", err)
+			return nil, fmt.Errorf("failed to scan transfer: %w", err)
+		}
+		transfers = append(transfers, *transfer)
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("Error iterating transfers: %v", err)
+		return nil, fmt.Errorf("error iterating transfers: %w", err)
+	}
+	return transfers, nil
+}
+
+// GetTransfer retrieves a single transfer of an owner
+func (r *postgresRepo) GetTransfer(ctx context.Context, ownerID int64, transferID int64) (*types.Transfer, error) {
+	query := transferQuery + ` WHERE tr.owner_id = $1 AND tr.id = $2`
+
+	transfer, err := scanTransfer(r.db.QueryRowContext(ctx, query, ownerID, transferID))
+	if err == sql.ErrNoRows {
+		return nil, ErrTransferNotFound
+	}
+	if err != nil {
+		log.Printf("Error fetching transfer %d: %v", transferID, err)
+		return nil, fmt.Errorf("failed to fetch transfer: %w", err)
+	}
+	return transfer, nil
+}
+
+// CreateTransfers inserts matched transfers and marks their transactions,
+// setting each transfer's ID. Either every transfer is saved or none is.
+func (r *postgresRepo) CreateTransfers(ctx context.Context, transfers []*types.Transfer) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, transfer := range transfers {
+		err := tx.QueryRowContext(ctx, `
+			INSERT INTO transfers (owner_id, from_transaction_id, to_transaction_id, score, source, status)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			RETURNING id, created_at`,
+			transfer.OwnerID, transfer.FromTransactionID, transfer.ToTransactionID,
+			transfer.Score, transfer.Source, types.TransferMatched,
+		).Scan(&transfer.ID, &transfer.CreatedAt)
+		if err != nil {
+			return translateError(err)
+		}
+		transfer.Status = types.TransferMatched
+
+		// Both sides must still be free; a concurrent match or manual
+		// transfer may have claimed one of them since they were read
+		result, err := tx.ExecContext(ctx, `
+			UPDATE transactions SET transfer_id = $1
+			WHERE transaction_id IN ($2, $3) AND transfer_id IS NULL`,
+			transfer.ID, transfer.FromTransactionID, transfer.ToTransactionID,
+		)
+		if err != nil {
+			log.Printf("Error marking transactions of transfer %d: %v", transfer.ID, err)
+			return fmt.Errorf("failed to mark transfer transactions: %w", err)
+		}
+		if n, err := result.RowsAffected(); err == nil && n != 2 {
+			return ErrAlreadyMatched
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transfers: %w", err)
+	}
+	return nil
+}
+
+// RejectTransfer marks a transfer as rejected and returns its transactions
+// to analytics
+func (r *postgresRepo) RejectTransfer(ctx context.Context, ownerID int64, transferID int64) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx,
+		`UPDATE transfers SET status = $3 WHERE owner_id = $1 AND id = $2`,
+		ownerID, transferID, types.TransferRejected,
+	)
+	if err != nil {
+		log.Printf("Error rejecting transfer %d: %v", transferID, err)
+		return fmt.Errorf("failed to reject transfer: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrTransferNotFound
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE transactions SET transfer_id = NULL WHERE transfer_id = $1`, transferID); err != nil {
+		log.Printf("Error releasing transactions of transfer %d: %v", transferID, err)
+		return fmt.Errorf("failed to release transfer transactions: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transfer: %w", err)
+	}
+	return nil
+}
+
+// translateError maps a unique violation on the matched-transfer indexes to
+// ErrAlreadyMatched
+func translateError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code.Name() == "unique_violation" {
+		return ErrAlreadyMatched
+	}
+	log.Printf("Error creating transfer: %v", err)
+	return fmt.Errorf("failed to create transfer: %w", err)
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanTransaction(row rowScanner) (*types.Transaction, error) {
+	var t types.Transaction
+	err := row.Scan(
+		&t.TransactionID, &t.AccountID, &t.Date, &t.Amount, &t.Category,
+		&t.Merchant, &t.Location, &t.Currency, &t.TransferID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func scanTransfer(row rowScanner) (*types.Transfer, error) {
+	var transfer types.Transfer
+	var from, to types.Transaction
+	err := row.Scan(
+		&transfer.ID, &transfer.OwnerID, &transfer.Score, &transfer.Source, &transfer.Status, &transfer.CreatedAt,
+		&from.TransactionID, &from.AccountID, &from.Date, &from.Amount, &from.Category,
+		&from.Merchant, &from.Location, &from.Currency, &from.TransferID,
+		&to.TransactionID, &to.AccountID, &to.Date, &to.Amount, &to.Category,
+		&to.Merchant, &to.Location, &to.Currency, &to.TransferID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	transfer.FromTransactionID = from.TransactionID
+	transfer.ToTransactionID = to.TransactionID
+	transfer.Amount = to.Amount
+	transfer.Currency = to.Currency
+	transfer.From = &from
+	transfer.To = &to
+	return &transfer, nil
+}
@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"server/types"
+	"time"
+)
+
+var (
+	// ErrTransferNotFound is returned when the transfer does not exist for the owner
+	ErrTransferNotFound = errors.New("transfer not found")
+
+	// ErrTransactionNotFound is returned when a transaction is not on one of the owner's accounts
+	ErrTransactionNotFound = errors.New("transaction not found")
+
+	// ErrAlreadyMatched is returned when a transaction is already part of a transfer
+	ErrAlreadyMatched = errors.New("transaction is already part of a transfer")
+)
+
+// Repository defines the interface for transfer data operations
+type Repository interface {
+	// ListCandidates retrieves the transactions of the accounts dated from
+	// startDate up to but excluding endDate that are not part of a transfer
+	// yet, with their currency resolved
+	ListCandidates(ctx context.Context, accountIDs []string, startDate, endDate time.Time) ([]types.Transaction, error)
+
+	// GetTransaction retrieves a single transaction from one of the accounts
+	GetTransaction(ctx context.Context, accountIDs []string, transactionID string) (*types.Transaction, error)
+
+	// ListTransfers retrieves an owner's transfers with both transactions,
+	// newest first. An empty status lists every transfer.
+	ListTransfers(ctx context.Context, ownerID int64, status string) ([]types.Transfer, error)
+
+	// GetTransfer retrieves a single transfer of an owner
+	GetTransfer(ctx context.Context, ownerID int64, transferID int64) (*types.Transfer, error)
+
+	// CreateTransfers inserts matched transfers and marks their transactions,
+	// setting each transfer's ID. Either every transfer is saved or none is.
+	CreateTransfers(ctx context.Context, transfers []*types.Transfer) error
+
+	// RejectTransfer marks a transfer as rejected and returns its
+	// transactions to analytics
+	RejectTransfer(ctx context.Context, ownerID int64, transferID int64) error
+}
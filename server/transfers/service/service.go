@@ -0,0 +1,179 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	ownersService "server/owners/service"
+	"server/transfers/matcher"
+	"server/transfers/repository"
+	"server/types"
+	"strings"
+	"time"
+)
+
+// maxDetectMonths limits how far back transfers are detected
+const maxDetectMonths = 24
+
+type Service interface {
+	ListTransfers(ctx context.Context, ownerID int64, status string) ([]types.Transfer, error)
+	GetTransfer(ctx context.Context, ownerID int64, transferID int64) (*types.Transfer, error)
+	DetectTransfers(ctx context.Context, ownerID int64, months int) ([]types.Transfer, error)
+	CreateTransfer(ctx context.Context, ownerID int64, input types.Transfer) (*types.Transfer, error)
+	RejectTransfer(ctx context.Context, ownerID int64, transferID int64) (*types.Transfer, error)
+}
+
+type service struct {
+	repo    repository.Repository
+	owners  ownersService.Service
+	matcher matcher.Config
+}
+
+func NewService(repo repository.Repository, owners ownersService.Service) Service {
+	return &service{repo: repo, owners: owners, matcher: matcher.DefaultConfig}
+}
+
+// ListTransfers lists an owner's transfers, optionally only those with the given status
+func (s *service) ListTransfers(ctx context.Context, ownerID int64, status string) ([]types.Transfer, error) {
+	status = strings.ToLower(strings.TrimSpace(status))
+	if status != "" && status != types.TransferMatched && status != types.TransferRejected {
+		return nil, &types.ValidationError{Field: "status", Message: "must be matched or rejected"}
+	}
+	return s.repo.ListTransfers(ctx, ownerID, status)
+}
+
+func (s *service) GetTransfer(ctx context.Context, ownerID int64, transferID int64) (*types.Transfer, error) {
+	return s.repo.GetTransfer(ctx, ownerID, transferID)
+}
+
+// DetectTransfers pairs the unmatched transactions of the owner's accounts
+// over the last months and saves the pairs as transfers, skipping pairs the
+// user rejected before. It returns only the newly detected transfers.
+func (s *service) DetectTransfers(ctx context.Context, ownerID int64, months int) ([]types.Transfer, error) {
+	if months < 1 || months > maxDetectMonths {
+		return nil, &types.ValidationError{Field: "months", Message: fmt.Sprintf("must be between 1 and %d", maxDetectMonths)}
+	}
+	accountIDs, err := s.accountIDs(ctx, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	if len(accountIDs) < 2 {
+		return nil, &types.ValidationError{Field: "owner", Message: "needs at least two linked accounts to detect transfers"}
+	}
+
+	now := time.Now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	candidates, err := s.repo.ListCandidates(ctx, accountIDs, today.AddDate(0, -months, 0), today.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+	rejected, err := s.repo.ListTransfers(ctx, ownerID, types.TransferRejected)
+	if err != nil {
+		return nil, err
+	}
+	skip := make(map[matcher.Key]bool, len(rejected))
+	for _, t := range rejected {
+		skip[matcher.Key{From: t.FromTransactionID, To: t.ToTransactionID}] = true
+	}
+
+	pairs := s.matcher.Match(candidates, skip)
+	if len(pairs) == 0 {
+		return []types.Transfer{}, nil
+	}
+
+	transfers := make([]*types.Transfer, len(pairs))
+	for i, p := range pairs {
+		transfers[i] = newTransfer(ownerID, p.From, p.To, p.Score, types.TransferSourceDetected)
+	}
+	if err := s.repo.CreateTransfers(ctx, transfers); err != nil {
+		return nil, err
+	}
+	log.Printf("Detected %d transfers for owner %d", len(transfers), ownerID)
+
+	detected := make([]types.Transfer, len(transfers))
+	for i, t := range transfers {
+		detected[i] = *t
+	}
+	return detected, nil
+}
+
+// CreateTransfer marks two transactions as a transfer by hand. Unlike
+// detection it accepts any amounts and dates, for transfers that lose a fee
+// or cross currencies, but the outgoing side must be negative, the incoming
+// side positive, and the two must be on different accounts of the owner.
+func (s *service) CreateTransfer(ctx context.Context, ownerID int64, input types.Transfer) (*types.Transfer, error) {
+	input.FromTransactionID = strings.TrimSpace(input.FromTransactionID)
+	input.ToTransactionID = strings.TrimSpace(input.ToTransactionID)
+	switch {
+	case input.FromTransactionID == "":
+		return nil, &types.ValidationError{Field: "from_transaction_id", Message: "is required"}
+	case input.ToTransactionID == "":
+		return nil, &types.ValidationError{Field: "to_transaction_id", Message: "is required"}
+	}
+
+	accountIDs, err := s.accountIDs(ctx, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	from, err := s.repo.GetTransaction(ctx, accountIDs, input.FromTransactionID)
+	if err != nil {
+		return nil, err
+	}
+	to, err := s.repo.GetTransaction(ctx, accountIDs, input.ToTransactionID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case from.Amount.Sign() >= 0:
+		return nil, &types.ValidationError{Field: "from_transaction_id", Message: "must be money leaving an account"}
+	case to.Amount.Sign() <= 0:
+		return nil, &types.ValidationError{Field: "to_transaction_id", Message: "must be money arriving in an account"}
+	case from.AccountID == to.AccountID:
+		return nil, &types.ValidationError{Field: "to_transaction_id", Message: "must be on a different account"}
+	case from.TransferID != nil || to.TransferID != nil:
+		return nil, repository.ErrAlreadyMatched
+	}
+
+	score, ok := s.matcher.Score(*from, *to)
+	if !ok {
+		score = 0
+	}
+	transfer := newTransfer(ownerID, *from, *to, score, types.TransferSourceManual)
+	if err := s.repo.CreateTransfers(ctx, []*types.Transfer{transfer}); err != nil {
+		return nil, err
+	}
+	return s.repo.GetTransfer(ctx, ownerID, transfer.ID)
+}
+
+// RejectTransfer undoes a transfer so that its transactions count in
+// analytics again. Detection will not propose the same pair again.
+func (s *service) RejectTransfer(ctx context.Context, ownerID int64, transferID int64) (*types.Transfer, error) {
+	if err := s.repo.RejectTransfer(ctx, ownerID, transferID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetTransfer(ctx, ownerID, transferID)
+}
+
+// accountIDs returns the accounts linked to the owner
+func (s *service) accountIDs(ctx context.Context, ownerID int64) ([]string, error) {
+	owner, err := s.owners.GetOwner(ctx, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	return owner.AccountIDs, nil
+}
+
+func newTransfer(ownerID int64, from, to types.Transaction, score float64, source string) *types.Transfer {
+	return &types.Transfer{
+		OwnerID:           ownerID,
+		FromTransactionID: from.TransactionID,
+		ToTransactionID:   to.TransactionID,
+		Amount:            to.Amount,
+		Currency:          to.Currency,
+		Score:             score,
+		Source:            source,
+		From:              &from,
+		To:                &to,
+	}
+}
@@ -19,7 +19,7 @@ bank_details
 	branch
 */
 
-// Account represents a user's account information as per init.sql schema
+// Account represents a user's account information as per the database schema in migrations/sql
 type Account struct {
 	AccountID     string  `json:"account_id"`     // VARCHAR(20) PRIMARY KEY
 	AccountName   string  `json:"account_name"`   // VARCHAR(50)
@@ -31,8 +31,8 @@ type Account struct {
 }
 
 type Balance struct {
-	Current   float64 `json:"current"`    // DECIMAL(10, 2)
-	Available float64 `json:"available"`   // DECIMAL(10, 2)
+	Current   Money   `json:"current"`    // DECIMAL(10, 2)
+	Available Money   `json:"available"`   // DECIMAL(10, 2)
 	Currency  string  `json:"currency"`    // VARCHAR(3)
 }
 
@@ -0,0 +1,31 @@
+package types
+
+import "time"
+
+// MaxAttachmentSize limits the size of an attached file
+const MaxAttachmentSize = 10 << 20
+
+// AttachmentContentTypes lists the content types a file may be attached
+// with: receipt photos and PDFs
+var AttachmentContentTypes = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/gif":       ".gif",
+	"image/webp":      ".webp",
+	"application/pdf": ".pdf",
+}
+
+// Attachment is a file attached to a transaction, as per the attachments
+// table in migrations/sql. The file is kept in the blob store under
+// StorageKey.
+type Attachment struct {
+	ID            int64     `json:"id"`             // SERIAL PRIMARY KEY
+	AccountID     string    `json:"account_id"`     // VARCHAR(20) REFERENCES users(account_id)
+	TransactionID string    `json:"transaction_id"` // VARCHAR(20) REFERENCES transactions(transaction_id)
+	Filename      string    `json:"filename"`       // VARCHAR(255)
+	ContentType   string    `json:"content_type"`   // one of AttachmentContentTypes
+	Size          int64     `json:"size"`           // in bytes, at most MaxAttachmentSize
+	SHA256        string    `json:"sha256"`         // hex digest of the content
+	StorageKey    string    `json:"-"`
+	CreatedAt     time.Time `json:"created_at"`
+}
@@ -0,0 +1,199 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Bill frequencies
+const (
+	BillWeekly     = "weekly"
+	BillBiweekly   = "biweekly"
+	BillMonthly    = "monthly"
+	BillQuarterly  = "quarterly"
+	BillSemiannual = "semiannual"
+	BillAnnual     = "annual"
+)
+
+// BillFrequencies maps each bill frequency to the months between two due
+// dates; weekly and biweekly bills are due on a day of the week instead
+var BillFrequencies = map[string]int{
+	BillWeekly:     0,
+	BillBiweekly:   0,
+	BillMonthly:    1,
+	BillQuarterly:  3,
+	BillSemiannual: 6,
+	BillAnnual:     12,
+}
+
+// Bill amount types
+const (
+	BillAmountFixed    = "fixed"    // the same amount every time
+	BillAmountVariable = "variable" // Amount is an estimate, such as a utility bill
+)
+
+// Sources of an upcoming bill
+const (
+	BillDeclared = "declared" // a Bill the account registered
+	BillInferred = "inferred" // detected from recurring bill payments
+)
+
+// Bill is a bill an account declared, as per the bills table in
+// migrations/sql. Amounts are in the account's currency.
+type Bill struct {
+	ID         int64  `json:"id"`
+	AccountID  string `json:"account_id"`
+	Payee      string `json:"payee"`       // VARCHAR(100)
+	Category   string `json:"category"`    // a bill category of the account; defaults to Bill Payment
+	Amount     Money  `json:"amount"`      // DECIMAL(10, 2), positive
+	AmountType string `json:"amount_type"` // fixed (the default) or variable
+	Frequency  string `json:"frequency"`   // one of BillFrequencies
+
+	// DueDay is the day of the month the bill is due, moved to the last day
+	// of shorter months, or for weekly and biweekly bills the day of the week
+	// (1 = Monday, 7 = Sunday). It defaults to the day of StartDate.
+	DueDay int `json:"due_day"`
+
+	// StartDate is the date from which the bill is due, today unless given.
+	// Quarterly, semiannual and annual bills are due in its month and every
+	// period after; biweekly bills every other week from it.
+	StartDate time.Time `json:"start_date"` // DATE
+
+	Autopay   bool      `json:"autopay"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Validate normalizes and checks the fields a client can set against the
+// account's categories
+func (b *Bill) Validate(categories Categories) error {
+	b.Payee = strings.TrimSpace(b.Payee)
+	b.Category = strings.TrimSpace(b.Category)
+	b.AmountType = strings.ToLower(strings.TrimSpace(b.AmountType))
+	b.Frequency = strings.ToLower(strings.TrimSpace(b.Frequency))
+	if b.Category == "" {
+		b.Category = CategoryBillPayment
+	}
+	if b.AmountType == "" {
+		b.AmountType = BillAmountFixed
+	}
+	if b.StartDate.IsZero() {
+		b.StartDate = time.Now().UTC()
+	}
+	b.StartDate = time.Date(b.StartDate.Year(), b.StartDate.Month(), b.StartDate.Day(), 0, 0, 0, 0, time.UTC)
+	months, known := BillFrequencies[b.Frequency]
+	if b.DueDay == 0 && known {
+		b.DueDay = b.StartDate.Day()
+		if months == 0 {
+			b.DueDay = isoWeekday(b.StartDate)
+		}
+	}
+
+	switch {
+	case b.Payee == "":
+		return &ValidationError{Field: "payee", Message: "is required"}
+	case len(b.Payee) > 100:
+		return &ValidationError{Field: "payee", Message: "must be at most 100 characters"}
+	case !categories.Has(b.Category):
+		return &ValidationError{Field: "category", Message: fmt.Sprintf("unknown category %q", b.Category)}
+	case categories.Type(b.Category) != CategoryTypeBill:
+		return &ValidationError{Field: "category", Message: fmt.Sprintf("%q is not a bill category", b.Category)}
+	case b.Amount.Sign() <= 0:
+		return &ValidationError{Field: "amount", Message: "must be positive"}
+	case b.Amount.Cents >= 1e10:
+		return &ValidationError{Field: "amount", Message: "is out of range"}
+	case b.AmountType != BillAmountFixed && b.AmountType != BillAmountVariable:
+		return &ValidationError{Field: "amount_type", Message: "must be fixed or variable"}
+	case b.Frequency == "":
+		return &ValidationError{Field: "frequency", Message: "is required"}
+	case !known:
+		return &ValidationError{Field: "frequency", Message: fmt.Sprintf("unknown frequency %q", b.Frequency)}
+	case months == 0 && (b.DueDay < 1 || b.DueDay > 7):
+		return &ValidationError{Field: "due_day", Message: "must be a day of the week from 1 (Monday) to 7 (Sunday)"}
+	case b.DueDay < 1 || b.DueDay > 31:
+		return &ValidationError{Field: "due_day", Message: "must be between 1 and 31"}
+	case b.StartDate.Year() < 1900 || b.StartDate.Year() > 9999:
+		return &ValidationError{Field: "start_date", Message: "is out of range"}
+	}
+	return nil
+}
+
+// NextDue returns the first date on or after from that the bill is due
+func (b Bill) NextDue(from time.Time) time.Time {
+	from = time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	for n := b.firstIndex(from); ; n++ {
+		if due := b.due(n); !due.Before(from) && !due.Before(b.StartDate) {
+			return due
+		}
+	}
+}
+
+// DueDates returns the dates from from through to that the bill is due
+func (b Bill) DueDates(from, to time.Time) []time.Time {
+	from = time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	var dates []time.Time
+	for n := b.firstIndex(from); ; n++ {
+		due := b.due(n)
+		if due.After(to) {
+			return dates
+		}
+		if !due.Before(from) && !due.Before(b.StartDate) {
+			dates = append(dates, due)
+		}
+	}
+}
+
+// due returns the n-th due date of the bill's cycle, counted from the
+// period of its start date. The first of them may fall before the start.
+func (b Bill) due(n int) time.Time {
+	start := b.StartDate
+	months := BillFrequencies[b.Frequency]
+	if months == 0 {
+		step := 7
+		if b.Frequency == BillBiweekly {
+			step = 14
+		}
+		first := start.AddDate(0, 0, (b.DueDay-isoWeekday(start)+7)%7)
+		return first.AddDate(0, 0, n*step)
+	}
+
+	month := time.Date(start.Year(), start.Month()+time.Month(n*months), 1, 0, 0, 0, 0, time.UTC)
+	day := b.DueDay
+	if last := month.AddDate(0, 1, -1).Day(); day > last {
+		day = last
+	}
+	return month.AddDate(0, 0, day-1)
+}
+
+// firstIndex returns a cycle index whose due date is no later than the first
+// one on or after from, so that searches need not start at the beginning
+func (b Bill) firstIndex(from time.Time) int {
+	start := b.StartDate
+	if !from.After(start) {
+		return 0
+	}
+	var n int
+	if months := BillFrequencies[b.Frequency]; months > 0 {
+		elapsed := (from.Year()-start.Year())*12 + int(from.Month()-start.Month())
+		n = elapsed/months - 1
+	} else {
+		step := 7
+		if b.Frequency == BillBiweekly {
+			step = 14
+		}
+		n = int(from.Sub(start).Hours()/24)/step - 1
+	}
+	if n < 0 {
+		return 0
+	}
+	return n
+}
+
+// isoWeekday returns the day of the week of t from 1 (Monday) to 7 (Sunday)
+func isoWeekday(t time.Time) int {
+	if t.Weekday() == time.Sunday {
+		return 7
+	}
+	return int(t.Weekday())
+}
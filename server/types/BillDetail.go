@@ -4,9 +4,10 @@ package types
 type BillDetail struct {
 	Name              string  `json:"name"`
 	Category          string  `json:"category"`
-	Amount            float64 `json:"amount"`
-	PaycheckPercentage float64 `json:"paycheckPercentage"`
+	Amount            Money   `json:"amount"`
+	PaycheckPercentage float64 `json:"paycheckPercentage"` // share of the paycheck that covers it
 	LastPaidDate      string  `json:"lastPaidDate"`
+	DueDate           string  `json:"dueDate,omitempty"`
 	Merchant          string  `json:"merchant"`
 	Location          string  `json:"location"`
 } 
\ No newline at end of file
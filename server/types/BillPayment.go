@@ -2,6 +2,6 @@ package types
 
 type BillPayment struct { 
 	Category    string  `json:"category"`
-	TotalSpent  string `json:"totalSpent"`
+	TotalSpent  Money  `json:"totalSpent"`
 	Percentage  string `json:"percentage"`  
 }
\ No newline at end of file
@@ -0,0 +1,42 @@
+package types
+
+import "time"
+
+// Statuses of an expected bill payment
+const (
+	BillPaid          = "paid"           // paid on time and for the expected amount
+	BillLate          = "late"           // paid after the grace period
+	BillMissed        = "missed"         // not paid, and too long ago to still be paid
+	BillAmountChanged = "amount_changed" // paid on time, but for a different amount
+	BillPending       = "pending"        // not paid yet, but there is still time
+)
+
+// BillPaymentStatus is how one expected payment of a bill went
+type BillPaymentStatus struct {
+	DueDate        time.Time  `json:"due_date"`
+	ExpectedAmount Money      `json:"expected_amount"`
+	Status         string     `json:"status"`
+	TransactionID  string     `json:"transaction_id,omitempty"` // the payment, when one was found
+	PaidDate       *time.Time `json:"paid_date,omitempty"`
+	PaidAmount     *Money     `json:"paid_amount,omitempty"`
+	DaysLate       int        `json:"days_late,omitempty"` // days after the due date it was paid
+}
+
+// BillStatusHistory is the payment history of one bill, oldest first
+type BillStatusHistory struct {
+	Merchant string              `json:"merchant"`
+	Category string              `json:"category"`
+	Source   string              `json:"source"`            // BillDeclared or BillInferred
+	BillID   *int64              `json:"bill_id,omitempty"` // the declared Bill
+	Autopay  bool                `json:"autopay"`
+	Payments []BillPaymentStatus `json:"payments"`
+	Counts   map[string]int      `json:"counts"` // payments per status
+}
+
+// BillStatusReport is the payment history of an account's bills from From
+// through To
+type BillStatusReport struct {
+	From  string              `json:"from"` // YYYY-MM-DD
+	To    string              `json:"to"`
+	Bills []BillStatusHistory `json:"bills"`
+}
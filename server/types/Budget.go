@@ -0,0 +1,163 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Budget is a monthly spending limit for one category of an account, as per
+// the budgets table in migrations/sql. Limits are in the account's currency.
+//
+// A budget also works as an envelope: income can be allocated to it on top
+// of its limit, and with Rollover set whatever is left (or overspent) at the
+// end of a month carries into the next one.
+type Budget struct {
+	ID           int64     `json:"id"`
+	AccountID    string    `json:"account_id"`
+	Category     string    `json:"category"`      // VARCHAR(50), an expense or bill category of the account
+	MonthlyLimit Money     `json:"monthly_limit"` // DECIMAL(10, 2); zero for envelopes funded only by allocations
+	Rollover     bool      `json:"rollover"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// Validate normalizes and checks the fields a client can set against the
+// account's categories
+func (b *Budget) Validate(categories Categories) error {
+	b.Category = strings.TrimSpace(b.Category)
+
+	switch {
+	case b.Category == "":
+		return &ValidationError{Field: "category", Message: "is required"}
+	case !categories.Has(b.Category):
+		return &ValidationError{Field: "category", Message: fmt.Sprintf("unknown category %q", b.Category)}
+	case categories.Type(b.Category) == CategoryTypeIncome:
+		return &ValidationError{Field: "category", Message: "income cannot be budgeted"}
+	case categories.Type(b.Category) == CategoryTypeTransfer:
+		return &ValidationError{Field: "category", Message: "transfers cannot be budgeted"}
+	case b.MonthlyLimit.Sign() < 0:
+		return &ValidationError{Field: "monthly_limit", Message: "cannot be negative"}
+	case b.MonthlyLimit.Cents >= 1e10:
+		return &ValidationError{Field: "monthly_limit", Message: "is out of range"}
+	}
+	return nil
+}
+
+// BudgetStatus reports an account's spending against its budgets for one month
+type BudgetStatus struct {
+	AccountID  string                 `json:"account_id"`
+	Year       int                    `json:"year"`
+	Month      int                    `json:"month"`
+	Currency   string                 `json:"currency"`
+	Categories []BudgetCategoryStatus `json:"categories"`
+
+	// Totals over the budgeted categories
+	TotalLimit     Money `json:"total_limit"`
+	TotalAvailable Money `json:"total_available"`
+	TotalSpent     Money `json:"total_spent"`
+	TotalRemaining Money `json:"total_remaining"`
+
+	// Unbudgeted is the month's spending in categories without a budget
+	Unbudgeted Money `json:"unbudgeted"`
+}
+
+// BudgetCategoryStatus is one category's spending against what it has
+// available for the month: its limit, plus allocated income and the balance
+// carried over from the previous month
+type BudgetCategoryStatus struct {
+	BudgetID    int64   `json:"budget_id"`
+	Category    string  `json:"category"`
+	Limit       Money   `json:"limit"`
+	CarriedOver Money   `json:"carried_over"` // zero unless the budget rolls over
+	Allocated   Money   `json:"allocated"`
+	Available   Money   `json:"available"` // limit + carried over + allocated
+	Spent       Money   `json:"spent"`
+	Remaining   Money   `json:"remaining"` // negative once over budget
+	PercentUsed float64 `json:"percent_used"`
+
+	// ProjectedSpend extrapolates the month's spending so far to the end of
+	// the month. It equals Spent for past months.
+	ProjectedSpend Money `json:"projected_spend"`
+	OverBudget     bool  `json:"over_budget"`
+	ProjectedOver  bool  `json:"projected_over"`
+}
+
+// BudgetAllocation assigns money to a budget for one month, as per the
+// budget_allocations table in migrations/sql. An allocation taken from an
+// income deposit names its TransactionID and falls in the deposit's month; a
+// negative allocation moves money out of the envelope.
+type BudgetAllocation struct {
+	ID            int64     `json:"id"`
+	BudgetID      int64     `json:"budget_id"`
+	Year          int       `json:"year"`
+	Month         int       `json:"month"`
+	Amount        Money     `json:"amount"` // DECIMAL(10, 2), in the account's currency
+	TransactionID string    `json:"transaction_id,omitempty"`
+	Note          string    `json:"note,omitempty"` // VARCHAR(255)
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// Validate normalizes and checks the fields a client can set
+func (a *BudgetAllocation) Validate() error {
+	a.TransactionID = strings.TrimSpace(a.TransactionID)
+	a.Note = strings.TrimSpace(a.Note)
+
+	switch {
+	case a.BudgetID <= 0:
+		return &ValidationError{Field: "budget_id", Message: "is required"}
+	case a.Amount.IsZero():
+		return &ValidationError{Field: "amount", Message: "cannot be zero"}
+	case a.Amount.Abs().Cents >= 1e10:
+		return &ValidationError{Field: "amount", Message: "is out of range"}
+	case a.TransactionID != "" && a.Amount.Sign() < 0:
+		return &ValidationError{Field: "amount", Message: "must be positive when allocating a deposit"}
+	case a.TransactionID == "" && (a.Month < 1 || a.Month > 12):
+		return &ValidationError{Field: "month", Message: "must be between 1 and 12"}
+	case a.TransactionID == "" && (a.Year < 1900 || a.Year > 9999):
+		return &ValidationError{Field: "year", Message: "is out of range"}
+	case len(a.Note) > 255:
+		return &ValidationError{Field: "note", Message: "must be at most 255 characters"}
+	}
+	return nil
+}
+
+// IncomeDeposit is an income transaction with how much of it has been
+// allocated to budgets
+type IncomeDeposit struct {
+	Transaction Transaction `json:"transaction"`
+	Allocated   Money       `json:"allocated"`
+	Unallocated Money       `json:"unallocated"`
+}
+
+// EnvelopeReport shows the running balance of each of an account's budgets
+// month by month
+type EnvelopeReport struct {
+	AccountID string     `json:"account_id"`
+	Currency  string     `json:"currency"`
+	From      string     `json:"from"` // YYYY-MM
+	To        string     `json:"to"`   // YYYY-MM
+	Envelopes []Envelope `json:"envelopes"`
+}
+
+// Envelope is one budget's balance history
+type Envelope struct {
+	BudgetID     int64           `json:"budget_id"`
+	Category     string          `json:"category"`
+	MonthlyLimit Money           `json:"monthly_limit"`
+	Rollover     bool            `json:"rollover"`
+	Months       []EnvelopeMonth `json:"months"`
+}
+
+// EnvelopeMonth is an envelope's activity in one month. Balance is
+// CarriedIn + Funded + Allocated - Spent, and is carried into the next month
+// when the budget rolls over.
+type EnvelopeMonth struct {
+	Year      int   `json:"year"`
+	Month     int   `json:"month"`
+	CarriedIn Money `json:"carried_in"`
+	Funded    Money `json:"funded"` // the monthly limit
+	Allocated Money `json:"allocated"`
+	Spent     Money `json:"spent"`
+	Balance   Money `json:"balance"`
+}
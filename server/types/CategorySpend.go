@@ -2,6 +2,7 @@ package types
 
 type CategorySpend struct {
 	Category    string  `json:"category"`
-	TotalSpent  string `json:"totalSpent"`
+	TotalSpent  Money  `json:"totalSpent"`
 	Percentage  string `json:"percentage"`
+	Original    Amounts `json:"original,omitempty"`
 } 
\ No newline at end of file
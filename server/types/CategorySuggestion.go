@@ -0,0 +1,18 @@
+package types
+
+// CategorySuggestion is the category the classifier expects a transaction to
+// have, learned from how the account's other transactions are categorized.
+// Category is empty when the account has nothing to learn from yet.
+type CategorySuggestion struct {
+	TransactionID string          `json:"transaction_id,omitempty"`
+	Category      string          `json:"category"`
+	Confidence    float64         `json:"confidence"`   // probability of Category, 0-1
+	Alternatives  []CategoryScore `json:"alternatives"` // the runners-up, most likely first
+	TrainedOn     int             `json:"trained_on"`   // transactions the suggestion was learned from
+}
+
+// CategoryScore is the probability of one category in a suggestion
+type CategoryScore struct {
+	Category   string  `json:"category"`
+	Confidence float64 `json:"confidence"`
+}
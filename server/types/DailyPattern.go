@@ -3,6 +3,7 @@ package types
 
 type DailyPattern struct {
 	DayOfWeek string
-	AverageAmount float64
+	AverageAmount Money
+	Currency string
 }
 
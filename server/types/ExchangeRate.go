@@ -0,0 +1,26 @@
+package types
+
+import "time"
+
+// ExchangeRate is a row of the fx_rates table: one unit of Base is worth
+// Rate units of Quote on Date
+type ExchangeRate struct {
+	Base   string    `json:"base"`   // VARCHAR(3)
+	Quote  string    `json:"quote"`  // VARCHAR(3)
+	Date   time.Time `json:"date"`   // DATE
+	Rate   float64   `json:"rate"`   // DECIMAL(20, 10)
+	Source string    `json:"source"` // VARCHAR(100), file name or API the rate was loaded from
+}
+
+// IsCurrencyCode reports whether code looks like an ISO 4217 currency code
+func IsCurrencyCode(code string) bool {
+	if len(code) != 3 {
+		return false
+	}
+	for _, r := range code {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,104 @@
+package types
+
+import (
+	"strings"
+	"time"
+)
+
+// Goal statuses reported with a goal's progress
+const (
+	GoalCompleted = "completed" // the target amount has been saved
+	GoalOnTrack   = "on_track"  // the savings trend reaches the target by its date
+	GoalBehind    = "behind"    // the savings trend reaches the target after its date
+	GoalStalled   = "stalled"   // the account is not saving, so the target is never reached
+)
+
+// Goal is a savings target of an account, as per the goals table in
+// migrations/sql. Amounts are in the account's currency.
+type Goal struct {
+	ID           int64      `json:"id"`
+	AccountID    string     `json:"account_id"`
+	Name         string     `json:"name"`                  // VARCHAR(100)
+	TargetAmount Money      `json:"target_amount"`         // DECIMAL(10, 2), positive
+	TargetDate   *time.Time `json:"target_date,omitempty"` // DATE, optional
+	Saved        Money      `json:"saved"`                 // sum of the contributions
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// Validate normalizes and checks the fields a client can set
+func (g *Goal) Validate() error {
+	g.Name = strings.TrimSpace(g.Name)
+
+	switch {
+	case g.Name == "":
+		return &ValidationError{Field: "name", Message: "is required"}
+	case len(g.Name) > 100:
+		return &ValidationError{Field: "name", Message: "must be at most 100 characters"}
+	case g.TargetAmount.Sign() <= 0:
+		return &ValidationError{Field: "target_amount", Message: "must be positive"}
+	case g.TargetAmount.Cents >= 1e10:
+		return &ValidationError{Field: "target_amount", Message: "is out of range"}
+	case g.TargetDate != nil && (g.TargetDate.Year() < 1900 || g.TargetDate.Year() > 9999):
+		return &ValidationError{Field: "target_date", Message: "is out of range"}
+	}
+	return nil
+}
+
+// GoalContribution is money put towards a goal, as per the
+// goal_contributions table in migrations/sql. A contribution linked to a
+// transaction takes its date, and its amount unless one is given; a
+// negative amount is a withdrawal.
+type GoalContribution struct {
+	ID            int64     `json:"id"`
+	GoalID        int64     `json:"goal_id"`
+	Date          time.Time `json:"date"`   // DATE
+	Amount        Money     `json:"amount"` // DECIMAL(10, 2), in the account's currency
+	TransactionID string    `json:"transaction_id,omitempty"`
+	Note          string    `json:"note,omitempty"` // VARCHAR(255)
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// Validate normalizes and checks the fields a client can set
+func (c *GoalContribution) Validate() error {
+	c.TransactionID = strings.TrimSpace(c.TransactionID)
+	c.Note = strings.TrimSpace(c.Note)
+
+	switch {
+	case c.TransactionID == "" && c.Date.IsZero():
+		return &ValidationError{Field: "date", Message: "is required"}
+	case c.TransactionID == "" && c.Amount.IsZero():
+		return &ValidationError{Field: "amount", Message: "cannot be zero"}
+	case c.Amount.Abs().Cents >= 1e10:
+		return &ValidationError{Field: "amount", Message: "is out of range"}
+	case c.Date.After(time.Now().Add(24 * time.Hour)):
+		return &ValidationError{Field: "date", Message: "cannot be in the future"}
+	case len(c.Note) > 255:
+		return &ValidationError{Field: "note", Message: "must be at most 255 characters"}
+	}
+	return nil
+}
+
+// GoalProgress reports how far a goal is and when it will be reached
+type GoalProgress struct {
+	Goal            Goal    `json:"goal"`
+	Currency        string  `json:"currency"`
+	Saved           Money   `json:"saved"`
+	Remaining       Money   `json:"remaining"`
+	PercentComplete float64 `json:"percent_complete"`
+
+	// MonthsLeft and RequiredMonthly are set for goals with a target date:
+	// the whole months until it and what must be saved in each to get there
+	MonthsLeft      *int   `json:"months_left,omitempty"`
+	RequiredMonthly *Money `json:"required_monthly,omitempty"`
+
+	// MonthlyNetSavings is the account's average income minus spending over
+	// the last TrendMonths complete months with activity
+	MonthlyNetSavings Money `json:"monthly_net_savings"`
+	TrendMonths       int   `json:"trend_months"`
+
+	// ProjectedCompletion is when the goal is reached if the account keeps
+	// saving MonthlyNetSavings; it is not set for stalled goals
+	ProjectedCompletion *time.Time `json:"projected_completion,omitempty"`
+	Status              string     `json:"status"`
+}
@@ -0,0 +1,142 @@
+package types
+
+import "time"
+
+// Amount sign conventions for single-column CSV amounts
+const (
+	// AmountSignDebitNegative means debits are negative, matching the transactions table
+	AmountSignDebitNegative = "debit_negative"
+
+	// AmountSignDebitPositive means debits are positive and credits negative
+	AmountSignDebitPositive = "debit_positive"
+)
+
+// ImportProfile describes how the columns of a bank's CSV export map onto a
+// transaction. Column references are header names when HasHeader is set and
+// zero-based column indexes otherwise.
+type ImportProfile struct {
+	ID               int    `json:"id"`
+	Name             string `json:"name"`
+	Delimiter        string `json:"delimiter"`
+	HasHeader        bool   `json:"has_header"`
+	SkipRows         int    `json:"skip_rows"`
+	DateColumn       string `json:"date_column"`
+	DateFormat       string `json:"date_format"` // Go reference layout, e.g. "01/02/2006"
+	AmountColumn     string `json:"amount_column"`
+	DebitColumn      string `json:"debit_column"`
+	CreditColumn     string `json:"credit_column"`
+	AmountSign       string `json:"amount_sign"`
+	DecimalSeparator string `json:"decimal_separator"` // "." or ","
+	MerchantColumn   string `json:"merchant_column"`
+	CategoryColumn   string `json:"category_column"`
+	LocationColumn   string `json:"location_column"`
+	DefaultCategory  string `json:"default_category"`
+
+	// Shared profiles are usable by every login but cannot be changed
+	// through the API; the others belong to the login that created them
+	Shared bool `json:"shared"`
+}
+
+// ImportRowError reports why a single statement row was not imported
+type ImportRowError struct {
+	Row   int    `json:"row"`
+	Error string `json:"error"`
+}
+
+// ImportResult summarizes a statement import
+type ImportResult struct {
+	AccountID      string           `json:"account_id"`
+	Format         string           `json:"format"`
+	DryRun         bool             `json:"dry_run"`
+	Rows           int              `json:"rows"`
+	Imported       int              `json:"imported"`
+	Skipped        int              `json:"skipped"`
+	Flagged        int              `json:"flagged"`
+	Failed         int              `json:"failed"`
+	TransactionIDs []string         `json:"transaction_ids"`
+	DuplicateIDs   []int            `json:"duplicate_ids"`
+	Errors         []ImportRowError `json:"errors"`
+	Balance        *UserBalance     `json:"balance,omitempty"`
+}
+
+// Review states of a flagged import duplicate
+const (
+	DuplicatePending   = "pending"
+	DuplicateMerged    = "merged"
+	DuplicateInserted  = "inserted"
+	DuplicateDismissed = "dismissed"
+)
+
+// ImportDuplicate is an imported row that was held back because it likely
+// duplicates a transaction already on the account
+type ImportDuplicate struct {
+	ID                    int          `json:"id"`
+	AccountID             string       `json:"account_id"`
+	Candidate             Transaction  `json:"candidate"`
+	ExistingTransactionID string       `json:"existing_transaction_id"`
+	Existing              *Transaction `json:"existing,omitempty"`
+	Fingerprint           string       `json:"fingerprint"`
+	Score                 float64      `json:"score"`
+	Reason                string       `json:"reason"`
+	Status                string       `json:"status"`
+	CreatedAt             time.Time    `json:"created_at"`
+	ResolvedAt            *time.Time   `json:"resolved_at,omitempty"`
+	ResolvedTransactionID string       `json:"resolved_transaction_id,omitempty"`
+}
+
+// ApplyDefaults fills in the optional profile settings
+func (p *ImportProfile) ApplyDefaults() {
+	if p.Delimiter == "" {
+		p.Delimiter = ","
+	}
+	if p.AmountSign == "" {
+		p.AmountSign = AmountSignDebitNegative
+	}
+	if p.DecimalSeparator == "" {
+		p.DecimalSeparator = "."
+	}
+}
+
+// Validate checks that the profile describes a usable column mapping
+func (p *ImportProfile) Validate() error {
+	if p.Name == "" || len(p.Name) > 50 {
+		return &ValidationError{Field: "name", Message: "is required and must be at most 50 characters"}
+	}
+	if len([]rune(p.Delimiter)) != 1 {
+		return &ValidationError{Field: "delimiter", Message: "must be a single character"}
+	}
+	if p.SkipRows < 0 {
+		return &ValidationError{Field: "skip_rows", Message: "cannot be negative"}
+	}
+	if p.DateColumn == "" {
+		return &ValidationError{Field: "date_column", Message: "is required"}
+	}
+	if p.DateFormat == "" {
+		return &ValidationError{Field: "date_format", Message: "is required"}
+	}
+	if p.MerchantColumn == "" {
+		return &ValidationError{Field: "merchant_column", Message: "is required"}
+	}
+	if p.AmountColumn == "" && (p.DebitColumn == "" || p.CreditColumn == "") {
+		return &ValidationError{Field: "amount_column", Message: "either amount_column or both debit_column and credit_column are required"}
+	}
+	if p.AmountColumn != "" && (p.DebitColumn != "" || p.CreditColumn != "") {
+		return &ValidationError{Field: "amount_column", Message: "cannot be combined with debit_column or credit_column"}
+	}
+	if p.AmountSign != AmountSignDebitNegative && p.AmountSign != AmountSignDebitPositive {
+		return &ValidationError{Field: "amount_sign", Message: "must be debit_negative or debit_positive"}
+	}
+	if p.DecimalSeparator != "." && p.DecimalSeparator != "," {
+		return &ValidationError{Field: "decimal_separator", Message: `must be "." or ","`}
+	}
+	if p.DefaultCategory != "" && !BuiltInCategories.Has(p.DefaultCategory) {
+		return &ValidationError{Field: "default_category", Message: "unknown category " + p.DefaultCategory}
+	}
+	return nil
+}
+
+// AddError records a failed row
+func (r *ImportResult) AddError(row int, err error) {
+	r.Failed++
+	r.Errors = append(r.Errors, ImportRowError{Row: row, Error: err.Error()})
+}
@@ -0,0 +1,36 @@
+package types
+
+import "time"
+
+// Login is a person who signs in to the API, as per the logins table in
+// migrations/sql. The password hash never leaves the server.
+type Login struct {
+	ID           int64     `json:"id"`
+	Username     string    `json:"username"`
+	PasswordHash string    `json:"-"`
+	CreatedAt    time.Time `json:"created_at"`
+	AccountIDs   []string  `json:"account_ids"`
+}
+
+// Credentials is the body of the register and login requests
+type Credentials struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// AccountClaim links an existing account to the caller's login. The claim
+// code, issued for the account by an operator and never returned by the API,
+// proves the caller holds the account.
+type AccountClaim struct {
+	AccountID string `json:"account_id"`
+	ClaimCode string `json:"claim_code"`
+}
+
+// AuthToken is returned when a login signs in. The token is sent back as
+// "Authorization: Bearer <token>" on every other request.
+type AuthToken struct {
+	Token     string    `json:"token"`
+	TokenType string    `json:"token_type"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Login     *Login    `json:"login"`
+}
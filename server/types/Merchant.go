@@ -0,0 +1,71 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Merchant is a canonical merchant of an account, as per the merchants
+// table in migrations/sql. Transactions whose merchant description matches
+// one of its aliases are linked to it and reported under its name.
+type Merchant struct {
+	ID              int64     `json:"id"`
+	AccountID       string    `json:"account_id"`
+	Name            string    `json:"name"`                       // VARCHAR(50)
+	DefaultCategory string    `json:"default_category,omitempty"` // given to new transactions filed under Other
+	Aliases         []string  `json:"aliases"`                    // normalized merchant descriptions
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// Validate normalizes and checks the fields a client can set against the
+// account's categories. Aliases are normalized by the merchants service,
+// which owns the normalizer.
+func (m *Merchant) Validate(categories Categories) error {
+	m.Name = strings.TrimSpace(m.Name)
+	m.DefaultCategory = strings.TrimSpace(m.DefaultCategory)
+
+	switch {
+	case m.Name == "":
+		return &ValidationError{Field: "name", Message: "is required"}
+	case len(m.Name) > 50:
+		return &ValidationError{Field: "name", Message: "must be at most 50 characters"}
+	case m.DefaultCategory != "" && !categories.Has(m.DefaultCategory):
+		return &ValidationError{Field: "default_category", Message: fmt.Sprintf("unknown category %q", m.DefaultCategory)}
+	}
+	for _, alias := range m.Aliases {
+		if len(alias) > 100 {
+			return &ValidationError{Field: "aliases", Message: "aliases must be at most 100 characters"}
+		}
+	}
+	return nil
+}
+
+// MerchantSpendReport reports an account's spending per merchant, month by
+// month, in the reporting currency
+type MerchantSpendReport struct {
+	AccountID string          `json:"account_id"`
+	Currency  string          `json:"currency"`
+	From      string          `json:"from"` // YYYY-MM
+	To        string          `json:"to"`   // YYYY-MM
+	Merchants []MerchantSpend `json:"merchants"`
+}
+
+// MerchantSpend is the spending at one merchant. Transactions not linked to
+// a canonical merchant are grouped by their own merchant description and
+// have no MerchantID.
+type MerchantSpend struct {
+	MerchantID *int64          `json:"merchant_id,omitempty"`
+	Merchant   string          `json:"merchant"`
+	Total      Money           `json:"total"`
+	Count      int             `json:"count"`
+	Months     []MerchantMonth `json:"months"`
+}
+
+// MerchantMonth is one month of spending at a merchant
+type MerchantMonth struct {
+	Month string `json:"month"` // YYYY-MM
+	Total Money  `json:"total"`
+	Count int    `json:"count"`
+}
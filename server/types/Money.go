@@ -0,0 +1,273 @@
+package types
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Money is an exact monetary amount held as integer minor units (cents) plus
+// an ISO 4217 currency code. The scale is fixed at two decimal places to
+// match the DECIMAL(10, 2) amount columns. An empty currency means the
+// account's own currency.
+//
+// Money scans from and writes to Postgres DECIMAL columns without going
+// through float64, and is encoded in JSON as a plain number (e.g. -12.50) so
+// existing clients keep working; the currency is reported by the enclosing
+// object where it matters.
+type Money struct {
+	Cents    int64
+	Currency string
+}
+
+// NewMoney returns an amount of the given number of cents
+func NewMoney(cents int64, currency string) Money {
+	return Money{Cents: cents, Currency: currency}
+}
+
+// ParseMoney parses a decimal amount such as "-1234.56" exactly. Amounts
+// with more than two decimal places are rounded half away from zero.
+func ParseMoney(value string, currency string) (Money, error) {
+	cents, err := parseCents(value)
+	if err != nil {
+		return Money{}, err
+	}
+	return Money{Cents: cents, Currency: currency}, nil
+}
+
+// MoneyFromFloat converts a float amount, rounding to the nearest cent. It is
+// meant for values that are inherently approximate, such as averages and
+// exchange-rate conversions, not for amounts read from storage.
+func MoneyFromFloat(amount float64, currency string) Money {
+	return Money{Cents: int64(math.Round(amount * 100)), Currency: currency}
+}
+
+// Add returns m + o. Both amounts are expected to be in the same currency;
+// an empty currency takes the other operand's.
+func (m Money) Add(o Money) Money {
+	return Money{Cents: m.Cents + o.Cents, Currency: m.currencyWith(o)}
+}
+
+// Sub returns m - o
+func (m Money) Sub(o Money) Money {
+	return Money{Cents: m.Cents - o.Cents, Currency: m.currencyWith(o)}
+}
+
+// Neg returns -m
+func (m Money) Neg() Money {
+	return Money{Cents: -m.Cents, Currency: m.Currency}
+}
+
+// Abs returns the absolute value of m
+func (m Money) Abs() Money {
+	if m.Cents < 0 {
+		return m.Neg()
+	}
+	return m
+}
+
+// Div divides m into n equal parts, rounding half away from zero. It is
+// used for averages; Div by zero returns zero.
+func (m Money) Div(n int) Money {
+	if n == 0 {
+		return Money{Currency: m.Currency}
+	}
+	q, r := m.Cents/int64(n), m.Cents%int64(n)
+	if 2*abs64(r) >= abs64(int64(n)) {
+		if (m.Cents < 0) != (n < 0) {
+			q--
+		} else {
+			q++
+		}
+	}
+	return Money{Cents: q, Currency: m.Currency}
+}
+
+// Ratio returns m / o as a float, for percentages and shares; it is 0 when o is zero
+func (m Money) Ratio(o Money) float64 {
+	if o.Cents == 0 {
+		return 0
+	}
+	return float64(m.Cents) / float64(o.Cents)
+}
+
+// Cmp compares two amounts and returns -1, 0 or +1
+func (m Money) Cmp(o Money) int {
+	switch {
+	case m.Cents < o.Cents:
+		return -1
+	case m.Cents > o.Cents:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// Sign returns -1, 0 or +1 depending on the sign of m
+func (m Money) Sign() int {
+	return m.Cmp(Money{})
+}
+
+// IsZero reports whether the amount is zero
+func (m Money) IsZero() bool {
+	return m.Cents == 0
+}
+
+// Float64 returns the amount in major units. Use it only for display-style
+// calculations; totals should be computed with Add.
+func (m Money) Float64() float64 {
+	return float64(m.Cents) / 100
+}
+
+// String formats the amount with exactly two decimal places, e.g. "-12.50"
+func (m Money) String() string {
+	sign := ""
+	if m.Cents < 0 {
+		sign = "-"
+	}
+	c := abs64(m.Cents)
+	return fmt.Sprintf("%s%d.%02d", sign, c/100, c%100)
+}
+
+func (m Money) currencyWith(o Money) string {
+	if m.Currency == "" {
+		return o.Currency
+	}
+	return m.Currency
+}
+
+// MarshalJSON encodes the amount as a JSON number with two decimal places
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalJSON accepts a JSON number or a numeric string. The decimal text
+// is parsed directly so that no precision is lost through float64.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	s := strings.TrimSpace(string(data))
+	if s == "null" {
+		return nil
+	}
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		s = unquoted
+	}
+	cents, err := parseCents(s)
+	if err != nil {
+		return err
+	}
+	m.Cents = cents
+	return nil
+}
+
+// Scan implements sql.Scanner for DECIMAL and integer columns. The
+// currency is left unchanged as amount columns do not carry one.
+func (m *Money) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		m.Cents = 0
+		return nil
+	case []byte:
+		cents, err := parseCents(string(v))
+		if err != nil {
+			return err
+		}
+		m.Cents = cents
+		return nil
+	case string:
+		cents, err := parseCents(v)
+		if err != nil {
+			return err
+		}
+		m.Cents = cents
+		return nil
+	case int64:
+		m.Cents = v * 100
+		return nil
+	case float64:
+		m.Cents = int64(math.Round(v * 100))
+		return nil
+	default:
+		return fmt.Errorf("cannot scan %T into Money", src)
+	}
+}
+
+// Value implements driver.Valuer, writing the amount as a decimal string
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
+
+// parseCents converts decimal text to cents, rounding any digits past the
+// second decimal place half away from zero
+func parseCents(value string) (int64, error) {
+	s := strings.TrimSpace(value)
+	invalid := fmt.Errorf("invalid amount %q", value)
+
+	negative := false
+	switch {
+	case strings.HasPrefix(s, "-"):
+		negative = true
+		s = s[1:]
+	case strings.HasPrefix(s, "+"):
+		s = s[1:]
+	}
+
+	whole, frac, _ := strings.Cut(s, ".")
+	if whole == "" && frac == "" {
+		return 0, invalid
+	}
+	if whole == "" {
+		whole = "0"
+	}
+	if strings.ContainsAny(frac, "eE") || strings.ContainsAny(whole, "eE") {
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return 0, invalid
+		}
+		return int64(math.Round(f * 100)), nil
+	}
+
+	if !isDigits(whole) || !isDigits(frac) {
+		return 0, invalid
+	}
+	units, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil || units > math.MaxInt64/100-1 {
+		return 0, invalid
+	}
+
+	padded := frac + "00"
+	cents := units*100 + int64(padded[0]-'0')*10 + int64(padded[1]-'0')
+	if len(frac) > 2 && frac[2] >= '5' {
+		cents++
+	}
+	if negative {
+		cents = -cents
+	}
+	return cents, nil
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func abs64(v int64) int64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// Amounts holds amounts in several currencies keyed by ISO code, such as a
+// total before conversion. It is encoded in JSON as {"EUR": 12.50, "USD": 3.00}.
+type Amounts map[string]Money
+
+// Add adds m to the amount held for currency
+func (a Amounts) Add(currency string, m Money) {
+	a[currency] = a[currency].Add(Money{Cents: m.Cents, Currency: currency})
+}
@@ -0,0 +1,105 @@
+package types
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseCents(t *testing.T) {
+	tests := []struct {
+		value string
+		want  int64
+		ok    bool
+	}{
+		{"12.50", 1250, true},
+		{"-12.50", -1250, true},
+		{"+3", 300, true},
+		{" 7.1 ", 710, true},
+		{".5", 50, true},
+		{"-.5", -50, true},
+		{"5.", 500, true},
+		{"0", 0, true},
+
+		// A third decimal place rounds half away from zero
+		{"1.005", 101, true},
+		{"1.004", 100, true},
+		{"-1.005", -101, true},
+		{"-1.004", -100, true},
+		{"0.995", 100, true},
+		{"2.675", 268, true}, // 2.675 is 2.67499... as a float64
+		{"1.00999", 101, true},
+
+		// Exponents go through float64
+		{"1e2", 10000, true},
+		{"1.5E1", 1500, true},
+
+		{"", 0, false},
+		{".", 0, false},
+		{"-", 0, false},
+		{"abc", 0, false},
+		{"1,000", 0, false},
+		{"1.2.3", 0, false},
+		{"1.-2", 0, false},
+		{"92233720368547758", 0, false},
+	}
+	for _, tt := range tests {
+		got, err := parseCents(tt.value)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("parseCents(%q) = %d, %v, want %d, ok %v", tt.value, got, err, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestMoneyDiv(t *testing.T) {
+	tests := []struct {
+		cents int64
+		n     int
+		want  int64
+	}{
+		{1000, 4, 250},
+		{1000, 3, 333},
+		{2000, 3, 667},
+		{1, 3, 0},
+		{2, 3, 1},
+
+		// Halves round away from zero
+		{11, 2, 6},
+		{-11, 2, -6},
+		{11, -2, -6},
+		{-11, -2, 6},
+		{1000, 8, 125},
+		{1001, 8, 125},
+		{1004, 8, 126},
+		{-1004, 8, -126},
+
+		{1000, 0, 0},
+		{0, 7, 0},
+	}
+	for _, tt := range tests {
+		got := NewMoney(tt.cents, "EUR").Div(tt.n)
+		if got.Cents != tt.want || got.Currency != "EUR" {
+			t.Errorf("%d.Div(%d) = %d %s, want %d EUR", tt.cents, tt.n, got.Cents, got.Currency, tt.want)
+		}
+	}
+}
+
+func TestMoneyJSON(t *testing.T) {
+	var amounts []Money
+	if err := json.Unmarshal([]byte(`[12.345, "-0.10", 3, null]`), &amounts); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	want := []int64{1235, -10, 300, 0}
+	for i, w := range want {
+		if amounts[i].Cents != w {
+			t.Errorf("amount %d = %d cents, want %d", i, amounts[i].Cents, w)
+		}
+	}
+
+	data, err := json.Marshal([]Money{NewMoney(-1250, "USD"), NewMoney(5, ""), NewMoney(0, "")})
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if string(data) != `[-12.50,0.05,0.00]` {
+		t.Errorf("Marshal() = %s, want [-12.50,0.05,0.00]", data)
+	}
+}
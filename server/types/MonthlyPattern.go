@@ -2,6 +2,7 @@ package types
 
 type MonthlyPattern struct {
 	Month string
-	AverageAmount float64
+	AverageAmount Money
+	Currency string
 }
 
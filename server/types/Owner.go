@@ -0,0 +1,33 @@
+package types
+
+import (
+	"strings"
+	"time"
+)
+
+// Owner is a person or household whose accounts are viewed together, as per
+// the owners table in migrations/sql. Each account links to at most one owner.
+type Owner struct {
+	ID         int64     `json:"id"`
+	LoginID    int64     `json:"-"`
+	Name       string    `json:"name"`        // VARCHAR(100)
+	Currency   string    `json:"currency"`    // reporting currency of the combined view; empty means the first account's
+	AccountIDs []string  `json:"account_ids"` // linked users rows
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Validate normalizes and checks the fields a client can set
+func (o *Owner) Validate() error {
+	o.Name = strings.TrimSpace(o.Name)
+	o.Currency = strings.ToUpper(strings.TrimSpace(o.Currency))
+
+	switch {
+	case o.Name == "":
+		return &ValidationError{Field: "name", Message: "is required"}
+	case len(o.Name) > 100:
+		return &ValidationError{Field: "name", Message: "must be at most 100 characters"}
+	case o.Currency != "" && !IsCurrencyCode(o.Currency):
+		return &ValidationError{Field: "currency", Message: "must be a three-letter ISO 4217 code"}
+	}
+	return nil
+}
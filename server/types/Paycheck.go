@@ -0,0 +1,39 @@
+package types
+
+import "time"
+
+// PaySchedule is a source of income paid on a regular cycle, detected from
+// income transactions
+type PaySchedule struct {
+	Source      string    `json:"source"`     // the payer
+	Frequency   string    `json:"frequency"`  // one of BillFrequencies
+	Amount      Money     `json:"amount"`     // the median paycheck
+	Confidence  float64   `json:"confidence"` // as in RecurringBill
+	LastPayDate time.Time `json:"last_pay_date"`
+	NextPayDate time.Time `json:"next_pay_date"`
+}
+
+// Paycheck is the income of one payday and the bills it must cover: those
+// due from that payday until the next. Its bills' amounts are in its
+// currency.
+type Paycheck struct {
+	Date                time.Time    `json:"date"`
+	Sources             []string     `json:"sources"`
+	Currency            string       `json:"currency"`
+	Amount              Money        `json:"amount"`
+	Received            bool         `json:"received"` // already paid rather than expected
+	Bills               []BillDetail `json:"bills"`
+	Committed           Money        `json:"committed"`
+	CommittedPercentage float64      `json:"committed_percentage"`
+	Remaining           Money        `json:"remaining"` // negative when the bills exceed the paycheck
+}
+
+// PaycheckPlan assigns the bills due from From through To to the paychecks
+// that must cover them
+type PaycheckPlan struct {
+	From       string        `json:"from"` // YYYY-MM-DD
+	To         string        `json:"to"`
+	Schedules  []PaySchedule `json:"schedules"`
+	Paychecks  []Paycheck    `json:"paychecks"`
+	Unassigned []BillDetail  `json:"unassigned"` // bills due before the first paycheck
+}
@@ -0,0 +1,136 @@
+package types
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Ways a rule's merchant pattern is compared with a transaction's merchant.
+// All of them ignore case.
+const (
+	MerchantContains = "contains"
+	MerchantExact    = "exact"
+	MerchantPrefix   = "prefix"
+	MerchantRegex    = "regex"
+)
+
+// Rule categorizes the transactions of an account, as per the rules table in
+// migrations/sql. A rule matches a transaction when every condition it sets
+// holds, and then assigns its category, cleaned merchant name and tags.
+type Rule struct {
+	ID        int64  `json:"id"`
+	AccountID string `json:"account_id"`
+	Name      string `json:"name"`     // VARCHAR(100)
+	Priority  int    `json:"priority"` // rules run lowest first
+	Disabled  bool   `json:"disabled"`
+
+	// Conditions. MinAmount and MaxAmount bound the size of the amount, so
+	// they are positive for spending and income alike. DayFrom and DayTo are
+	// days of the month; a range such as 28 to 3 wraps around the month end.
+	MerchantPattern string `json:"merchant_pattern,omitempty"`
+	MerchantMatch   string `json:"merchant_match,omitempty"`   // defaults to MerchantContains
+	LocationPattern string `json:"location_pattern,omitempty"` // substring of the location
+	MinAmount       *Money `json:"min_amount,omitempty"`
+	MaxAmount       *Money `json:"max_amount,omitempty"`
+	DayFrom         *int   `json:"day_from,omitempty"`
+	DayTo           *int   `json:"day_to,omitempty"`
+
+	// Actions
+	Category string   `json:"category,omitempty"` // one of the account's categories
+	Merchant string   `json:"merchant,omitempty"` // cleaned merchant name, VARCHAR(50)
+	Tags     []string `json:"tags,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Validate normalizes and checks the fields a client can set against the
+// account's categories
+func (r *Rule) Validate(categories Categories) error {
+	r.Name = strings.TrimSpace(r.Name)
+	r.MerchantPattern = strings.TrimSpace(r.MerchantPattern)
+	r.LocationPattern = strings.TrimSpace(r.LocationPattern)
+	r.Category = strings.TrimSpace(r.Category)
+	r.Merchant = strings.TrimSpace(r.Merchant)
+	if r.MerchantMatch == "" {
+		r.MerchantMatch = MerchantContains
+	}
+	if r.DayFrom == nil {
+		r.DayFrom = r.DayTo
+	}
+	if r.DayTo == nil {
+		r.DayTo = r.DayFrom
+	}
+
+	tags, err := NormalizeTags("tags", r.Tags)
+	if err != nil {
+		return err
+	}
+	r.Tags = tags
+
+	switch {
+	case r.Name == "":
+		return &ValidationError{Field: "name", Message: "is required"}
+	case len(r.Name) > 100:
+		return &ValidationError{Field: "name", Message: "must be at most 100 characters"}
+	case len(r.MerchantPattern) > 255:
+		return &ValidationError{Field: "merchant_pattern", Message: "must be at most 255 characters"}
+	case len(r.LocationPattern) > 100:
+		return &ValidationError{Field: "location_pattern", Message: "must be at most 100 characters"}
+	case r.MinAmount != nil && (r.MinAmount.Sign() < 0 || r.MinAmount.Cents >= 1e10):
+		return &ValidationError{Field: "min_amount", Message: "must be a positive amount"}
+	case r.MaxAmount != nil && (r.MaxAmount.Sign() < 0 || r.MaxAmount.Cents >= 1e10):
+		return &ValidationError{Field: "max_amount", Message: "must be a positive amount"}
+	case r.MinAmount != nil && r.MaxAmount != nil && r.MinAmount.Cents > r.MaxAmount.Cents:
+		return &ValidationError{Field: "max_amount", Message: "cannot be less than min_amount"}
+	case r.DayFrom != nil && (*r.DayFrom < 1 || *r.DayFrom > 31):
+		return &ValidationError{Field: "day_from", Message: "must be between 1 and 31"}
+	case r.DayTo != nil && (*r.DayTo < 1 || *r.DayTo > 31):
+		return &ValidationError{Field: "day_to", Message: "must be between 1 and 31"}
+	case r.MerchantPattern == "" && r.LocationPattern == "" && r.MinAmount == nil && r.MaxAmount == nil && r.DayFrom == nil:
+		return &ValidationError{Field: "merchant_pattern", Message: "a rule needs at least one condition"}
+	case r.Category != "" && !categories.Has(r.Category):
+		return &ValidationError{Field: "category", Message: fmt.Sprintf("unknown category %q", r.Category)}
+	case len(r.Merchant) > 50:
+		return &ValidationError{Field: "merchant", Message: "must be at most 50 characters"}
+	case r.Category == "" && r.Merchant == "" && len(r.Tags) == 0:
+		return &ValidationError{Field: "category", Message: "a rule needs a category, merchant or tags to assign"}
+	}
+
+	switch r.MerchantMatch {
+	case MerchantContains, MerchantExact, MerchantPrefix:
+	case MerchantRegex:
+		if _, err := regexp.Compile("(?i)" + r.MerchantPattern); err != nil {
+			return &ValidationError{Field: "merchant_pattern", Message: "is not a valid regular expression"}
+		}
+	default:
+		return &ValidationError{Field: "merchant_match", Message: fmt.Sprintf("unknown match %q", r.MerchantMatch)}
+	}
+	return nil
+}
+
+// RuleChange is what the rules do to one transaction. The New fields hold the
+// transaction's category, merchant and tags after the rules ran.
+type RuleChange struct {
+	TransactionID string    `json:"transaction_id"`
+	Date          time.Time `json:"date"`
+	Amount        Money     `json:"amount"`
+	Category      string    `json:"category"`
+	Merchant      string    `json:"merchant"`
+	Tags          []string  `json:"tags"`
+	NewCategory   string    `json:"new_category"`
+	NewMerchant   string    `json:"new_merchant"`
+	NewTags       []string  `json:"new_tags"`
+	RuleIDs       []int64   `json:"rule_ids"` // the matching rules, in the order they ran
+}
+
+// RuleRun reports a run of an account's rules over its existing transactions
+type RuleRun struct {
+	AccountID string       `json:"account_id"`
+	DryRun    bool         `json:"dry_run"`
+	Scanned   int          `json:"scanned"`
+	Changed   int          `json:"changed"`
+	Changes   []RuleChange `json:"changes"`
+}
@@ -1,12 +1,16 @@
 package types
 
-// SpendingAnalytics represents the spending analysis for an account
+// SpendingAnalytics represents the spending analysis for an account, or for
+// all accounts of an owner combined
 type SpendingAnalytics struct {
 	Account          *Account         `json:"account"`
+	Accounts         []*Account       `json:"accounts,omitempty"` // every account of a combined analysis
 	TopCategories    []CategorySpend  `json:"top_categories"`
 	SpendingPatterns []TimePattern    `json:"spending_patterns"`
 	PredictedSpending []PredictedSpend `json:"predicted_spending"`
-	TotalSpent       float64          `json:"total_spent"`
-	MonthlyAverage   float64          `json:"monthly_average"`
+	TotalSpent       Money            `json:"total_spent"`
+	MonthlyAverage   Money            `json:"monthly_average"`
+	Currency         string           `json:"currency"`             // reporting currency of the totals
+	OriginalTotals   Amounts          `json:"original_total_spent"` // total spent per original currency
 }
 
@@ -0,0 +1,59 @@
+package types
+
+import "time"
+
+// Flags raised on a subscription
+const (
+	SubscriptionPriceIncrease = "price_increase" // its price went up recently
+	SubscriptionNew           = "new"            // it was first charged recently
+	SubscriptionUnused        = "unused"         // nothing else happened at its merchant lately
+)
+
+// SubscriptionPrice is a price a subscription was charged, from Since until
+// the next price in its history
+type SubscriptionPrice struct {
+	Amount  Money     `json:"amount"`
+	Since   time.Time `json:"since"`   // the first charge at this price
+	Charges int       `json:"charges"` // charges at this price
+}
+
+// SubscriptionPriceChange is the latest change in a subscription's price
+type SubscriptionPriceChange struct {
+	From    Money     `json:"from"`
+	To      Money     `json:"to"`
+	Date    time.Time `json:"date"`    // the first charge at the new price
+	Percent float64   `json:"percent"` // the change relative to the old price
+}
+
+// Subscription is a recurring charge in the Subscription category
+type Subscription struct {
+	Merchant     string                   `json:"merchant"`
+	Currency     string                   `json:"currency"`
+	Frequency    string                   `json:"frequency"`    // one of BillFrequencies
+	Confidence   float64                  `json:"confidence"`   // as in RecurringBill; 0 when too new to tell
+	Price        Money                    `json:"price"`        // the latest charge
+	MonthlyCost  Money                    `json:"monthly_cost"` // the price spread over a month
+	Charges      int                      `json:"charges"`
+	FirstCharge  time.Time                `json:"first_charge"`
+	LastCharge   time.Time                `json:"last_charge"`
+	NextDueDate  time.Time                `json:"next_due_date"`
+	PriceHistory []SubscriptionPrice      `json:"price_history"` // oldest first
+	PriceChange  *SubscriptionPriceChange `json:"price_change,omitempty"`
+
+	// RelatedTransactions counts the other transactions at the merchant,
+	// such as purchases or refunds, and LastActivity is the latest of them
+	RelatedTransactions int        `json:"related_transactions"`
+	LastActivity        *time.Time `json:"last_activity,omitempty"`
+
+	Flags []string `json:"flags"`
+}
+
+// SubscriptionReport is an audit of an account's subscriptions charged from
+// From through To
+type SubscriptionReport struct {
+	From          string         `json:"from"` // YYYY-MM-DD
+	To            string         `json:"to"`
+	Subscriptions []Subscription `json:"subscriptions"`
+	MonthlyCost   Amounts        `json:"monthly_cost"` // per currency
+	Counts        map[string]int `json:"counts"`       // subscriptions per flag
+}
@@ -0,0 +1,96 @@
+package types
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// NormalizeTags trims and lower-cases tag names, drops duplicates and sorts
+// them. Tag names are at most 50 characters, as per the tags table in
+// migrations/sql.
+func NormalizeTags(field string, tags []string) ([]string, error) {
+	seen := make(map[string]bool, len(tags))
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		switch {
+		case tag == "":
+			return nil, &ValidationError{Field: field, Message: "tags cannot be empty"}
+		case len(tag) > 50:
+			return nil, &ValidationError{Field: field, Message: fmt.Sprintf("tag %q must be at most 50 characters", tag)}
+		case seen[tag]:
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	sort.Strings(normalized)
+	return normalized, nil
+}
+
+// TagFilter limits the transactions an endpoint returns to those that carry
+// every one of its tags. An empty filter matches every transaction.
+type TagFilter []string
+
+// ParseTagFilter reads a tag filter from the values of the tag query
+// parameter, which may be repeated or hold a comma-separated list
+func ParseTagFilter(values []string) (TagFilter, error) {
+	var tags []string
+	for _, value := range values {
+		tags = append(tags, strings.Split(value, ",")...)
+	}
+	normalized, err := NormalizeTags("tag", tags)
+	if err != nil {
+		return nil, err
+	}
+	return TagFilter(normalized), nil
+}
+
+// MaxTagChange is the most transactions a single tag change may cover
+const MaxTagChange = 1000
+
+// TagChange adds tags to or removes them from several transactions at once
+type TagChange struct {
+	TransactionIDs []string `json:"transaction_ids"`
+	Tags           []string `json:"tags"`
+}
+
+// Validate checks the change and normalizes its transaction IDs and tags
+func (c *TagChange) Validate() error {
+	if len(c.TransactionIDs) == 0 {
+		return &ValidationError{Field: "transaction_ids", Message: "is required"}
+	}
+	if len(c.TransactionIDs) > MaxTagChange {
+		return &ValidationError{Field: "transaction_ids", Message: fmt.Sprintf("at most %d transactions can be changed at once", MaxTagChange)}
+	}
+	seen := make(map[string]bool, len(c.TransactionIDs))
+	ids := make([]string, 0, len(c.TransactionIDs))
+	for _, id := range c.TransactionIDs {
+		if id == "" {
+			return &ValidationError{Field: "transaction_ids", Message: "transaction IDs cannot be empty"}
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	c.TransactionIDs = ids
+
+	if len(c.Tags) == 0 {
+		return &ValidationError{Field: "tags", Message: "is required"}
+	}
+	tags, err := NormalizeTags("tags", c.Tags)
+	if err != nil {
+		return err
+	}
+	c.Tags = tags
+	return nil
+}
+
+// TagChangeResult reports how many tags a tag change added or removed
+// across its transactions
+type TagChangeResult struct {
+	Transactions int `json:"transactions"`
+	Changed      int `json:"changed"`
+}
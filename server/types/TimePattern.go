@@ -7,5 +7,6 @@ type TimePattern struct {
 	TimeOfDay   string  `json:"timeOfDay"`
 	DayOfWeek   string  `json:"dayOfWeek"`
 	Frequency   int     `json:"frequency"`
-	AverageSpend float64 `json:"averageSpend"`
+	AverageSpend Money   `json:"averageSpend"`
+	Currency    string  `json:"currency"`
 }
\ No newline at end of file
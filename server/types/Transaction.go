@@ -1,15 +1,211 @@
 package types
 
-import "time"
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
 
-// Transaction represents a financial transaction as per init.sql schema
+// Transaction represents a financial transaction as per the database schema in migrations/sql
 type Transaction struct {
-	TransactionID string    `json:"transaction_id"` // VARCHAR(20) PRIMARY KEY
-	AccountID     string    `json:"account_id"`     // VARCHAR(20) REFERENCES users(account_id)
-	Date          time.Time `json:"date"`           // TIMESTAMP
-	Amount        float64   `json:"amount"`         // DECIMAL(10, 2)
-	Category      string    `json:"category"`       // VARCHAR(50)
-	Merchant      string    `json:"merchant"`       // VARCHAR(50)
-	Location      string    `json:"location"`       // VARCHAR(100)
+	TransactionID string    `json:"transaction_id"`  // VARCHAR(20) PRIMARY KEY
+	AccountID     string    `json:"account_id"`      // VARCHAR(20) REFERENCES users(account_id)
+	Date          time.Time `json:"date"`            // TIMESTAMP
+	Amount        Money     `json:"amount"`          // DECIMAL(10, 2)
+	Category      string    `json:"category"`        // VARCHAR(50)
+	Merchant      string    `json:"merchant"`        // VARCHAR(50)
+	Location      string    `json:"location"`        // VARCHAR(100)
+	Currency      string    `json:"currency"`        // VARCHAR(3), defaults to the account's currency
+	FITID         string    `json:"fitid,omitempty"` // VARCHAR(255), bank-assigned ID from OFX imports
 	UserPrefix    string    `json:"userPrefix,omitempty"`
-}
\ No newline at end of file
+
+	// MerchantID links the transaction to the canonical merchant its
+	// merchant description was matched to
+	MerchantID *int64 `json:"merchant_id,omitempty"` // INTEGER REFERENCES merchants(id)
+
+	// Tags label the transaction across categories, as per the tags and
+	// transaction_tags tables
+	Tags []string `json:"tags,omitempty"`
+
+	// Notes is free-form text about the transaction
+	Notes string `json:"notes,omitempty"` // VARCHAR(1000)
+
+	// Splits allocate the transaction to several categories and add up to
+	// Amount. Category totals and budgets count the splits instead of the
+	// transaction's own category.
+	Splits []TransactionSplit `json:"splits,omitempty"`
+
+	// TransferID is set when the transaction is one side of a transfer
+	// between two of an owner's accounts. Transfers are left out of analytics.
+	TransferID *int64 `json:"transfer_id,omitempty"` // INTEGER REFERENCES transfers(id)
+
+	// ConvertedAmount is Amount in the reporting currency requested by the
+	// client. It is only set on read endpoints that convert.
+	ConvertedAmount   *Money `json:"converted_amount,omitempty"`
+	ConvertedCurrency string `json:"converted_currency,omitempty"`
+}
+
+// MaxSplits is the most categories a transaction can be split across
+const MaxSplits = 20
+
+// TransactionSplit allocates part of a transaction to a category, as per the
+// transaction_splits table in migrations/sql
+type TransactionSplit struct {
+	ID       int64  `json:"id,omitempty"`   // SERIAL PRIMARY KEY
+	Category string `json:"category"`       // VARCHAR(50)
+	Amount   Money  `json:"amount"`         // DECIMAL(10, 2), with the sign of the transaction's amount
+	Memo     string `json:"memo,omitempty"` // VARCHAR(100)
+}
+
+// TransactionPatch holds the fields of a partial transaction update.
+// Nil fields are left unchanged.
+type TransactionPatch struct {
+	Date     *time.Time `json:"date"`
+	Amount   *Money     `json:"amount"`
+	Category *string    `json:"category"`
+	Merchant *string    `json:"merchant"`
+	Location *string    `json:"location"`
+	Currency *string    `json:"currency"`
+	Notes    *string    `json:"notes"`
+
+	// Splits replaces the transaction's splits; an empty list removes them
+	Splits *[]TransactionSplit `json:"splits"`
+}
+
+// Apply copies the non-nil fields of the patch onto t
+func (p *TransactionPatch) Apply(t *Transaction) {
+	if p.Date != nil {
+		t.Date = *p.Date
+	}
+	if p.Amount != nil {
+		t.Amount = *p.Amount
+	}
+	if p.Category != nil {
+		t.Category = *p.Category
+	}
+	if p.Merchant != nil {
+		t.Merchant = *p.Merchant
+	}
+	if p.Location != nil {
+		t.Location = *p.Location
+	}
+	if p.Currency != nil {
+		t.Currency = *p.Currency
+	}
+	if p.Notes != nil {
+		t.Notes = *p.Notes
+	}
+	if p.Splits != nil {
+		t.Splits = *p.Splits
+	}
+}
+
+// ValidationError reports a transaction field that failed validation
+type ValidationError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid %s: %s", e.Field, e.Message)
+}
+
+// Validate checks the transaction fields against the schema limits, the
+// account's categories and the sign convention used throughout the database:
+// income is stored as a positive amount, expenses and bills as negative ones,
+// and transfers either way.
+func (t *Transaction) Validate(categories Categories) error {
+	if t.AccountID == "" {
+		return &ValidationError{Field: "account_id", Message: "is required"}
+	}
+	if t.Date.IsZero() {
+		return &ValidationError{Field: "date", Message: "is required"}
+	}
+	if t.Date.After(time.Now().Add(24 * time.Hour)) {
+		return &ValidationError{Field: "date", Message: "cannot be in the future"}
+	}
+	if t.Date.Year() < 1900 {
+		return &ValidationError{Field: "date", Message: "is out of range"}
+	}
+	if !categories.Has(t.Category) {
+		return &ValidationError{Field: "category", Message: fmt.Sprintf("unknown category %q", t.Category)}
+	}
+	if t.Amount.IsZero() {
+		return &ValidationError{Field: "amount", Message: "cannot be zero"}
+	}
+	if !categories.FitsSign(t.Category, t.Amount) {
+		if categories.Type(t.Category) == CategoryTypeIncome {
+			return &ValidationError{Field: "amount", Message: "income must be positive"}
+		}
+		return &ValidationError{Field: "amount", Message: "spending must be negative"}
+	}
+	if t.Amount.Abs().Cents >= 1e10 {
+		return &ValidationError{Field: "amount", Message: "is out of range"}
+	}
+	if t.Merchant == "" {
+		return &ValidationError{Field: "merchant", Message: "is required"}
+	}
+	if len(t.Merchant) > 50 {
+		return &ValidationError{Field: "merchant", Message: "must be at most 50 characters"}
+	}
+	if len(t.Location) > 100 {
+		return &ValidationError{Field: "location", Message: "must be at most 100 characters"}
+	}
+	if len(t.Notes) > 1000 {
+		return &ValidationError{Field: "notes", Message: "must be at most 1000 characters"}
+	}
+	if t.Currency != "" && !IsCurrencyCode(t.Currency) {
+		return &ValidationError{Field: "currency", Message: "must be a three-letter ISO 4217 code"}
+	}
+	return t.validateSplits(categories)
+}
+
+// validateSplits checks that the splits, if any, each have a category that
+// fits the sign of the transaction and together add up to its amount
+func (t *Transaction) validateSplits(categories Categories) error {
+	if len(t.Splits) == 0 {
+		return nil
+	}
+	if len(t.Splits) < 2 {
+		return &ValidationError{Field: "splits", Message: "a transaction is split across at least two categories"}
+	}
+	if len(t.Splits) > MaxSplits {
+		return &ValidationError{Field: "splits", Message: fmt.Sprintf("a transaction is split across at most %d categories", MaxSplits)}
+	}
+
+	var total int64
+	for i, split := range t.Splits {
+		field := fmt.Sprintf("splits[%d]", i)
+		if !categories.Has(split.Category) {
+			return &ValidationError{Field: field + ".category", Message: fmt.Sprintf("unknown category %q", split.Category)}
+		}
+		if split.Amount.IsZero() {
+			return &ValidationError{Field: field + ".amount", Message: "cannot be zero"}
+		}
+		if split.Amount.Sign() != t.Amount.Sign() {
+			return &ValidationError{Field: field + ".amount", Message: "must have the same sign as the transaction's amount"}
+		}
+		if !categories.FitsSign(split.Category, split.Amount) {
+			return &ValidationError{Field: field + ".category", Message: fmt.Sprintf("%q does not fit the sign of the amount", split.Category)}
+		}
+		if len(split.Memo) > 100 {
+			return &ValidationError{Field: field + ".memo", Message: "must be at most 100 characters"}
+		}
+		total += split.Amount.Cents
+	}
+	if total != t.Amount.Cents {
+		return &ValidationError{Field: "splits", Message: fmt.Sprintf("add up to %s instead of the amount %s", NewMoney(total, "").String(), t.Amount.String())}
+	}
+	return nil
+}
+
+// NewTransactionID generates a server-side transaction ID that fits the
+// VARCHAR(20) transaction_id column
+func NewTransactionID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("failed to generate transaction ID: %v", err))
+	}
+	return "TXN" + hex.EncodeToString(b)
+}
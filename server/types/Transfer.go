@@ -0,0 +1,35 @@
+package types
+
+import "time"
+
+// Transfer sources
+const (
+	TransferSourceDetected = "detected"
+	TransferSourceManual   = "manual"
+)
+
+// Transfer statuses. A rejected transfer is kept so that detection does not
+// pair the same two transactions again.
+const (
+	TransferMatched  = "matched"
+	TransferRejected = "rejected"
+)
+
+// Transfer pairs the outgoing and incoming side of money moved between two
+// accounts of the same owner, as per the transfers table in migrations/sql.
+// Both transactions of a matched transfer are excluded from analytics.
+type Transfer struct {
+	ID                int64     `json:"id"`
+	OwnerID           int64     `json:"owner_id"`
+	FromTransactionID string    `json:"from_transaction_id"` // the negative side
+	ToTransactionID   string    `json:"to_transaction_id"`   // the positive side
+	Amount            Money     `json:"amount"`              // amount received, in Currency
+	Currency          string    `json:"currency"`
+	Score             float64   `json:"score"`  // DECIMAL(4, 3), how closely the two sides match
+	Source            string    `json:"source"` // detected or manual
+	Status            string    `json:"status"` // matched or rejected
+	CreatedAt         time.Time `json:"created_at"`
+
+	From *Transaction `json:"from,omitempty"`
+	To   *Transaction `json:"to,omitempty"`
+}
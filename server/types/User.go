@@ -1,8 +1,8 @@
 package types
 
 type UserBalance struct {
-	Current   float64 `json:"current"`
-	Available float64 `json:"available"`
+	Current   Money   `json:"current"`
+	Available Money   `json:"available"`
 	Currency  string  `json:"currency"`
 }
 
@@ -7,16 +7,30 @@ type RecurringBill struct {
 	Merchant        string    `json:"merchant"`
 	Category        string    `json:"category"`
 	MonthsPresent   int       `json:"months_present"`
-	AverageAmount   float64   `json:"average_amount"`
-	MedianAmount    float64   `json:"median_amount"`
+	AverageAmount   Money     `json:"average_amount"`
+	MedianAmount    Money     `json:"median_amount"`
 	FirstOccurrence time.Time `json:"first_occurrence"`
 	LastOccurrence  time.Time `json:"last_occurrence"`
+	Occurrences     int       `json:"occurrences"`
+	Frequency       string    `json:"frequency"`     // one of BillFrequencies
+	PeriodDays      float64   `json:"period_days"`   // median days between payments
+	JitterDays      float64   `json:"jitter_days"`   // median days a payment strays from its due date
+	DueDay          int       `json:"due_day"`       // as in Bill
+	MonthEnd        bool      `json:"month_end"`     // due on the last day of the month
+	Confidence      float64   `json:"confidence"`    // 0-1, how regular the payments are
+	NextDueDate     time.Time `json:"next_due_date"`
 }
 
 // UpcomingBill represents a predicted future bill payment
 type UpcomingBill struct {
 	Merchant        string    `json:"merchant"`
 	Category        string    `json:"category"`
-	ExpectedAmount  float64   `json:"expected_amount"`
+	ExpectedAmount  Money     `json:"expected_amount"`
 	DueDate         time.Time `json:"due_date"`
+	Source          string    `json:"source"`                // BillDeclared or BillInferred
+	BillID          *int64    `json:"bill_id,omitempty"`     // the declared Bill
+	AmountType      string    `json:"amount_type,omitempty"` // of a declared bill
+	Frequency       string    `json:"frequency"`
+	Confidence      float64   `json:"confidence"`            // of an inferred bill; 1 for a declared one
+	Autopay         bool      `json:"autopay"`
 } 
\ No newline at end of file
@@ -1,10 +1,145 @@
 package types
 
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
 // Category represents a transaction category
 type Category struct {
 	ID          string  `json:"id"`
 	Name        string  `json:"name"`
 	Description string  `json:"description"`
-	TotalSpent  float64 `json:"total_spent"`
+	Type        string  `json:"type,omitempty"`   // the category's type, see CategoryTypes
+	Parent      string  `json:"parent,omitempty"` // the parent category's name, for a subcategory
+	TotalSpent  Money   `json:"total_spent"`      // in Currency
 	Count       int     `json:"count"`
-} 
\ No newline at end of file
+	Currency    string  `json:"currency,omitempty"`
+	Original    Amounts `json:"original,omitempty"` // spending per original currency
+}
+
+// Category names used by the importers and the analytics queries
+const (
+	CategoryIncome       = "Income"
+	CategoryBillPayment  = "Bill Payment"
+	CategorySubscription = "Subscription"
+	CategoryTransfer     = "Transfer"
+	CategoryOther        = "Other"
+)
+
+// Category types decide how the transactions of a category are counted.
+// Queries select transactions by the type of their category rather than by
+// its name, so an account's own categories count like the built-in ones.
+const (
+	// CategoryTypeExpense is spending; its transactions are negative
+	CategoryTypeExpense = "expense"
+
+	// CategoryTypeIncome is earnings; its transactions are positive
+	CategoryTypeIncome = "income"
+
+	// CategoryTypeBill is recurring spending such as bills and subscriptions;
+	// its transactions are negative and also count as spending
+	CategoryTypeBill = "bill"
+
+	// CategoryTypeTransfer is money moved between accounts; its transactions
+	// have either sign and are left out of spending and income like linked
+	// transfers
+	CategoryTypeTransfer = "transfer"
+)
+
+// CategoryTypes lists the valid category types
+var CategoryTypes = map[string]bool{
+	CategoryTypeExpense:  true,
+	CategoryTypeIncome:   true,
+	CategoryTypeBill:     true,
+	CategoryTypeTransfer: true,
+}
+
+// CategoryDefinition is a category transactions can be filed under, as per
+// the categories table in migrations/sql: either a built-in category shared
+// by every account or one an account defined for itself. A subcategory has
+// the type of its parent.
+type CategoryDefinition struct {
+	ID        int64     `json:"id"`
+	AccountID string    `json:"account_id,omitempty"` // empty for built-in categories
+	Name      string    `json:"name"`                 // VARCHAR(50), unique among the account's and the built-in categories
+	ParentID  *int64    `json:"parent_id,omitempty"`
+	Type      string    `json:"type"` // one of CategoryTypes; defaults to the parent's
+	BuiltIn   bool      `json:"built_in"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Validate normalizes and checks the fields a client can set. The parent is
+// checked by the categories service, which knows the account's categories.
+func (c *CategoryDefinition) Validate() error {
+	c.Name = strings.TrimSpace(c.Name)
+	c.Type = strings.ToLower(strings.TrimSpace(c.Type))
+
+	switch {
+	case c.Name == "":
+		return &ValidationError{Field: "name", Message: "is required"}
+	case len(c.Name) > 50:
+		return &ValidationError{Field: "name", Message: "must be at most 50 characters"}
+	case c.Type == "" && c.ParentID == nil:
+		return &ValidationError{Field: "type", Message: "is required for a top-level category"}
+	case c.Type != "" && !CategoryTypes[c.Type]:
+		return &ValidationError{Field: "type", Message: fmt.Sprintf("unknown type %q", c.Type)}
+	}
+	return nil
+}
+
+// Categories is the set of categories an account can file transactions
+// under, by name
+type Categories map[string]CategoryDefinition
+
+// Has reports whether name is one of the categories
+func (c Categories) Has(name string) bool {
+	_, ok := c[name]
+	return ok
+}
+
+// Type returns the type of the named category, or "" when it is unknown
+func (c Categories) Type(name string) string {
+	return c[name].Type
+}
+
+// FitsSign reports whether a transaction of the given amount may be filed
+// under the named category: income is positive, expenses and bills are
+// negative and transfers go either way
+func (c Categories) FitsSign(name string, amount Money) bool {
+	switch c.Type(name) {
+	case CategoryTypeIncome:
+		return amount.Sign() > 0
+	case CategoryTypeTransfer:
+		return true
+	case "":
+		return false
+	default:
+		return amount.Sign() < 0
+	}
+}
+
+// BuiltInCategories are the categories every account starts with. They are
+// seeded into the categories table by migrations/sql/0014_categories.up.sql.
+var BuiltInCategories = Categories{
+	CategoryIncome:       {Name: CategoryIncome, Type: CategoryTypeIncome, BuiltIn: true},
+	CategoryBillPayment:  {Name: CategoryBillPayment, Type: CategoryTypeBill, BuiltIn: true},
+	CategorySubscription: {Name: CategorySubscription, Type: CategoryTypeBill, BuiltIn: true},
+	CategoryTransfer:     {Name: CategoryTransfer, Type: CategoryTypeTransfer, BuiltIn: true},
+	CategoryOther:        {Name: CategoryOther, Type: CategoryTypeExpense, BuiltIn: true},
+	"Rent":               {Name: "Rent", Type: CategoryTypeExpense, BuiltIn: true},
+	"Utilities":          {Name: "Utilities", Type: CategoryTypeExpense, BuiltIn: true},
+	"Groceries":          {Name: "Groceries", Type: CategoryTypeExpense, BuiltIn: true},
+	"Dining":             {Name: "Dining", Type: CategoryTypeExpense, BuiltIn: true},
+	"Fast Food":          {Name: "Fast Food", Type: CategoryTypeExpense, BuiltIn: true},
+	"Food Delivery":      {Name: "Food Delivery", Type: CategoryTypeExpense, BuiltIn: true},
+	"Transportation":     {Name: "Transportation", Type: CategoryTypeExpense, BuiltIn: true},
+	"Entertainment":      {Name: "Entertainment", Type: CategoryTypeExpense, BuiltIn: true},
+	"Shopping":           {Name: "Shopping", Type: CategoryTypeExpense, BuiltIn: true},
+	"Home":               {Name: "Home", Type: CategoryTypeExpense, BuiltIn: true},
+	"Books":              {Name: "Books", Type: CategoryTypeExpense, BuiltIn: true},
+	"Electronics":        {Name: "Electronics", Type: CategoryTypeExpense, BuiltIn: true},
+	"Clothing":           {Name: "Clothing", Type: CategoryTypeExpense, BuiltIn: true},
+}